@@ -0,0 +1,52 @@
+/**
+ * Copyright 2022 IBM Corp.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package provider ...
+package provider
+
+import (
+	"errors"
+	"time"
+
+	"github.com/IBM/ibmcloud-volume-interface/lib/provider"
+	vpciam "github.com/IBM/ibmcloud-volume-vpc/common/iam"
+	userError "github.com/IBM/ibmcloud-volume-vpc/common/messages"
+	"go.uber.org/zap"
+)
+
+// tokenAccountID returns the account of the access token of the session. The account must be the one of the config
+// and of the credentials, a token of another account fails the session instead of the volume requests.
+// Tokens which are not JWTs can not be verified, the account of the credentials is used.
+func (vpcp *VPCBlockProvider) tokenAccountID(contextCredentials provider.ContextCredentials, token string, ctxLogger *zap.Logger) (string, error) {
+	claims, err := vpciam.ParseAccessTokenClaims(token)
+	if err != nil {
+		ctxLogger.Debug("Access token claims not readable, the account is not verified", zap.Error(err))
+		return contextCredentials.IAMAccountID, nil
+	}
+	ctxLogger.Info("Access token of the session", zap.String("iamID", claims.IAMID), zap.String("account", claims.Account.BSS), zap.Time("expiry", time.Unix(claims.Exp, 0)))
+
+	accountID := claims.Account.BSS
+	if accountID == "" {
+		return contextCredentials.IAMAccountID, nil
+	}
+	for _, expected := range []string{vpcp.Config.AccountID, contextCredentials.IAMAccountID} {
+		if expected != "" && expected != accountID {
+			ctxLogger.Error("Access token belongs to another account", zap.String("account", accountID), zap.String("expected", expected))
+			return "", userError.GetUserError(userError.AccountMismatch, errors.New("access token account mismatch"), accountID, expected)
+		}
+	}
+	return accountID, nil
+}
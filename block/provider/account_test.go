@@ -0,0 +1,97 @@
+/**
+ * Copyright 2022 IBM Corp.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package provider ...
+package provider
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/IBM/ibmcloud-volume-interface/lib/provider"
+	userError "github.com/IBM/ibmcloud-volume-vpc/common/messages"
+	"github.com/IBM/ibmcloud-volume-vpc/common/vpcclient/riaas/fakes"
+	"github.com/stretchr/testify/assert"
+)
+
+// testAccountToken returns an unsigned access token of the account
+func testAccountToken(account string) string {
+	payload := fmt.Sprintf(`{"iam_id":"iam-ServiceId-1","exp":%d,"account":{"bss":%q}}`, time.Now().Add(time.Hour).Unix(), account)
+	return "eyJhbGciOiJSUzI1NiJ9." + base64.RawURLEncoding.EncodeToString([]byte(payload)) + ".c2lnbmF0dXJl"
+}
+
+func TestOpenSessionAccount(t *testing.T) {
+	logger, teardown := GetTestLogger(t)
+	defer teardown()
+	userError.MessagesEn = userError.InitMessages()
+
+	testCases := []struct {
+		name              string
+		configAccountID   string
+		credentials       provider.ContextCredentials
+		expectedAccountID string
+		expectedErr       string
+	}{
+		{
+			name:              "account of the token",
+			credentials:       provider.ContextCredentials{AuthType: provider.IAMAccessToken, Credential: testAccountToken("account-1")},
+			expectedAccountID: "account-1",
+		}, {
+			name:              "account of the config",
+			configAccountID:   "account-1",
+			credentials:       provider.ContextCredentials{AuthType: provider.IAMAccessToken, Credential: testAccountToken("account-1"), IAMAccountID: "account-1"},
+			expectedAccountID: "account-1",
+		}, {
+			name:            "token of another account than the config",
+			configAccountID: "account-1",
+			credentials:     provider.ContextCredentials{AuthType: provider.IAMAccessToken, Credential: testAccountToken("account-2")},
+			expectedErr:     userError.AccountMismatch,
+		}, {
+			name:        "token of another account than the credentials",
+			credentials: provider.ContextCredentials{AuthType: provider.IAMAccessToken, Credential: testAccountToken("account-2"), IAMAccountID: "account-1"},
+			expectedErr: userError.AccountMismatch,
+		}, {
+			name:              "opaque token",
+			configAccountID:   "account-1",
+			credentials:       provider.ContextCredentials{AuthType: provider.IAMAccessToken, Credential: TestProviderAccessToken, IAMAccountID: TestIKSAccountID},
+			expectedAccountID: TestIKSAccountID,
+		},
+	}
+
+	for _, testcase := range testCases {
+		t.Run(testcase.name, func(t *testing.T) {
+			vpcp, _ := GetTestProvider(t, logger)
+			cp := &fakes.RegionalAPIClientProvider{}
+			cp.NewReturns(&fakes.RegionalAPI{}, nil)
+			vpcp.ClientProvider = cp
+			vpcp.Config.AccountID = testcase.configAccountID
+
+			sessn, err := vpcp.OpenSession(context.Background(), testcase.credentials, logger)
+			if testcase.expectedErr != "" {
+				assert.Equal(t, testcase.expectedErr, userError.GetUserErrorCode(err))
+				assert.Equal(t, 0, cp.NewCallCount())
+				return
+			}
+			assert.Nil(t, err)
+			vpcSession := sessn.(*VPCSession)
+			assert.Equal(t, testcase.expectedAccountID, vpcSession.VPCAccountID)
+			assert.Equal(t, testcase.expectedAccountID, cp.NewArgsForCall(0).AccountID)
+		})
+	}
+}
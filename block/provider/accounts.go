@@ -0,0 +1,230 @@
+/**
+ * Copyright 2022 IBM Corp.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package provider ...
+package provider
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/IBM/ibmcloud-volume-interface/lib/provider"
+	"github.com/IBM/ibmcloud-volume-interface/provider/local"
+	vpcconfig "github.com/IBM/ibmcloud-volume-vpc/block/vpcconfig"
+	vpcauth "github.com/IBM/ibmcloud-volume-vpc/common/auth"
+	userError "github.com/IBM/ibmcloud-volume-vpc/common/messages"
+	"github.com/IBM/ibmcloud-volume-vpc/common/registry"
+	vpcclient "github.com/IBM/ibmcloud-volume-vpc/common/vpcclient/client"
+	"go.uber.org/zap"
+)
+
+// accountContextKey is the context key of the account of a request
+type accountContextKey struct{}
+
+// WithAccount returns a context naming the account or resource group whose credentials open the sessions
+func WithAccount(ctx context.Context, account string) context.Context {
+	return context.WithValue(ctx, accountContextKey{}, account)
+}
+
+// AccountFromContext returns the account or resource group named by the context, see WithAccount
+func AccountFromContext(ctx context.Context) (string, bool) {
+	if ctx == nil {
+		return "", false
+	}
+	account, ok := ctx.Value(accountContextKey{}).(string)
+	return account, ok && account != ""
+}
+
+// CredentialSet holds the credentials of the tenant accounts, keyed by both their account and resource group
+type CredentialSet struct {
+	accounts []vpcconfig.AccountConfig
+	byKey    map[string]int
+}
+
+// NewCredentialSet validates the credentials of the accounts. Each account needs an API key and an account or
+// resource group, which must not name other credentials.
+func NewCredentialSet(accounts []vpcconfig.AccountConfig) (*CredentialSet, error) {
+	cs := &CredentialSet{byKey: map[string]int{}}
+	for i, account := range accounts {
+		if account.APIKey == "" {
+			return nil, fmt.Errorf("no API key for the account %d", i)
+		}
+		if account.AccountID == "" && account.ResourceGroupID == "" {
+			return nil, fmt.Errorf("no account or resource group for the account %d", i)
+		}
+		for _, key := range []string{account.AccountID, account.ResourceGroupID} {
+			if key == "" {
+				continue
+			}
+			other, ok := cs.byKey[key]
+			if !ok {
+				// The account of several resource groups names the credentials of the first one
+				cs.byKey[key] = len(cs.accounts)
+			} else if !sameAccount(cs.accounts[other], account) {
+				return nil, fmt.Errorf("account or resource group %q has several credentials", key)
+			}
+		}
+		cs.accounts = append(cs.accounts, account)
+	}
+	return cs, nil
+}
+
+// sameAccount tells if the credentials are the ones of resource groups of the same account
+func sameAccount(a, b vpcconfig.AccountConfig) bool {
+	return a.AccountID != "" && a.AccountID == b.AccountID && a.ResourceGroupID != b.ResourceGroupID
+}
+
+// Lookup returns the credentials of the account or resource group
+func (cs *CredentialSet) Lookup(key string) (vpcconfig.AccountConfig, bool) {
+	if cs == nil {
+		return vpcconfig.AccountConfig{}, false
+	}
+	i, ok := cs.byKey[key]
+	if !ok {
+		return vpcconfig.AccountConfig{}, false
+	}
+	return cs.accounts[i], true
+}
+
+// Keys returns the sorted accounts and resource groups of the credentials
+func (cs *CredentialSet) Keys() []string {
+	keys := make([]string, 0, len(cs.byKey))
+	for key := range cs.byKey {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// accountProviders are the providers of the tenant accounts, derived on first use. They are shared by the copies
+// of the provider.
+type accountProviders struct {
+	credentials *CredentialSet
+
+	mu        sync.Mutex
+	providers map[vpcconfig.AccountConfig]*VPCBlockProvider
+}
+
+// AccountProvider returns the provider of the account or resource group of the multi-account mode. It shares the
+// HTTP transport, token generator and settings of the provider, and has its own credentials, session pool, read
+// cache, resource group name cache and volume poller. Rate limiters are shared by the sessions of the same account only.
+func (vpcp *VPCBlockProvider) AccountProvider(key string) (*VPCBlockProvider, error) {
+	if vpcp.accounts == nil {
+		return nil, userError.GetUserError(userError.AccountNotConfigured, errors.New("multi-account mode is not enabled for the provider"), key)
+	}
+	account, ok := vpcp.accounts.credentials.Lookup(key)
+	if !ok {
+		return nil, userError.GetUserError(userError.AccountNotConfigured, errors.New("no credentials for the account"), key)
+	}
+
+	vpcp.accounts.mu.Lock()
+	defer vpcp.accounts.mu.Unlock()
+	if accountProvider, ok := vpcp.accounts.providers[account]; ok {
+		return accountProvider, nil
+	}
+	accountProvider := vpcp.newAccountProvider(key, account)
+	vpcp.accounts.providers[account] = accountProvider
+	return accountProvider, nil
+}
+
+// newAccountProvider derives the provider of the account credentials
+func (vpcp *VPCBlockProvider) newAccountProvider(key string, account vpcconfig.AccountConfig) *VPCBlockProvider {
+	conf := *vpcp.Config
+	vpcConfig := *vpcp.Config.VPCConfig
+	vpcConfig.G2APIKey = account.APIKey
+	vpcConfig.APIKey = account.APIKey
+	if account.ResourceGroupID != "" {
+		vpcConfig.G2ResourceGroupID = account.ResourceGroupID
+	}
+	conf.VPCConfig = &vpcConfig
+	conf.AccountID = account.AccountID
+	conf.Accounts = nil
+
+	derived := *vpcp
+	derived.Config = &conf
+	derived.APIConfig.ResourceGroup = vpcConfig.G2ResourceGroupID
+	derived.APIConfig.Coalescer = vpcclient.NewCoalescer()
+	derived.accounts = nil
+	derived.accountKey = key
+	derived.poller = NewVolumePoller(VolumePollerConfig{})
+	derived.readCache = newReadCache()
+	derived.sessionPool = newSessionPool()
+	derived.resourceGroupNames = newResourceGroupCache(resourceGroupCacheTTL)
+	// API key of the account is exchanged with IAM, the one of the secret store is the one of the provider.
+	// Building the factory can not fail, unlike the one of the provider reading its secret.
+	derived.ContextCF = vpcauth.NewAPIKeyContextCredentialsFactory(&conf, vpcp.httpClient)
+	derived.credentials = &credentialsHolder{
+		newContextCF: func(conf *vpcconfig.VPCBlockConfig) (local.ContextCredentialsFactory, error) {
+			return vpcauth.NewAPIKeyContextCredentialsFactory(conf, vpcp.httpClient), nil
+		},
+	}
+	return &derived
+}
+
+// openAccountSession opens the session of the account named by the context, with the credentials of the account
+func (vpcp *VPCBlockProvider) openAccountSession(ctx context.Context, account string, ctxLogger *zap.Logger) (provider.Session, error) {
+	accountProvider, err := vpcp.AccountProvider(account)
+	if err != nil {
+		ctxLogger.Error("No provider for the account of the request", zap.String("account", account), zap.Error(err))
+		return nil, err
+	}
+	ctxLogger.Info("Opening the session of the account", zap.String("account", account))
+	return accountProvider.OpenSession(ctx, provider.ContextCredentials{}, ctxLogger)
+}
+
+// accountCredentials exchanges the API key of the account provider for the credentials of a session, until the
+// context of the session is done
+func (vpcp *VPCBlockProvider) accountCredentials(ctx context.Context, ctxLogger *zap.Logger) (provider.ContextCredentials, error) {
+	ccf, err := vpcp.ContextCredentialsFactory(nil)
+	if err != nil {
+		return provider.ContextCredentials{}, err
+	}
+	if ccf == nil {
+		return provider.ContextCredentials{}, userError.GetUserError(userError.AccountNotConfigured, errors.New("no context credentials factory for the account"), vpcp.accountKey)
+	}
+	contextCredentials, err := forIAMAccessToken(ctx, ccf, vpcp.APIKey(), ctxLogger)
+	if err != nil {
+		ctxLogger.Error("Failed to get the access token of the account", zap.String("account", vpcp.accountKey), zap.Error(err))
+		return provider.ContextCredentials{}, err
+	}
+	return contextCredentials, nil
+}
+
+// AccountProviderID returns the registry ID of the provider of the account or resource group
+func AccountProviderID(providerID string, account string) string {
+	return providerID + "/" + account
+}
+
+// RegisterAccountProviders registers the provider of each account and resource group of the multi-account mode,
+// see AccountProviderID. Account providers open the sessions of empty credentials with the ones of their account.
+func (vpcp *VPCBlockProvider) RegisterAccountProviders(providers registry.Providers, providerID string, logger *zap.Logger) error {
+	if vpcp.accounts == nil {
+		return nil
+	}
+	for _, key := range vpcp.accounts.credentials.Keys() {
+		accountProvider, err := vpcp.AccountProvider(key)
+		if err != nil {
+			logger.Error("Failed to initialize the provider of the account", zap.String("account", key), zap.Error(err))
+			return err
+		}
+		providers.Register(AccountProviderID(providerID, key), accountProvider)
+	}
+	logger.Info("Registered the account providers", zap.String("providerID", providerID), zap.Strings("accounts", vpcp.accounts.credentials.Keys()))
+	return nil
+}
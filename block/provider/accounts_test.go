@@ -0,0 +1,257 @@
+/**
+ * Copyright 2022 IBM Corp.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package provider ...
+package provider
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/IBM/ibmcloud-volume-interface/lib/provider"
+	vpcconfig "github.com/IBM/ibmcloud-volume-vpc/block/vpcconfig"
+	vpciam "github.com/IBM/ibmcloud-volume-vpc/common/iam"
+	userError "github.com/IBM/ibmcloud-volume-vpc/common/messages"
+	"github.com/IBM/ibmcloud-volume-vpc/common/registry"
+	"github.com/IBM/ibmcloud-volume-vpc/common/vpcclient/riaas/fakes"
+)
+
+// testAccounts are the credentials of two accounts, the second one with two resource groups
+var testAccounts = []vpcconfig.AccountConfig{
+	{AccountID: "account-1", APIKey: "apikey-1"},
+	{AccountID: "account-2", ResourceGroupID: "rg-2a", APIKey: "apikey-2a"},
+	{AccountID: "account-2", ResourceGroupID: "rg-2b", APIKey: "apikey-2b"},
+}
+
+func TestNewCredentialSet(t *testing.T) {
+	testCases := []struct {
+		name          string
+		accounts      []vpcconfig.AccountConfig
+		key           string
+		expectedKey   string
+		expectedFound bool
+		expectErr     bool
+	}{
+		{
+			name:          "account",
+			accounts:      testAccounts,
+			key:           "account-1",
+			expectedKey:   "apikey-1",
+			expectedFound: true,
+		}, {
+			name:          "resource group",
+			accounts:      testAccounts,
+			key:           "rg-2b",
+			expectedKey:   "apikey-2b",
+			expectedFound: true,
+		}, {
+			name:          "account of several resource groups",
+			accounts:      testAccounts,
+			key:           "account-2",
+			expectedKey:   "apikey-2a",
+			expectedFound: true,
+		}, {
+			name:     "unknown account",
+			accounts: testAccounts,
+			key:      "account-3",
+		}, {
+			name:      "no API key",
+			accounts:  []vpcconfig.AccountConfig{{AccountID: "account-1"}},
+			expectErr: true,
+		}, {
+			name:      "no account or resource group",
+			accounts:  []vpcconfig.AccountConfig{{APIKey: "apikey-1"}},
+			expectErr: true,
+		}, {
+			name:      "resource group of several accounts",
+			accounts:  []vpcconfig.AccountConfig{{AccountID: "account-1", ResourceGroupID: "rg-1", APIKey: "apikey-1"}, {AccountID: "account-2", ResourceGroupID: "rg-1", APIKey: "apikey-2"}},
+			expectErr: true,
+		}, {
+			name:      "account with several credentials",
+			accounts:  []vpcconfig.AccountConfig{{AccountID: "account-1", APIKey: "apikey-1"}, {AccountID: "account-1", APIKey: "apikey-2"}},
+			expectErr: true,
+		},
+	}
+
+	for _, testcase := range testCases {
+		t.Run(testcase.name, func(t *testing.T) {
+			cs, err := NewCredentialSet(testcase.accounts)
+			if testcase.expectErr {
+				assert.NotNil(t, err)
+				return
+			}
+			require.Nil(t, err)
+			account, found := cs.Lookup(testcase.key)
+			assert.Equal(t, testcase.expectedFound, found)
+			assert.Equal(t, testcase.expectedKey, account.APIKey)
+		})
+	}
+}
+
+// testAccountsProvider returns a test provider of the accounts, whose API keys are exchanged with the IAM server
+func testAccountsProvider(t *testing.T, iamURL string) *VPCBlockProvider {
+	logger, teardown := GetTestLogger(t)
+	t.Cleanup(teardown)
+	vpcp, _ := GetTestProvider(t, logger)
+	vpcp.Config.VPCConfig.G2TokenExchangeURL = iamURL
+	vpcp.Config.VPCConfig.G2ResourceGroupID = "rg-default"
+	vpcp.httpClient = http.DefaultClient
+	credentialSet, err := NewCredentialSet(testAccounts)
+	require.Nil(t, err)
+	vpcp.accounts = &accountProviders{credentials: credentialSet, providers: map[vpcconfig.AccountConfig]*VPCBlockProvider{}}
+	return vpcp
+}
+
+func TestOpenAccountSession(t *testing.T) {
+	logger, teardown := GetTestLogger(t)
+	defer teardown()
+	userError.MessagesEn = userError.InitMessages()
+
+	// IAM server issues the tokens of the account of each API key
+	exchanges := map[string]int{}
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = r.ParseForm()
+		apiKey := r.PostForm.Get("apikey")
+		exchanges[apiKey]++
+		account := map[string]string{"apikey-1": "account-1", "apikey-2a": "account-2", "apikey-2b": "account-2"}[apiKey]
+		fmt.Fprintf(w, `{"access_token":%q}`, testAccountToken(account))
+	}))
+	defer s.Close()
+
+	testCases := []struct {
+		name                  string
+		account               string
+		expectedAccountID     string
+		expectedResourceGroup string
+		expectedErr           string
+	}{
+		{
+			name:                  "account",
+			account:               "account-1",
+			expectedAccountID:     "account-1",
+			expectedResourceGroup: "rg-default",
+		}, {
+			name:                  "resource group",
+			account:               "rg-2b",
+			expectedAccountID:     "account-2",
+			expectedResourceGroup: "rg-2b",
+		}, {
+			name:        "unknown account",
+			account:     "account-3",
+			expectedErr: userError.AccountNotConfigured,
+		},
+	}
+
+	for _, testcase := range testCases {
+		t.Run(testcase.name, func(t *testing.T) {
+			vpcp := testAccountsProvider(t, s.URL)
+			cp := &fakes.RegionalAPIClientProvider{}
+			cp.NewReturns(&fakes.RegionalAPI{}, nil)
+			vpcp.ClientProvider = cp
+
+			// Credentials of the request are the ones of the account of the context
+			ctx := WithAccount(context.Background(), testcase.account)
+			sessn, err := vpcp.OpenSession(ctx, provider.ContextCredentials{AuthType: provider.IAMAccessToken, Credential: TestProviderAccessToken}, logger)
+			if testcase.expectedErr != "" {
+				assert.Equal(t, testcase.expectedErr, userError.GetUserErrorCode(err))
+				return
+			}
+			require.Nil(t, err)
+			assert.Equal(t, testcase.expectedAccountID, sessn.(*VPCSession).VPCAccountID)
+			assert.Equal(t, testcase.expectedAccountID, cp.NewArgsForCall(0).AccountID)
+			assert.Equal(t, testcase.expectedResourceGroup, cp.NewArgsForCall(0).ResourceGroup)
+			assert.Equal(t, testcase.expectedResourceGroup, sessn.(*VPCSession).Config.VPCConfig.G2ResourceGroupID)
+		})
+	}
+
+	// Each account exchanges its own API key, the default one is never exchanged
+	assert.Equal(t, map[string]int{"apikey-1": 1, "apikey-2b": 1}, exchanges)
+}
+
+func TestOpenAccountSessionCancelled(t *testing.T) {
+	logger, teardown := GetTestLogger(t)
+	defer teardown()
+
+	// IAM server is unreachable, the exchange of the API key of the account retries the connection errors
+	s := httptest.NewServer(http.NotFoundHandler())
+	s.Close()
+	vpcp := testAccountsProvider(t, s.URL)
+	cp := &fakes.RegionalAPIClientProvider{}
+	cp.NewReturns(&fakes.RegionalAPI{}, nil)
+	vpcp.ClientProvider = cp
+
+	// Cancelled request does not wait for the remaining attempts
+	ctx, cancel := context.WithCancel(WithAccount(context.Background(), "account-1"))
+	defer cancel()
+	time.AfterFunc(100*time.Millisecond, cancel)
+	start := time.Now()
+	_, err := vpcp.OpenSession(ctx, provider.ContextCredentials{}, logger)
+	assert.True(t, vpciam.IsExchangeCancelled(err), "error: %v", err)
+	assert.Less(t, int64(time.Since(start)), int64(2*time.Second))
+	assert.Equal(t, 0, cp.NewCallCount())
+}
+
+func TestAccountProviderIsolation(t *testing.T) {
+	vpcp := testAccountsProvider(t, "")
+
+	first, err := vpcp.AccountProvider("rg-2a")
+	require.Nil(t, err)
+	again, err := vpcp.AccountProvider("account-2")
+	require.Nil(t, err)
+	other, err := vpcp.AccountProvider("rg-2b")
+	require.Nil(t, err)
+
+	// Account and its first resource group are the same credentials
+	assert.Same(t, first, again)
+	assert.NotSame(t, first, other)
+	assert.Equal(t, "apikey-2a", first.APIKey())
+	assert.Equal(t, "apikey-2b", other.APIKey())
+	assert.Equal(t, IamClientSecret, vpcp.APIKey())
+
+	// Transport is shared, the token caches are not
+	assert.Same(t, vpcp.httpClient, first.httpClient)
+	assert.NotSame(t, first.sessionPool, other.sessionPool)
+	assert.NotSame(t, first.readCache, other.readCache)
+	assert.NotSame(t, first.ContextCF, other.ContextCF)
+	assert.Nil(t, first.accounts)
+}
+
+func TestRegisterAccountProviders(t *testing.T) {
+	logger, teardown := GetTestLogger(t)
+	defer teardown()
+	vpcp := testAccountsProvider(t, "")
+
+	providers := &registry.ProviderRegistry{}
+	assert.Nil(t, vpcp.RegisterAccountProviders(providers, "vpc-block", logger))
+	for _, key := range []string{"account-1", "account-2", "rg-2a", "rg-2b"} {
+		prov, err := providers.Get(AccountProviderID("vpc-block", key))
+		assert.Nil(t, err)
+		assert.NotNil(t, prov)
+	}
+	_, err := providers.Get("vpc-block/account-3")
+	assert.NotNil(t, err)
+
+	// Provider without accounts registers none
+	vpcp.accounts = nil
+	assert.Nil(t, vpcp.RegisterAccountProviders(providers, "other", logger))
+}
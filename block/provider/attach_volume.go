@@ -35,14 +35,16 @@ const (
 	StatusAttached      = "attached"
 	StatusAttaching     = "attaching"
 	StatusDetaching     = "detaching"
+	StatusDeleting      = "deleting"
 )
 
 // AttachVolume attach volume based on given volume attachment request
-func (vpcs *VPCSession) AttachVolume(volumeAttachmentRequest provider.VolumeAttachmentRequest) (*provider.VolumeAttachmentResponse, error) {
+func (vpcs *VPCSession) AttachVolume(volumeAttachmentRequest provider.VolumeAttachmentRequest) (_ *provider.VolumeAttachmentResponse, err error) {
 	vpcs.Logger.Debug("Entry of AttachVolume method...")
 	defer vpcs.Logger.Debug("Exit from AttachVolume method...")
+	vpcs, end := vpcs.trace("AttachVolume", attachmentAttributes(volumeAttachmentRequest)...)
+	defer func() { end(err) }()
 	defer metrics.UpdateDurationFromStart(vpcs.Logger, "AttachVolume", time.Now())
-	var err error
 
 	//check if ServiceSession is valid
 	if err = isValidServiceSession(vpcs); err != nil {
@@ -63,6 +65,20 @@ func (vpcs *VPCSession) AttachVolume(volumeAttachmentRequest provider.VolumeAtta
 	}
 	volumeAttachment := models.NewVolumeAttachment(volumeAttachmentRequest)
 
+	// Hold the volume lease (if enabled) so that other replicas do not mutate the volume concurrently.
+	// On success it is kept till the end of the wait for the volume, see HoldVolumeLease.
+	lease, err := vpcs.acquireSessionVolumeLease(volumeAttachmentRequest.VolumeID)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if err != nil {
+			lease.Release()
+			return
+		}
+		vpcs.HoldVolumeLease(lease)
+	}()
+
 	err = vpcs.APIRetry.FlexyRetry(vpcs.Logger, func() (error, bool) {
 		// First , check if volume is already attached or attaching to given instance
 		vpcs.Logger.Info("Checking if volume is already attached by other thread")
@@ -84,7 +100,7 @@ func (vpcs *VPCSession) AttachVolume(volumeAttachmentRequest provider.VolumeAtta
 	})
 
 	if err != nil {
-		userErr := userError.GetUserError(string(userError.VolumeAttachFailed), err, volumeAttachmentRequest.VolumeID, volumeAttachmentRequest.InstanceID)
+		userErr := GetBackendUserError(string(userError.VolumeAttachFailed), err, volumeAttachmentRequest.VolumeID, volumeAttachmentRequest.InstanceID)
 		return nil, userErr
 	}
 	vpcs.Logger.Info("Successfully attached volume from VPC provider", zap.Reflect("volumeResponse", varp))
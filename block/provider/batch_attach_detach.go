@@ -0,0 +1,250 @@
+/**
+ * Copyright 2022 IBM Corp.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package provider ...
+package provider
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/IBM/ibmcloud-volume-interface/lib/metrics"
+	"github.com/IBM/ibmcloud-volume-interface/lib/provider"
+	userError "github.com/IBM/ibmcloud-volume-vpc/common/messages"
+	"github.com/IBM/ibmcloud-volume-vpc/common/vpcclient/models"
+	"go.uber.org/zap"
+)
+
+// DefaultBatchWorkers is the number of instances processed in parallel by AttachVolumes and DetachVolumes
+const DefaultBatchWorkers = 4
+
+// VolumeAttachmentResult is the per item result of AttachVolumes and DetachVolumes
+type VolumeAttachmentResult struct {
+	Request  provider.VolumeAttachmentRequest
+	Response *provider.VolumeAttachmentResponse // Attached volume details, nil for detach
+	Error    error
+}
+
+// AttachVolumes attaches many volumes and waits till all of them are attached. Requests for
+// the same instance are processed in order, different instances in parallel by up to
+// BatchWorkers workers. Results are returned in the order of the requests.
+func (vpcs *VPCSession) AttachVolumes(volumeAttachmentRequests []provider.VolumeAttachmentRequest) []VolumeAttachmentResult {
+	vpcs.Logger.Debug("Entry of AttachVolumes method...", zap.Int("count", len(volumeAttachmentRequests)))
+	defer vpcs.Logger.Debug("Exit from AttachVolumes method...")
+	vpcs, end := vpcs.trace("AttachVolumes")
+	defer end(nil)
+	defer metrics.UpdateDurationFromStart(vpcs.Logger, "AttachVolumes", time.Now())
+
+	// Leases of the items are held by the batch itself, so that it releases only the ones it acquired
+	vpcs = vpcs.withBatchLeases()
+	results := vpcs.runBatch(volumeAttachmentRequests, func(result *VolumeAttachmentResult) {
+		result.Response, result.Error = vpcs.AttachVolume(result.Request)
+	})
+	vpcs.waitForBatch(results, true)
+	vpcs.releaseBatchLeases()
+	return results
+}
+
+// DetachVolumes detaches many volumes and waits till all of them are detached. Requests for
+// the same instance are processed in order, different instances in parallel by up to
+// BatchWorkers workers. Results are returned in the order of the requests.
+func (vpcs *VPCSession) DetachVolumes(volumeAttachmentRequests []provider.VolumeAttachmentRequest) []VolumeAttachmentResult {
+	vpcs.Logger.Debug("Entry of DetachVolumes method...", zap.Int("count", len(volumeAttachmentRequests)))
+	defer vpcs.Logger.Debug("Exit from DetachVolumes method...")
+	vpcs, end := vpcs.trace("DetachVolumes")
+	defer end(nil)
+	defer metrics.UpdateDurationFromStart(vpcs.Logger, "DetachVolumes", time.Now())
+
+	// Leases of the items are held by the batch itself, so that it releases only the ones it acquired
+	vpcs = vpcs.withBatchLeases()
+	results := vpcs.runBatch(volumeAttachmentRequests, func(result *VolumeAttachmentResult) {
+		_, result.Error = vpcs.DetachVolume(result.Request) //nolint:bodyclose
+	})
+	vpcs.waitForBatch(results, false)
+	vpcs.releaseBatchLeases()
+	return results
+}
+
+// attachmentFailed tells if an attachment waited to get attached is being removed instead
+func attachmentFailed(volumeAttachment models.VolumeAttachment) bool {
+	return volumeAttachment.Status == StatusDetaching || volumeAttachment.Status == StatusDeleting
+}
+
+// attachmentFailedError is the error of an attach wait which found the attachment in a failed state
+func attachmentFailedError(request provider.VolumeAttachmentRequest, volumeAttachment models.VolumeAttachment) error {
+	return userError.GetUserError(string(userError.VolumeAttachFailed), fmt.Errorf("volume attachment '%s' is %s", volumeAttachment.ID, volumeAttachment.Status), request.VolumeID, request.InstanceID)
+}
+
+// withBatchLeases returns a copy of the session holding the leases of the items of a batch apart from the
+// other operations of the session
+func (vpcs *VPCSession) withBatchLeases() *VPCSession {
+	batch := *vpcs
+	batch.heldLeases = newHeldVolumeLeases()
+	return &batch
+}
+
+// releaseBatchLeases releases the leases acquired by the items of the batch, held from their attach or detach
+// till the end of the wait. Items which failed to acquire their lease hold none.
+func (vpcs *VPCSession) releaseBatchLeases() {
+	for _, lease := range vpcs.heldLeases.take("") {
+		lease.Release()
+	}
+}
+
+// runBatch groups the requests by instance and runs each group sequentially on the worker pool
+func (vpcs *VPCSession) runBatch(requests []provider.VolumeAttachmentRequest, runItem func(result *VolumeAttachmentResult)) []VolumeAttachmentResult {
+	results := make([]VolumeAttachmentResult, len(requests))
+	var instanceOrder []string
+	groups := map[string][]int{}
+	for i, request := range requests {
+		results[i].Request = request
+		if _, ok := groups[request.InstanceID]; !ok {
+			instanceOrder = append(instanceOrder, request.InstanceID)
+		}
+		groups[request.InstanceID] = append(groups[request.InstanceID], i)
+	}
+
+	workers := vpcs.BatchWorkers
+	if workers <= 0 {
+		workers = DefaultBatchWorkers
+	}
+	if workers > len(instanceOrder) {
+		workers = len(instanceOrder)
+	}
+	vpcs.Logger.Info("Processing volume attachment batch", zap.Int("requests", len(requests)), zap.Int("instances", len(instanceOrder)), zap.Int("workers", workers))
+
+	groupCh := make(chan []int)
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for group := range groupCh {
+				for _, i := range group {
+					runItem(&results[i])
+				}
+			}
+		}()
+	}
+	for _, instanceID := range instanceOrder {
+		groupCh <- groups[instanceID]
+	}
+	close(groupCh)
+	wg.Wait()
+	return results
+}
+
+// waitForBatch waits for all successfully submitted items of the batch. Each poll lists the volume
+// attachments once per instance and updates all pending items of that instance.
+func (vpcs *VPCSession) waitForBatch(results []VolumeAttachmentResult, attach bool) {
+	pending := map[string][]int{}
+	for i := range results {
+		if results[i].Error == nil {
+			pending[results[i].Request.InstanceID] = append(pending[results[i].Request.InstanceID], i)
+		}
+	}
+
+	// Shared poller serves all the items together with the other waits of the provider
+	if vpcs.poller != nil {
+		var wg sync.WaitGroup
+		for _, items := range pending {
+			for _, i := range items {
+				wg.Add(1)
+				go func(result *VolumeAttachmentResult) {
+					defer wg.Done()
+					result.Response, result.Error = vpcs.poller.waitForAttachment(vpcs, result.Request, attach)
+				}(&results[i])
+			}
+		}
+		wg.Wait()
+		return
+	}
+
+	_ = vpcs.APIRetry.FlexyRetryWithCustomGap(vpcs.Logger, func() (error, bool) {
+		for instanceID, items := range pending {
+			template := models.NewVolumeAttachment(results[items[0]].Request)
+			if !vpcs.Config.VPCConfig.IsIKS {
+				template.ClusterID = nil
+			}
+			volumeAttachmentList, err := vpcs.APIClientVolAttachMgr.ListVolumeAttachments(&template, vpcs.Logger)
+			if err != nil {
+				if skipRetryForObviousErrors(err, vpcs.Config.VPCConfig.IsIKS) {
+					for _, i := range items {
+						switch {
+						case attach:
+							results[i].Error = GetBackendUserError(string(userError.VolumeAttachFindFailed), err, results[i].Request.VolumeID, instanceID)
+						case isNotFoundError(err):
+							// Same as WaitForDetachVolume, the instance does not exist any more
+							vpcs.Logger.Info("Volume detachment is complete", zap.String("volumeID", results[i].Request.VolumeID), zap.String("instanceID", instanceID), zap.Error(err))
+						default:
+							results[i].Error = GetBackendUserError(string(userError.VolumeDetachTimedOut), err, results[i].Request.VolumeID, instanceID)
+						}
+					}
+					delete(pending, instanceID)
+				}
+				continue
+			}
+			attachments := map[string]models.VolumeAttachment{}
+			for _, volumeAttachment := range volumeAttachmentList.VolumeAttachments {
+				if volumeAttachment.Volume != nil {
+					attachments[volumeAttachment.Volume.ID] = volumeAttachment
+				}
+			}
+
+			var stillPending []int
+			for _, i := range items {
+				volumeAttachment, found := attachments[results[i].Request.VolumeID]
+				switch {
+				case attach && found && attachmentFailed(volumeAttachment):
+					// No need to wait for an attachment which is being removed
+					results[i].Response = nil
+					results[i].Error = attachmentFailedError(results[i].Request, volumeAttachment)
+				case attach && found && volumeAttachment.Status == StatusAttached:
+					volumeAttachment.InstanceID = &results[i].Request.InstanceID
+					results[i].Response = volumeAttachment.ToVolumeAttachmentResponse(vpcs.Config.VPCConfig.VPCBlockProviderType)
+				case !attach && !found:
+					vpcs.Logger.Info("Volume detachment is complete", zap.String("volumeID", results[i].Request.VolumeID), zap.String("instanceID", instanceID))
+				default:
+					stillPending = append(stillPending, i)
+				}
+			}
+			if len(stillPending) == 0 {
+				delete(pending, instanceID)
+			} else {
+				pending[instanceID] = stillPending
+			}
+		}
+		if len(pending) > 0 {
+			return errors.New("volume attachments are not yet completed"), false
+		}
+		return nil, true
+	})
+
+	// Remaining items timed out
+	for instanceID, items := range pending {
+		for _, i := range items {
+			results[i].Response = nil
+			if attach {
+				results[i].Error = userError.GetUserError(string(userError.VolumeAttachTimedOut), nil, results[i].Request.VolumeID, instanceID)
+			} else {
+				results[i].Error = userError.GetUserError(string(userError.VolumeDetachTimedOut), nil, results[i].Request.VolumeID, instanceID)
+			}
+			vpcs.Logger.Info("Wait for volume attachment batch item timed out", zap.Error(results[i].Error))
+		}
+	}
+}
@@ -0,0 +1,278 @@
+/**
+ * Copyright 2022 IBM Corp.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package provider ...
+package provider
+
+import (
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/IBM/ibmcloud-volume-interface/lib/provider"
+	userError "github.com/IBM/ibmcloud-volume-vpc/common/messages"
+	volumeAttachServiceFakes "github.com/IBM/ibmcloud-volume-vpc/common/vpcclient/instances/fakes"
+	"github.com/IBM/ibmcloud-volume-vpc/common/vpcclient/models"
+	volumeServiceFakes "github.com/IBM/ibmcloud-volume-vpc/common/vpcclient/vpcvolume/fakes"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+)
+
+// fakeInstances keeps the volume attachments of the fake volume attach service
+type fakeInstances struct {
+	mu          sync.Mutex
+	attachments map[string]map[string]string // instance ID -> volume ID -> status
+	calls       map[string][]string          // instance ID -> attached/detached volume IDs in call order
+	failVolume  string
+	// detachingVolume is found detaching after its attach, i.e its attachment failed
+	detachingVolume string
+}
+
+func newFakeInstances() *fakeInstances {
+	return &fakeInstances{
+		attachments: map[string]map[string]string{},
+		calls:       map[string][]string{},
+	}
+}
+
+func (fi *fakeInstances) install(volumeAttachService *volumeAttachServiceFakes.VolumeAttachService) {
+	volumeAttachService.ListVolumeAttachmentsStub = func(template *models.VolumeAttachment, logger *zap.Logger) (*models.VolumeAttachmentList, error) {
+		fi.mu.Lock()
+		defer fi.mu.Unlock()
+		list := &models.VolumeAttachmentList{}
+		for volumeID, status := range fi.attachments[*template.InstanceID] {
+			list.VolumeAttachments = append(list.VolumeAttachments, models.VolumeAttachment{
+				ID:     "attachment-" + volumeID,
+				Status: status,
+				Volume: &models.Volume{ID: volumeID},
+			})
+		}
+		return list, nil
+	}
+	volumeAttachService.AttachVolumeStub = func(template *models.VolumeAttachment, logger *zap.Logger) (*models.VolumeAttachment, error) {
+		fi.mu.Lock()
+		defer fi.mu.Unlock()
+		if template.Volume.ID == fi.failVolume {
+			return nil, &models.Error{Errors: []models.ErrorItem{{Code: "volume_id_invalid"}}}
+		}
+		if fi.attachments[*template.InstanceID] == nil {
+			fi.attachments[*template.InstanceID] = map[string]string{}
+		}
+		fi.attachments[*template.InstanceID][template.Volume.ID] = StatusAttached
+		if template.Volume.ID == fi.detachingVolume {
+			fi.attachments[*template.InstanceID][template.Volume.ID] = StatusDetaching
+		}
+		fi.calls[*template.InstanceID] = append(fi.calls[*template.InstanceID], template.Volume.ID)
+		return &models.VolumeAttachment{ID: "attachment-" + template.Volume.ID, Status: StatusAttaching, Volume: template.Volume}, nil
+	}
+	volumeAttachService.DetachVolumeStub = func(template *models.VolumeAttachment, logger *zap.Logger) (*http.Response, error) {
+		fi.mu.Lock()
+		defer fi.mu.Unlock()
+		delete(fi.attachments[*template.InstanceID], template.Volume.ID)
+		fi.calls[*template.InstanceID] = append(fi.calls[*template.InstanceID], template.Volume.ID)
+		return &http.Response{StatusCode: http.StatusOK}, nil
+	}
+}
+
+func TestAttachDetachVolumes(t *testing.T) {
+	userError.MessagesEn = userError.InitMessages()
+	logger, teardown := getTestSyncLogger(t)
+	defer teardown()
+
+	vpcs, _, _, err := GetTestOpenSession(t, logger)
+	assert.Nil(t, err)
+	vpcs.BatchWorkers = 2
+
+	volumeAttachService := &volumeAttachServiceFakes.VolumeAttachService{}
+	vpcs.APIClientVolAttachMgr = volumeAttachService
+	instances := newFakeInstances()
+	instances.failVolume = "volume-bad"
+	instances.install(volumeAttachService)
+
+	requests := []provider.VolumeAttachmentRequest{
+		{VolumeID: "volume-1", InstanceID: "instance-1"},
+		{VolumeID: "volume-2", InstanceID: "instance-2"},
+		{VolumeID: "volume-3", InstanceID: "instance-1"},
+		{VolumeID: "volume-bad", InstanceID: "instance-2"},
+		{VolumeID: "volume-4", InstanceID: "instance-1"},
+		{VolumeID: "", InstanceID: "instance-3"},
+	}
+
+	results := vpcs.AttachVolumes(requests)
+	assert.Equal(t, len(requests), len(results))
+	for i, result := range results {
+		assert.Equal(t, requests[i], result.Request)
+		switch result.Request.VolumeID {
+		case "volume-bad":
+			assert.Nil(t, result.Response)
+			assert.Equal(t, userError.VolumeAttachFailed, userError.GetUserErrorCode(result.Error))
+		case "":
+			assert.Nil(t, result.Response)
+			assert.NotNil(t, result.Error)
+		default:
+			assert.Nil(t, result.Error)
+			if assert.NotNil(t, result.Response) {
+				assert.Equal(t, StatusAttached, result.Response.Status)
+				assert.Equal(t, result.Request.InstanceID, result.Response.InstanceID)
+			}
+		}
+	}
+	// Requests of the same instance are processed in order
+	assert.Equal(t, []string{"volume-1", "volume-3", "volume-4"}, instances.calls["instance-1"])
+	// One lookup per submitted volume and one list per instance for the wait
+	assert.Equal(t, 5+2, volumeAttachService.ListVolumeAttachmentsCallCount())
+
+	instances.calls = map[string][]string{}
+	results = vpcs.DetachVolumes(requests[:3])
+	for _, result := range results {
+		assert.Nil(t, result.Error)
+		assert.Nil(t, result.Response)
+	}
+	assert.Equal(t, []string{"volume-1", "volume-3"}, instances.calls["instance-1"])
+	assert.Empty(t, instances.attachments["instance-1"]["volume-1"])
+	assert.Empty(t, instances.attachments["instance-2"]["volume-2"])
+
+	// Empty batch
+	assert.Empty(t, vpcs.AttachVolumes(nil))
+}
+
+func TestAttachVolumesFailedAttachment(t *testing.T) {
+	userError.MessagesEn = userError.InitMessages()
+	logger, teardown := getTestSyncLogger(t)
+	defer teardown()
+
+	for _, withPoller := range []bool{false, true} {
+		vpcs, _, _, err := GetTestOpenSession(t, logger)
+		assert.Nil(t, err)
+		if withPoller {
+			vpcs.poller = NewVolumePoller(VolumePollerConfig{Interval: 10 * time.Millisecond, Timeout: time.Minute})
+		}
+
+		volumeAttachService := &volumeAttachServiceFakes.VolumeAttachService{}
+		vpcs.APIClientVolAttachMgr = volumeAttachService
+		instances := newFakeInstances()
+		instances.detachingVolume = "volume-2"
+		instances.install(volumeAttachService)
+
+		requests := []provider.VolumeAttachmentRequest{
+			{VolumeID: "volume-1", InstanceID: "instance-1"},
+			{VolumeID: "volume-2", InstanceID: "instance-1"},
+		}
+		start := time.Now()
+		results := vpcs.AttachVolumes(requests)
+		assert.Nil(t, results[0].Error)
+		assert.NotNil(t, results[0].Response)
+		// Attachment being removed fails without waiting for the timeout
+		assert.Nil(t, results[1].Response)
+		assert.Equal(t, userError.VolumeAttachFailed, userError.GetUserErrorCode(results[1].Error))
+		assert.Less(t, int64(time.Since(start)), int64(time.Minute))
+	}
+}
+
+func TestAttachVolumesLeases(t *testing.T) {
+	userError.MessagesEn = userError.InitMessages()
+	logger, teardown := getTestSyncLogger(t)
+	defer teardown()
+
+	vpcs, uc, _, err := GetTestOpenSession(t, logger)
+	assert.Nil(t, err)
+	vpcs.VolumeLease = &VolumeLeaseConfig{Holder: "replica-1"}
+	vpcs.heldLeases = newHeldVolumeLeases()
+	// Tags of each volume are kept apart
+	stores := map[string]*volumeServiceFakes.VolumeService{}
+	tags := map[string]*fakeTagStore{}
+	for _, volumeID := range []string{"volume-1", "volume-2"} {
+		stores[volumeID] = &volumeServiceFakes.VolumeService{}
+		tags[volumeID] = &fakeTagStore{}
+		tags[volumeID].install(stores[volumeID])
+	}
+	volumeService := &volumeServiceFakes.VolumeService{}
+	volumeService.ListVolumeTagsStub = func(volumeID string, logger *zap.Logger) (*[]string, error) {
+		return stores[volumeID].ListVolumeTags(volumeID, logger)
+	}
+	volumeService.SetVolumeTagStub = func(volumeID string, tag string, logger *zap.Logger) error {
+		return stores[volumeID].SetVolumeTag(volumeID, tag, logger)
+	}
+	volumeService.DeleteVolumeTagStub = func(volumeID string, tag string, logger *zap.Logger) error {
+		return stores[volumeID].DeleteVolumeTag(volumeID, tag, logger)
+	}
+	uc.VolumeServiceReturns(volumeService)
+
+	volumeAttachService := &volumeAttachServiceFakes.VolumeAttachService{}
+	vpcs.APIClientVolAttachMgr = volumeAttachService
+	instances := newFakeInstances()
+	instances.install(volumeAttachService)
+
+	// Lease of an operation in flight on volume-1, held till its wait
+	lease, err := vpcs.AcquireVolumeLease("volume-1", *vpcs.VolumeLease)
+	assert.Nil(t, err)
+	vpcs.HoldVolumeLease(lease)
+
+	results := vpcs.AttachVolumes([]provider.VolumeAttachmentRequest{
+		{VolumeID: "volume-1", InstanceID: "instance-1"},
+		{VolumeID: "volume-2", InstanceID: "instance-1"},
+	})
+	assert.Equal(t, userError.VolumeLeaseHeld, userError.GetUserErrorCode(results[0].Error))
+	assert.Nil(t, results[1].Error)
+
+	// Batch released the lease of volume-2 only
+	assert.Equal(t, []string{lease.tag.name}, tags["volume-1"].get())
+	assert.Empty(t, tags["volume-2"].get())
+	vpcs.ReleaseVolumeLease("volume-1")
+	assert.Empty(t, tags["volume-1"].get())
+}
+
+func TestDetachVolumesListError(t *testing.T) {
+	userError.MessagesEn = userError.InitMessages()
+	logger, teardown := getTestSyncLogger(t)
+	defer teardown()
+
+	testCases := []struct {
+		testCaseName    string
+		listErr         error
+		expectedErrCode string
+	}{
+		{
+			testCaseName: "Instance not found completes the detach",
+			listErr:      &models.Error{Errors: []models.ErrorItem{{Code: "not_found"}}, ResponseMetadata: models.ResponseMetadata{StatusCode: http.StatusNotFound}},
+		}, {
+			testCaseName:    "Rejected token fails the detach",
+			listErr:         &models.Error{Errors: []models.ErrorItem{{Code: "token_invalid"}}, ResponseMetadata: models.ResponseMetadata{StatusCode: http.StatusUnauthorized}},
+			expectedErrCode: userError.VolumeDetachTimedOut,
+		},
+	}
+
+	for _, testcase := range testCases {
+		t.Run(testcase.testCaseName, func(t *testing.T) {
+			vpcs, _, _, err := GetTestOpenSession(t, logger)
+			assert.Nil(t, err)
+
+			volumeAttachService := &volumeAttachServiceFakes.VolumeAttachService{}
+			vpcs.APIClientVolAttachMgr = volumeAttachService
+			volumeAttachService.DetachVolumeReturns(&http.Response{StatusCode: http.StatusOK}, nil)
+			volumeAttachService.ListVolumeAttachmentsReturns(nil, testcase.listErr)
+
+			results := []VolumeAttachmentResult{{Request: provider.VolumeAttachmentRequest{VolumeID: "volume-1", InstanceID: "instance-1"}}}
+			vpcs.waitForBatch(results, false)
+			if testcase.expectedErrCode == "" {
+				assert.Nil(t, results[0].Error)
+			} else {
+				assert.Equal(t, testcase.expectedErrCode, userError.GetUserErrorCode(results[0].Error))
+			}
+		})
+	}
+}
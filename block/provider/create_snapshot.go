@@ -25,17 +25,19 @@ import (
 	"github.com/IBM/ibmcloud-volume-interface/lib/utils/reasoncode"
 	userError "github.com/IBM/ibmcloud-volume-vpc/common/messages"
 	"github.com/IBM/ibmcloud-volume-vpc/common/vpcclient/models"
+	"github.com/IBM/ibmcloud-volume-vpc/common/vpctracing"
 	"go.uber.org/zap"
 )
 
 const snapshotReadyState = "stable"
 
 // CreateSnapshot creates snapshot
-func (vpcs *VPCSession) CreateSnapshot(sourceVolumeID string, snapshotParameters provider.SnapshotParameters) (*provider.Snapshot, error) {
+func (vpcs *VPCSession) CreateSnapshot(sourceVolumeID string, snapshotParameters provider.SnapshotParameters) (_ *provider.Snapshot, err error) {
 	vpcs.Logger.Info("Entry CreateSnapshot", zap.Reflect("snapshotRequest", snapshotParameters), zap.Reflect("sourceVolumeID", sourceVolumeID))
 	defer vpcs.Logger.Info("Exit CreateSnapshot", zap.Reflect("snapshotRequest", snapshotParameters), zap.Reflect("sourceVolumeID", sourceVolumeID))
+	vpcs, end := vpcs.trace("CreateSnapshot", vpctracing.VolumeIDKey.String(sourceVolumeID))
+	defer func() { end(err) }()
 	defer metrics.UpdateDurationFromStart(vpcs.Logger, "CreateSnapshot", time.Now())
-	var err error
 	err = vpcs.validateSnapshotRequest(sourceVolumeID)
 	if err != nil {
 		return nil, err
@@ -43,19 +45,23 @@ func (vpcs *VPCSession) CreateSnapshot(sourceVolumeID string, snapshotParameters
 	var snapshotResult *models.Snapshot
 
 	// Step 1- validate input which are required
+	resourceGroupID, err := vpcs.explicitResourceGroup(snapshotParameters.SnapshotTags)
+	if err != nil {
+		return nil, err
+	}
 
 	snapshotTemplate := &models.Snapshot{
 		Name:          snapshotParameters.Name,
 		SourceVolume:  &models.SourceVolume{ID: sourceVolumeID},
-		ResourceGroup: &models.ResourceGroup{ID: vpcs.Config.VPCConfig.G2ResourceGroupID},
+		ResourceGroup: &models.ResourceGroup{ID: vpcs.operationResourceGroup(resourceGroupID)},
 	}
 
-	err = retry(vpcs.Logger, func() error {
+	err = tracedRetry(vpcs.tracing, vpcs.Logger, func() error {
 		snapshotResult, err = vpcs.Apiclient.SnapshotService().CreateSnapshot(snapshotTemplate, vpcs.Logger)
 		return err
 	})
 	if err != nil {
-		return nil, userError.GetUserError("SnapshotSpaceOrderFailed", err)
+		return nil, GetBackendUserError("SnapshotSpaceOrderFailed", err)
 	}
 
 	vpcs.Logger.Info("Successfully created snapshot with backend (vpcclient) call. Snapshot details", zap.Reflect("Snapshot", snapshotResult))
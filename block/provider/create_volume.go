@@ -25,6 +25,7 @@ import (
 	"github.com/IBM/ibmcloud-volume-interface/lib/provider"
 	userError "github.com/IBM/ibmcloud-volume-vpc/common/messages"
 	"github.com/IBM/ibmcloud-volume-vpc/common/vpcclient/models"
+	"github.com/IBM/ibmcloud-volume-vpc/common/vpctracing"
 	"go.uber.org/zap"
 )
 
@@ -37,8 +38,14 @@ const (
 func (vpcs *VPCSession) CreateVolume(volumeRequest provider.Volume) (volumeResponse *provider.Volume, err error) {
 	vpcs.Logger.Debug("Entry of CreateVolume method...")
 	defer vpcs.Logger.Debug("Exit from CreateVolume method...")
+	vpcs, end := vpcs.trace("CreateVolume")
+	defer func() { end(err) }()
 	defer metrics.UpdateDurationFromStart(vpcs.Logger, "CreateVolume", time.Now())
 
+	// Resource group of the request context applies to the volumes without one
+	if volumeRequest.VPCVolume.ResourceGroup == nil && vpcs.resourceGroup != "" {
+		volumeRequest.VPCVolume.ResourceGroup = &provider.ResourceGroup{ID: vpcs.resourceGroup}
+	}
 	vpcs.Logger.Info("Basic validation for CreateVolume request... ", zap.Reflect("RequestedVolumeDetails", volumeRequest))
 	resourceGroup, iops, err := validateVolumeRequest(&volumeRequest, vpcs.Config.VPCConfig.ClusterVolumeLabel)
 	if err != nil {
@@ -46,6 +53,23 @@ func (vpcs *VPCSession) CreateVolume(volumeRequest provider.Volume) (volumeRespo
 	}
 	vpcs.Logger.Info("Successfully validated inputs for CreateVolume request... ")
 
+	// Resource group name is resolved to its ID, as Name is not supported by RIaaS
+	if resourceGroup.ID == "" && resourceGroup.Name != "" {
+		resourceGroup.ID, err = vpcs.resolveResourceGroupName(resourceGroup.Name)
+		if err != nil {
+			return nil, err
+		}
+		resourceGroup.Name = ""
+	}
+
+	// Encryption key is checked before the order is placed
+	if volumeRequest.VPCVolume.VolumeEncryptionKey != nil && len(volumeRequest.VPCVolume.VolumeEncryptionKey.CRN) > 0 {
+		err = vpcs.validateEncryptionKey(volumeRequest.VPCVolume.VolumeEncryptionKey.CRN, volumeRequest.Az)
+		if err != nil {
+			return nil, err
+		}
+	}
+
 	// Build the template to send to backend
 	volumeTemplate := &models.Volume{
 		Name:          *volumeRequest.Name,
@@ -73,7 +97,7 @@ func (vpcs *VPCSession) CreateVolume(volumeRequest provider.Volume) (volumeRespo
 
 	vpcs.Logger.Info("Calling VPC provider for volume creation...")
 	var volume *models.Volume
-	err = retry(vpcs.Logger, func() error {
+	err = tracedRetry(vpcs.tracing, vpcs.Logger, func() error {
 		volume, err = vpcs.Apiclient.VolumeService().CreateVolume(volumeTemplate, vpcs.Logger)
 		return err
 	})
@@ -82,17 +106,18 @@ func (vpcs *VPCSession) CreateVolume(volumeRequest provider.Volume) (volumeRespo
 		vpcs.Logger.Debug("Failed to create volume from VPC provider", zap.Reflect("BackendError", err))
 		modelError, ok := err.(*models.Error)
 		if ok && len(modelError.Errors) > 0 && string(modelError.Errors[0].Code) == SnapshotIDNotFound {
-			return nil, userError.GetUserError("SnapshotIDNotFound", err)
+			return nil, GetBackendUserError("SnapshotIDNotFound", err)
 		}
-		return nil, userError.GetUserError("FailedToPlaceOrder", err)
+		return nil, GetBackendUserError("FailedToPlaceOrder", err)
 	}
 
 	vpcs.Logger.Info("Successfully created volume from VPC provider...", zap.Reflect("VolumeDetails", volume))
+	vpctracing.SetAttributes(vpcs.tracing, vpctracing.VolumeIDKey.String(volume.ID))
 
 	vpcs.Logger.Info("Waiting for volume to be in valid (available) state", zap.Reflect("VolumeDetails", volume))
 	err = WaitForValidVolumeState(vpcs, volume)
 	if err != nil {
-		return nil, userError.GetUserError("VolumeNotInValidState", err, volume.ID)
+		return nil, GetBackendUserError("VolumeNotInValidState", err, volume.ID)
 	}
 
 	// Converting volume to lib volume type
@@ -151,7 +176,7 @@ func validateVolumeRequest(volumeRequest *provider.Volume, clusterVolumeLabel st
 		resourceGroup.ID = volumeRequest.VPCVolume.ResourceGroup.ID
 	}
 	if len(volumeRequest.VPCVolume.ResourceGroup.Name) > 0 {
-		// get the resource group ID from resource group name as Name is not supported by RIaaS
+		// resource group name is resolved to its ID by CreateVolume as Name is not supported by RIaaS
 		resourceGroup.Name = volumeRequest.VPCVolume.ResourceGroup.Name
 	}
 
@@ -0,0 +1,155 @@
+/**
+ * Copyright 2022 IBM Corp.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package provider ...
+package provider
+
+import (
+	"errors"
+	"strings"
+	"sync"
+
+	"github.com/IBM/ibmcloud-volume-interface/config"
+	"github.com/IBM/ibmcloud-volume-interface/provider/local"
+	vpcconfig "github.com/IBM/ibmcloud-volume-vpc/block/vpcconfig"
+	userError "github.com/IBM/ibmcloud-volume-vpc/common/messages"
+	"go.uber.org/zap"
+)
+
+// Credentials are the API key and context credentials factory of a provider, validated by a test token exchange
+type Credentials struct {
+	apiKey    string
+	contextCF local.ContextCredentialsFactory
+	scope     string // See credentialsScope
+}
+
+// credentialsHolder holds the credentials reloaded since the provider construction. It is shared by the copies
+// of the provider, so that the sessions opened after a reload all use the new credentials.
+type credentialsHolder struct {
+	mu          sync.RWMutex
+	credentials *Credentials
+
+	// newContextCF builds the context credentials factory of a config
+	newContextCF func(conf *vpcconfig.VPCBlockConfig) (local.ContextCredentialsFactory, error)
+}
+
+// APIKey returns the current API key of the provider
+func (vpcp *VPCBlockProvider) APIKey() string {
+	if credentials := vpcp.currentCredentials(); credentials != nil {
+		return credentials.apiKey
+	}
+	return vpcp.Config.VPCConfig.G2APIKey
+}
+
+// contextCredentialsFactory returns the current context credentials factory of the provider
+func (vpcp *VPCBlockProvider) contextCredentialsFactory() local.ContextCredentialsFactory {
+	if credentials := vpcp.currentCredentials(); credentials != nil {
+		return credentials.contextCF
+	}
+	return vpcp.ContextCF
+}
+
+// credentialsScope returns the scope of the current credentials of the provider
+func (vpcp *VPCBlockProvider) credentialsScope() string {
+	if credentials := vpcp.currentCredentials(); credentials != nil {
+		return credentials.scope
+	}
+	return credentialsScope(vpcp.Config)
+}
+
+// credentialsScope identifies the token exchange of the config apart from the API key: the IAM endpoint and client,
+// the IKS private route and the trusted profile. Pooled tokens of another scope, e.g before a reload, are not reused.
+func credentialsScope(conf *vpcconfig.VPCBlockConfig) string {
+	if conf == nil || conf.VPCConfig == nil {
+		return ""
+	}
+	parts := []string{conf.VPCConfig.G2TokenExchangeURL, conf.VPCConfig.IamClientID, conf.VPCConfig.IamClientSecret, conf.VPCConfig.IKSTokenExchangePrivateURL}
+	if conf.TrustedProfile != nil {
+		parts = append(parts, conf.TrustedProfile.ProfileID, conf.TrustedProfile.ComputeResourceTokenFile, conf.TrustedProfile.MetadataURL)
+	}
+	return strings.Join(parts, "\x00")
+}
+
+// currentCredentials returns the reloaded credentials, nil until the first reload
+func (vpcp *VPCBlockProvider) currentCredentials() *Credentials {
+	if vpcp.credentials == nil {
+		return nil
+	}
+	vpcp.credentials.mu.RLock()
+	defer vpcp.credentials.mu.RUnlock()
+	return vpcp.credentials.credentials
+}
+
+// NewCredentials reads the credentials of the config data, in the format of the storage secret store, and
+// validates them with a test token exchange. The provider keeps its current credentials.
+func (vpcp *VPCBlockProvider) NewCredentials(data string, logger *zap.Logger) (*Credentials, error) {
+	if vpcp.credentials == nil || vpcp.credentials.newContextCF == nil {
+		return nil, userError.GetUserError(userError.CredentialsReloadFailed, errors.New("credentials reload is not enabled for the provider"))
+	}
+	conf, err := config.ParseConfig(logger, data)
+	if err != nil {
+		logger.Error("Failed to parse the rotated config", zap.Error(err))
+		return nil, userError.GetUserError(userError.CredentialsReloadFailed, err)
+	}
+	if conf.VPC == nil || (conf.VPC.G2APIKey == "" && vpcp.Config.TrustedProfile == nil) {
+		return nil, userError.GetUserError(userError.CredentialsReloadFailed, errors.New("rotated config has no VPC API key"))
+	}
+
+	// Only the credentials are reloaded, the other settings of the provider are kept
+	reloaded := *vpcp.Config
+	vpcConfig := *vpcp.Config.VPCConfig
+	vpcConfig.G2APIKey = conf.VPC.G2APIKey
+	vpcConfig.APIKey = conf.VPC.G2APIKey
+	vpcConfig.IamClientID = conf.VPC.IamClientID
+	vpcConfig.IamClientSecret = conf.VPC.IamClientSecret
+	reloaded.VPCConfig = &vpcConfig
+
+	contextCF, err := vpcp.credentials.newContextCF(&reloaded)
+	if err != nil {
+		logger.Error("Failed to initialize the context credentials factory of the rotated credentials", zap.Error(err))
+		return nil, userError.GetUserError(userError.CredentialsReloadFailed, err)
+	}
+	if _, err = contextCF.ForIAMAccessToken(vpcConfig.G2APIKey, logger); err != nil {
+		logger.Error("Test token exchange of the rotated credentials failed", zap.Error(err))
+		return nil, userError.GetUserError(userError.CredentialsReloadFailed, err)
+	}
+	return &Credentials{apiKey: vpcConfig.G2APIKey, contextCF: contextCF, scope: credentialsScope(&reloaded)}, nil
+}
+
+// SetCredentials swaps the credentials used by the sessions opened from now on
+func (vpcp *VPCBlockProvider) SetCredentials(credentials *Credentials) error {
+	if vpcp.credentials == nil {
+		return userError.GetUserError(userError.CredentialsReloadFailed, errors.New("credentials reload is not enabled for the provider"))
+	}
+	vpcp.credentials.mu.Lock()
+	defer vpcp.credentials.mu.Unlock()
+	vpcp.credentials.credentials = credentials
+	return nil
+}
+
+// ReloadCredentials swaps the credentials of the provider with the ones of the config data, once they are
+// validated. The current credentials are kept if the validation fails.
+func (vpcp *VPCBlockProvider) ReloadCredentials(data string, logger *zap.Logger) error {
+	credentials, err := vpcp.NewCredentials(data, logger)
+	if err != nil {
+		return err
+	}
+	if err = vpcp.SetCredentials(credentials); err != nil {
+		return err
+	}
+	logger.Info("Reloaded the provider credentials")
+	return nil
+}
@@ -0,0 +1,128 @@
+/**
+ * Copyright 2022 IBM Corp.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package provider ...
+package provider
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/IBM/ibmcloud-volume-interface/lib/provider"
+	"github.com/IBM/ibmcloud-volume-interface/provider/local"
+	localFakes "github.com/IBM/ibmcloud-volume-interface/provider/local/fakes"
+	vpcconfig "github.com/IBM/ibmcloud-volume-vpc/block/vpcconfig"
+	userError "github.com/IBM/ibmcloud-volume-vpc/common/messages"
+	"github.com/stretchr/testify/assert"
+)
+
+// testCredentialsData returns storage secret store data holding the API key
+func testCredentialsData(apiKey string) string {
+	return fmt.Sprintf("[server]\n  debug_trace = false\n[vpc]\n  g2_api_key = %q\n  iam_client_id = \"client-id\"\n", apiKey)
+}
+
+// testCredentialsHolder returns a credentials holder whose factories accept all API keys but "invalid-key"
+func testCredentialsHolder(built *[]*vpcconfig.VPCBlockConfig) *credentialsHolder {
+	return &credentialsHolder{
+		newContextCF: func(conf *vpcconfig.VPCBlockConfig) (local.ContextCredentialsFactory, error) {
+			*built = append(*built, conf)
+			ccf := &localFakes.ContextCredentialsFactory{}
+			if conf.VPCConfig.G2APIKey == "invalid-key" {
+				ccf.ForIAMAccessTokenReturns(provider.ContextCredentials{}, errors.New("invalid API key"))
+			} else {
+				ccf.ForIAMAccessTokenReturns(provider.ContextCredentials{AuthType: provider.IAMAccessToken, Credential: "token-" + conf.VPCConfig.G2APIKey}, nil)
+			}
+			return ccf, nil
+		},
+	}
+}
+
+func TestReloadCredentials(t *testing.T) {
+	logger, teardown := GetTestLogger(t)
+	defer teardown()
+	userError.MessagesEn = userError.InitMessages()
+
+	vpcp, _ := GetTestProvider(t, logger)
+	initialCF := &localFakes.ContextCredentialsFactory{}
+	vpcp.ContextCF = initialCF
+	initialKey := vpcp.Config.VPCConfig.G2APIKey
+
+	// Reload is not enabled without the holder of NewProvider
+	err := vpcp.ReloadCredentials(testCredentialsData("rotated-key"), logger)
+	assert.Equal(t, userError.CredentialsReloadFailed, userError.GetUserErrorCode(err))
+
+	var built []*vpcconfig.VPCBlockConfig
+	vpcp.credentials = testCredentialsHolder(&built)
+	ccf, _ := vpcp.ContextCredentialsFactory(nil)
+	assert.Equal(t, initialCF, ccf)
+	assert.Equal(t, initialKey, vpcp.APIKey())
+
+	testCases := []struct {
+		name           string
+		data           string
+		expectedAPIKey string
+		expectErr      bool
+	}{
+		{
+			name:      "data not TOML",
+			data:      "not = [toml",
+			expectErr: true,
+		}, {
+			name:      "no API key",
+			data:      "[server]\n  debug_trace = false\n",
+			expectErr: true,
+		}, {
+			name:      "test exchange fails",
+			data:      testCredentialsData("invalid-key"),
+			expectErr: true,
+		}, {
+			name:           "rotated key",
+			data:           testCredentialsData("rotated-key"),
+			expectedAPIKey: "rotated-key",
+		},
+	}
+
+	for _, testcase := range testCases {
+		t.Run(testcase.name, func(t *testing.T) {
+			previousKey := vpcp.APIKey()
+			previousCF, _ := vpcp.ContextCredentialsFactory(nil)
+			err := vpcp.ReloadCredentials(testcase.data, logger)
+			if testcase.expectErr {
+				assert.Equal(t, userError.CredentialsReloadFailed, userError.GetUserErrorCode(err))
+				// Current credentials stay in use
+				assert.Equal(t, previousKey, vpcp.APIKey())
+				ccf, _ := vpcp.ContextCredentialsFactory(nil)
+				assert.Equal(t, previousCF, ccf)
+				return
+			}
+			assert.Nil(t, err)
+			assert.Equal(t, testcase.expectedAPIKey, vpcp.APIKey())
+			ccf, _ := vpcp.ContextCredentialsFactory(nil)
+			assert.NotEqual(t, previousCF, ccf)
+		})
+	}
+
+	// Only the credentials are reloaded, the config of the provider is left as is
+	reloaded := built[len(built)-1]
+	assert.Equal(t, "client-id", reloaded.VPCConfig.IamClientID)
+	assert.Equal(t, vpcp.Config.VPCConfig.EndpointURL, reloaded.VPCConfig.EndpointURL)
+	assert.Equal(t, initialKey, vpcp.Config.VPCConfig.G2APIKey)
+
+	// Copies of the provider share the reloaded credentials
+	derived := *vpcp
+	assert.Equal(t, "rotated-key", derived.APIKey())
+}
@@ -0,0 +1,144 @@
+/**
+ * Copyright 2022 IBM Corp.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package provider ...
+package provider
+
+import (
+	"context"
+	"crypto/sha256"
+	"errors"
+	"io/ioutil"
+	"sync"
+	"time"
+
+	"github.com/IBM/secret-utils-lib/pkg/k8s_utils"
+	"github.com/IBM/secret-utils-lib/pkg/utils"
+	"go.uber.org/zap"
+)
+
+// DefaultCredentialsPollInterval is the default interval of the credentials source polls
+const DefaultCredentialsPollInterval = time.Minute
+
+// CredentialsReloader swaps the credentials of a provider, see VPCBlockProvider.ReloadCredentials
+type CredentialsReloader interface {
+	ReloadCredentials(data string, logger *zap.Logger) error
+}
+
+// CredentialsSource reads the config data holding the credentials, in the format of the storage secret store
+type CredentialsSource interface {
+	Read() (string, error)
+}
+
+// SecretCredentialsSource reads the credentials from a kubernetes secret, the storage secret store by default
+type SecretCredentialsSource struct {
+	K8sClient  *k8s_utils.KubernetesClient
+	SecretName string
+	DataName   string
+}
+
+// Read ...
+func (s *SecretCredentialsSource) Read() (string, error) {
+	if s.K8sClient == nil {
+		return "", errors.New("no kubernetes client to read the credentials secret")
+	}
+	secretName, dataName := s.SecretName, s.DataName
+	if secretName == "" {
+		secretName = utils.STORAGE_SECRET_STORE_SECRET
+	}
+	if dataName == "" {
+		dataName = utils.SECRET_STORE_FILE
+	}
+	return k8s_utils.GetSecretData(*s.K8sClient, secretName, dataName)
+}
+
+// FileCredentialsSource reads the credentials from a mounted file, kubernetes updates it when the secret rotates
+type FileCredentialsSource struct {
+	Path string
+}
+
+// Read ...
+func (s *FileCredentialsSource) Read() (string, error) {
+	data, err := ioutil.ReadFile(s.Path)
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+// CredentialsWatcher polls a credentials source and reloads the provider when the data changes
+type CredentialsWatcher struct {
+	source   CredentialsSource
+	reloader CredentialsReloader
+	interval time.Duration
+	logger   *zap.Logger
+
+	mu      sync.Mutex
+	applied [sha256.Size]byte
+}
+
+// NewCredentialsWatcher returns a watcher of the source, which reloads the provider when the data changes from the
+// one read now. The interval defaults to DefaultCredentialsPollInterval.
+func NewCredentialsWatcher(source CredentialsSource, reloader CredentialsReloader, interval time.Duration, logger *zap.Logger) *CredentialsWatcher {
+	if interval <= 0 {
+		interval = DefaultCredentialsPollInterval
+	}
+	watcher := &CredentialsWatcher{source: source, reloader: reloader, interval: interval, logger: logger}
+	// Data of the source is the one the provider was built with
+	if data, err := source.Read(); err == nil {
+		watcher.applied = sha256.Sum256([]byte(data))
+	} else {
+		logger.Warn("Failed to read the credentials source, the first poll reloads the provider", zap.Error(err))
+	}
+	return watcher
+}
+
+// Check reloads the provider if the data of the source changed since the last reload. The data is checked again
+// on the next poll when the reload fails, the provider keeps the current credentials meanwhile.
+func (w *CredentialsWatcher) Check() (bool, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	data, err := w.source.Read()
+	if err != nil {
+		w.logger.Error("Failed to read the credentials source", zap.Error(err))
+		return false, err
+	}
+	digest := sha256.Sum256([]byte(data))
+	if digest == w.applied {
+		return false, nil
+	}
+	w.logger.Info("Credentials source changed, reloading the provider credentials")
+	if err = w.reloader.ReloadCredentials(data, w.logger); err != nil {
+		return false, err
+	}
+	w.applied = digest
+	return true, nil
+}
+
+// Run polls the source until the context is done
+func (w *CredentialsWatcher) Run(ctx context.Context) {
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			_, _ = w.Check()
+		}
+	}
+}
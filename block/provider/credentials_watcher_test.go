@@ -0,0 +1,125 @@
+/**
+ * Copyright 2022 IBM Corp.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package provider ...
+package provider
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/IBM/secret-utils-lib/pkg/k8s_utils"
+	"github.com/IBM/secret-utils-lib/pkg/utils"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+// stubCredentialsReloader records the reloaded data, and fails the reloads of invalid data
+type stubCredentialsReloader struct {
+	mu       sync.Mutex
+	reloaded []string
+}
+
+func (r *stubCredentialsReloader) ReloadCredentials(data string, logger *zap.Logger) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if data == "invalid" {
+		return errors.New("test exchange failed")
+	}
+	r.reloaded = append(r.reloaded, data)
+	return nil
+}
+
+func (r *stubCredentialsReloader) reloads() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]string{}, r.reloaded...)
+}
+
+func TestCredentialsWatcherFile(t *testing.T) {
+	logger, teardown := GetTestLogger(t)
+	defer teardown()
+
+	file := filepath.Join(t.TempDir(), "slclient.toml")
+	require.NoError(t, ioutil.WriteFile(file, []byte("initial"), 0600))
+	reloader := &stubCredentialsReloader{}
+	watcher := NewCredentialsWatcher(&FileCredentialsSource{Path: file}, reloader, 0, logger)
+	assert.Equal(t, DefaultCredentialsPollInterval, watcher.interval)
+
+	// Data the provider was built with is not reloaded
+	changed, err := watcher.Check()
+	assert.Nil(t, err)
+	assert.False(t, changed)
+
+	// Failed reload is retried on the next poll
+	require.NoError(t, ioutil.WriteFile(file, []byte("invalid"), 0600))
+	_, err = watcher.Check()
+	assert.EqualError(t, err, "test exchange failed")
+	_, err = watcher.Check()
+	assert.NotNil(t, err)
+
+	require.NoError(t, ioutil.WriteFile(file, []byte("rotated"), 0600))
+	changed, err = watcher.Check()
+	assert.Nil(t, err)
+	assert.True(t, changed)
+	changed, _ = watcher.Check()
+	assert.False(t, changed)
+	assert.Equal(t, []string{"rotated"}, reloader.reloads())
+
+	// Missing file keeps the current credentials
+	_, err = (&FileCredentialsSource{Path: filepath.Join(t.TempDir(), "missing")}).Read()
+	assert.NotNil(t, err)
+}
+
+func TestCredentialsWatcherSecret(t *testing.T) {
+	logger, teardown := getTestSyncLogger(t)
+	defer teardown()
+
+	kc, _ := k8s_utils.FakeGetk8sClientSet()
+	file := filepath.Join(t.TempDir(), "slclient.toml")
+	require.NoError(t, ioutil.WriteFile(file, []byte(testCredentialsData("initial-key")), 0600))
+	require.NoError(t, k8s_utils.FakeCreateSecretWithKey(kc, utils.STORAGE_SECRET_STORE_SECRET, utils.SECRET_STORE_FILE, file))
+
+	source := &SecretCredentialsSource{K8sClient: &kc}
+	data, err := source.Read()
+	assert.Nil(t, err)
+	assert.Contains(t, data, `g2_api_key = "initial-key"`)
+
+	_, err = (&SecretCredentialsSource{K8sClient: &kc, SecretName: "missing"}).Read()
+	assert.NotNil(t, err)
+	_, err = (&SecretCredentialsSource{}).Read()
+	assert.NotNil(t, err)
+
+	// Watcher polls until the context is done
+	reloader := &stubCredentialsReloader{}
+	watcher := NewCredentialsWatcher(&FileCredentialsSource{Path: file}, reloader, 10*time.Millisecond, logger)
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		watcher.Run(ctx)
+		close(done)
+	}()
+	require.NoError(t, ioutil.WriteFile(file, []byte(testCredentialsData("rotated-key")), 0600))
+	assert.Eventually(t, func() bool { return len(reloader.reloads()) == 1 }, 5*time.Second, 10*time.Millisecond)
+	cancel()
+	<-done
+}
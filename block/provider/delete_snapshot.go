@@ -24,23 +24,25 @@ import (
 	"github.com/IBM/ibmcloud-volume-interface/lib/provider"
 	userError "github.com/IBM/ibmcloud-volume-vpc/common/messages"
 	"github.com/IBM/ibmcloud-volume-vpc/common/vpcclient/models"
+	"github.com/IBM/ibmcloud-volume-vpc/common/vpctracing"
 	"go.uber.org/zap"
 )
 
 // DeleteSnapshot delete snapshot
-func (vpcs *VPCSession) DeleteSnapshot(snapshot *provider.Snapshot) error {
+func (vpcs *VPCSession) DeleteSnapshot(snapshot *provider.Snapshot) (err error) {
 	vpcs.Logger.Info("Entry DeleteSnapshot", zap.Reflect("snapshotID", snapshot.SnapshotID))
 	defer vpcs.Logger.Info("Exit DeleteSnapshot", zap.Reflect("snapshotID", snapshot.SnapshotID))
+	vpcs, end := vpcs.trace("DeleteSnapshot", vpctracing.SnapshotIDKey.String(snapshot.SnapshotID))
+	defer func() { end(err) }()
 	defer metrics.UpdateDurationFromStart(vpcs.Logger, "DeleteSnapshot", time.Now())
 
-	var err error
 	if snapshot == nil {
 		err = userError.GetUserError("InvalidSnapshotID", nil, nil)
 		return err
 	}
 
 	vpcs.Logger.Info("Deleting snapshot from VPC provider...")
-	err = retry(vpcs.Logger, func() error {
+	err = tracedRetry(vpcs.tracing, vpcs.Logger, func() error {
 		err = vpcs.Apiclient.SnapshotService().DeleteSnapshot(snapshot.SnapshotID, vpcs.Logger)
 		return err
 	})
@@ -48,14 +50,14 @@ func (vpcs *VPCSession) DeleteSnapshot(snapshot *provider.Snapshot) error {
 	if err != nil {
 		modelError, ok := err.(*models.Error)
 		if ok && len(modelError.Errors) > 0 && string(modelError.Errors[0].Code) == SnapshotNotFound {
-			return userError.GetUserError("SnapshotIDNotFound", err)
+			return GetBackendUserError("SnapshotIDNotFound", err)
 		}
-		return userError.GetUserError("FailedToDeleteSnapshot", err)
+		return GetBackendUserError("FailedToDeleteSnapshot", err)
 	}
 
 	err = WaitForSnapshotDeletion(vpcs, snapshot.SnapshotID)
 	if err != nil {
-		return userError.GetUserError("FailedToDeleteSnapshot", err, snapshot.SnapshotID)
+		return GetBackendUserError("FailedToDeleteSnapshot", err, snapshot.SnapshotID)
 	}
 	vpcs.Logger.Info("Successfully deleted the snapshot with backend (vpcclient) call)")
 	return err
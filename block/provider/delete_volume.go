@@ -24,6 +24,7 @@ import (
 	"github.com/IBM/ibmcloud-volume-interface/lib/provider"
 	userError "github.com/IBM/ibmcloud-volume-vpc/common/messages"
 	"github.com/IBM/ibmcloud-volume-vpc/common/vpcclient/models"
+	"github.com/IBM/ibmcloud-volume-vpc/common/vpctracing"
 	"go.uber.org/zap"
 )
 
@@ -31,6 +32,8 @@ import (
 func (vpcs *VPCSession) DeleteVolume(volume *provider.Volume) (err error) {
 	vpcs.Logger.Debug("Entry of DeleteVolume method...")
 	defer vpcs.Logger.Debug("Exit from DeleteVolume method...")
+	vpcs, end := vpcs.trace("DeleteVolume")
+	defer func() { end(err) }()
 	defer metrics.UpdateDurationFromStart(vpcs.Logger, "DeleteVolume", time.Now())
 
 	vpcs.Logger.Info("Validating basic inputs for DeleteVolume method...", zap.Reflect("VolumeDetails", volume))
@@ -38,19 +41,20 @@ func (vpcs *VPCSession) DeleteVolume(volume *provider.Volume) (err error) {
 	if err != nil {
 		return err
 	}
+	vpctracing.SetAttributes(vpcs.tracing, vpctracing.VolumeIDKey.String(volume.VolumeID))
 
 	vpcs.Logger.Info("Deleting volume from VPC provider...")
-	err = retry(vpcs.Logger, func() error {
+	err = tracedRetry(vpcs.tracing, vpcs.Logger, func() error {
 		err = vpcs.Apiclient.VolumeService().DeleteVolume(volume.VolumeID, vpcs.Logger)
 		return err
 	})
 	if err != nil {
-		return userError.GetUserError("failedToDeleteVolume", err, volume.VolumeID)
+		return GetBackendUserError("failedToDeleteVolume", err, volume.VolumeID)
 	}
 
 	err = WaitForVolumeDeletion(vpcs, volume.VolumeID)
 	if err != nil {
-		return userError.GetUserError("failedToDeleteVolume", err, volume.VolumeID)
+		return GetBackendUserError("failedToDeleteVolume", err, volume.VolumeID)
 	}
 
 	vpcs.Logger.Info("Successfully deleted volume from VPC provider")
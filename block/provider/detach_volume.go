@@ -30,11 +30,12 @@ import (
 )
 
 // DetachVolume detach volume based on given volume attachment request
-func (vpcs *VPCSession) DetachVolume(volumeAttachmentTemplate provider.VolumeAttachmentRequest) (*http.Response, error) {
+func (vpcs *VPCSession) DetachVolume(volumeAttachmentTemplate provider.VolumeAttachmentRequest) (_ *http.Response, err error) {
 	vpcs.Logger.Debug("Entry of DetachVolume method...")
 	defer vpcs.Logger.Debug("Exit from DetachVolume method...")
+	vpcs, end := vpcs.trace("DetachVolume", attachmentAttributes(volumeAttachmentTemplate)...)
+	defer func() { end(err) }()
 	defer metrics.UpdateDurationFromStart(vpcs.Logger, "DetachVolume", time.Now())
-	var err error
 
 	//check if ServiceSession is valid
 	if err = isValidServiceSession(vpcs); err != nil {
@@ -50,6 +51,20 @@ func (vpcs *VPCSession) DetachVolume(volumeAttachmentTemplate provider.VolumeAtt
 	var response *http.Response
 	var volumeAttachment models.VolumeAttachment
 
+	// Hold the volume lease (if enabled) so that other replicas do not mutate the volume concurrently.
+	// On success it is kept till the end of the wait for the volume, see HoldVolumeLease.
+	lease, err := vpcs.acquireSessionVolumeLease(volumeAttachmentTemplate.VolumeID)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if err != nil {
+			lease.Release()
+			return
+		}
+		vpcs.HoldVolumeLease(lease)
+	}()
+
 	err = vpcs.APIRetry.FlexyRetry(vpcs.Logger, func() (error, bool) {
 		// First , check if volume is already attached to given instance
 		vpcs.Logger.Info("Checking if volume is already attached ")
@@ -74,7 +89,7 @@ func (vpcs *VPCSession) DetachVolume(volumeAttachmentTemplate provider.VolumeAtt
 		return nil, true // skip retry if volume is not found OR alreadd in detaching state
 	})
 	if err != nil {
-		userErr := userError.GetUserError(string(userError.VolumeDetachFailed), err, volumeAttachmentTemplate.VolumeID, volumeAttachmentTemplate.InstanceID, volumeAttachment.ID)
+		userErr := GetBackendUserError(string(userError.VolumeDetachFailed), err, volumeAttachmentTemplate.VolumeID, volumeAttachmentTemplate.InstanceID, volumeAttachment.ID)
 		vpcs.Logger.Error("Volume detach failed with error", zap.Error(err))
 		return response, userErr
 	}
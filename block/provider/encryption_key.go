@@ -0,0 +1,74 @@
+/**
+ * Copyright 2020 IBM Corp.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package provider ...
+package provider
+
+import (
+	"net/http"
+
+	userError "github.com/IBM/ibmcloud-volume-vpc/common/messages"
+	"github.com/IBM/ibmcloud-volume-vpc/common/vpcclient/keymanagement"
+	"github.com/IBM/ibmcloud-volume-vpc/common/vpcclient/models"
+	"go.uber.org/zap"
+)
+
+// validateEncryptionKey checks the encryption key of a volume of the zone before the volume is ordered, as RIaaS
+// only reports the key errors once the volume has failed. The key is read from its key management instance when
+// the session has a key management client, to check that it is enabled and can be accessed with the token of the
+// session. The service authorization from Block Storage to the instance is then checked with the IAM policies of
+// the account of the key, if the session has a policy endpoint.
+func (vpcs *VPCSession) validateEncryptionKey(crn string, zone string) error {
+	keyCRN, err := keymanagement.ParseKeyCRN(crn)
+	if err != nil {
+		vpcs.Logger.Error("Invalid encryption key CRN", zap.String("crn", crn), zap.Error(err))
+		return userError.GetUserError(userError.InvalidEncryptionKeyCRN, err, crn, err.Error())
+	}
+	if !keyCRN.CompatibleWithZone(zone) {
+		return userError.GetUserError(userError.EncryptionKeyRegionMismatch, nil, keyCRN.Region, zone, keymanagement.ZoneRegion(zone))
+	}
+	if vpcs.keyManager == nil {
+		return nil
+	}
+
+	vpcs.Logger.Info("Checking the encryption key with the key management service", zap.String("crn", crn))
+	key, err := vpcs.keyManager.GetKey(keyCRN, vpcs.Logger)
+	if err != nil {
+		vpcs.Logger.Error("Failed to get the encryption key", zap.String("crn", crn), zap.Error(err))
+		if metadata := models.GetResponseMetadata(err); metadata != nil {
+			switch metadata.StatusCode {
+			case http.StatusNotFound:
+				return GetBackendUserError(userError.EncryptionKeyNotFound, err, keyCRN.KeyID, keyCRN.InstanceID)
+			case http.StatusUnauthorized, http.StatusForbidden:
+				return GetBackendUserError(userError.EncryptionKeyAccessDenied, err, keyCRN.InstanceID)
+			}
+		}
+		return GetBackendUserError(userError.EncryptionKeyCheckFailed, err, crn)
+	}
+	if key.State != models.KeyStateActive {
+		return userError.GetUserError(userError.EncryptionKeyNotEnabled, nil, keyCRN.KeyID, models.KeyStateName(key.State))
+	}
+
+	authorized, checked, err := vpcs.keyManager.CheckServiceAuthorization(keyCRN, vpcs.Logger)
+	if err != nil {
+		vpcs.Logger.Error("Failed to check the service authorization of the encryption key", zap.String("crn", crn), zap.Error(err))
+		return GetBackendUserError(userError.EncryptionKeyCheckFailed, err, crn)
+	}
+	if checked && !authorized {
+		return userError.GetUserError(userError.EncryptionKeyNotAuthorized, nil, keyCRN.InstanceID, keyCRN.ServiceName)
+	}
+	return nil
+}
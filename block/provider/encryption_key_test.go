@@ -0,0 +1,171 @@
+/**
+ * Copyright 2020 IBM Corp.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package provider ...
+package provider
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/IBM/ibmcloud-volume-interface/lib/provider"
+	userError "github.com/IBM/ibmcloud-volume-vpc/common/messages"
+	keyManagementFakes "github.com/IBM/ibmcloud-volume-vpc/common/vpcclient/keymanagement/fakes"
+	"github.com/IBM/ibmcloud-volume-vpc/common/vpcclient/models"
+	serviceFakes "github.com/IBM/ibmcloud-volume-vpc/common/vpcclient/vpcvolume/fakes"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testKeyCRN is the CRN of a Key Protect root key in us-south
+const testKeyCRN = "crn:v1:bluemix:public:kms:us-south:a/account-1:instance-1:key:key-1"
+
+func TestValidateEncryptionKey(t *testing.T) {
+	logger, teardown := GetTestLogger(t)
+	defer teardown()
+	userError.MessagesEn = userError.InitMessages()
+
+	testCases := []struct {
+		name         string
+		crn          string
+		zone         string
+		noKeyManager bool
+		key          *models.Key
+		keyErr       error
+		authorized   bool
+		authChecked  bool
+		authErr      error
+		expectedErr  string
+	}{
+		{
+			name: "enabled key of the region",
+			crn:  testKeyCRN,
+			zone: "us-south-1",
+			key:  &models.Key{ID: "key-1", State: models.KeyStateActive},
+		}, {
+			name:        "enabled key of an instance Block Storage is authorized to use",
+			crn:         testKeyCRN,
+			zone:        "us-south-1",
+			key:         &models.Key{ID: "key-1", State: models.KeyStateActive},
+			authorized:  true,
+			authChecked: true,
+		}, {
+			name:        "enabled key of an instance Block Storage is not authorized to use",
+			crn:         testKeyCRN,
+			zone:        "us-south-1",
+			key:         &models.Key{ID: "key-1", State: models.KeyStateActive},
+			authChecked: true,
+			expectedErr: userError.EncryptionKeyNotAuthorized,
+		}, {
+			name:        "IAM policy management failure",
+			crn:         testKeyCRN,
+			key:         &models.Key{ID: "key-1", State: models.KeyStateActive},
+			authChecked: true,
+			authErr:     &models.Error{ResponseMetadata: models.ResponseMetadata{StatusCode: http.StatusForbidden}},
+			expectedErr: userError.EncryptionKeyCheckFailed,
+		}, {
+			name:         "key without key management service",
+			crn:          testKeyCRN,
+			zone:         "us-south-1",
+			noKeyManager: true,
+		}, {
+			name:        "invalid CRN",
+			crn:         "crn:v1:bluemix:public:is:us-south:a/account-1::volume:volume-1",
+			expectedErr: userError.InvalidEncryptionKeyCRN,
+		}, {
+			name:        "key of another region",
+			crn:         testKeyCRN,
+			zone:        "eu-de-1",
+			expectedErr: userError.EncryptionKeyRegionMismatch,
+		}, {
+			name:        "suspended key",
+			crn:         testKeyCRN,
+			key:         &models.Key{ID: "key-1", State: models.KeyStateSuspended},
+			expectedErr: userError.EncryptionKeyNotEnabled,
+		}, {
+			name:        "unknown key",
+			crn:         testKeyCRN,
+			keyErr:      &models.KeyManagementError{ResponseMetadata: models.ResponseMetadata{StatusCode: http.StatusNotFound}},
+			expectedErr: userError.EncryptionKeyNotFound,
+		}, {
+			name:        "instance not accessible with the token of the session",
+			crn:         testKeyCRN,
+			keyErr:      &models.KeyManagementError{ResponseMetadata: models.ResponseMetadata{StatusCode: http.StatusForbidden}},
+			expectedErr: userError.EncryptionKeyAccessDenied,
+		}, {
+			name:        "key management service failure",
+			crn:         testKeyCRN,
+			keyErr:      errors.New("key management service is unavailable"),
+			expectedErr: userError.EncryptionKeyCheckFailed,
+		},
+	}
+
+	for _, testcase := range testCases {
+		t.Run(testcase.name, func(t *testing.T) {
+			keyManager := &keyManagementFakes.KeyManager{}
+			keyManager.GetKeyReturns(testcase.key, testcase.keyErr)
+			keyManager.CheckServiceAuthorizationReturns(testcase.authorized, testcase.authChecked, testcase.authErr)
+			vpcs := &VPCSession{Logger: logger, keyManager: keyManager}
+			if testcase.noKeyManager {
+				vpcs.keyManager = nil
+			}
+
+			err := vpcs.validateEncryptionKey(testcase.crn, testcase.zone)
+			if testcase.expectedErr != "" {
+				assert.Equal(t, testcase.expectedErr, userError.GetUserErrorCode(err))
+				return
+			}
+			assert.Nil(t, err)
+			if !testcase.noKeyManager {
+				keyCRN, _ := keyManager.GetKeyArgsForCall(0)
+				assert.Equal(t, "instance-1", keyCRN.InstanceID)
+				assert.Equal(t, "key-1", keyCRN.KeyID)
+				keyCRN, _ = keyManager.CheckServiceAuthorizationArgsForCall(0)
+				assert.Equal(t, "account-1", keyCRN.AccountID)
+				assert.Equal(t, "instance-1", keyCRN.InstanceID)
+			}
+		})
+	}
+}
+
+func TestCreateVolumeEncryptionKey(t *testing.T) {
+	logger, teardown := GetTestLogger(t)
+	defer teardown()
+	userError.MessagesEn = userError.InitMessages()
+
+	vpcs, uc, _, err := GetTestOpenSession(t, logger)
+	require.Nil(t, err)
+	volumeService := &serviceFakes.VolumeService{}
+	uc.VolumeServiceReturns(volumeService)
+	keyManager := &keyManagementFakes.KeyManager{}
+	keyManager.GetKeyReturns(&models.Key{ID: "key-1", State: models.KeyStateDestroyed}, nil)
+	vpcs.keyManager = keyManager
+
+	// Volume of a destroyed key is not ordered
+	_, err = vpcs.CreateVolume(provider.Volume{
+		Name:     String("volume"),
+		Capacity: Int(10),
+		Az:       "us-south-1",
+		VPCVolume: provider.VPCVolume{
+			Profile:             &provider.Profile{Name: "general-purpose"},
+			ResourceGroup:       &provider.ResourceGroup{ID: "rg-1"},
+			VolumeEncryptionKey: &provider.VolumeEncryptionKey{CRN: testKeyCRN},
+		},
+	})
+	assert.Equal(t, userError.EncryptionKeyNotEnabled, userError.GetUserErrorCode(err))
+	assert.Equal(t, 0, volumeService.CreateVolumeCallCount())
+}
@@ -22,8 +22,8 @@ import (
 
 	"github.com/IBM/ibmcloud-volume-interface/lib/metrics"
 	"github.com/IBM/ibmcloud-volume-interface/lib/provider"
-	userError "github.com/IBM/ibmcloud-volume-vpc/common/messages"
 	"github.com/IBM/ibmcloud-volume-vpc/common/vpcclient/models"
+	"github.com/IBM/ibmcloud-volume-vpc/common/vpctracing"
 	"go.uber.org/zap"
 )
 
@@ -36,6 +36,8 @@ const (
 func (vpcs *VPCSession) ExpandVolume(expandVolumeRequest provider.ExpandVolumeRequest) (size int64, err error) {
 	vpcs.Logger.Debug("Entry of ExpandVolume method...")
 	defer vpcs.Logger.Debug("Exit from ExpandVolume method...")
+	vpcs, end := vpcs.trace("ExpandVolume", vpctracing.VolumeIDKey.String(expandVolumeRequest.VolumeID))
+	defer func() { end(err) }()
 	defer metrics.UpdateDurationFromStart(vpcs.Logger, "ExpandVolume", time.Now())
 
 	// Get volume details
@@ -58,20 +60,20 @@ func (vpcs *VPCSession) ExpandVolume(expandVolumeRequest provider.ExpandVolumeRe
 
 	vpcs.Logger.Info("Calling VPC provider for volume expand...")
 	var volume *models.Volume
-	err = retry(vpcs.Logger, func() error {
+	err = tracedRetry(vpcs.tracing, vpcs.Logger, func() error {
 		volume, err = vpcs.Apiclient.VolumeService().ExpandVolume(expandVolumeRequest.VolumeID, volumeTemplate, vpcs.Logger)
 		return err
 	})
 
 	if err != nil {
 		vpcs.Logger.Debug("Failed to expand volume from VPC provider", zap.Reflect("BackendError", err))
-		return -1, userError.GetUserError("FailedToExpandVolume", err, expandVolumeRequest.VolumeID)
+		return -1, GetBackendUserError("FailedToExpandVolume", err, expandVolumeRequest.VolumeID)
 	}
 
 	vpcs.Logger.Info("Successfully accepted volume expansion request, now waiting for volume state equal to available")
 	err = WaitForValidVolumeState(vpcs, volume)
 	if err != nil {
-		return -1, userError.GetUserError("VolumeNotInValidState", err, volume.ID)
+		return -1, GetBackendUserError("VolumeNotInValidState", err, volume.ID)
 	}
 
 	vpcs.Logger.Info("Volume got valid (available) state", zap.Reflect("VolumeDetails", volume))
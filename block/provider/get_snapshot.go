@@ -21,25 +21,27 @@ import (
 	"github.com/IBM/ibmcloud-volume-interface/lib/provider"
 	userError "github.com/IBM/ibmcloud-volume-vpc/common/messages"
 	"github.com/IBM/ibmcloud-volume-vpc/common/vpcclient/models"
+	"github.com/IBM/ibmcloud-volume-vpc/common/vpctracing"
 	"go.uber.org/zap"
 )
 
 // GetSnapshot get snapshot
-func (vpcs *VPCSession) GetSnapshot(snapshotID string) (*provider.Snapshot, error) {
+func (vpcs *VPCSession) GetSnapshot(snapshotID string) (_ *provider.Snapshot, err error) {
 	vpcs.Logger.Info("Entry GetSnapshot", zap.Reflect("SnapshotID", snapshotID))
 	defer vpcs.Logger.Info("Exit GetSnapshot", zap.Reflect("SnapshotID", snapshotID))
+	vpcs, end := vpcs.trace("GetSnapshot", vpctracing.SnapshotIDKey.String(snapshotID))
+	defer func() { end(err) }()
 
 	vpcs.Logger.Info("Getting snapshot details from VPC provider...", zap.Reflect("SnapshotID", snapshotID))
 
 	var snapshot *models.Snapshot
-	var err error
-	err = retry(vpcs.Logger, func() error {
+	err = tracedRetry(vpcs.tracing, vpcs.Logger, func() error {
 		snapshot, err = vpcs.Apiclient.SnapshotService().GetSnapshot(snapshotID, vpcs.Logger)
 		return err
 	})
 
 	if err != nil {
-		return nil, userError.GetUserError("SnapshotIDNotFound", err, snapshotID)
+		return nil, GetBackendUserError("SnapshotIDNotFound", err, snapshotID)
 	}
 
 	vpcs.Logger.Info("Successfully retrieved snpashot details from VPC backend", zap.Reflect("snapshotDetails", snapshot))
@@ -62,13 +64,13 @@ func (vpcs *VPCSession) GetSnapshotByName(name string) (respSnap *provider.Snaps
 	vpcs.Logger.Info("Getting snapshot details from VPC provider...", zap.Reflect("SnapshotName", name))
 
 	var snapshot *models.Snapshot
-	err = retry(vpcs.Logger, func() error {
+	err = tracedRetry(vpcs.tracing, vpcs.Logger, func() error {
 		snapshot, err = vpcs.Apiclient.SnapshotService().GetSnapshotByName(name, vpcs.Logger)
 		return err
 	})
 
 	if err != nil {
-		return nil, userError.GetUserError("StorageFindFailedWithSnapshotName", err, snapshot)
+		return nil, GetBackendUserError("StorageFindFailedWithSnapshotName", err, snapshot)
 	}
 
 	vpcs.Logger.Info("Successfully retrieved snpashot details from VPC backend", zap.Reflect("snapshotDetails", snapshot))
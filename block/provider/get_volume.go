@@ -21,6 +21,7 @@ import (
 	"github.com/IBM/ibmcloud-volume-interface/lib/provider"
 	userError "github.com/IBM/ibmcloud-volume-vpc/common/messages"
 	"github.com/IBM/ibmcloud-volume-vpc/common/vpcclient/models"
+	"github.com/IBM/ibmcloud-volume-vpc/common/vpctracing"
 	"go.uber.org/zap"
 )
 
@@ -28,6 +29,8 @@ import (
 func (vpcs *VPCSession) GetVolume(id string) (respVolume *provider.Volume, err error) {
 	vpcs.Logger.Debug("Entry of GetVolume method...")
 	defer vpcs.Logger.Debug("Exit from GetVolume method...")
+	vpcs, end := vpcs.trace("GetVolume", vpctracing.VolumeIDKey.String(id))
+	defer func() { end(err) }()
 
 	vpcs.Logger.Info("Basic validation for volume ID...", zap.Reflect("VolumeID", id))
 	// validating volume ID
@@ -39,13 +42,13 @@ func (vpcs *VPCSession) GetVolume(id string) (respVolume *provider.Volume, err e
 	vpcs.Logger.Info("Getting volume details from VPC provider...", zap.Reflect("VolumeID", id))
 
 	var volume *models.Volume
-	err = retry(vpcs.Logger, func() error {
+	err = tracedRetry(vpcs.tracing, vpcs.Logger, func() error {
 		volume, err = vpcs.Apiclient.VolumeService().GetVolume(id, vpcs.Logger)
 		return err
 	})
 
 	if err != nil {
-		return nil, userError.GetUserError("StorageFindFailedWithVolumeId", err, id)
+		return nil, GetBackendUserError("StorageFindFailedWithVolumeId", err, id)
 	}
 
 	vpcs.Logger.Info("Successfully retrieved volume details from VPC backend", zap.Reflect("VolumeDetails", volume))
@@ -59,6 +62,8 @@ func (vpcs *VPCSession) GetVolume(id string) (respVolume *provider.Volume, err e
 func (vpcs *VPCSession) GetVolumeByName(name string) (respVolume *provider.Volume, err error) {
 	vpcs.Logger.Debug("Entry of GetVolumeByName method...")
 	defer vpcs.Logger.Debug("Exit from GetVolumeByName method...")
+	vpcs, end := vpcs.trace("GetVolumeByName")
+	defer func() { end(err) }()
 
 	vpcs.Logger.Info("Basic validation for volume Name...", zap.Reflect("VolumeName", name))
 	if len(name) <= 0 {
@@ -69,13 +74,13 @@ func (vpcs *VPCSession) GetVolumeByName(name string) (respVolume *provider.Volum
 	vpcs.Logger.Info("Getting volume details from VPC provider...", zap.Reflect("VolumeName", name))
 
 	var volume *models.Volume
-	err = retry(vpcs.Logger, func() error {
+	err = tracedRetry(vpcs.tracing, vpcs.Logger, func() error {
 		volume, err = vpcs.Apiclient.VolumeService().GetVolumeByName(name, vpcs.Logger)
 		return err
 	})
 
 	if err != nil {
-		return nil, userError.GetUserError("StorageFindFailedWithVolumeName", err, name)
+		return nil, GetBackendUserError("StorageFindFailedWithVolumeName", err, name)
 	}
 
 	vpcs.Logger.Info("Successfully retrieved volume details from VPC backend", zap.Reflect("VolumeDetails", volume))
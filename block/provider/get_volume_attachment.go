@@ -27,10 +27,11 @@ import (
 )
 
 // GetVolumeAttachment  get the volume attachment based on the request
-func (vpcs *VPCSession) GetVolumeAttachment(volumeAttachmentRequest provider.VolumeAttachmentRequest) (*provider.VolumeAttachmentResponse, error) {
+func (vpcs *VPCSession) GetVolumeAttachment(volumeAttachmentRequest provider.VolumeAttachmentRequest) (_ *provider.VolumeAttachmentResponse, err error) {
 	vpcs.Logger.Debug("Entry of GetVolumeAttachment method...", zap.Reflect("volumeAttachmentRequest", volumeAttachmentRequest))
 	defer vpcs.Logger.Debug("Exit from GetVolumeAttachment method...")
-	var err error
+	vpcs, end := vpcs.trace("GetVolumeAttachment", attachmentAttributes(volumeAttachmentRequest)...)
+	defer func() { end(err) }()
 
 	//check if ServiceSession is valid
 	if err = isValidServiceSession(vpcs); err != nil {
@@ -77,7 +78,7 @@ func (vpcs *VPCSession) getVolumeAttachmentByID(volumeAttachmentRequest models.V
 
 	if err != nil {
 		// API call is failed
-		userErr := userError.GetUserError(string(userError.VolumeAttachFindFailed), err, volumeAttachmentRequest.Volume.ID, *volumeAttachmentRequest.InstanceID)
+		userErr := GetBackendUserError(string(userError.VolumeAttachFindFailed), err, volumeAttachmentRequest.Volume.ID, *volumeAttachmentRequest.InstanceID)
 		return nil, userErr
 	}
 
@@ -103,7 +104,7 @@ func (vpcs *VPCSession) getVolumeAttachmentByVolumeID(volumeAttachmentRequest mo
 
 	if err != nil {
 		// API call is failed
-		userErr := userError.GetUserError(string(userError.VolumeAttachFindFailed), err, volumeAttachmentRequest.Volume.ID, *volumeAttachmentRequest.InstanceID)
+		userErr := GetBackendUserError(string(userError.VolumeAttachFindFailed), err, volumeAttachmentRequest.Volume.ID, *volumeAttachmentRequest.InstanceID)
 		return nil, userErr
 	}
 	// Iterate over the volume attachment list for given instance
@@ -20,10 +20,12 @@ package provider
 import (
 	"errors"
 	"testing"
+	"time"
 
 	"github.com/IBM/ibmcloud-volume-interface/lib/provider"
 	util "github.com/IBM/ibmcloud-volume-interface/lib/utils"
 	"github.com/IBM/ibmcloud-volume-interface/lib/utils/reasoncode"
+	userError "github.com/IBM/ibmcloud-volume-vpc/common/messages"
 	"github.com/IBM/ibmcloud-volume-vpc/common/vpcclient/models"
 	volumeServiceFakes "github.com/IBM/ibmcloud-volume-vpc/common/vpcclient/vpcvolume/fakes"
 	"github.com/stretchr/testify/assert"
@@ -234,3 +236,47 @@ func TestGetVolumeByName(t *testing.T) {
 		})
 	}
 }
+
+func TestGetVolumeEndpointUnavailable(t *testing.T) {
+	logger, teardown := GetTestLogger(t)
+	defer teardown()
+	userError.MessagesEn = userError.InitMessages()
+
+	vpcs, uc, _, err := GetTestOpenSession(t, logger)
+	assert.Nil(t, err)
+
+	volumeService := &volumeServiceFakes.VolumeService{}
+	uc.VolumeServiceReturns(volumeService)
+	volumeService.GetVolumeReturns(nil, &models.EndpointUnavailableError{BaseURL: "https://us-south.iaas.cloud.ibm.com", RetryAt: time.Now().Add(time.Minute)})
+
+	// Open circuit fails fast without retries
+	volume, err := vpcs.GetVolume("16f293bf-test-4bff-816f-e199c0c65db5")
+	assert.Nil(t, volume)
+	assert.Equal(t, userError.EndpointUnavailable, userError.GetUserErrorCode(err))
+	assert.Contains(t, err.Error(), "https://us-south.iaas.cloud.ibm.com")
+	assert.Equal(t, 1, volumeService.GetVolumeCallCount())
+}
+
+func TestGetVolumeReplay(t *testing.T) {
+	logger, teardown := GetTestLogger(t)
+	defer teardown()
+	userError.MessagesEn = userError.InitMessages()
+
+	vpcs := GetTestReplaySession(t, logger, "get_volume.json")
+
+	volume, err := vpcs.GetVolume("16f293bf-test-4bff-816f-e199c0c65db5")
+	assert.Nil(t, err)
+	if assert.NotNil(t, volume) {
+		assert.Equal(t, "16f293bf-test-4bff-816f-e199c0c65db5", volume.VolumeID)
+		assert.Equal(t, 10, *volume.Capacity)
+		assert.Equal(t, "3000", *volume.Iops)
+		assert.Equal(t, "us-south-1", volume.Az)
+		assert.Equal(t, "crn:v1:bluemix:public:is:us-south-1:a/test-account::volume:16f293bf-test-4bff-816f-e199c0c65db5", volume.CRN)
+	}
+
+	// Volume deleted out of band is not retried
+	volume, err = vpcs.GetVolume("2a4f0f4e-test-4b4e-9c4e-0e8b5e3f5a6d")
+	assert.Nil(t, volume)
+	assert.Equal(t, "StorageFindFailedWithVolumeId", userError.GetUserErrorCode(err))
+	assert.Contains(t, err.Error(), "Trace Code:a8f3e0a2-test-4c1f-8f4e-2b7d9c3e1f0a")
+}
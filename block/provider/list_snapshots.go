@@ -32,9 +32,11 @@ import (
 const startSnapshoIDNotFoundMsg = "start parameter is not valid"
 
 // ListSnapshots list all snapshots
-func (vpcs *VPCSession) ListSnapshots(limit int, start string, filters map[string]string) (*provider.SnapshotList, error) {
+func (vpcs *VPCSession) ListSnapshots(limit int, start string, filters map[string]string) (_ *provider.SnapshotList, err error) {
 	vpcs.Logger.Info("Entry ListeSnapshots")
 	defer vpcs.Logger.Info("Exit ListSnapshots")
+	vpcs, end := vpcs.trace("ListSnapshots")
+	defer func() { end(err) }()
 	defer metrics.UpdateDurationFromStart(vpcs.Logger, "ListSnapshots", time.Now())
 
 	if limit < 0 {
@@ -46,8 +48,13 @@ func (vpcs *VPCSession) ListSnapshots(limit int, start string, filters map[strin
 		limit = maxLimit
 	}
 
+	resourceGroupID, err := vpcs.explicitResourceGroup(filters)
+	if err != nil {
+		return nil, err
+	}
+
 	filter := &models.LisSnapshotFilters{
-		ResourceGroupID: filters["resource_group.id"],
+		ResourceGroupID: vpcs.listResourceGroup(resourceGroupID),
 		Name:            filters["name"],
 		SourceVolumeID:  filters["source_volume.id"],
 	}
@@ -55,17 +62,16 @@ func (vpcs *VPCSession) ListSnapshots(limit int, start string, filters map[strin
 	vpcs.Logger.Info("Getting snapshot list from VPC provider...", zap.Reflect("start", start), zap.Reflect("filters", filters))
 
 	var snapshots *models.SnapshotList
-	var err error
-	err = retry(vpcs.Logger, func() error {
+	err = tracedRetry(vpcs.tracing, vpcs.Logger, func() error {
 		snapshots, err = vpcs.Apiclient.SnapshotService().ListSnapshots(limit, start, filter, vpcs.Logger)
 		return err
 	})
 
 	if err != nil {
 		if strings.Contains(err.Error(), startSnapshoIDNotFoundMsg) {
-			return nil, userError.GetUserError("StartSnapshotIDNotFound", err, start)
+			return nil, GetBackendUserError("StartSnapshotIDNotFound", err, start)
 		}
-		return nil, userError.GetUserError("ListSnapshotsFailed", err)
+		return nil, GetBackendUserError("ListSnapshotsFailed", err)
 	}
 
 	vpcs.Logger.Info("Successfully retrieved snapshot list from VPC backend", zap.Reflect("SnapshotList", snapshots))
@@ -35,9 +35,11 @@ const (
 )
 
 // ListVolumes list all volumes
-func (vpcs *VPCSession) ListVolumes(limit int, start string, tags map[string]string) (*provider.VolumeList, error) {
+func (vpcs *VPCSession) ListVolumes(limit int, start string, tags map[string]string) (_ *provider.VolumeList, err error) {
 	vpcs.Logger.Info("Entry ListVolumes", zap.Reflect("start", start), zap.Reflect("filters", tags))
 	defer vpcs.Logger.Info("Exit ListVolumes", zap.Reflect("start", start), zap.Reflect("filters", tags))
+	vpcs, end := vpcs.trace("ListVolumes")
+	defer func() { end(err) }()
 	defer metrics.UpdateDurationFromStart(vpcs.Logger, "ListVolumes", time.Now())
 
 	if limit < 0 {
@@ -49,9 +51,14 @@ func (vpcs *VPCSession) ListVolumes(limit int, start string, tags map[string]str
 		limit = maxLimit
 	}
 
+	resourceGroupID, err := vpcs.explicitResourceGroup(tags)
+	if err != nil {
+		return nil, err
+	}
+
 	filters := &models.ListVolumeFilters{
 		// Tag:          tags["tag"],
-		ResourceGroupID: tags["resource_group.id"],
+		ResourceGroupID: vpcs.listResourceGroup(resourceGroupID),
 		ZoneName:        tags["zone.name"],
 		VolumeName:      tags["name"],
 	}
@@ -59,17 +66,16 @@ func (vpcs *VPCSession) ListVolumes(limit int, start string, tags map[string]str
 	vpcs.Logger.Info("Getting volumes list from VPC provider...", zap.Reflect("start", start), zap.Reflect("filters", filters))
 
 	var volumes *models.VolumeList
-	var err error
-	err = retry(vpcs.Logger, func() error {
+	err = tracedRetry(vpcs.tracing, vpcs.Logger, func() error {
 		volumes, err = vpcs.Apiclient.VolumeService().ListVolumes(limit, start, filters, vpcs.Logger)
 		return err
 	})
 
 	if err != nil {
 		if strings.Contains(err.Error(), startVolumeIDNotFoundMsg) {
-			return nil, userError.GetUserError("StartVolumeIDNotFound", err, start)
+			return nil, GetBackendUserError("StartVolumeIDNotFound", err, start)
 		}
-		return nil, userError.GetUserError("ListVolumesFailed", err)
+		return nil, GetBackendUserError("ListVolumesFailed", err)
 	}
 
 	vpcs.Logger.Info("Successfully retrieved volumes list from VPC backend", zap.Reflect("VolumesList", volumes))
@@ -22,7 +22,6 @@ import (
 	"errors"
 	"fmt"
 	"net/http"
-	"os"
 	"strings"
 	"time"
 
@@ -38,7 +37,12 @@ import (
 	vpcauth "github.com/IBM/ibmcloud-volume-vpc/common/auth"
 	"github.com/IBM/ibmcloud-volume-vpc/common/messages"
 	userError "github.com/IBM/ibmcloud-volume-vpc/common/messages"
+	vpcclient "github.com/IBM/ibmcloud-volume-vpc/common/vpcclient/client"
+	"github.com/IBM/ibmcloud-volume-vpc/common/vpcclient/keymanagement"
+	"github.com/IBM/ibmcloud-volume-vpc/common/vpcclient/resourcemanager"
 	"github.com/IBM/ibmcloud-volume-vpc/common/vpcclient/riaas"
+	"github.com/IBM/ibmcloud-volume-vpc/common/vpcmetrics"
+	"github.com/IBM/ibmcloud-volume-vpc/common/vpctracing"
 	"go.uber.org/zap"
 )
 
@@ -67,6 +71,38 @@ type VPCBlockProvider struct {
 	ClientProvider riaas.RegionalAPIClientProvider
 	httpClient     *http.Client
 	APIConfig      riaas.Config
+
+	// VolumeLease enables the volume tag based lease for sessions of this provider
+	VolumeLease *VolumeLeaseConfig
+
+	// BatchWorkers is the worker pool size of AttachVolumes and DetachVolumes, defaults to DefaultBatchWorkers
+	BatchWorkers int
+
+	// VolumePoller enables the poller serving the wait operations of all sessions of this provider
+	VolumePoller *VolumePollerConfig
+	poller       *VolumePoller
+
+	// ReadCache enables the read-through cache shared by the sessions of this provider
+	ReadCache *ReadCacheConfig
+	readCache *readCache
+
+	// HTTPLog configures the HTTP debug events logged when ServerConfig.DebugTrace is set
+	HTTPLog *vpcclient.HTTPLogConfig
+
+	// SessionPool enables the reuse of the access tokens by the sessions of this provider
+	SessionPool *SessionPoolConfig
+	sessionPool *sessionPool
+
+	// credentials are the ones reloaded on rotation, see ReloadCredentials
+	credentials *credentialsHolder
+
+	// resourceGroupNames caches the resource group IDs of the names resolved by the sessions of this provider
+	resourceGroupNames *resourceGroupCache
+
+	// accounts are the providers of the multi-account mode, see AccountProvider
+	accounts *accountProviders
+	// accountKey is the account or resource group of an account provider
+	accountKey string
 }
 
 var _ local.Provider = &VPCBlockProvider{}
@@ -97,10 +133,6 @@ func NewProvider(conf *vpcconfig.VPCBlockConfig, k8sClient *k8s_utils.Kubernetes
 	//Mark this as enabled/active
 	conf.VPCConfig.VPCTypeEnabled = VPCNextGen
 
-	contextCF, err := vpcauth.NewVPCContextCredentialsFactory(conf, k8sClient)
-	if err != nil {
-		return nil, err
-	}
 	timeoutString := conf.VPCConfig.VPCTimeout
 	if timeoutString == "" || timeoutString == "0s" {
 		logger.Info("Using VPC default timeout")
@@ -117,12 +149,17 @@ func NewProvider(conf *vpcconfig.VPCBlockConfig, k8sClient *k8s_utils.Kubernetes
 		return nil, err
 	}
 
+	contextCF, err := vpcauth.NewVPCContextCredentialsFactory(conf, k8sClient, httpClient)
+	if err != nil {
+		return nil, err
+	}
+
 	// SetRetryParameters sets the retry logic parameters
 	SetRetryParameters(conf.VPCConfig.MaxRetryAttempt, conf.VPCConfig.MaxRetryGap)
 	provider := &VPCBlockProvider{
 		timeout:        timeout,
 		Config:         conf,
-		tokenGenerator: &tokenGenerator{config: conf.VPCConfig},
+		tokenGenerator: newTokenGenerator(conf),
 		ContextCF:      contextCF,
 		httpClient:     httpClient,
 		APIConfig: riaas.Config{
@@ -131,8 +168,26 @@ func NewProvider(conf *vpcconfig.VPCBlockConfig, k8sClient *k8s_utils.Kubernetes
 			APIVersion:    conf.VPCConfig.G2APIVersion,
 			APIGeneration: conf.VPCConfig.G2VPCAPIGeneration,
 			ResourceGroup: conf.VPCConfig.G2ResourceGroupID,
+			Coalescer:     vpcclient.NewCoalescer(),
+		},
+		poller:             NewVolumePoller(VolumePollerConfig{}),
+		readCache:          newReadCache(),
+		sessionPool:        newSessionPool(),
+		resourceGroupNames: newResourceGroupCache(resourceGroupCacheTTL),
+		credentials: &credentialsHolder{
+			newContextCF: func(conf *vpcconfig.VPCBlockConfig) (local.ContextCredentialsFactory, error) {
+				return vpcauth.NewVPCContextCredentialsFactory(conf, k8sClient, httpClient)
+			},
 		},
 	}
+	if len(conf.Accounts) > 0 {
+		credentialSet, err := NewCredentialSet(conf.Accounts)
+		if err != nil {
+			logger.Error("Invalid account credentials", zap.Error(err))
+			return nil, err
+		}
+		provider.accounts = &accountProviders{credentials: credentialSet, providers: map[vpcconfig.AccountConfig]*VPCBlockProvider{}}
+	}
 	// Update VPC config for IKS deployment
 	provider.Config.VPCConfig.IsIKS = conf.IKSConfig != nil && conf.IKSConfig.Enabled
 	userError.MessagesEn = messages.InitMessages()
@@ -142,7 +197,16 @@ func NewProvider(conf *vpcconfig.VPCBlockConfig, k8sClient *k8s_utils.Kubernetes
 // ContextCredentialsFactory ...
 func (vpcp *VPCBlockProvider) ContextCredentialsFactory(zone *string) (local.ContextCredentialsFactory, error) {
 	//  Datacenter name not required by VPC provider implementation
-	return vpcp.ContextCF, nil
+	contextCF := vpcp.contextCredentialsFactory()
+	if vpcp.SessionPool != nil && vpcp.sessionPool != nil && contextCF != nil {
+		// Tokens are pooled per credentials and zone of the request
+		scope := vpcp.credentialsScope()
+		if zone != nil {
+			scope += "\x00" + *zone
+		}
+		return &pooledCredentialsFactory{ContextCredentialsFactory: contextCF, pool: vpcp.sessionPool, config: *vpcp.SessionPool, scope: scope}, nil
+	}
+	return contextCF, nil
 }
 
 // OpenSession opens a session on the provider
@@ -153,28 +217,31 @@ func (vpcp *VPCBlockProvider) OpenSession(ctx context.Context, contextCredential
 		ctxLogger.Debug("Exiting OpenSession")
 	}()
 
-	// validate that we have what we need - i.e. valid credentials
-	if contextCredentials.Credential == "" {
-		return nil, util.NewError("Error Insufficient Authentication", "No authentication credential provided")
+	// Sessions of the multi-account mode use the credentials of the account named by the request context
+	if account, ok := AccountFromContext(ctx); ok && vpcp.accounts != nil {
+		return vpcp.openAccountSession(ctx, account, ctxLogger)
 	}
-
-	if vpcp.Config.ServerConfig.DebugTrace {
-		vpcp.APIConfig.DebugWriter = os.Stdout
+	if vpcp.accountKey != "" && contextCredentials.AuthType == "" && contextCredentials.Credential == "" {
+		var err error
+		contextCredentials, err = vpcp.accountCredentials(ctx, ctxLogger)
+		if err != nil {
+			return nil, err
+		}
 	}
 
-	if vpcp.ClientProvider == nil {
-		vpcp.ClientProvider = riaas.DefaultRegionalAPIClientProvider{}
+	// validate that we have what we need - i.e. valid credentials, trusted profiles default to the one of the config
+	if contextCredentials.Credential == "" && !isTrustedProfileAuthType(contextCredentials.AuthType) {
+		return nil, util.NewError("Error Insufficient Authentication", "No authentication credential provided")
 	}
-	ctxLogger.Debug("", zap.Reflect("apiConfig.BaseURL", vpcp.APIConfig.BaseURL))
 
-	if ctx != nil && ctx.Value(provider.RequestID) != nil {
-		// set ContextID only of speicifed in the context
-		vpcp.APIConfig.ContextID = fmt.Sprintf("%v", ctx.Value(provider.RequestID))
-		ctxLogger.Info("", zap.Reflect("apiConfig.ContextID", vpcp.APIConfig.ContextID))
-	}
-	client, err := vpcp.ClientProvider.New(vpcp.APIConfig)
-	if err != nil {
-		return nil, err
+	// Trusted profile credentials are exchanged for an access token, the exchange is repeated to refresh it
+	refreshCredentials := contextCredentials
+	if isTrustedProfileAuthType(contextCredentials.AuthType) {
+		var err error
+		contextCredentials, err = vpcp.trustedProfileCredentials(ctx, contextCredentials, ctxLogger)
+		if err != nil {
+			return nil, err
+		}
 	}
 
 	// Create a token for all other API calls
@@ -182,44 +249,104 @@ func (vpcp *VPCBlockProvider) OpenSession(ctx context.Context, contextCredential
 	if err != nil {
 		return nil, err
 	}
-	ctxLogger.Debug("", zap.Reflect("Token", token.Token))
 
-	err = client.Login(token.Token)
+	// Account of the session is the one of the token, verified against the expected one
+	contextCredentials.IAMAccountID, err = vpcp.tokenAccountID(contextCredentials, token.Token, ctxLogger)
 	if err != nil {
 		return nil, err
 	}
+	ctxLogger.Debug("", zap.Reflect("Token", token.Token))
 
-	// Update retry logic default values
-	if vpcp.Config.VPCConfig.MaxRetryAttempt > 0 {
-		ctxLogger.Debug("", zap.Reflect("MaxRetryAttempt", vpcp.Config.VPCConfig.MaxRetryAttempt))
-		maxRetryAttempt = vpcp.Config.VPCConfig.MaxRetryAttempt
+	// Config of the session is derived from the one of the provider, which is shared by the concurrent sessions
+	clientProvider := vpcp.ClientProvider
+	if clientProvider == nil {
+		clientProvider = riaas.DefaultRegionalAPIClientProvider{}
+	}
+	apiConfig := vpcp.APIConfig
+	ctxLogger.Debug("", zap.Reflect("apiConfig.BaseURL", apiConfig.BaseURL))
+
+	if ctx != nil && ctx.Value(provider.RequestID) != nil {
+		// set ContextID only of speicifed in the context
+		apiConfig.ContextID = fmt.Sprintf("%v", ctx.Value(provider.RequestID))
+		ctxLogger.Info("", zap.Reflect("apiConfig.ContextID", apiConfig.ContextID))
 	}
-	if vpcp.Config.VPCConfig.MaxRetryGap > 0 {
-		ctxLogger.Debug("", zap.Reflect("MaxRetryGap", vpcp.Config.VPCConfig.MaxRetryGap))
-		maxRetryGap = vpcp.Config.VPCConfig.MaxRetryGap
+	// Resource group of the request context is the one of the header, and the default of the operations
+	resourceGroup, _ := ResourceGroupFromContext(ctx)
+	if resourceGroup != "" {
+		apiConfig.ResourceGroup = resourceGroup
+	}
+	// Account of the session keys the shared rate limiter, the account providers never share it even for opaque tokens
+	apiConfig.AccountID = contextCredentials.IAMAccountID
+	if apiConfig.AccountID == "" {
+		apiConfig.AccountID = vpcp.accountKey
+	}
+	// Spans of the session are children of the span in the context
+	tracing := ctx
+	if tracing == nil {
+		tracing = context.Background()
+	}
+	apiConfig.Middlewares = append(apiConfig.Middlewares[:len(apiConfig.Middlewares):len(apiConfig.Middlewares)], vpctracing.Middleware(), vpcmetrics.Middleware())
+	// Requests and responses are logged as debug events of the session logger
+	if vpcp.Config.ServerConfig != nil && vpcp.Config.ServerConfig.DebugTrace {
+		var httpLog vpcclient.HTTPLogConfig
+		if vpcp.HTTPLog != nil {
+			httpLog = *vpcp.HTTPLog
+		}
+		apiConfig.Middlewares = append(apiConfig.Middlewares, vpcclient.HTTPLogMiddleware(ctxLogger, httpLog))
+	}
+	// Pooled token of the session is dropped once the API rejects it
+	if vpcp.SessionPool != nil && vpcp.sessionPool != nil {
+		apiConfig.Middlewares = append(apiConfig.Middlewares, vpcp.sessionPool.invalidateMiddleware())
+	}
+	client, err := clientProvider.New(apiConfig)
+	if err != nil {
+		return nil, err
 	}
 
-	//Update retry logic for custom retry with default values
-	/*
-		Default MaxVPCRetryAttempt = 46 times(~7 mins), MinVPCRetryGap = 3sec , MinVPCRetryGapAttempt = 3sec
-		1.) Honour the MinVPCRetryGap only if it is greater than 3 and less than 10 sec
-		2.) Honour the MinVPCRetryGapAttempt only if it is greater than 0
-		3.) Honour the MaxVPCRetryAttempt only if it is greater than 46 ( ~7 mins default)
-	*/
-	if vpcp.Config.VPCConfig.MinVPCRetryGap > ConstMinVPCRetryGap && vpcp.Config.VPCConfig.MinVPCRetryGap < ConstantRetryGap {
-		minVPCRetryGap = vpcp.Config.VPCConfig.MinVPCRetryGap
+	// Token of the session is refreshed once the API rejects it, requests are replayed with the new one
+	tokenSource := vpcp.sessionTokenSource(tracing, refreshCredentials, token.Token, contextCredentials.IAMAccountID, ctxLogger)
+	err = client.LoginWithTokenSource(tokenSource)
+	if err != nil {
+		return nil, err
 	}
 
-	if vpcp.Config.VPCConfig.MinVPCRetryGapAttempt > 0 {
-		minVPCRetryGapAttempt = vpcp.Config.VPCConfig.MinVPCRetryGapAttempt
+	// Resource group names are resolved with the Resource Manager, with the token of the session
+	resourceManager := resourcemanager.New(resourcemanager.Config{
+		BaseURL:     vpcp.Config.ResourceManagerURL,
+		ContextID:   apiConfig.ContextID,
+		HTTPClient:  apiConfig.HTTPClient,
+		Middlewares: apiConfig.Middlewares,
+	})
+	resourceManager.LoginWithTokenSource(tokenSource)
+
+	// Encryption keys are read from the key management service, with the token of the session, if it is configured
+	var keyManager keymanagement.KeyManager
+	if vpcp.Config.KeyManagementURL != "" || len(vpcp.Config.KeyManagementInstanceURLs) > 0 {
+		keyManagement := keymanagement.New(keymanagement.Config{
+			BaseURL:      vpcp.Config.KeyManagementURL,
+			InstanceURLs: vpcp.Config.KeyManagementInstanceURLs,
+			PolicyURL:    vpcp.Config.KeyManagementPolicyURL,
+			ContextID:    apiConfig.ContextID,
+			HTTPClient:   apiConfig.HTTPClient,
+			Middlewares:  apiConfig.Middlewares,
+		})
+		keyManagement.LoginWithTokenSource(tokenSource)
+		keyManager = keyManagement.KeyService()
 	}
 
-	if vpcp.Config.VPCConfig.MaxVPCRetryAttempt > ConstMaxVPCRetryAttempt {
-		maxVPCRetryAttempt = vpcp.Config.VPCConfig.MaxVPCRetryAttempt
+	// Reads are served from the cache shared by the sessions of the provider, except for an unknown account
+	if vpcp.ReadCache != nil && vpcp.readCache != nil {
+		client = newCachedRegionalAPI(client, vpcp.readCache, *vpcp.ReadCache, contextCredentials.IAMAccountID)
 	}
 
-	ctxLogger.Info("VPC Retry details for WaitAttach and WaitDetach operations", zap.Reflect("MinVPCRetryGap", minVPCRetryGap), zap.Reflect("MinVPCRetryGapAttempt", minVPCRetryGapAttempt), zap.Reflect("MaxVPCRetryAttempt", maxVPCRetryAttempt))
+	// Waits of the session are served by the shared poller only if it is enabled
+	var poller *VolumePoller
+	if vpcp.VolumePoller != nil && vpcp.poller != nil {
+		poller = vpcp.poller.configure(*vpcp.VolumePoller)
+	}
 
+	apiRetry := vpcp.sessionRetry(ctxLogger)
+	apiRetry.tracing = tracing
 	vpcSession := &VPCSession{
 		VPCAccountID:          contextCredentials.IAMAccountID,
 		Config:                vpcp.Config,
@@ -229,12 +356,60 @@ func (vpcp *VPCBlockProvider) OpenSession(ctx context.Context, contextCredential
 		Apiclient:             client,
 		APIClientVolAttachMgr: client.VolumeAttachService(),
 		Logger:                ctxLogger,
-		APIRetry:              NewFlexyRetryDefault(),
+		APIRetry:              apiRetry,
 		SessionError:          nil,
+		VolumeLease:           vpcp.VolumeLease,
+		BatchWorkers:          vpcp.BatchWorkers,
+		poller:                poller,
+		ctx:                   ctx,
+		heldLeases:            newHeldVolumeLeases(),
+		tracing:               tracing,
+		resourceGroup:         resourceGroup,
+		resourceGroups:        resourceManager.ResourceGroupService(),
+		resourceGroupNames:    vpcp.resourceGroupNames,
+		keyManager:            keyManager,
 	}
 	return vpcSession, nil
 }
 
+// sessionRetry derives the retry parameters of a session from the config, leaving the package defaults untouched
+func (vpcp *VPCBlockProvider) sessionRetry(ctxLogger *zap.Logger) FlexyRetry {
+	apiRetry := NewFlexyRetryDefault()
+	vpcConfig := vpcp.Config.VPCConfig
+
+	// Update retry logic default values
+	if vpcConfig.MaxRetryAttempt > 0 {
+		ctxLogger.Debug("", zap.Reflect("MaxRetryAttempt", vpcConfig.MaxRetryAttempt))
+		apiRetry.maxRetryAttempt = vpcConfig.MaxRetryAttempt
+	}
+	if vpcConfig.MaxRetryGap > 0 {
+		ctxLogger.Debug("", zap.Reflect("MaxRetryGap", vpcConfig.MaxRetryGap))
+		apiRetry.maxRetryGap = vpcConfig.MaxRetryGap
+	}
+
+	//Update retry logic for custom retry with default values
+	/*
+		Default MaxVPCRetryAttempt = 46 times(~7 mins), MinVPCRetryGap = 3sec , MinVPCRetryGapAttempt = 3sec
+		1.) Honour the MinVPCRetryGap only if it is greater than 3 and less than 10 sec
+		2.) Honour the MinVPCRetryGapAttempt only if it is greater than 0
+		3.) Honour the MaxVPCRetryAttempt only if it is greater than 46 ( ~7 mins default)
+	*/
+	if vpcConfig.MinVPCRetryGap > ConstMinVPCRetryGap && vpcConfig.MinVPCRetryGap < ConstantRetryGap {
+		apiRetry.minVPCRetryGap = vpcConfig.MinVPCRetryGap
+	}
+
+	if vpcConfig.MinVPCRetryGapAttempt > 0 {
+		apiRetry.minVPCRetryGapAttempt = vpcConfig.MinVPCRetryGapAttempt
+	}
+
+	if vpcConfig.MaxVPCRetryAttempt > ConstMaxVPCRetryAttempt {
+		apiRetry.maxVPCRetryAttempt = vpcConfig.MaxVPCRetryAttempt
+	}
+
+	ctxLogger.Info("VPC Retry details for WaitAttach and WaitDetach operations", zap.Reflect("MinVPCRetryGap", apiRetry.minVPCRetryGap), zap.Reflect("MinVPCRetryGapAttempt", apiRetry.minVPCRetryGapAttempt), zap.Reflect("MaxVPCRetryAttempt", apiRetry.maxVPCRetryAttempt))
+	return apiRetry
+}
+
 // getAccessToken ...
 func getAccessToken(creds provider.ContextCredentials, logger *zap.Logger) (token *iam.AccessToken, err error) {
 	switch creds.AuthType {
@@ -20,6 +20,7 @@ package provider
 import (
 	"bytes"
 	"context"
+	"fmt"
 	"net/http"
 	"net/http/httptest"
 	"os"
@@ -33,7 +34,10 @@ import (
 	"github.com/IBM/ibmcloud-volume-interface/provider/auth"
 	"github.com/IBM/ibmcloud-volume-interface/provider/local"
 	vpcconfig "github.com/IBM/ibmcloud-volume-vpc/block/vpcconfig"
+	vpcclient "github.com/IBM/ibmcloud-volume-vpc/common/vpcclient/client"
+	"github.com/IBM/ibmcloud-volume-vpc/common/vpcclient/riaas"
 	"github.com/IBM/ibmcloud-volume-vpc/common/vpcclient/riaas/fakes"
+	"github.com/IBM/ibmcloud-volume-vpc/common/vpcclient/riaas/test"
 	volumeServiceFakes "github.com/IBM/ibmcloud-volume-vpc/common/vpcclient/vpcvolume/fakes"
 	"github.com/IBM/secret-utils-lib/pkg/k8s_utils"
 	"github.com/stretchr/testify/assert"
@@ -92,6 +96,32 @@ func GetTestLogger(t *testing.T) (logger *zap.Logger, teardown func()) {
 	return
 }
 
+// getTestSyncLogger is GetTestLogger for the tests logging from several goroutines
+func getTestSyncLogger(t *testing.T) (logger *zap.Logger, teardown func()) {
+	encoderCfg := zap.NewProductionEncoderConfig()
+	encoderCfg.TimeKey = "timestamp"
+	encoderCfg.EncodeTime = zapcore.ISO8601TimeEncoder
+
+	buf := &bytes.Buffer{}
+
+	logger = zap.New(
+		zapcore.NewCore(
+			zapcore.NewJSONEncoder(encoderCfg),
+			zapcore.Lock(zapcore.AddSync(buf)),
+			zap.NewAtomicLevelAt(zap.DebugLevel),
+		),
+		zap.AddCaller(),
+	)
+
+	teardown = func() {
+		assert.Nil(t, logger.Sync())
+		if t.Failed() {
+			t.Log(buf.String())
+		}
+	}
+	return
+}
+
 func TestNewProvider(t *testing.T) {
 	var err error
 	logger, teardown := GetTestLogger(t)
@@ -309,6 +339,39 @@ func GetTestOpenSession(t *testing.T, logger *zap.Logger) (sessn *VPCSession, uc
 	return
 }
 
+// GetTestReplaySession returns a session whose requests are served from the cassette in testdata/cassettes,
+// or recorded to it with RECORD_CASSETTES=true and the endpoint and token of RECORD_BASE_URL and RECORD_TOKEN
+func GetTestReplaySession(t *testing.T, logger *zap.Logger, cassette string) *VPCSession {
+	baseURL, token := os.Getenv("RECORD_BASE_URL"), os.Getenv("RECORD_TOKEN")
+	if baseURL == "" {
+		baseURL = TestEndpointURL
+	}
+	if token == "" {
+		token = TestProviderAccessToken
+	}
+	apiClient, err := riaas.New(riaas.Config{
+		BaseURL:    baseURL,
+		HTTPClient: test.CassetteHTTPClient(t, filepath.Join("testdata", "cassettes", cassette)),
+		ContextID:  "test-context",
+	})
+	require.Nil(t, err)
+	require.Nil(t, apiClient.Login(token))
+
+	return &VPCSession{
+		VPCAccountID: TestIKSAccountID,
+		ContextCredentials: provider.ContextCredentials{
+			AuthType:     provider.IAMAccessToken,
+			Credential:   token,
+			IAMAccountID: TestIKSAccountID,
+		},
+		VolumeType: "vpc-block",
+		Provider:   VPC,
+		Apiclient:  apiClient,
+		Logger:     logger,
+		APIRetry:   NewFlexyRetryDefault(),
+	}
+}
+
 func GetTestOpenInvalidSession(t *testing.T, logger *zap.Logger) (sessn *VPCSession, uc, sc *fakes.RegionalAPI, err error) {
 	vpcp, err := GetTestProvider(t, logger)
 
@@ -335,6 +398,77 @@ func GetTestOpenInvalidSession(t *testing.T, logger *zap.Logger) (sessn *VPCSess
 	return
 }
 
+func TestOpenSessionHTTPLog(t *testing.T) {
+	logger, teardown := GetTestLogger(t)
+	defer teardown()
+
+	vpcp, _ := GetTestProvider(t, logger)
+	vpcp.Config.ServerConfig = &config.ServerConfig{DebugTrace: true}
+	vpcp.HTTPLog = &vpcclient.HTTPLogConfig{MaxBodySize: 1024}
+	cp := &fakes.RegionalAPIClientProvider{}
+	cp.NewReturns(&fakes.RegionalAPI{}, nil)
+	vpcp.ClientProvider = cp
+
+	sessn, err := vpcp.OpenSession(context.Background(), provider.ContextCredentials{
+		AuthType:     provider.IAMAccessToken,
+		Credential:   TestProviderAccessToken,
+		IAMAccountID: TestIKSAccountID,
+	}, logger)
+	require.NoError(t, err)
+	assert.NotNil(t, sessn)
+
+	// HTTP exchanges go to the session logger instead of stdout, the provider config is left untouched
+	apiConfig := cp.NewArgsForCall(0)
+	assert.Nil(t, apiConfig.DebugWriter)
+	assert.Equal(t, 3, len(apiConfig.Middlewares))
+	assert.Nil(t, vpcp.APIConfig.DebugWriter)
+	assert.Equal(t, 0, len(vpcp.APIConfig.Middlewares))
+}
+
+func TestOpenSessionConcurrent(t *testing.T) {
+	logger, teardown := getTestSyncLogger(t)
+	defer teardown()
+
+	vpcp, _ := GetTestProvider(t, logger)
+	cp := &fakes.RegionalAPIClientProvider{}
+	cp.NewReturns(&fakes.RegionalAPI{}, nil)
+	vpcp.ClientProvider = cp
+	vpcp.APIConfig.ContextID = "provider-context"
+
+	// Sessions opened in parallel each get the request ID of their context, none leaks into the provider
+	const sessions = 50
+	requestIDs := map[string]bool{}
+	done := make(chan error, sessions)
+	for i := 0; i < sessions; i++ {
+		requestID := fmt.Sprintf("request-%d", i)
+		requestIDs[requestID] = true
+		go func() {
+			ctx := context.WithValue(context.Background(), provider.RequestID, requestID)
+			sessn, err := vpcp.OpenSession(ctx, provider.ContextCredentials{
+				AuthType:     provider.IAMAccessToken,
+				Credential:   TestProviderAccessToken,
+				IAMAccountID: TestIKSAccountID,
+			}, logger)
+			if err == nil {
+				sessn.Close()
+			}
+			done <- err
+		}()
+	}
+	for i := 0; i < sessions; i++ {
+		require.NoError(t, <-done)
+	}
+
+	contextIDs := map[string]bool{}
+	for i := 0; i < cp.NewCallCount(); i++ {
+		contextIDs[cp.NewArgsForCall(i).ContextID] = true
+	}
+	assert.Equal(t, requestIDs, contextIDs)
+	assert.Equal(t, "provider-context", vpcp.APIConfig.ContextID)
+	assert.Equal(t, 0, len(vpcp.APIConfig.Middlewares))
+	assert.Equal(t, cp, vpcp.ClientProvider)
+}
+
 func TestGetTestOpenSession(t *testing.T) {
 	//var err error
 	logger, teardown := GetTestLogger(t)
@@ -0,0 +1,361 @@
+/**
+ * Copyright 2022 IBM Corp.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package provider ...
+package provider
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/IBM/ibmcloud-volume-vpc/common/vpcclient/instances"
+	"github.com/IBM/ibmcloud-volume-vpc/common/vpcclient/models"
+	"github.com/IBM/ibmcloud-volume-vpc/common/vpcclient/riaas"
+	"github.com/IBM/ibmcloud-volume-vpc/common/vpcclient/vpcvolume"
+	"go.uber.org/zap"
+)
+
+// ReadCacheConfig enables the read-through cache shared by the sessions of a provider.
+// A zero TTL disables the cache for that resource. Only resources in a stable state are
+// cached (available volumes, stable snapshots, attachment lists with attached items only),
+// so the wait operations always see fresh transitional states. Entries are scoped by the
+// account of the session, the sessions whose account is unknown e.g opaque tokens are not
+// cached. Volume profiles are not cached: the provider never looks them up,
+// the profile name of the request is passed as is in the volume template.
+type ReadCacheConfig struct {
+	// VolumeTTL is the life time of GetVolume results
+	VolumeTTL time.Duration
+
+	// SnapshotTTL is the life time of GetSnapshot results
+	SnapshotTTL time.Duration
+
+	// AttachmentTTL is the life time of ListVolumeAttachments results
+	AttachmentTTL time.Duration
+}
+
+// readCache holds the cached entries of a provider, mutations made through the provider
+// invalidate the affected entries. Reads of the backend are bracketed by begin and end so
+// that a read started before an invalidation does not cache what it read.
+type readCache struct {
+	mu      sync.Mutex
+	entries map[string]readCacheEntry
+
+	// generation is bumped by each invalidation, invalidated records the generation at
+	// which a key was last invalidated while reads were in flight
+	generation  uint64
+	reads       int
+	invalidated map[string]uint64
+}
+
+type readCacheEntry struct {
+	value   interface{}
+	expires time.Time
+}
+
+func newReadCache() *readCache {
+	return &readCache{entries: map[string]readCacheEntry{}, invalidated: map[string]uint64{}}
+}
+
+func (rc *readCache) get(key string) (interface{}, bool) {
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+	entry, ok := rc.entries[key]
+	if !ok {
+		return nil, false
+	}
+	if !time.Now().Before(entry.expires) {
+		delete(rc.entries, key)
+		return nil, false
+	}
+	return entry.value, true
+}
+
+// begin registers a read of the backend and returns the generation it started at,
+// each begin must be followed by end
+func (rc *readCache) begin() uint64 {
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+	rc.reads++
+	return rc.generation
+}
+
+// end completes a read started at generation and caches its value, unless the value is nil
+// or the key was invalidated after the read started
+func (rc *readCache) end(key string, generation uint64, value interface{}, ttl time.Duration) {
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+	if value != nil && ttl > 0 && rc.invalidated[key] <= generation {
+		rc.entries[key] = readCacheEntry{value: value, expires: time.Now().Add(ttl)}
+	}
+	rc.reads--
+	if rc.reads == 0 && len(rc.invalidated) > 0 {
+		// No read in flight can be stale anymore
+		rc.invalidated = map[string]uint64{}
+	}
+}
+
+func (rc *readCache) invalidate(keys ...string) {
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+	rc.generation++
+	for _, key := range keys {
+		delete(rc.entries, key)
+		if rc.reads > 0 {
+			rc.invalidated[key] = rc.generation
+		}
+	}
+}
+
+func volumeCacheKey(account string, volumeID string) string {
+	return account + "/volume/" + volumeID
+}
+
+func snapshotCacheKey(account string, snapshotID string) string {
+	return account + "/snapshot/" + snapshotID
+}
+
+// attachmentsCacheKey is the key of the attachment list of an instance. The instance ID alone
+// identifies the list, the cluster ID the IKS templates may carry is left out so that the
+// same instance always gets the same key.
+func attachmentsCacheKey(account string, kind string, template *models.VolumeAttachment) string {
+	if template == nil || template.InstanceID == nil {
+		return account + "/" + kind + "/"
+	}
+	return account + "/" + kind + "/" + *template.InstanceID
+}
+
+const (
+	vpcAttachmentsKind = "attachments"
+	iksAttachmentsKind = "iks-attachments"
+)
+
+// cachedRegionalAPI returns the services backed by the read cache
+type cachedRegionalAPI struct {
+	riaas.RegionalAPI
+	cache   *readCache
+	config  ReadCacheConfig
+	account string
+}
+
+var _ riaas.RegionalAPI = &cachedRegionalAPI{}
+
+// newCachedRegionalAPI wraps the API client of the account with the read cache. The client of an unknown
+// account is not wrapped, the sessions of all the accounts would share its entries.
+func newCachedRegionalAPI(api riaas.RegionalAPI, cache *readCache, config ReadCacheConfig, account string) riaas.RegionalAPI {
+	if account == "" {
+		return api
+	}
+	return &cachedRegionalAPI{RegionalAPI: api, cache: cache, config: config, account: account}
+}
+
+// WithContext ...
+func (c *cachedRegionalAPI) WithContext(ctx context.Context) riaas.RegionalAPI {
+	return newCachedRegionalAPI(c.RegionalAPI.WithContext(ctx), c.cache, c.config, c.account)
+}
+
+// VolumeService ...
+func (c *cachedRegionalAPI) VolumeService() vpcvolume.VolumeManager {
+	return &cachedVolumeManager{VolumeManager: c.RegionalAPI.VolumeService(), cache: c.cache, ttl: c.config.VolumeTTL, account: c.account}
+}
+
+// VolumeAttachService ...
+func (c *cachedRegionalAPI) VolumeAttachService() instances.VolumeAttachManager {
+	return &cachedVolumeAttachManager{VolumeAttachManager: c.RegionalAPI.VolumeAttachService(), cache: c.cache, ttl: c.config.AttachmentTTL, account: c.account, kind: vpcAttachmentsKind}
+}
+
+// IKSVolumeAttachService ...
+func (c *cachedRegionalAPI) IKSVolumeAttachService() instances.VolumeAttachManager {
+	return &cachedVolumeAttachManager{VolumeAttachManager: c.RegionalAPI.IKSVolumeAttachService(), cache: c.cache, ttl: c.config.AttachmentTTL, account: c.account, kind: iksAttachmentsKind}
+}
+
+// SnapshotService ...
+func (c *cachedRegionalAPI) SnapshotService() vpcvolume.SnapshotManager {
+	return &cachedSnapshotManager{SnapshotManager: c.RegionalAPI.SnapshotService(), cache: c.cache, ttl: c.config.SnapshotTTL, account: c.account}
+}
+
+// cachedVolumeManager caches GetVolume
+type cachedVolumeManager struct {
+	vpcvolume.VolumeManager
+	cache   *readCache
+	ttl     time.Duration
+	account string
+}
+
+// GetVolume ...
+func (c *cachedVolumeManager) GetVolume(volumeID string, ctxLogger *zap.Logger) (*models.Volume, error) {
+	key := volumeCacheKey(c.account, volumeID)
+	if value, ok := c.cache.get(key); ok {
+		ctxLogger.Info("Volume found in read cache", zap.String("volumeID", volumeID))
+		volume := *value.(*models.Volume)
+		return &volume, nil
+	}
+	generation := c.cache.begin()
+	volume, err := c.VolumeManager.GetVolume(volumeID, ctxLogger)
+	var cached interface{}
+	if err == nil && volume != nil && volume.Status == validVolumeStatus {
+		volumeCopy := *volume
+		cached = &volumeCopy
+	}
+	c.cache.end(key, generation, cached, c.ttl)
+	return volume, err
+}
+
+// UpdateVolume ...
+func (c *cachedVolumeManager) UpdateVolume(volumeTemplate *models.Volume, ctxLogger *zap.Logger) error {
+	if volumeTemplate != nil {
+		defer c.cache.invalidate(volumeCacheKey(c.account, volumeTemplate.ID))
+	}
+	return c.VolumeManager.UpdateVolume(volumeTemplate, ctxLogger)
+}
+
+// ExpandVolume ...
+func (c *cachedVolumeManager) ExpandVolume(volumeID string, volumeTemplate *models.Volume, ctxLogger *zap.Logger) (*models.Volume, error) {
+	defer c.cache.invalidate(volumeCacheKey(c.account, volumeID))
+	return c.VolumeManager.ExpandVolume(volumeID, volumeTemplate, ctxLogger)
+}
+
+// DeleteVolume ...
+func (c *cachedVolumeManager) DeleteVolume(volumeID string, ctxLogger *zap.Logger) error {
+	defer c.cache.invalidate(volumeCacheKey(c.account, volumeID))
+	return c.VolumeManager.DeleteVolume(volumeID, ctxLogger)
+}
+
+// SetVolumeTag ...
+func (c *cachedVolumeManager) SetVolumeTag(volumeID string, tagName string, ctxLogger *zap.Logger) error {
+	defer c.cache.invalidate(volumeCacheKey(c.account, volumeID))
+	return c.VolumeManager.SetVolumeTag(volumeID, tagName, ctxLogger)
+}
+
+// DeleteVolumeTag ...
+func (c *cachedVolumeManager) DeleteVolumeTag(volumeID string, tagName string, ctxLogger *zap.Logger) error {
+	defer c.cache.invalidate(volumeCacheKey(c.account, volumeID))
+	return c.VolumeManager.DeleteVolumeTag(volumeID, tagName, ctxLogger)
+}
+
+// cachedSnapshotManager caches GetSnapshot
+type cachedSnapshotManager struct {
+	vpcvolume.SnapshotManager
+	cache   *readCache
+	ttl     time.Duration
+	account string
+}
+
+// GetSnapshot ...
+func (c *cachedSnapshotManager) GetSnapshot(snapshotID string, ctxLogger *zap.Logger) (*models.Snapshot, error) {
+	key := snapshotCacheKey(c.account, snapshotID)
+	if value, ok := c.cache.get(key); ok {
+		ctxLogger.Info("Snapshot found in read cache", zap.String("snapshotID", snapshotID))
+		snapshot := *value.(*models.Snapshot)
+		return &snapshot, nil
+	}
+	generation := c.cache.begin()
+	snapshot, err := c.SnapshotManager.GetSnapshot(snapshotID, ctxLogger)
+	var cached interface{}
+	if err == nil && snapshot != nil && snapshot.LifecycleState == snapshotReadyState {
+		snapshotCopy := *snapshot
+		cached = &snapshotCopy
+	}
+	c.cache.end(key, generation, cached, c.ttl)
+	return snapshot, err
+}
+
+// DeleteSnapshot ...
+func (c *cachedSnapshotManager) DeleteSnapshot(snapshotID string, ctxLogger *zap.Logger) error {
+	defer c.cache.invalidate(snapshotCacheKey(c.account, snapshotID))
+	return c.SnapshotManager.DeleteSnapshot(snapshotID, ctxLogger)
+}
+
+// SetSnapshotTag ...
+func (c *cachedSnapshotManager) SetSnapshotTag(volumeID string, snapshotID string, tagName string, ctxLogger *zap.Logger) error {
+	defer c.cache.invalidate(snapshotCacheKey(c.account, snapshotID))
+	return c.SnapshotManager.SetSnapshotTag(volumeID, snapshotID, tagName, ctxLogger)
+}
+
+// DeleteSnapshotTag ...
+func (c *cachedSnapshotManager) DeleteSnapshotTag(volumeID string, snapshotID string, tagName string, ctxLogger *zap.Logger) error {
+	defer c.cache.invalidate(snapshotCacheKey(c.account, snapshotID))
+	return c.SnapshotManager.DeleteSnapshotTag(volumeID, snapshotID, tagName, ctxLogger)
+}
+
+// cachedVolumeAttachManager caches ListVolumeAttachments, kind separates the lists of the VPC
+// and the IKS attachment services
+type cachedVolumeAttachManager struct {
+	instances.VolumeAttachManager
+	cache   *readCache
+	ttl     time.Duration
+	account string
+	kind    string
+}
+
+// ListVolumeAttachments ...
+func (c *cachedVolumeAttachManager) ListVolumeAttachments(volumeAttachmentTemplate *models.VolumeAttachment, ctxLogger *zap.Logger) (*models.VolumeAttachmentList, error) {
+	key := attachmentsCacheKey(c.account, c.kind, volumeAttachmentTemplate)
+	if value, ok := c.cache.get(key); ok {
+		ctxLogger.Info("Volume attachments found in read cache", zap.String("key", key))
+		return copyVolumeAttachmentList(value.(*models.VolumeAttachmentList)), nil
+	}
+	generation := c.cache.begin()
+	volumeAttachmentList, err := c.VolumeAttachManager.ListVolumeAttachments(volumeAttachmentTemplate, ctxLogger)
+	var cached interface{}
+	if err == nil && volumeAttachmentList != nil && isStableAttachmentList(volumeAttachmentList) {
+		cached = copyVolumeAttachmentList(volumeAttachmentList)
+	}
+	c.cache.end(key, generation, cached, c.ttl)
+	return volumeAttachmentList, err
+}
+
+// AttachVolume ...
+func (c *cachedVolumeAttachManager) AttachVolume(volumeAttachmentTemplate *models.VolumeAttachment, ctxLogger *zap.Logger) (*models.VolumeAttachment, error) {
+	defer c.invalidate(volumeAttachmentTemplate)
+	return c.VolumeAttachManager.AttachVolume(volumeAttachmentTemplate, ctxLogger)
+}
+
+// DetachVolume ...
+func (c *cachedVolumeAttachManager) DetachVolume(volumeAttachmentTemplate *models.VolumeAttachment, ctxLogger *zap.Logger) (*http.Response, error) {
+	defer c.invalidate(volumeAttachmentTemplate)
+	return c.VolumeAttachManager.DetachVolume(volumeAttachmentTemplate, ctxLogger)
+}
+
+// invalidate removes the attachment lists of the instance, from both services since they
+// describe the same attachments, and the volume, which lists its attachments
+func (c *cachedVolumeAttachManager) invalidate(volumeAttachmentTemplate *models.VolumeAttachment) {
+	keys := []string{
+		attachmentsCacheKey(c.account, vpcAttachmentsKind, volumeAttachmentTemplate),
+		attachmentsCacheKey(c.account, iksAttachmentsKind, volumeAttachmentTemplate),
+	}
+	if volumeAttachmentTemplate != nil && volumeAttachmentTemplate.Volume != nil {
+		keys = append(keys, volumeCacheKey(c.account, volumeAttachmentTemplate.Volume.ID))
+	}
+	c.cache.invalidate(keys...)
+}
+
+// isStableAttachmentList tells if no attachment of the list is in a transitional state
+func isStableAttachmentList(volumeAttachmentList *models.VolumeAttachmentList) bool {
+	for _, volumeAttachment := range volumeAttachmentList.VolumeAttachments {
+		if volumeAttachment.Status != StatusAttached {
+			return false
+		}
+	}
+	return true
+}
+
+func copyVolumeAttachmentList(volumeAttachmentList *models.VolumeAttachmentList) *models.VolumeAttachmentList {
+	listCopy := *volumeAttachmentList
+	listCopy.VolumeAttachments = append([]models.VolumeAttachment(nil), volumeAttachmentList.VolumeAttachments...)
+	return &listCopy
+}
@@ -0,0 +1,235 @@
+/**
+ * Copyright 2022 IBM Corp.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package provider ...
+package provider
+
+import (
+	"testing"
+	"time"
+
+	volumeAttachServiceFakes "github.com/IBM/ibmcloud-volume-vpc/common/vpcclient/instances/fakes"
+	"github.com/IBM/ibmcloud-volume-vpc/common/vpcclient/models"
+	"github.com/IBM/ibmcloud-volume-vpc/common/vpcclient/riaas/fakes"
+	volumeServiceFakes "github.com/IBM/ibmcloud-volume-vpc/common/vpcclient/vpcvolume/fakes"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+)
+
+func TestReadCacheVolume(t *testing.T) {
+	logger, teardown := GetTestLogger(t)
+	defer teardown()
+
+	api := &fakes.RegionalAPI{}
+	volumeService := &volumeServiceFakes.VolumeService{}
+	api.VolumeServiceReturns(volumeService)
+	cached := newCachedRegionalAPI(api, newReadCache(), ReadCacheConfig{VolumeTTL: time.Minute}, "account-1")
+
+	// Transitional state is not cached
+	volumeService.GetVolumeReturns(&models.Volume{ID: "volume-1", Status: "pending"}, nil)
+	_, _ = cached.VolumeService().GetVolume("volume-1", logger)
+	_, _ = cached.VolumeService().GetVolume("volume-1", logger)
+	assert.Equal(t, 2, volumeService.GetVolumeCallCount())
+
+	volumeService.GetVolumeReturns(&models.Volume{ID: "volume-1", Status: validVolumeStatus, Capacity: 10}, nil)
+	volume, err := cached.VolumeService().GetVolume("volume-1", logger)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(10), volume.Capacity)
+	volume.Capacity = 99 // Callers get their own copy
+	volume, err = cached.VolumeService().GetVolume("volume-1", logger)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(10), volume.Capacity)
+	assert.Equal(t, 3, volumeService.GetVolumeCallCount())
+
+	// Expand invalidates the volume
+	_, _ = cached.VolumeService().ExpandVolume("volume-1", &models.Volume{Capacity: 20}, logger)
+	volumeService.GetVolumeReturns(&models.Volume{ID: "volume-1", Status: validVolumeStatus, Capacity: 20}, nil)
+	volume, _ = cached.VolumeService().GetVolume("volume-1", logger)
+	assert.Equal(t, int64(20), volume.Capacity)
+	assert.Equal(t, 4, volumeService.GetVolumeCallCount())
+
+	// Other volumes are cached separately
+	_, _ = cached.VolumeService().GetVolume("volume-2", logger)
+	assert.Equal(t, 5, volumeService.GetVolumeCallCount())
+}
+
+func TestReadCacheExpiry(t *testing.T) {
+	logger, teardown := GetTestLogger(t)
+	defer teardown()
+
+	api := &fakes.RegionalAPI{}
+	snapshotService := &volumeServiceFakes.SnapshotManager{}
+	api.SnapshotServiceReturns(snapshotService)
+	snapshotService.GetSnapshotReturns(&models.Snapshot{ID: "snapshot-1", LifecycleState: snapshotReadyState}, nil)
+
+	// Zero TTL does not cache
+	cached := newCachedRegionalAPI(api, newReadCache(), ReadCacheConfig{VolumeTTL: time.Minute}, "account-1")
+	_, _ = cached.SnapshotService().GetSnapshot("snapshot-1", logger)
+	_, _ = cached.SnapshotService().GetSnapshot("snapshot-1", logger)
+	assert.Equal(t, 2, snapshotService.GetSnapshotCallCount())
+
+	cached = newCachedRegionalAPI(api, newReadCache(), ReadCacheConfig{SnapshotTTL: 50 * time.Millisecond}, "account-1")
+	_, _ = cached.SnapshotService().GetSnapshot("snapshot-1", logger)
+	_, _ = cached.SnapshotService().GetSnapshot("snapshot-1", logger)
+	assert.Equal(t, 3, snapshotService.GetSnapshotCallCount())
+
+	time.Sleep(60 * time.Millisecond)
+	_, _ = cached.SnapshotService().GetSnapshot("snapshot-1", logger)
+	assert.Equal(t, 4, snapshotService.GetSnapshotCallCount())
+
+	// Delete invalidates the snapshot
+	_ = cached.SnapshotService().DeleteSnapshot("snapshot-1", logger)
+	_, _ = cached.SnapshotService().GetSnapshot("snapshot-1", logger)
+	assert.Equal(t, 5, snapshotService.GetSnapshotCallCount())
+}
+
+func TestReadCacheVolumeAttachments(t *testing.T) {
+	logger, teardown := GetTestLogger(t)
+	defer teardown()
+
+	api := &fakes.RegionalAPI{}
+	volumeService := &volumeServiceFakes.VolumeService{}
+	api.VolumeServiceReturns(volumeService)
+	volumeAttachService := &volumeAttachServiceFakes.VolumeAttachService{}
+	api.VolumeAttachServiceReturns(volumeAttachService)
+	cache := newReadCache()
+	cached := newCachedRegionalAPI(api, cache, ReadCacheConfig{VolumeTTL: time.Minute, AttachmentTTL: time.Minute}, "account-1")
+
+	instanceID := "instance-1"
+	template := &models.VolumeAttachment{InstanceID: &instanceID, Volume: &models.Volume{ID: "volume-1"}}
+	volumeService.GetVolumeReturns(&models.Volume{ID: "volume-1", Status: validVolumeStatus}, nil)
+	_, _ = cached.VolumeService().GetVolume("volume-1", logger)
+
+	volumeAttachService.ListVolumeAttachmentsReturns(&models.VolumeAttachmentList{VolumeAttachments: []models.VolumeAttachment{{ID: "attachment-2", Status: StatusAttached}}}, nil)
+	_, _ = cached.VolumeAttachService().ListVolumeAttachments(template, logger)
+	list, err := cached.VolumeAttachService().ListVolumeAttachments(template, logger)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(list.VolumeAttachments))
+	assert.Equal(t, 1, volumeAttachService.ListVolumeAttachmentsCallCount())
+
+	// Attach invalidates the attachments of the instance and the volume
+	_, _ = cached.VolumeAttachService().AttachVolume(template, logger)
+	_, ok := cache.get(volumeCacheKey("account-1", "volume-1"))
+	assert.False(t, ok)
+
+	// List with transitional attachments is not cached, so waits see fresh states
+	volumeAttachService.ListVolumeAttachmentsReturns(&models.VolumeAttachmentList{VolumeAttachments: []models.VolumeAttachment{{ID: "attachment-1", Status: StatusAttaching}, {ID: "attachment-2", Status: StatusAttached}}}, nil)
+	_, _ = cached.VolumeAttachService().ListVolumeAttachments(template, logger)
+	_, _ = cached.VolumeAttachService().ListVolumeAttachments(template, logger)
+	assert.Equal(t, 3, volumeAttachService.ListVolumeAttachmentsCallCount())
+
+	volumeAttachService.ListVolumeAttachmentsReturns(&models.VolumeAttachmentList{VolumeAttachments: []models.VolumeAttachment{{ID: "attachment-1", Status: StatusAttached}, {ID: "attachment-2", Status: StatusAttached}}}, nil)
+	_, _ = cached.VolumeAttachService().ListVolumeAttachments(template, logger)
+	_, _ = cached.VolumeAttachService().ListVolumeAttachments(template, logger)
+	assert.Equal(t, 4, volumeAttachService.ListVolumeAttachmentsCallCount())
+
+	// Detach invalidates the attachments of the instance
+	_, _ = cached.VolumeAttachService().DetachVolume(template, logger)
+	_, _ = cached.VolumeAttachService().ListVolumeAttachments(template, logger)
+	assert.Equal(t, 5, volumeAttachService.ListVolumeAttachmentsCallCount())
+}
+
+func TestReadCacheAccounts(t *testing.T) {
+	logger, teardown := GetTestLogger(t)
+	defer teardown()
+
+	api := &fakes.RegionalAPI{}
+	volumeService := &volumeServiceFakes.VolumeService{}
+	api.VolumeServiceReturns(volumeService)
+	volumeService.GetVolumeReturns(&models.Volume{ID: "volume-1", Status: validVolumeStatus}, nil)
+	cache := newReadCache()
+	first := newCachedRegionalAPI(api, cache, ReadCacheConfig{VolumeTTL: time.Minute}, "account-1")
+	other := newCachedRegionalAPI(api, cache, ReadCacheConfig{VolumeTTL: time.Minute}, "account-2")
+
+	_, _ = first.VolumeService().GetVolume("volume-1", logger)
+	_, _ = first.VolumeService().GetVolume("volume-1", logger)
+	assert.Equal(t, 1, volumeService.GetVolumeCallCount())
+
+	// Another account does not see the entries of the first one
+	_, _ = other.VolumeService().GetVolume("volume-1", logger)
+	assert.Equal(t, 2, volumeService.GetVolumeCallCount())
+
+	// Sessions of an unknown account are never cached
+	unknown := newCachedRegionalAPI(api, cache, ReadCacheConfig{VolumeTTL: time.Minute}, "")
+	_, _ = unknown.VolumeService().GetVolume("volume-1", logger)
+	_, _ = unknown.VolumeService().GetVolume("volume-1", logger)
+	assert.Equal(t, 4, volumeService.GetVolumeCallCount())
+}
+
+func TestReadCacheInvalidatedRead(t *testing.T) {
+	logger, teardown := GetTestLogger(t)
+	defer teardown()
+
+	api := &fakes.RegionalAPI{}
+	volumeService := &volumeServiceFakes.VolumeService{}
+	api.VolumeServiceReturns(volumeService)
+	cached := newCachedRegionalAPI(api, newReadCache(), ReadCacheConfig{VolumeTTL: time.Minute}, "account-1")
+
+	// The volume is expanded while the read is in flight, the stale read is not cached
+	volumeService.GetVolumeStub = func(volumeID string, ctxLogger *zap.Logger) (*models.Volume, error) {
+		_, _ = cached.VolumeService().ExpandVolume(volumeID, &models.Volume{Capacity: 20}, ctxLogger)
+		return &models.Volume{ID: volumeID, Status: validVolumeStatus, Capacity: 10}, nil
+	}
+	_, _ = cached.VolumeService().GetVolume("volume-1", logger)
+
+	volumeService.GetVolumeStub = nil
+	volumeService.GetVolumeReturns(&models.Volume{ID: "volume-1", Status: validVolumeStatus, Capacity: 20}, nil)
+	volume, err := cached.VolumeService().GetVolume("volume-1", logger)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(20), volume.Capacity)
+	assert.Equal(t, 2, volumeService.GetVolumeCallCount())
+
+	// Reads started after the invalidation are cached again
+	_, _ = cached.VolumeService().GetVolume("volume-1", logger)
+	assert.Equal(t, 2, volumeService.GetVolumeCallCount())
+}
+
+func TestReadCacheClusterAttachments(t *testing.T) {
+	logger, teardown := GetTestLogger(t)
+	defer teardown()
+
+	api := &fakes.RegionalAPI{}
+	volumeAttachService := &volumeAttachServiceFakes.VolumeAttachService{}
+	api.VolumeAttachServiceReturns(volumeAttachService)
+	iksVolumeAttachService := &volumeAttachServiceFakes.VolumeAttachService{}
+	api.IKSVolumeAttachServiceReturns(iksVolumeAttachService)
+	cached := newCachedRegionalAPI(api, newReadCache(), ReadCacheConfig{AttachmentTTL: time.Minute}, "account-1")
+
+	instanceID := "instance-1"
+	clusterID := "cluster-1"
+	template := &models.VolumeAttachment{InstanceID: &instanceID, Volume: &models.Volume{ID: "volume-1"}}
+	clusterTemplate := &models.VolumeAttachment{InstanceID: &instanceID, ClusterID: &clusterID, Volume: &models.Volume{ID: "volume-1"}}
+	list := &models.VolumeAttachmentList{VolumeAttachments: []models.VolumeAttachment{{ID: "attachment-1", Status: StatusAttached}}}
+	volumeAttachService.ListVolumeAttachmentsReturns(list, nil)
+	iksVolumeAttachService.ListVolumeAttachmentsReturns(list, nil)
+
+	// The cluster ID does not change the key of the instance
+	_, _ = cached.VolumeAttachService().ListVolumeAttachments(template, logger)
+	_, _ = cached.VolumeAttachService().ListVolumeAttachments(clusterTemplate, logger)
+	assert.Equal(t, 1, volumeAttachService.ListVolumeAttachmentsCallCount())
+
+	// The IKS service keeps its own lists
+	_, _ = cached.IKSVolumeAttachService().ListVolumeAttachments(clusterTemplate, logger)
+	_, _ = cached.IKSVolumeAttachService().ListVolumeAttachments(clusterTemplate, logger)
+	assert.Equal(t, 1, iksVolumeAttachService.ListVolumeAttachmentsCallCount())
+
+	// A detach through IKS invalidates the lists of both services
+	_, _ = cached.IKSVolumeAttachService().DetachVolume(clusterTemplate, logger)
+	_, _ = cached.VolumeAttachService().ListVolumeAttachments(template, logger)
+	_, _ = cached.IKSVolumeAttachService().ListVolumeAttachments(clusterTemplate, logger)
+	assert.Equal(t, 2, volumeAttachService.ListVolumeAttachmentsCallCount())
+	assert.Equal(t, 2, iksVolumeAttachService.ListVolumeAttachmentsCallCount())
+}
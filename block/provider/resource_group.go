@@ -0,0 +1,195 @@
+/**
+ * Copyright 2022 IBM Corp.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package provider ...
+package provider
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"time"
+
+	userError "github.com/IBM/ibmcloud-volume-vpc/common/messages"
+	"github.com/IBM/ibmcloud-volume-vpc/common/vpcclient/models"
+	"go.uber.org/zap"
+)
+
+const (
+	// resourceGroupTag is the tag or filter naming the resource group of an operation
+	resourceGroupTag = "resource_group.id"
+	// resourceGroupNameTag is the tag or filter naming the resource group of an operation by its name, resolved to
+	// its ID with the Resource Manager
+	resourceGroupNameTag = "resource_group.name"
+
+	// resourceGroupCacheTTL is how long the ID of a resource group name is cached
+	resourceGroupCacheTTL = 10 * time.Minute
+)
+
+// resourceGroupContextKey is the context key of the resource group of a request
+type resourceGroupContextKey struct{}
+
+// WithResourceGroup returns a context whose sessions create and list the volumes and snapshots in the resource
+// group, instead of the one of the config
+func WithResourceGroup(ctx context.Context, resourceGroupID string) context.Context {
+	return context.WithValue(ctx, resourceGroupContextKey{}, resourceGroupID)
+}
+
+// ResourceGroupFromContext returns the resource group of the context, see WithResourceGroup
+func ResourceGroupFromContext(ctx context.Context) (string, bool) {
+	if ctx == nil {
+		return "", false
+	}
+	resourceGroupID, ok := ctx.Value(resourceGroupContextKey{}).(string)
+	return resourceGroupID, ok && resourceGroupID != ""
+}
+
+// ResourceGroupID returns the resource group of the session, the one of the request context if any, else the one
+// of the config
+func (vpcs *VPCSession) ResourceGroupID() string {
+	if vpcs.resourceGroup != "" {
+		return vpcs.resourceGroup
+	}
+	if vpcs.Config == nil || vpcs.Config.VPCConfig == nil {
+		return ""
+	}
+	return vpcs.Config.VPCConfig.G2ResourceGroupID
+}
+
+// operationResourceGroup returns the resource group of an operation, the explicit one if any, else the one of
+// the session
+func (vpcs *VPCSession) operationResourceGroup(explicit string) string {
+	if explicit != "" {
+		return explicit
+	}
+	return vpcs.ResourceGroupID()
+}
+
+// explicitResourceGroup returns the ID of the resource group named by the tags or filters of an operation, the
+// resource_group.name one is resolved when no resource_group.id is given
+func (vpcs *VPCSession) explicitResourceGroup(tags map[string]string) (string, error) {
+	if id := tags[resourceGroupTag]; id != "" {
+		return id, nil
+	}
+	if name := tags[resourceGroupNameTag]; name != "" {
+		return vpcs.resolveResourceGroupName(name)
+	}
+	return "", nil
+}
+
+// resolveResourceGroupName returns the ID of the resource group of the account with the name, as RIaaS does not
+// support the names. Resolved names are cached per account, the names of sessions whose account is unknown are
+// resolved each time.
+func (vpcs *VPCSession) resolveResourceGroupName(name string) (string, error) {
+	if id, ok := vpcs.resourceGroupNames.get(vpcs.VPCAccountID, name); ok {
+		return id, nil
+	}
+	if vpcs.resourceGroups == nil {
+		return "", userError.GetUserError(userError.ResourceGroupLookupFailed, errors.New("no Resource Manager client for the session"), name)
+	}
+
+	vpcs.Logger.Info("Resolving the resource group name with the Resource Manager", zap.String("name", name), zap.String("accountID", vpcs.VPCAccountID))
+	resourceGroups, err := vpcs.resourceGroups.ListResourceGroups(&models.ListResourceGroupFilters{Name: name, AccountID: vpcs.VPCAccountID}, vpcs.Logger)
+	if err != nil {
+		vpcs.Logger.Error("Failed to list the resource groups of the name", zap.String("name", name), zap.Error(err))
+		return "", GetBackendUserError(userError.ResourceGroupLookupFailed, err, name)
+	}
+
+	var ids []string
+	for _, resourceGroup := range resourceGroups.Resources {
+		if resourceGroup.Name == name {
+			ids = append(ids, resourceGroup.ID)
+		}
+	}
+	switch len(ids) {
+	case 0:
+		return "", userError.GetUserError(userError.ResourceGroupNotFound, nil, name)
+	case 1:
+		vpcs.resourceGroupNames.put(vpcs.VPCAccountID, name, ids[0])
+		return ids[0], nil
+	default:
+		return "", userError.GetUserError(userError.ResourceGroupNameAmbiguous, nil, name, strings.Join(ids, ", "))
+	}
+}
+
+// listResourceGroup returns the resource group filter of a list operation, the explicit one if any, else the one
+// of the request context. Lists are not filtered by the resource group of the config.
+func (vpcs *VPCSession) listResourceGroup(explicit string) string {
+	if explicit != "" {
+		return explicit
+	}
+	return vpcs.resourceGroup
+}
+
+// resourceGroupCache caches the IDs of the resource group names, keyed by account so that the sessions of the
+// accounts sharing a provider never see the groups of each other
+type resourceGroupCache struct {
+	ttl time.Duration
+	now func() time.Time
+
+	mu      sync.Mutex
+	entries map[resourceGroupCacheKey]resourceGroupCacheEntry
+}
+
+type resourceGroupCacheKey struct {
+	accountID string
+	name      string
+}
+
+type resourceGroupCacheEntry struct {
+	id      string
+	expires time.Time
+}
+
+// newResourceGroupCache ...
+func newResourceGroupCache(ttl time.Duration) *resourceGroupCache {
+	return &resourceGroupCache{
+		ttl:     ttl,
+		now:     time.Now,
+		entries: map[resourceGroupCacheKey]resourceGroupCacheEntry{},
+	}
+}
+
+// get returns the cached ID of the name, a nil cache caches nothing. Names of an unknown account are never cached,
+// the sessions of all the accounts would share them.
+func (c *resourceGroupCache) get(accountID, name string) (string, bool) {
+	if c == nil || accountID == "" {
+		return "", false
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	key := resourceGroupCacheKey{accountID: accountID, name: name}
+	entry, ok := c.entries[key]
+	if !ok {
+		return "", false
+	}
+	if !c.now().Before(entry.expires) {
+		delete(c.entries, key)
+		return "", false
+	}
+	return entry.id, true
+}
+
+// put caches the ID of the name of the account until the TTL expires
+func (c *resourceGroupCache) put(accountID, name, id string) {
+	if c == nil || accountID == "" {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.entries[resourceGroupCacheKey{accountID: accountID, name: name}] = resourceGroupCacheEntry{id: id, expires: c.now().Add(c.ttl)}
+}
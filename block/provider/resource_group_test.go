@@ -0,0 +1,303 @@
+/**
+ * Copyright 2022 IBM Corp.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package provider ...
+package provider
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/IBM/ibmcloud-volume-interface/lib/provider"
+	userError "github.com/IBM/ibmcloud-volume-vpc/common/messages"
+	"github.com/IBM/ibmcloud-volume-vpc/common/vpcclient/models"
+	resourceManagerFakes "github.com/IBM/ibmcloud-volume-vpc/common/vpcclient/resourcemanager/fakes"
+	"github.com/IBM/ibmcloud-volume-vpc/common/vpcclient/riaas/fakes"
+	serviceFakes "github.com/IBM/ibmcloud-volume-vpc/common/vpcclient/vpcvolume/fakes"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOpenSessionResourceGroup(t *testing.T) {
+	logger, teardown := GetTestLogger(t)
+	defer teardown()
+
+	testCases := []struct {
+		name                  string
+		ctx                   context.Context
+		expectedResourceGroup string
+	}{
+		{
+			name:                  "resource group of the config",
+			ctx:                   context.Background(),
+			expectedResourceGroup: "rg-config",
+		}, {
+			name:                  "resource group of the request",
+			ctx:                   WithResourceGroup(context.Background(), "rg-request"),
+			expectedResourceGroup: "rg-request",
+		},
+	}
+
+	for _, testcase := range testCases {
+		t.Run(testcase.name, func(t *testing.T) {
+			vpcp, _ := GetTestProvider(t, logger)
+			vpcp.Config.VPCConfig.G2ResourceGroupID = "rg-config"
+			vpcp.APIConfig.ResourceGroup = "rg-config"
+			cp := &fakes.RegionalAPIClientProvider{}
+			cp.NewReturns(&fakes.RegionalAPI{}, nil)
+			vpcp.ClientProvider = cp
+
+			sessn, err := vpcp.OpenSession(testcase.ctx, provider.ContextCredentials{AuthType: provider.IAMAccessToken, Credential: TestProviderAccessToken}, logger)
+			require.Nil(t, err)
+			assert.Equal(t, testcase.expectedResourceGroup, cp.NewArgsForCall(0).ResourceGroup)
+			assert.Equal(t, testcase.expectedResourceGroup, sessn.(*VPCSession).ResourceGroupID())
+			// Config shared by the sessions is left untouched
+			assert.Equal(t, "rg-config", vpcp.Config.VPCConfig.G2ResourceGroupID)
+		})
+	}
+}
+
+func TestOperationResourceGroup(t *testing.T) {
+	logger, teardown := GetTestLogger(t)
+	defer teardown()
+
+	testCases := []struct {
+		name                  string
+		sessionResourceGroup  string
+		explicitResourceGroup string
+		explicitName          string
+		expectedCreate        string
+		expectedList          string
+	}{
+		{
+			name:           "resource group of the config",
+			expectedCreate: "rg-config",
+			expectedList:   "",
+		}, {
+			name:                 "resource group of the request",
+			sessionResourceGroup: "rg-request",
+			expectedCreate:       "rg-request",
+			expectedList:         "rg-request",
+		}, {
+			name:                  "explicit resource group of the operation",
+			sessionResourceGroup:  "rg-request",
+			explicitResourceGroup: "rg-operation",
+			expectedCreate:        "rg-operation",
+			expectedList:          "rg-operation",
+		}, {
+			name:                 "resource group name of the operation",
+			sessionResourceGroup: "rg-request",
+			explicitName:         "operation",
+			expectedCreate:       "rg-operation",
+			expectedList:         "rg-operation",
+		},
+	}
+
+	for _, testcase := range testCases {
+		t.Run(testcase.name, func(t *testing.T) {
+			vpcs, uc, _, err := GetTestOpenSession(t, logger)
+			require.Nil(t, err)
+			vpcs.Config.VPCConfig.G2ResourceGroupID = "rg-config"
+			vpcs.resourceGroup = testcase.sessionResourceGroup
+			snapshotService := &serviceFakes.SnapshotManager{}
+			snapshotService.CreateSnapshotReturns(&models.Snapshot{ID: "snapshot-1", SourceVolume: &models.SourceVolume{ID: "volume-1"}}, nil)
+			snapshotService.ListSnapshotsReturns(&models.SnapshotList{}, nil)
+			uc.SnapshotServiceReturns(snapshotService)
+			volumeService := &serviceFakes.VolumeService{}
+			volumeService.ListVolumesReturns(&models.VolumeList{}, nil)
+			uc.VolumeServiceReturns(volumeService)
+			resourceGroups := &resourceManagerFakes.ResourceGroupManager{}
+			resourceGroups.ListResourceGroupsReturns(&models.ResourceGroupList{Resources: []models.ResourceGroup{{ID: "rg-operation", Name: "operation"}}}, nil)
+			vpcs.resourceGroups = resourceGroups
+
+			tags := map[string]string{}
+			if testcase.explicitResourceGroup != "" {
+				tags[resourceGroupTag] = testcase.explicitResourceGroup
+			}
+			if testcase.explicitName != "" {
+				tags[resourceGroupNameTag] = testcase.explicitName
+			}
+			_, err = vpcs.CreateSnapshot("volume-1", provider.SnapshotParameters{Name: "snapshot", SnapshotTags: tags})
+			assert.Nil(t, err)
+			template, _ := snapshotService.CreateSnapshotArgsForCall(0)
+			assert.Equal(t, testcase.expectedCreate, template.ResourceGroup.ID)
+
+			_, err = vpcs.ListSnapshots(10, "", tags)
+			assert.Nil(t, err)
+			_, _, snapshotFilters, _ := snapshotService.ListSnapshotsArgsForCall(0)
+			assert.Equal(t, testcase.expectedList, snapshotFilters.ResourceGroupID)
+
+			_, err = vpcs.ListVolumes(10, "", tags)
+			assert.Nil(t, err)
+			_, _, volumeFilters, _ := volumeService.ListVolumesArgsForCall(0)
+			assert.Equal(t, testcase.expectedList, volumeFilters.ResourceGroupID)
+		})
+	}
+}
+
+func TestResolveResourceGroupName(t *testing.T) {
+	logger, teardown := GetTestLogger(t)
+	defer teardown()
+	userError.MessagesEn = userError.InitMessages()
+
+	testCases := []struct {
+		name           string
+		resourceGroups []models.ResourceGroup
+		listErr        error
+		noClient       bool
+		expectedID     string
+		expectedErr    string
+	}{
+		{
+			name:           "resource group of the name",
+			resourceGroups: []models.ResourceGroup{{ID: "rg-1", Name: "default"}},
+			expectedID:     "rg-1",
+		}, {
+			name:           "other names are ignored",
+			resourceGroups: []models.ResourceGroup{{ID: "rg-1", Name: "default"}, {ID: "rg-2", Name: "other"}},
+			expectedID:     "rg-1",
+		}, {
+			name:        "unknown name",
+			expectedErr: userError.ResourceGroupNotFound,
+		}, {
+			name:           "ambiguous name",
+			resourceGroups: []models.ResourceGroup{{ID: "rg-1", Name: "default"}, {ID: "rg-2", Name: "default"}},
+			expectedErr:    userError.ResourceGroupNameAmbiguous,
+		}, {
+			name:        "Resource Manager failure",
+			listErr:     errors.New("resource manager is unavailable"),
+			expectedErr: userError.ResourceGroupLookupFailed,
+		}, {
+			name:        "no Resource Manager client",
+			noClient:    true,
+			expectedErr: userError.ResourceGroupLookupFailed,
+		},
+	}
+
+	for _, testcase := range testCases {
+		t.Run(testcase.name, func(t *testing.T) {
+			resourceGroups := &resourceManagerFakes.ResourceGroupManager{}
+			resourceGroups.ListResourceGroupsReturns(&models.ResourceGroupList{Resources: testcase.resourceGroups}, testcase.listErr)
+			vpcs := &VPCSession{VPCAccountID: "account-1", Logger: logger, resourceGroups: resourceGroups, resourceGroupNames: newResourceGroupCache(time.Minute)}
+			if testcase.noClient {
+				vpcs.resourceGroups = nil
+			}
+
+			id, err := vpcs.resolveResourceGroupName("default")
+			if testcase.expectedErr != "" {
+				assert.Equal(t, testcase.expectedErr, userError.GetUserErrorCode(err))
+				assert.Empty(t, id)
+				return
+			}
+			assert.Nil(t, err)
+			assert.Equal(t, testcase.expectedID, id)
+			filters, _ := resourceGroups.ListResourceGroupsArgsForCall(0)
+			assert.Equal(t, &models.ListResourceGroupFilters{Name: "default", AccountID: "account-1"}, filters)
+
+			// Resolved name is served from the cache
+			id, err = vpcs.resolveResourceGroupName("default")
+			assert.Nil(t, err)
+			assert.Equal(t, testcase.expectedID, id)
+			assert.Equal(t, 1, resourceGroups.ListResourceGroupsCallCount())
+		})
+	}
+}
+
+func TestResolveResourceGroupNameUnknownAccount(t *testing.T) {
+	logger, teardown := GetTestLogger(t)
+	defer teardown()
+
+	// Sessions whose token has no readable account resolve the names each time
+	resourceGroups := &resourceManagerFakes.ResourceGroupManager{}
+	resourceGroups.ListResourceGroupsReturns(&models.ResourceGroupList{Resources: []models.ResourceGroup{{ID: "rg-1", Name: "default"}}}, nil)
+	vpcs := &VPCSession{Logger: logger, resourceGroups: resourceGroups, resourceGroupNames: newResourceGroupCache(time.Minute)}
+	for i := 0; i < 2; i++ {
+		id, err := vpcs.resolveResourceGroupName("default")
+		assert.Nil(t, err)
+		assert.Equal(t, "rg-1", id)
+	}
+	assert.Equal(t, 2, resourceGroups.ListResourceGroupsCallCount())
+}
+
+func TestResourceGroupCache(t *testing.T) {
+	now := time.Now()
+	cache := newResourceGroupCache(time.Minute)
+	cache.now = func() time.Time { return now }
+
+	cache.put("account-1", "default", "rg-1")
+	id, ok := cache.get("account-1", "default")
+	assert.True(t, ok)
+	assert.Equal(t, "rg-1", id)
+
+	// Names of another account are not shared
+	_, ok = cache.get("account-2", "default")
+	assert.False(t, ok)
+
+	// Names of an unknown account are not cached
+	cache.put("", "default", "rg-1")
+	_, ok = cache.get("", "default")
+	assert.False(t, ok)
+
+	// Names expire after the TTL
+	now = now.Add(time.Minute)
+	_, ok = cache.get("account-1", "default")
+	assert.False(t, ok)
+
+	// Nil cache caches nothing
+	var nilCache *resourceGroupCache
+	nilCache.put("account-1", "default", "rg-1")
+	_, ok = nilCache.get("account-1", "default")
+	assert.False(t, ok)
+}
+
+func TestCreateVolumeResourceGroupName(t *testing.T) {
+	logger, teardown := GetTestLogger(t)
+	defer teardown()
+	userError.MessagesEn = userError.InitMessages()
+
+	vpcs, uc, _, err := GetTestOpenSession(t, logger)
+	require.Nil(t, err)
+	volumeService := &serviceFakes.VolumeService{}
+	volume := &models.Volume{ID: "volume-1", Name: "volume", Status: models.StatusType("available"), Capacity: int64(10), Zone: &models.Zone{Name: "test-zone"}}
+	volumeService.CreateVolumeReturns(volume, nil)
+	volumeService.GetVolumeReturns(volume, nil)
+	uc.VolumeServiceReturns(volumeService)
+	resourceGroups := &resourceManagerFakes.ResourceGroupManager{}
+	resourceGroups.ListResourceGroupsReturns(&models.ResourceGroupList{Resources: []models.ResourceGroup{{ID: "rg-1", Name: "default"}}}, nil)
+	vpcs.resourceGroups = resourceGroups
+
+	volumeRequest := provider.Volume{
+		Name:     String("volume"),
+		Capacity: Int(10),
+		VPCVolume: provider.VPCVolume{
+			Profile:       &provider.Profile{Name: "general-purpose"},
+			ResourceGroup: &provider.ResourceGroup{Name: "default"},
+		},
+	}
+	_, err = vpcs.CreateVolume(volumeRequest)
+	assert.Nil(t, err)
+	template, _ := volumeService.CreateVolumeArgsForCall(0)
+	assert.Equal(t, &models.ResourceGroup{ID: "rg-1"}, template.ResourceGroup)
+
+	// Unknown name fails before the volume is created
+	volumeRequest.VPCVolume.ResourceGroup = &provider.ResourceGroup{Name: "unknown"}
+	resourceGroups.ListResourceGroupsReturns(&models.ResourceGroupList{}, nil)
+	_, err = vpcs.CreateVolume(volumeRequest)
+	assert.Equal(t, userError.ResourceGroupNotFound, userError.GetUserErrorCode(err))
+	assert.Equal(t, 1, volumeService.CreateVolumeCallCount())
+}
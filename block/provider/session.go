@@ -18,9 +18,13 @@
 package provider
 
 import (
+	"context"
+
 	"github.com/IBM/ibmcloud-volume-interface/lib/provider"
 	vpcconfig "github.com/IBM/ibmcloud-volume-vpc/block/vpcconfig"
 	"github.com/IBM/ibmcloud-volume-vpc/common/vpcclient/instances"
+	"github.com/IBM/ibmcloud-volume-vpc/common/vpcclient/keymanagement"
+	"github.com/IBM/ibmcloud-volume-vpc/common/vpcclient/resourcemanager"
 	"github.com/IBM/ibmcloud-volume-vpc/common/vpcclient/riaas"
 	"go.uber.org/zap"
 )
@@ -39,6 +43,18 @@ type VPCSession struct {
 	Logger                *zap.Logger
 	APIRetry              FlexyRetry
 	SessionError          error
+	VolumeLease           *VolumeLeaseConfig // Optional, volume tag based lease taken before attach and detach
+	BatchWorkers          int                // Number of instances processed in parallel by AttachVolumes and DetachVolumes
+	poller                *VolumePoller      // Shared by the sessions of the provider, nil means each wait polls on its own
+	ctx                   context.Context    // Context of the request of the session, its cancellation ends the waits of the poller
+	heldLeases            *heldVolumeLeases  // Leases of the session from attach and detach till their wait, nil means released by attach and detach
+	tracing               context.Context    // Carries the span of the operation in progress, nil means not traced
+	iksVolumeAttach       bool               // APIClientVolAttachMgr is the IKS volume attach service of Apiclient
+	resourceGroup         string             // Resource group of the request context, overrides the one of the config
+
+	resourceGroups     resourcemanager.ResourceGroupManager // Resolves the resource group names, nil means names are not resolved
+	resourceGroupNames *resourceGroupCache                  // Shared by the sessions of the provider, nil means no caching
+	keyManager         keymanagement.KeyManager             // Checks the encryption keys, nil means only their CRN is checked
 }
 
 const (
@@ -60,6 +76,12 @@ func (*VPCSession) Close() {
 	// Do nothing for now
 }
 
+// UseIKSVolumeAttachService makes the session attach and detach the volumes through the IKS storage API
+func (vpcs *VPCSession) UseIKSVolumeAttachService() {
+	vpcs.APIClientVolAttachMgr = vpcs.Apiclient.IKSVolumeAttachService()
+	vpcs.iksVolumeAttach = true
+}
+
 // GetProviderDisplayName returns the name of the VPC provider
 func (vpcs *VPCSession) GetProviderDisplayName() provider.VolumeProvider {
 	return VPC
@@ -0,0 +1,249 @@
+/**
+ * Copyright 2022 IBM Corp.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package provider ...
+package provider
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/IBM/ibmcloud-volume-interface/lib/provider"
+	"github.com/IBM/ibmcloud-volume-interface/provider/local"
+	vpciam "github.com/IBM/ibmcloud-volume-vpc/common/iam"
+	vpcclient "github.com/IBM/ibmcloud-volume-vpc/common/vpcclient/client"
+	"go.uber.org/zap"
+)
+
+const (
+	// DefaultTokenRefreshBefore is how long before their expiry the pooled tokens are refreshed in the background
+	DefaultTokenRefreshBefore = 5 * time.Minute
+
+	// DefaultTokenExpiryMargin is how long before their expiry the pooled tokens stop being handed out
+	DefaultTokenExpiryMargin = time.Minute
+
+	// DefaultSessionPoolEntries is the count of credentials whose tokens are pooled
+	DefaultSessionPoolEntries = 100
+)
+
+// SessionPoolConfig enables the reuse of the access tokens by the sessions of a provider opened with the same
+// credentials, instead of a token exchange per session. The sessions also share the HTTP client of the provider.
+// Tokens are refreshed in the background shortly before their JWT expiry, and dropped once the API rejects them
+// as token_invalid.
+type SessionPoolConfig struct {
+	// RefreshBefore is how long before its expiry a token is refreshed, DefaultTokenRefreshBefore if 0
+	RefreshBefore time.Duration
+
+	// ExpiryMargin is how long before its expiry a token stops being reused, DefaultTokenExpiryMargin if 0
+	ExpiryMargin time.Duration
+
+	// MaxEntries is the count of credentials pooled, the least recently used are evicted. DefaultSessionPoolEntries if 0
+	MaxEntries int
+}
+
+func (c SessionPoolConfig) refreshBefore() time.Duration {
+	if c.RefreshBefore <= 0 {
+		return DefaultTokenRefreshBefore
+	}
+	return c.RefreshBefore
+}
+
+func (c SessionPoolConfig) expiryMargin() time.Duration {
+	if c.ExpiryMargin <= 0 {
+		return DefaultTokenExpiryMargin
+	}
+	return c.ExpiryMargin
+}
+
+func (c SessionPoolConfig) maxEntries() int {
+	if c.MaxEntries <= 0 {
+		return DefaultSessionPoolEntries
+	}
+	return c.MaxEntries
+}
+
+// sessionPool holds the credentials of the sessions of a provider, keyed by the credentials they were exchanged for
+type sessionPool struct {
+	mu        sync.Mutex
+	entries   map[string]*sessionPoolEntry
+	exchanges *vpcclient.Coalescer
+}
+
+type sessionPoolEntry struct {
+	credentials provider.ContextCredentials
+	expires     time.Time
+	lastUsed    time.Time
+	refreshing  bool
+}
+
+func newSessionPool() *sessionPool {
+	return &sessionPool{entries: map[string]*sessionPoolEntry{}, exchanges: vpcclient.NewCoalescer()}
+}
+
+// sessionPoolKey keys the pool by a digest of the credentials and their scope, so that they are not kept in clear
+func sessionPoolKey(kind string, credentials ...string) string {
+	digest := sha256.Sum256([]byte(kind + "\x00" + strings.Join(credentials, "\x00")))
+	return kind + "/" + hex.EncodeToString(digest[:])
+}
+
+// get returns the pooled credentials of key, calling exchange when there are none usable. Credentials close to
+// their expiry are still returned, while they get refreshed in the background.
+func (sp *sessionPool) get(ctx context.Context, key string, config SessionPoolConfig, logger *zap.Logger, exchange func(ctx context.Context) (provider.ContextCredentials, error)) (provider.ContextCredentials, error) {
+	now := time.Now()
+	sp.mu.Lock()
+	if entry, ok := sp.entries[key]; ok && now.Before(entry.expires.Add(-config.expiryMargin())) {
+		entry.lastUsed = now
+		if !entry.refreshing && !now.Before(entry.expires.Add(-config.refreshBefore())) {
+			entry.refreshing = true
+			go sp.refresh(key, config, logger, exchange)
+		}
+		credentials := entry.credentials
+		sp.mu.Unlock()
+		return credentials, nil
+	}
+	sp.mu.Unlock()
+
+	// Concurrent sessions of the same credentials share one exchange. An exchange given up as the context of the
+	// session running it was done is run again by the other sessions, unless their own context is done too.
+	result, shared, err := sp.exchanges.Do(key, func() (interface{}, error) {
+		return sp.exchange(ctx, key, config, logger, exchange)
+	})
+	if shared && vpciam.IsExchangeCancelled(err) && ctx.Err() == nil {
+		return sp.exchange(ctx, key, config, logger, exchange)
+	}
+	return result.(provider.ContextCredentials), err
+}
+
+// exchange exchanges the credentials of key, and pools them
+func (sp *sessionPool) exchange(ctx context.Context, key string, config SessionPoolConfig, logger *zap.Logger, exchange func(ctx context.Context) (provider.ContextCredentials, error)) (provider.ContextCredentials, error) {
+	credentials, err := exchange(ctx)
+	if err != nil {
+		return credentials, err
+	}
+	sp.put(key, config, logger, credentials)
+	return credentials, nil
+}
+
+// refresh exchanges the credentials of key again, the pooled ones stay in use if that fails. The refresh outlives
+// the session which started it, so it does not stop with its context.
+func (sp *sessionPool) refresh(key string, config SessionPoolConfig, logger *zap.Logger, exchange func(ctx context.Context) (provider.ContextCredentials, error)) {
+	_, _, err := sp.exchanges.Do(key, func() (interface{}, error) {
+		return sp.exchange(context.Background(), key, config, logger, exchange)
+	})
+	if err != nil {
+		logger.Warn("Failed to refresh the pooled access token, it is used until it expires", zap.Error(err))
+		sp.mu.Lock()
+		if entry, ok := sp.entries[key]; ok {
+			entry.refreshing = false
+		}
+		sp.mu.Unlock()
+	}
+}
+
+// put pools the credentials until the expiry of their token. Tokens whose expiry can not be read are not pooled.
+func (sp *sessionPool) put(key string, config SessionPoolConfig, logger *zap.Logger, credentials provider.ContextCredentials) {
+	expires, err := vpciam.AccessTokenExpiry(credentials.Credential)
+	if err != nil {
+		logger.Debug("Access token is not pooled, its expiry is unknown", zap.Error(err))
+		return
+	}
+
+	now := time.Now()
+	sp.mu.Lock()
+	defer sp.mu.Unlock()
+	if _, ok := sp.entries[key]; !ok && len(sp.entries) >= config.maxEntries() {
+		sp.evictLeastRecentlyUsed()
+	}
+	sp.entries[key] = &sessionPoolEntry{credentials: credentials, expires: expires, lastUsed: now}
+}
+
+func (sp *sessionPool) evictLeastRecentlyUsed() {
+	var oldestKey string
+	var oldest time.Time
+	for key, entry := range sp.entries {
+		if oldestKey == "" || entry.lastUsed.Before(oldest) {
+			oldestKey, oldest = key, entry.lastUsed
+		}
+	}
+	delete(sp.entries, oldestKey)
+}
+
+// invalidate drops the pooled credentials using the token
+func (sp *sessionPool) invalidate(token string) {
+	sp.mu.Lock()
+	defer sp.mu.Unlock()
+	for key, entry := range sp.entries {
+		if entry.credentials.Credential == token {
+			delete(sp.entries, key)
+		}
+	}
+}
+
+// len returns the count of pooled credentials
+func (sp *sessionPool) len() int {
+	sp.mu.Lock()
+	defer sp.mu.Unlock()
+	return len(sp.entries)
+}
+
+// invalidateMiddleware drops the token a request was sent with from the pool when the API rejects it as
+// token_invalid, so that the next sessions get a new one. The token is read from the request that got the
+// response, which is the replay with the refreshed token for the sessions with a token source.
+func (sp *sessionPool) invalidateMiddleware() vpcclient.Middleware {
+	return vpcclient.MiddlewareFunc(func(next vpcclient.Handler) vpcclient.Handler {
+		return func(request *vpcclient.Request, httpRequest *http.Request) (*http.Response, error) {
+			resp, err := next(request, httpRequest)
+			if err == nil && vpcclient.IsTokenInvalid(resp) {
+				sent := httpRequest
+				if resp.Request != nil {
+					sent = resp.Request
+				}
+				if token := strings.TrimPrefix(sent.Header.Get("Authorization"), "Bearer "); token != "" {
+					sp.invalidate(token)
+				}
+			}
+			return resp, err
+		}
+	})
+}
+
+// pooledCredentialsFactory serves the IAM access tokens of the wrapped factory from the session pool. The scope
+// identifies the token exchange of the factory, see credentialsScope, so the tokens are keyed by it and the API key.
+type pooledCredentialsFactory struct {
+	local.ContextCredentialsFactory
+	pool   *sessionPool
+	config SessionPoolConfig
+	scope  string
+}
+
+var _ local.ContextCredentialsFactory = &pooledCredentialsFactory{}
+
+// ForIAMAccessToken returns the pooled access token of the API key, exchanging it when there is none usable
+func (f *pooledCredentialsFactory) ForIAMAccessToken(apiKey string, logger *zap.Logger) (provider.ContextCredentials, error) {
+	return f.forIAMAccessToken(context.Background(), apiKey, logger)
+}
+
+// forIAMAccessToken is ForIAMAccessToken, the exchange stops retrying once the context is done
+func (f *pooledCredentialsFactory) forIAMAccessToken(ctx context.Context, apiKey string, logger *zap.Logger) (provider.ContextCredentials, error) {
+	return f.pool.get(ctx, sessionPoolKey(string(provider.IAMAccessToken), f.scope, apiKey), f.config, logger, func(ctx context.Context) (provider.ContextCredentials, error) {
+		return forIAMAccessToken(ctx, f.ContextCredentialsFactory, apiKey, logger)
+	})
+}
@@ -0,0 +1,311 @@
+/**
+ * Copyright 2022 IBM Corp.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package provider ...
+package provider
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/IBM/ibmcloud-volume-interface/lib/provider"
+	"github.com/IBM/ibmcloud-volume-interface/provider/iam"
+	localFakes "github.com/IBM/ibmcloud-volume-interface/provider/local/fakes"
+	vpciam "github.com/IBM/ibmcloud-volume-vpc/common/iam"
+	vpcclient "github.com/IBM/ibmcloud-volume-vpc/common/vpcclient/client"
+	"github.com/IBM/ibmcloud-volume-vpc/common/vpcclient/models"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+)
+
+// testAccessToken returns an unsigned JWT expiring at exp
+func testAccessToken(name string, exp time.Time) string {
+	payload := fmt.Sprintf(`{"iam_id":%q,"exp":%d}`, name, exp.Unix())
+	return "eyJhbGciOiJSUzI1NiJ9." + base64.RawURLEncoding.EncodeToString([]byte(payload)) + ".c2lnbmF0dXJl"
+}
+
+func TestSessionPool(t *testing.T) {
+	logger, teardown := GetTestLogger(t)
+	defer teardown()
+
+	validToken := testAccessToken("valid", time.Now().Add(time.Hour))
+	testCases := []struct {
+		name              string
+		config            SessionPoolConfig
+		tokens            []string
+		exchangeErr       error
+		expectedExchanges int
+		expectedPooled    int
+	}{
+		{
+			name:              "token reused until its expiry",
+			tokens:            []string{validToken},
+			expectedExchanges: 1,
+			expectedPooled:    1,
+		}, {
+			name:              "token close to its expiry is not reused",
+			tokens:            []string{testAccessToken("expiring", time.Now().Add(30*time.Second))},
+			expectedExchanges: 3,
+			expectedPooled:    1,
+		}, {
+			name:              "token without expiry is not pooled",
+			tokens:            []string{"access-token"},
+			expectedExchanges: 3,
+		}, {
+			name:              "failed exchange is not pooled",
+			exchangeErr:       errors.New("exchange failed"),
+			expectedExchanges: 3,
+		},
+	}
+
+	for _, testcase := range testCases {
+		t.Run(testcase.name, func(t *testing.T) {
+			ccf := &localFakes.ContextCredentialsFactory{}
+			for i, token := range testcase.tokens {
+				ccf.ForIAMAccessTokenReturnsOnCall(i, provider.ContextCredentials{AuthType: provider.IAMAccessToken, Credential: token}, nil)
+			}
+			if len(testcase.tokens) > 0 {
+				ccf.ForIAMAccessTokenReturns(provider.ContextCredentials{AuthType: provider.IAMAccessToken, Credential: testcase.tokens[len(testcase.tokens)-1]}, nil)
+			} else {
+				ccf.ForIAMAccessTokenReturns(provider.ContextCredentials{}, testcase.exchangeErr)
+			}
+			pool := newSessionPool()
+			factory := &pooledCredentialsFactory{ContextCredentialsFactory: ccf, pool: pool, config: testcase.config}
+
+			for i := 0; i < 3; i++ {
+				credentials, err := factory.ForIAMAccessToken("api-key", logger)
+				if testcase.exchangeErr != nil {
+					assert.Equal(t, testcase.exchangeErr, err)
+					continue
+				}
+				assert.Nil(t, err)
+				assert.Equal(t, testcase.tokens[0], credentials.Credential)
+			}
+			assert.Equal(t, testcase.expectedExchanges, ccf.ForIAMAccessTokenCallCount())
+			assert.Equal(t, testcase.expectedPooled, pool.len())
+		})
+	}
+}
+
+func TestSessionPoolKeyedByCredentials(t *testing.T) {
+	logger, teardown := GetTestLogger(t)
+	defer teardown()
+
+	ccf := &localFakes.ContextCredentialsFactory{}
+	ccf.ForIAMAccessTokenCalls(func(apiKey string, _ *zap.Logger) (provider.ContextCredentials, error) {
+		return provider.ContextCredentials{Credential: testAccessToken(apiKey, time.Now().Add(time.Hour))}, nil
+	})
+	factory := &pooledCredentialsFactory{ContextCredentialsFactory: ccf, pool: newSessionPool(), config: SessionPoolConfig{MaxEntries: 2}}
+
+	for _, apiKey := range []string{"api-key-1", "api-key-2", "api-key-1", "api-key-3", "api-key-1"} {
+		credentials, err := factory.ForIAMAccessToken(apiKey, logger)
+		assert.Nil(t, err)
+		assert.Equal(t, testAccessToken(apiKey, time.Now().Add(time.Hour)), credentials.Credential)
+	}
+	// api-key-2 was evicted as the least recently used, api-key-1 stayed pooled
+	assert.Equal(t, 3, ccf.ForIAMAccessTokenCallCount())
+	assert.Equal(t, 2, factory.pool.len())
+
+	// Same API key exchanged with other credentials, e.g another IAM client, is pooled apart
+	other := &pooledCredentialsFactory{ContextCredentialsFactory: ccf, pool: factory.pool, config: factory.config, scope: "other-client"}
+	_, err := other.ForIAMAccessToken("api-key-1", logger)
+	assert.Nil(t, err)
+	assert.Equal(t, 4, ccf.ForIAMAccessTokenCallCount())
+}
+
+func TestSessionPoolBackgroundRefresh(t *testing.T) {
+	logger, teardown := GetTestLogger(t)
+	defer teardown()
+
+	exp := time.Now().Add(10 * time.Minute)
+	firstToken, refreshedToken := testAccessToken("first", exp), testAccessToken("refreshed", exp.Add(time.Hour))
+	ccf := &localFakes.ContextCredentialsFactory{}
+	ccf.ForIAMAccessTokenReturnsOnCall(0, provider.ContextCredentials{Credential: firstToken}, nil)
+	ccf.ForIAMAccessTokenReturnsOnCall(1, provider.ContextCredentials{Credential: refreshedToken}, nil)
+	factory := &pooledCredentialsFactory{ContextCredentialsFactory: ccf, pool: newSessionPool(), config: SessionPoolConfig{RefreshBefore: 20 * time.Minute}}
+
+	credentials, err := factory.ForIAMAccessToken("api-key", logger)
+	assert.Nil(t, err)
+	assert.Equal(t, firstToken, credentials.Credential)
+
+	// Token in the refresh window is still handed out while it is refreshed
+	credentials, err = factory.ForIAMAccessToken("api-key", logger)
+	assert.Nil(t, err)
+	assert.Equal(t, firstToken, credentials.Credential)
+	assert.Eventually(t, func() bool {
+		credentials, _ := factory.ForIAMAccessToken("api-key", logger)
+		return credentials.Credential == refreshedToken
+	}, 5*time.Second, 10*time.Millisecond)
+	assert.Equal(t, 2, ccf.ForIAMAccessTokenCallCount())
+}
+
+func TestSessionPoolCancelledExchange(t *testing.T) {
+	logger, teardown := GetTestLogger(t)
+	defer teardown()
+
+	// Exchange of a cancelled session gives up after its first connection error
+	s := httptest.NewServer(http.NotFoundHandler())
+	s.Close()
+	cancelled, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, cancelledErr := vpciam.NewTokenExchangeAPIKeyService(&iam.AuthConfiguration{IamURL: s.URL}, nil).ExchangeIAMAPIKeyForAccessTokenWithContext(cancelled, "api-key", logger)
+	assert.True(t, vpciam.IsExchangeCancelled(cancelledErr))
+
+	pool := newSessionPool()
+	key := sessionPoolKey(string(provider.IAMAccessToken), "api-key")
+	leaderDone := make(chan error)
+	go func() {
+		_, err := pool.get(cancelled, key, SessionPoolConfig{}, logger, func(context.Context) (provider.ContextCredentials, error) {
+			// Session waiting for the exchange joins it before it is given up
+			assert.Eventually(t, func() bool { return pool.exchanges.Stats().Saved == 1 }, 5*time.Second, time.Millisecond)
+			return provider.ContextCredentials{}, cancelledErr
+		})
+		leaderDone <- err
+	}()
+	assert.Eventually(t, func() bool { return pool.exchanges.Stats().Calls == 1 }, 5*time.Second, time.Millisecond)
+
+	// Session whose context is not done exchanges again by itself
+	token := testAccessToken("follower", time.Now().Add(time.Hour))
+	credentials, err := pool.get(context.Background(), key, SessionPoolConfig{}, logger, func(context.Context) (provider.ContextCredentials, error) {
+		return provider.ContextCredentials{Credential: token}, nil
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, token, credentials.Credential)
+	assert.True(t, vpciam.IsExchangeCancelled(<-leaderDone))
+	assert.Equal(t, 1, pool.len())
+}
+
+func TestSessionPoolInvalidateMiddleware(t *testing.T) {
+	logger, teardown := GetTestLogger(t)
+	defer teardown()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if r.URL.Path == "/expired" {
+			w.WriteHeader(http.StatusUnauthorized)
+			fmt.Fprint(w, `{"errors":[{"code":"token_invalid","message":"Token expired"}],"trace":"trace-1"}`)
+			return
+		}
+		w.WriteHeader(http.StatusUnauthorized)
+		fmt.Fprint(w, `{"errors":[{"code":"not_authorized","message":"Not authorized"}],"trace":"trace-2"}`)
+	}))
+	defer server.Close()
+
+	token := testAccessToken("pooled", time.Now().Add(time.Hour))
+	ccf := &localFakes.ContextCredentialsFactory{}
+	ccf.ForIAMAccessTokenReturns(provider.ContextCredentials{Credential: token}, nil)
+	pool := newSessionPool()
+	factory := &pooledCredentialsFactory{ContextCredentialsFactory: ccf, pool: pool}
+	_, err := factory.ForIAMAccessToken("api-key", logger)
+	assert.Nil(t, err)
+
+	c := vpcclient.New(context.Background(), server.URL, nil, http.DefaultClient, "test-context", "default").WithAuthToken(token).WithMiddleware(pool.invalidateMiddleware())
+	invoke := func(path string) error {
+		var errResult models.Error
+		_, err := c.NewRequest(&vpcclient.Operation{Name: "GetVolume", Method: http.MethodGet, PathPattern: path}).JSONError(&errResult).Invoke()
+		return err
+	}
+
+	// Other authorization errors keep the token pooled
+	assert.EqualError(t, invoke("/forbidden"), "Trace Code:trace-2, Not authorized Please check ")
+	assert.Equal(t, 1, pool.len())
+
+	// Error body is still consumed once the token was dropped
+	assert.EqualError(t, invoke("/expired"), "Trace Code:trace-1, Token expired Please check ")
+	assert.Equal(t, 0, pool.len())
+	_, err = factory.ForIAMAccessToken("api-key", logger)
+	assert.Nil(t, err)
+	assert.Equal(t, 2, ccf.ForIAMAccessTokenCallCount())
+}
+
+// replayTokenSource hands out token, then refreshed once token is rejected
+type replayTokenSource struct {
+	token     string
+	refreshed string
+}
+
+func (ts *replayTokenSource) Token() (string, error) {
+	return ts.token, nil
+}
+
+func (ts *replayTokenSource) Refresh(rejected string) (string, error) {
+	return ts.refreshed, nil
+}
+
+func TestSessionPoolInvalidateRefreshedToken(t *testing.T) {
+	logger, teardown := GetTestLogger(t)
+	defer teardown()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusUnauthorized)
+		fmt.Fprint(w, `{"errors":[{"code":"token_invalid","message":"Token expired"}],"trace":"trace-1"}`)
+	}))
+	defer server.Close()
+
+	// Session was opened with the first token, the pool now holds the refreshed one
+	firstToken := testAccessToken("first", time.Now().Add(time.Hour))
+	refreshedToken := testAccessToken("refreshed", time.Now().Add(time.Hour))
+	ccf := &localFakes.ContextCredentialsFactory{}
+	ccf.ForIAMAccessTokenReturns(provider.ContextCredentials{Credential: refreshedToken}, nil)
+	pool := newSessionPool()
+	factory := &pooledCredentialsFactory{ContextCredentialsFactory: ccf, pool: pool}
+	_, err := factory.ForIAMAccessToken("api-key", logger)
+	assert.Nil(t, err)
+
+	c := vpcclient.New(context.Background(), server.URL, nil, http.DefaultClient, "test-context", "default").WithTokenSource(&replayTokenSource{token: firstToken, refreshed: refreshedToken}).WithMiddleware(pool.invalidateMiddleware())
+	var errResult models.Error
+	_, err = c.NewRequest(&vpcclient.Operation{Name: "GetVolume", Method: http.MethodGet, PathPattern: "/volumes"}).JSONError(&errResult).Invoke()
+	assert.NotNil(t, err)
+
+	// Rejection of the replay drops the refreshed token it was sent with
+	assert.Equal(t, 0, pool.len())
+}
+
+func TestContextCredentialsFactorySessionPool(t *testing.T) {
+	logger, teardown := GetTestLogger(t)
+	defer teardown()
+
+	vpcp, _ := GetTestProvider(t, logger)
+	vpcp.ContextCF = &localFakes.ContextCredentialsFactory{}
+	vpcp.sessionPool = newSessionPool()
+
+	ccf, err := vpcp.ContextCredentialsFactory(nil)
+	assert.Nil(t, err)
+	assert.Equal(t, vpcp.ContextCF, ccf)
+
+	vpcp.SessionPool = &SessionPoolConfig{}
+	ccf, err = vpcp.ContextCredentialsFactory(nil)
+	assert.Nil(t, err)
+	assert.IsType(t, &pooledCredentialsFactory{}, ccf)
+	assert.Equal(t, credentialsScope(vpcp.Config), ccf.(*pooledCredentialsFactory).scope)
+
+	// Tokens of other zones and credentials are pooled apart
+	zone := "us-south-1"
+	zoneCCF, _ := vpcp.ContextCredentialsFactory(&zone)
+	assert.NotEqual(t, ccf.(*pooledCredentialsFactory).scope, zoneCCF.(*pooledCredentialsFactory).scope)
+	reloaded := *vpcp.Config
+	vpcConfig := *vpcp.Config.VPCConfig
+	vpcConfig.IamClientID = "rotated-client"
+	reloaded.VPCConfig = &vpcConfig
+	assert.NotEqual(t, credentialsScope(vpcp.Config), credentialsScope(&reloaded))
+}
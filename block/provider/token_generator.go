@@ -31,6 +31,7 @@ import (
 	"github.com/IBM/ibmcloud-volume-interface/lib/provider"
 	"github.com/IBM/ibmcloud-volume-interface/provider/auth"
 	"github.com/IBM/ibmcloud-volume-interface/provider/local"
+	vpcconfig "github.com/IBM/ibmcloud-volume-vpc/block/vpcconfig"
 )
 
 // tokenGenerator ...
@@ -42,6 +43,40 @@ type tokenGenerator struct {
 	tokenBeforeTime time.Duration
 
 	privateKey *rsa.PrivateKey // Secret. Do not export
+
+	// keys are the keys of the configured key directory, the single key of the etc directory is used if nil
+	keys *tokenKeySet
+}
+
+// newTokenGenerator ...
+func newTokenGenerator(conf *vpcconfig.VPCBlockConfig) *tokenGenerator {
+	tg := &tokenGenerator{config: conf.VPCConfig}
+	if conf.TokenKeys != nil {
+		tg.keys = newTokenKeySet(*conf.TokenKeys)
+	}
+	return tg
+}
+
+// signingKey returns the kid and the key signing the tokens
+func (tg *tokenGenerator) signingKey(logger zap.Logger) (string, *rsa.PrivateKey, error) {
+	if tg.keys != nil {
+		return tg.keys.signingKey(logger)
+	}
+	if err := tg.readConfig(logger); err != nil {
+		return "", nil, err
+	}
+	return tg.tokenKID, tg.privateKey, nil
+}
+
+// publicKeys returns the public keys verifying the tokens by kid
+func (tg *tokenGenerator) publicKeys(logger zap.Logger) (map[string]*rsa.PublicKey, error) {
+	if tg.keys != nil {
+		return tg.keys.publicKeys(logger)
+	}
+	if err := tg.readConfig(logger); err != nil {
+		return nil, err
+	}
+	return map[string]*rsa.PublicKey{tg.tokenKID: &tg.privateKey.PublicKey}, nil
 }
 
 // readConfig ...
@@ -75,13 +110,13 @@ func (tg *tokenGenerator) readConfig(logger zap.Logger) (err error) {
 }
 
 // buildToken ...
-func (tg *tokenGenerator) buildToken(contextCredentials provider.ContextCredentials, ts time.Time, logger zap.Logger) (token *jwt.Token, err error) {
+func (tg *tokenGenerator) buildToken(contextCredentials provider.ContextCredentials, ts time.Time, logger zap.Logger) (token *jwt.Token, key *rsa.PrivateKey, err error) {
 	logger.Info("Entering getJWTToken", zap.Reflect("contextCredentials", contextCredentials))
 	defer func() {
 		logger.Info("Exiting getJWTToken", zap.Reflect("token", token), local.ZapError(err))
 	}()
 
-	err = tg.readConfig(logger)
+	kid, key, err := tg.signingKey(logger)
 	if err != nil {
 		return
 	}
@@ -108,19 +143,19 @@ func (tg *tokenGenerator) buildToken(contextCredentials provider.ContextCredenti
 	}
 
 	token = jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
-	token.Header["kid"] = tg.tokenKID
+	token.Header["kid"] = kid
 
 	return
 }
 
 // getServiceToken ...
 func (tg *tokenGenerator) getServiceToken(contextCredentials provider.ContextCredentials, logger zap.Logger) (signedToken *string, err error) {
-	token, err := tg.buildToken(contextCredentials, time.Now(), logger)
+	token, key, err := tg.buildToken(contextCredentials, time.Now(), logger)
 	if err != nil {
 		return
 	}
 
-	signedString, err := token.SignedString(tg.privateKey)
+	signedString, err := token.SignedString(key)
 	if err != nil {
 		return
 	}
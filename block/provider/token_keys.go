@@ -0,0 +1,258 @@
+/**
+ * Copyright 2022 IBM Corp.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package provider ...
+package provider
+
+import (
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+	"go.uber.org/zap"
+
+	"github.com/IBM/ibmcloud-volume-interface/provider/local"
+	vpcconfig "github.com/IBM/ibmcloud-volume-vpc/block/vpcconfig"
+)
+
+const (
+	// DefaultTokenKeysCheckInterval is the default interval of the checks for changed token keys
+	DefaultTokenKeysCheckInterval = 30 * time.Second
+
+	// activeKIDFile is the file of the key directory holding the kid of the signing key
+	activeKIDFile = "active"
+)
+
+// tokenKeySet holds the keys of a key directory, reloaded when the files of the directory change
+type tokenKeySet struct {
+	config vpcconfig.TokenKeysConfig
+
+	mu          sync.RWMutex
+	keys        map[string]*rsa.PrivateKey
+	activeKID   string
+	fingerprint string
+	checked     time.Time
+}
+
+// newTokenKeySet ...
+func newTokenKeySet(config vpcconfig.TokenKeysConfig) *tokenKeySet {
+	if config.CheckInterval <= 0 {
+		config.CheckInterval = DefaultTokenKeysCheckInterval
+	}
+	return &tokenKeySet{config: config}
+}
+
+// signingKey returns the active key, the keys are reloaded first if the directory changed
+func (ks *tokenKeySet) signingKey(logger zap.Logger) (string, *rsa.PrivateKey, error) {
+	if err := ks.reload(logger); err != nil {
+		return "", nil, err
+	}
+	ks.mu.RLock()
+	defer ks.mu.RUnlock()
+	if ks.activeKID == "" {
+		return "", nil, errors.New("no active token key configured")
+	}
+	key, ok := ks.keys[ks.activeKID]
+	if !ok {
+		return "", nil, fmt.Errorf("active token key %q not found in %s", ks.activeKID, ks.config.Dir)
+	}
+	return ks.activeKID, key, nil
+}
+
+// publicKeys returns the public keys of all the keys by kid, verifiers need the previous keys until the tokens
+// they signed expire
+func (ks *tokenKeySet) publicKeys(logger zap.Logger) (map[string]*rsa.PublicKey, error) {
+	if err := ks.reload(logger); err != nil {
+		return nil, err
+	}
+	ks.mu.RLock()
+	defer ks.mu.RUnlock()
+	publicKeys := make(map[string]*rsa.PublicKey, len(ks.keys))
+	for kid, key := range ks.keys {
+		publicKeys[kid] = &key.PublicKey
+	}
+	return publicKeys, nil
+}
+
+// reload reads the keys again if the files of the directory changed since the last check. The loaded keys are
+// kept if the directory can not be read.
+func (ks *tokenKeySet) reload(logger zap.Logger) error {
+	ks.mu.RLock()
+	fresh := ks.keys != nil && time.Since(ks.checked) < ks.config.CheckInterval
+	ks.mu.RUnlock()
+	if fresh {
+		return nil
+	}
+
+	ks.mu.Lock()
+	defer ks.mu.Unlock()
+	if ks.keys != nil && time.Since(ks.checked) < ks.config.CheckInterval {
+		return nil
+	}
+	files, fingerprint, err := ks.listKeyFiles()
+	if err != nil {
+		logger.Error("Error reading the token key directory", zap.String("dir", ks.config.Dir), local.ZapError(err))
+		if ks.keys != nil {
+			return nil
+		}
+		return err
+	}
+	ks.checked = time.Now()
+	if ks.keys != nil && fingerprint == ks.fingerprint {
+		return nil
+	}
+
+	keys := make(map[string]*rsa.PrivateKey, len(files))
+	for kid, path := range files {
+		pem, err := ioutil.ReadFile(filepath.Clean(path))
+		if err != nil {
+			logger.Error("Error reading PEM", zap.String("kid", kid), local.ZapError(err))
+			continue
+		}
+		key, err := jwt.ParseRSAPrivateKeyFromPEM(pem)
+		if err != nil {
+			logger.Warn("Skipping token key, not an RSA private key", zap.String("kid", kid), local.ZapError(err))
+			continue
+		}
+		keys[kid] = key
+	}
+
+	activeKID := ks.config.ActiveKID
+	if activeKID == "" {
+		data, err := ioutil.ReadFile(filepath.Join(ks.config.Dir, activeKIDFile))
+		if err != nil && !os.IsNotExist(err) {
+			logger.Error("Error reading the active token kid", local.ZapError(err))
+		}
+		activeKID = strings.TrimSpace(string(data))
+	}
+	if _, ok := keys[activeKID]; !ok && ks.keys != nil {
+		// Keys being rotated are kept until the directory holds the active key
+		logger.Error("Active token key not found, keeping the loaded keys", zap.String("kid", activeKID))
+		return nil
+	}
+
+	ks.keys = keys
+	ks.activeKID = activeKID
+	ks.fingerprint = fingerprint
+	logger.Info("Loaded token keys", zap.String("dir", ks.config.Dir), zap.Int("keys", len(keys)), zap.String("activeKID", activeKID))
+	return nil
+}
+
+// listKeyFiles returns the key files of the directory by kid, and a fingerprint of their names, sizes and times
+func (ks *tokenKeySet) listKeyFiles() (map[string]string, string, error) {
+	entries, err := ioutil.ReadDir(ks.config.Dir)
+	if err != nil {
+		return nil, "", err
+	}
+	files := map[string]string{}
+	var fingerprint strings.Builder
+	for _, entry := range entries {
+		name := entry.Name()
+		// Kubernetes mounts keep the data in hidden directories, linked from the visible files
+		if strings.HasPrefix(name, ".") || strings.HasSuffix(name, ".pub") {
+			continue
+		}
+		path := filepath.Join(ks.config.Dir, name)
+		info, err := os.Stat(path)
+		if err != nil || info.IsDir() {
+			continue
+		}
+		fmt.Fprintf(&fingerprint, "%s:%d:%d;", name, info.Size(), info.ModTime().UnixNano())
+		if name != activeKIDFile {
+			files[strings.TrimSuffix(name, ".pem")] = path
+		}
+	}
+	return files, fingerprint.String(), nil
+}
+
+// jsonWebKey is an RSA public key of a JWKS document, see RFC 7517
+type jsonWebKey struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+}
+
+// jsonWebKeySet ...
+type jsonWebKeySet struct {
+	Keys []jsonWebKey `json:"keys"`
+}
+
+// newJSONWebKeySet returns the JWKS document of the public keys, sorted by kid
+func newJSONWebKeySet(publicKeys map[string]*rsa.PublicKey) jsonWebKeySet {
+	jwks := jsonWebKeySet{Keys: []jsonWebKey{}}
+	for kid, key := range publicKeys {
+		jwks.Keys = append(jwks.Keys, jsonWebKey{
+			Kty: "RSA",
+			Kid: kid,
+			Use: "sig",
+			Alg: jwt.SigningMethodRS256.Alg(),
+			N:   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+			E:   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+		})
+	}
+	sort.Slice(jwks.Keys, func(i, j int) bool { return jwks.Keys[i].Kid < jwks.Keys[j].Kid })
+	return jwks
+}
+
+// keysCheckInterval is the interval of the checks for changed keys, verifiers must not cache the keys any longer
+func (tg *tokenGenerator) keysCheckInterval() time.Duration {
+	if tg.keys == nil {
+		return DefaultTokenKeysCheckInterval
+	}
+	return tg.keys.config.CheckInterval
+}
+
+// TokenJWKS returns the JWKS document of the public keys verifying the service tokens of the provider
+func (vpcp *VPCBlockProvider) TokenJWKS(logger *zap.Logger) ([]byte, error) {
+	if vpcp.tokenGenerator == nil {
+		return nil, errors.New("no token generator configured")
+	}
+	publicKeys, err := vpcp.tokenGenerator.publicKeys(*logger)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(newJSONWebKeySet(publicKeys))
+}
+
+// JWKSHandler serves the JWKS document of the provider, see TokenJWKS
+func (vpcp *VPCBlockProvider) JWKSHandler(logger *zap.Logger) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		jwks, err := vpcp.TokenJWKS(logger)
+		if err != nil {
+			logger.Error("Failed to build the JWKS document", zap.Error(err))
+			http.Error(w, "token keys unavailable", http.StatusServiceUnavailable)
+			return
+		}
+		w.Header().Set("Content-Type", "application/jwk-set+json")
+		w.Header().Set("Cache-Control", fmt.Sprintf("max-age=%d", int(vpcp.tokenGenerator.keysCheckInterval().Seconds())))
+		_, _ = w.Write(jwks)
+	})
+}
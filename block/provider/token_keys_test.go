@@ -0,0 +1,176 @@
+/**
+ * Copyright 2022 IBM Corp.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package provider ...
+package provider
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"io/ioutil"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/IBM/ibmcloud-volume-interface/lib/provider"
+	vpcconfig "github.com/IBM/ibmcloud-volume-vpc/block/vpcconfig"
+)
+
+// writeTestKey writes a new RSA private key to the file of the directory
+func writeTestKey(t *testing.T, dir, name string) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	data := pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)})
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, name), data, 0600))
+}
+
+// verifyWithJWKS parses the signed token with the key of the JWKS document named by its kid
+func verifyWithJWKS(t *testing.T, jwksData []byte, signedToken string) *jwt.Token {
+	var jwks jsonWebKeySet
+	require.NoError(t, json.Unmarshal(jwksData, &jwks))
+	token, err := jwt.Parse(signedToken, func(token *jwt.Token) (interface{}, error) {
+		for _, key := range jwks.Keys {
+			if key.Kid == token.Header["kid"] {
+				n, _ := base64.RawURLEncoding.DecodeString(key.N)
+				e, _ := base64.RawURLEncoding.DecodeString(key.E)
+				return &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())}, nil
+			}
+		}
+		return nil, jwt.ErrInvalidKey
+	})
+	require.NoError(t, err)
+	return token
+}
+
+func TestTokenKeyRotation(t *testing.T) {
+	logger, teardown := GetTestLogger(t)
+	defer teardown()
+
+	dir := t.TempDir()
+	writeTestKey(t, dir, "key-1.pem")
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, "key-1.pub"), []byte("public key"), 0600))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, "active"), []byte("key-1\n"), 0600))
+
+	vpcp, _ := GetTestProvider(t, logger)
+	vpcp.tokenGenerator = newTokenGenerator(&vpcconfig.VPCBlockConfig{
+		VPCConfig: vpcp.Config.VPCConfig,
+		TokenKeys: &vpcconfig.TokenKeysConfig{Dir: dir, CheckInterval: time.Nanosecond},
+	})
+	vpcp.tokenGenerator.tokenTTL = time.Hour
+	cf := provider.ContextCredentials{AuthType: provider.IAMAccessToken, UserID: TestIKSAccountID}
+
+	signedToken, err := vpcp.tokenGenerator.getServiceToken(cf, *logger)
+	require.NoError(t, err)
+	jwks, err := vpcp.TokenJWKS(logger)
+	require.NoError(t, err)
+	token := verifyWithJWKS(t, jwks, *signedToken)
+	assert.Equal(t, "key-1", token.Header["kid"])
+
+	// New key is published before it signs, previous key stays published for the tokens it signed
+	writeTestKey(t, dir, "key-2.pem")
+	jwks, _ = vpcp.TokenJWKS(logger)
+	assert.Contains(t, string(jwks), `"kid":"key-2"`)
+	signedToken, _ = vpcp.tokenGenerator.getServiceToken(cf, *logger)
+	assert.Equal(t, "key-1", verifyWithJWKS(t, jwks, *signedToken).Header["kid"])
+
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, "active"), []byte("key-2-rotated"), 0600))
+	time.Sleep(time.Millisecond)
+	// Active key missing from the directory keeps the loaded keys
+	signedToken, err = vpcp.tokenGenerator.getServiceToken(cf, *logger)
+	require.NoError(t, err)
+	assert.Equal(t, "key-1", verifyWithJWKS(t, jwks, *signedToken).Header["kid"])
+
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, "active"), []byte("key-2"), 0600))
+	signedToken, err = vpcp.tokenGenerator.getServiceToken(cf, *logger)
+	require.NoError(t, err)
+	assert.Equal(t, "key-2", verifyWithJWKS(t, jwks, *signedToken).Header["kid"])
+
+	// JWKS document is served over HTTP
+	s := httptest.NewServer(vpcp.JWKSHandler(logger))
+	defer s.Close()
+	resp, err := http.Get(s.URL)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "application/jwk-set+json", resp.Header.Get("Content-Type"))
+	// Keys are not cached past the next check for changed keys
+	assert.Equal(t, "max-age=0", resp.Header.Get("Cache-Control"))
+	var served jsonWebKeySet
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&served))
+	assert.Equal(t, 2, len(served.Keys))
+	assert.Equal(t, "key-1", served.Keys[0].Kid)
+	assert.Equal(t, "RS256", served.Keys[0].Alg)
+}
+
+func TestTokenKeySetConfig(t *testing.T) {
+	logger, teardown := GetTestLogger(t)
+	defer teardown()
+
+	dir := t.TempDir()
+	writeTestKey(t, dir, "key-1")
+	writeTestKey(t, dir, "key-2")
+
+	testCases := []struct {
+		name        string
+		config      vpcconfig.TokenKeysConfig
+		expectedKID string
+		expectErr   bool
+	}{
+		{
+			name:        "active kid of the config",
+			config:      vpcconfig.TokenKeysConfig{Dir: dir, ActiveKID: "key-2"},
+			expectedKID: "key-2",
+		}, {
+			name:      "no active kid",
+			config:    vpcconfig.TokenKeysConfig{Dir: dir},
+			expectErr: true,
+		}, {
+			name:      "active kid not found",
+			config:    vpcconfig.TokenKeysConfig{Dir: dir, ActiveKID: "key-3"},
+			expectErr: true,
+		}, {
+			name:      "missing directory",
+			config:    vpcconfig.TokenKeysConfig{Dir: filepath.Join(dir, "missing"), ActiveKID: "key-1"},
+			expectErr: true,
+		},
+	}
+
+	for _, testcase := range testCases {
+		t.Run(testcase.name, func(t *testing.T) {
+			ks := newTokenKeySet(testcase.config)
+			assert.Equal(t, DefaultTokenKeysCheckInterval, ks.config.CheckInterval)
+			kid, key, err := ks.signingKey(*logger)
+			if testcase.expectErr {
+				assert.NotNil(t, err)
+				return
+			}
+			assert.Nil(t, err)
+			assert.Equal(t, testcase.expectedKID, kid)
+			assert.NotNil(t, key)
+		})
+	}
+}
@@ -0,0 +1,192 @@
+/**
+ * Copyright 2022 IBM Corp.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package provider ...
+package provider
+
+import (
+	"context"
+	"errors"
+	"sync"
+
+	"github.com/IBM/ibmcloud-volume-interface/lib/provider"
+	"github.com/IBM/ibmcloud-volume-interface/provider/auth"
+	"github.com/IBM/ibmcloud-volume-interface/provider/local"
+	vpciam "github.com/IBM/ibmcloud-volume-vpc/common/iam"
+	vpcclient "github.com/IBM/ibmcloud-volume-vpc/common/vpcclient/client"
+	"go.uber.org/zap"
+)
+
+// errTokenNotRefreshable is returned when the token of a session can not be refreshed
+var errTokenNotRefreshable = errors.New("access token of the session can not be refreshed, no API key or trusted profile configured")
+
+// sessionTokenSource provides the access token of a session, and refreshes it through the credentials factory of
+// the provider once the API rejects it. Long waits and retries can outlive the token the session was opened with.
+type sessionTokenSource struct {
+	mu         sync.Mutex
+	token      string
+	refreshing *tokenRefresh
+	refresh    func(rejected string) (provider.ContextCredentials, error)
+	verify     func(token string) error
+	pool       *sessionPool
+	logger     *zap.Logger
+}
+
+// tokenRefresh is the refresh in flight of a session, concurrent requests rejected with the same token wait for it
+type tokenRefresh struct {
+	done  chan struct{}
+	token string
+	err   error
+}
+
+var _ vpcclient.TokenSource = &sessionTokenSource{}
+
+// Token returns the current access token of the session
+func (ts *sessionTokenSource) Token() (string, error) {
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+	return ts.token, nil
+}
+
+// Refresh exchanges a new access token, unless a concurrent request of the session already replaced the rejected one.
+// The exchange runs outside the lock so that the other requests of the session keep their token meanwhile.
+func (ts *sessionTokenSource) Refresh(rejected string) (string, error) {
+	ts.mu.Lock()
+	if token := ts.token; token != rejected {
+		ts.mu.Unlock()
+		return token, nil
+	}
+	if ts.refresh == nil {
+		ts.mu.Unlock()
+		return "", errTokenNotRefreshable
+	}
+	if refreshing := ts.refreshing; refreshing != nil {
+		ts.mu.Unlock()
+		<-refreshing.done
+		return refreshing.token, refreshing.err
+	}
+	refreshing := &tokenRefresh{done: make(chan struct{})}
+	ts.refreshing = refreshing
+	ts.mu.Unlock()
+
+	refreshing.token, refreshing.err = ts.exchange(rejected)
+
+	ts.mu.Lock()
+	if refreshing.err == nil {
+		ts.token = refreshing.token
+	}
+	ts.refreshing = nil
+	ts.mu.Unlock()
+	close(refreshing.done)
+	return refreshing.token, refreshing.err
+}
+
+// exchange returns a new access token of the account of the session in place of the rejected one
+func (ts *sessionTokenSource) exchange(rejected string) (string, error) {
+	// Pooled token is the rejected one, the exchange must not return it again
+	if ts.pool != nil {
+		ts.pool.invalidate(rejected)
+	}
+	credentials, err := ts.refresh(rejected)
+	if err == nil && ts.verify != nil {
+		err = ts.verify(credentials.Credential)
+	}
+	if err != nil {
+		ts.logger.Error("Failed to refresh the access token rejected as token_invalid", zap.Error(err))
+		return "", err
+	}
+	ts.logger.Info("Refreshed the access token rejected as token_invalid")
+	return credentials.Credential, nil
+}
+
+// sessionTokenSource returns the token source of a session of the account opened with the credentials. IAM access
+// tokens are refreshed with the API key or trusted profile of the config, only if the account of the session is
+// known, trusted profile credentials are exchanged again, other credentials are used as they are. Refreshed tokens
+// must belong to the account of the session. The exchanges stop retrying once the context of the session is done.
+func (vpcp *VPCBlockProvider) sessionTokenSource(ctx context.Context, contextCredentials provider.ContextCredentials, token string, accountID string, ctxLogger *zap.Logger) *sessionTokenSource {
+	tokenSource := &sessionTokenSource{token: token, logger: ctxLogger}
+	if vpcp.SessionPool != nil {
+		tokenSource.pool = vpcp.sessionPool
+	}
+	if accountID != "" {
+		tokenSource.verify = func(token string) error {
+			_, err := vpcp.tokenAccountID(provider.ContextCredentials{IAMAccountID: accountID}, token, ctxLogger)
+			return err
+		}
+	}
+
+	// Trusted profile credentials are exchanged again
+	if isTrustedProfileAuthType(contextCredentials.AuthType) {
+		tokenSource.refresh = func(string) (provider.ContextCredentials, error) {
+			return vpcp.trustedProfileCredentials(ctx, contextCredentials, ctxLogger)
+		}
+		return tokenSource
+	}
+
+	// Tokens of an unknown account may not be minted by the provider, its credentials could switch the account.
+	// Factory of a trusted profile config exchanges the profile token, no API key is needed.
+	apiKey := vpcp.APIKey()
+	if contextCredentials.AuthType != provider.IAMAccessToken || accountID == "" || (apiKey == "" && vpcp.Config.TrustedProfile == nil) || vpcp.contextCredentialsFactory() == nil {
+		return tokenSource
+	}
+	tokenSource.refresh = func(rejected string) (provider.ContextCredentials, error) {
+		ccf, err := vpcp.ContextCredentialsFactory(nil)
+		if err != nil {
+			return provider.ContextCredentials{}, err
+		}
+		// Token cached by the exchange service is the rejected one, credentials rotated since the session was
+		// opened are used
+		invalidateExchangedToken(ccf, rejected)
+		return forIAMAccessToken(ctx, ccf, vpcp.APIKey(), ctxLogger)
+	}
+	return tokenSource
+}
+
+// forIAMAccessToken exchanges the API key for IAM access token credentials with the factory, the exchange stops
+// retrying once the context is done if the token exchange service of the factory supports it
+func forIAMAccessToken(ctx context.Context, ccf local.ContextCredentialsFactory, apiKey string, ctxLogger *zap.Logger) (provider.ContextCredentials, error) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	switch factory := ccf.(type) {
+	case *pooledCredentialsFactory:
+		return factory.forIAMAccessToken(ctx, apiKey, ctxLogger)
+	case *auth.ContextCredentialsFactory:
+		// Same as auth.ContextCredentialsFactory.ForIAMAccessToken, with the context
+		accessToken, err := vpciam.ExchangeIAMAPIKeyForAccessTokenWithContext(ctx, factory.TokenExchangeService, apiKey, ctxLogger)
+		if err != nil {
+			ctxLogger.Error("Unable to retrieve IAM access token from IAM API key", zap.Error(err))
+			return provider.ContextCredentials{}, err
+		}
+		iamAccountID, err := factory.TokenExchangeService.GetIAMAccountIDFromAccessToken(*accessToken, ctxLogger)
+		if err != nil {
+			ctxLogger.Error("Unable to retrieve the account of the IAM access token", zap.Error(err))
+			return provider.ContextCredentials{}, err
+		}
+		return provider.ContextCredentials{AuthType: provider.IAMAccessToken, IAMAccountID: iamAccountID, Credential: accessToken.Token}, nil
+	}
+	return ccf.ForIAMAccessToken(apiKey, ctxLogger)
+}
+
+// invalidateExchangedToken drops the token from the cache of the token exchange service of the factory
+func invalidateExchangedToken(ccf local.ContextCredentialsFactory, token string) {
+	switch factory := ccf.(type) {
+	case *pooledCredentialsFactory:
+		invalidateExchangedToken(factory.ContextCredentialsFactory, token)
+	case *auth.ContextCredentialsFactory:
+		vpciam.InvalidateAccessToken(factory.TokenExchangeService, token)
+	}
+}
@@ -0,0 +1,196 @@
+/**
+ * Copyright 2022 IBM Corp.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package provider ...
+package provider
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/IBM/ibmcloud-volume-interface/config"
+	"github.com/IBM/ibmcloud-volume-interface/lib/provider"
+	localFakes "github.com/IBM/ibmcloud-volume-interface/provider/local/fakes"
+	vpcconfig "github.com/IBM/ibmcloud-volume-vpc/block/vpcconfig"
+	vpcauth "github.com/IBM/ibmcloud-volume-vpc/common/auth"
+	userError "github.com/IBM/ibmcloud-volume-vpc/common/messages"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSessionTokenSource(t *testing.T) {
+	logger, teardown := getTestSyncLogger(t)
+	defer teardown()
+
+	expiredToken := testAccountToken("account-1")
+	freshToken := testAccountToken("account-1") + "-fresh"
+	vpcp, _ := GetTestProvider(t, logger)
+	ccf := &localFakes.ContextCredentialsFactory{}
+	ccf.ForIAMAccessTokenReturns(provider.ContextCredentials{AuthType: provider.IAMAccessToken, Credential: freshToken}, nil)
+	vpcp.ContextCF = ccf
+
+	// IMS tokens are not refreshed
+	tokenSource := vpcp.sessionTokenSource(context.Background(), provider.ContextCredentials{AuthType: "IMS_TOKEN", Credential: "ims-token"}, "ims-token", "", logger)
+	_, err := tokenSource.Refresh("ims-token")
+	assert.Equal(t, errTokenNotRefreshable, err)
+
+	// Tokens of an unknown account are not refreshed with the API key of the provider
+	tokenSource = vpcp.sessionTokenSource(context.Background(), provider.ContextCredentials{AuthType: provider.IAMAccessToken, Credential: "opaque-token"}, "opaque-token", "", logger)
+	_, err = tokenSource.Refresh("opaque-token")
+	assert.Equal(t, errTokenNotRefreshable, err)
+	assert.Equal(t, 0, ccf.ForIAMAccessTokenCallCount())
+
+	// Concurrent requests rejected with the same token share one refresh
+	tokenSource = vpcp.sessionTokenSource(context.Background(), provider.ContextCredentials{AuthType: provider.IAMAccessToken, Credential: expiredToken}, expiredToken, "account-1", logger)
+	token, err := tokenSource.Token()
+	assert.Nil(t, err)
+	assert.Equal(t, expiredToken, token)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			token, err := tokenSource.Refresh(expiredToken)
+			assert.Nil(t, err)
+			assert.Equal(t, freshToken, token)
+		}()
+	}
+	wg.Wait()
+	assert.Equal(t, 1, ccf.ForIAMAccessTokenCallCount())
+	apiKey, _ := ccf.ForIAMAccessTokenArgsForCall(0)
+	assert.Equal(t, vpcp.Config.VPCConfig.G2APIKey, apiKey)
+
+	// Failed refresh keeps the current token
+	ccf.ForIAMAccessTokenReturns(provider.ContextCredentials{}, errors.New("exchange failed"))
+	_, err = tokenSource.Refresh(freshToken)
+	assert.EqualError(t, err, "exchange failed")
+	token, _ = tokenSource.Token()
+	assert.Equal(t, freshToken, token)
+}
+
+func TestSessionTokenSourceAccount(t *testing.T) {
+	logger, teardown := GetTestLogger(t)
+	defer teardown()
+	userError.MessagesEn = userError.InitMessages()
+
+	vpcp, _ := GetTestProvider(t, logger)
+	ccf := &localFakes.ContextCredentialsFactory{}
+	ccf.ForIAMAccessTokenReturns(provider.ContextCredentials{AuthType: provider.IAMAccessToken, Credential: testAccountToken("account-2")}, nil)
+	vpcp.ContextCF = ccf
+
+	// Refreshed token of another account is refused, the session keeps its token
+	token := testAccountToken("account-1")
+	tokenSource := vpcp.sessionTokenSource(context.Background(), provider.ContextCredentials{AuthType: provider.IAMAccessToken, Credential: token}, token, "account-1", logger)
+	_, err := tokenSource.Refresh(token)
+	assert.Equal(t, userError.AccountMismatch, userError.GetUserErrorCode(err))
+	current, _ := tokenSource.Token()
+	assert.Equal(t, token, current)
+}
+
+func TestSessionTokenSourceRefreshUnlocked(t *testing.T) {
+	logger, teardown := getTestSyncLogger(t)
+	defer teardown()
+
+	exchanging := make(chan struct{})
+	release := make(chan struct{})
+	tokenSource := &sessionTokenSource{token: "expired-token", logger: logger}
+	tokenSource.refresh = func(string) (provider.ContextCredentials, error) {
+		close(exchanging)
+		<-release
+		return provider.ContextCredentials{Credential: "fresh-token"}, nil
+	}
+
+	refreshed := make(chan string)
+	go func() {
+		token, _ := tokenSource.Refresh("expired-token")
+		refreshed <- token
+	}()
+
+	// Other requests of the session get the current token during the exchange
+	<-exchanging
+	token, err := tokenSource.Token()
+	assert.Nil(t, err)
+	assert.Equal(t, "expired-token", token)
+	close(release)
+	assert.Equal(t, "fresh-token", <-refreshed)
+	token, _ = tokenSource.Token()
+	assert.Equal(t, "fresh-token", token)
+}
+
+func TestSessionTokenSourceExchangeCache(t *testing.T) {
+	logger, teardown := GetTestLogger(t)
+	defer teardown()
+
+	tokens := []string{testAccountToken("account-1") + "-rejected", testAccountToken("account-1") + "-fresh"}
+	requests := 0
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, `{"access_token":%q}`, tokens[requests])
+		requests++
+	}))
+	defer s.Close()
+
+	// Exchange service caches its tokens until their expiry, like the one of IKS
+	vpcp, _ := GetTestProvider(t, logger)
+	vpcp.ContextCF = vpcauth.NewAPIKeyContextCredentialsFactory(&vpcconfig.VPCBlockConfig{VPCConfig: &config.VPCProviderConfig{G2TokenExchangeURL: s.URL}}, s.Client())
+	vpcp.SessionPool = &SessionPoolConfig{}
+	vpcp.sessionPool = newSessionPool()
+	ccf, _ := vpcp.ContextCredentialsFactory(nil)
+	credentials, err := ccf.ForIAMAccessToken(vpcp.APIKey(), logger)
+	assert.Nil(t, err)
+	assert.Equal(t, tokens[0], credentials.Credential)
+
+	// Rejected token is dropped from the cache of the exchange service, the session gets a fresh one
+	tokenSource := vpcp.sessionTokenSource(context.Background(), credentials, credentials.Credential, "account-1", logger)
+	token, err := tokenSource.Refresh(tokens[0])
+	assert.Nil(t, err)
+	assert.Equal(t, tokens[1], token)
+	assert.Equal(t, 2, requests)
+}
+
+func TestSessionTokenSourceSessionPool(t *testing.T) {
+	logger, teardown := GetTestLogger(t)
+	defer teardown()
+
+	expiredToken := testAccessToken("expired", time.Now().Add(time.Hour))
+	freshToken := testAccessToken("fresh", time.Now().Add(time.Hour))
+	vpcp, _ := GetTestProvider(t, logger)
+	ccf := &localFakes.ContextCredentialsFactory{}
+	ccf.ForIAMAccessTokenReturnsOnCall(0, provider.ContextCredentials{AuthType: provider.IAMAccessToken, Credential: expiredToken}, nil)
+	ccf.ForIAMAccessTokenReturnsOnCall(1, provider.ContextCredentials{AuthType: provider.IAMAccessToken, Credential: freshToken}, nil)
+	vpcp.ContextCF = ccf
+	vpcp.SessionPool = &SessionPoolConfig{}
+	vpcp.sessionPool = newSessionPool()
+
+	pooled, _ := vpcp.ContextCredentialsFactory(nil)
+	credentials, err := pooled.ForIAMAccessToken(vpcp.Config.VPCConfig.G2APIKey, logger)
+	assert.Nil(t, err)
+
+	// Rejected token is dropped from the pool, the refreshed one replaces it
+	tokenSource := vpcp.sessionTokenSource(context.Background(), credentials, credentials.Credential, "account-1", logger)
+	token, err := tokenSource.Refresh(expiredToken)
+	assert.Nil(t, err)
+	assert.Equal(t, freshToken, token)
+	credentials, err = pooled.ForIAMAccessToken(vpcp.Config.VPCConfig.G2APIKey, logger)
+	assert.Nil(t, err)
+	assert.Equal(t, freshToken, credentials.Credential)
+	assert.Equal(t, 2, ccf.ForIAMAccessTokenCallCount())
+}
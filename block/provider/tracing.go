@@ -0,0 +1,59 @@
+/**
+ * Copyright 2022 IBM Corp.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package provider ...
+package provider
+
+import (
+	"context"
+
+	"github.com/IBM/ibmcloud-volume-interface/lib/provider"
+	"github.com/IBM/ibmcloud-volume-vpc/common/vpctracing"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// trace starts the span of a session operation and returns the copy of the session the operation runs with,
+// whose requests and retry attempts are children of the span, and the function ending the span with the error
+// of the operation. Concurrent operations of the session get their own copies, so their spans never mix.
+func (vpcs *VPCSession) trace(operation string, attrs ...attribute.KeyValue) (*VPCSession, func(err error)) {
+	if vpcs.tracing == nil {
+		return vpcs, func(error) {}
+	}
+	attrs = append([]attribute.KeyValue{vpctracing.OperationKey.String(operation)}, attrs...)
+	ctx, end := vpctracing.Start(vpcs.tracing, "VPCSession."+operation, attrs...)
+	return vpcs.withTracing(ctx), end
+}
+
+// withTracing returns a copy of the session sending its requests with the tracing context
+func (vpcs *VPCSession) withTracing(ctx context.Context) *VPCSession {
+	traced := *vpcs
+	traced.tracing = ctx
+	traced.APIRetry.tracing = ctx
+	if vpcs.Apiclient != nil {
+		traced.Apiclient = vpcs.Apiclient.WithContext(ctx)
+		if vpcs.iksVolumeAttach {
+			traced.APIClientVolAttachMgr = traced.Apiclient.IKSVolumeAttachService()
+		} else {
+			traced.APIClientVolAttachMgr = traced.Apiclient.VolumeAttachService()
+		}
+	}
+	return &traced
+}
+
+// attachmentAttributes are the span attributes of an attachment request
+func attachmentAttributes(request provider.VolumeAttachmentRequest) []attribute.KeyValue {
+	return []attribute.KeyValue{vpctracing.VolumeIDKey.String(request.VolumeID), vpctracing.InstanceIDKey.String(request.InstanceID)}
+}
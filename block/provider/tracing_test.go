@@ -0,0 +1,128 @@
+/**
+ * Copyright 2022 IBM Corp.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package provider ...
+package provider
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	"github.com/IBM/ibmcloud-volume-vpc/common/vpcclient/models"
+	"github.com/IBM/ibmcloud-volume-vpc/common/vpcclient/riaas"
+	"github.com/IBM/ibmcloud-volume-vpc/common/vpcclient/riaas/fakes"
+	volumeServiceFakes "github.com/IBM/ibmcloud-volume-vpc/common/vpcclient/vpcvolume/fakes"
+	"github.com/IBM/ibmcloud-volume-vpc/common/vpctracing"
+	tracingtest "github.com/IBM/ibmcloud-volume-vpc/common/vpctracing/test"
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap"
+)
+
+func TestTraceGetVolume(t *testing.T) {
+	logger, teardown := GetTestLogger(t)
+	defer teardown()
+
+	exporter := tracingtest.UseInMemoryExporter()
+	defer vpctracing.SetTracerProvider(nil)
+
+	vpcs, uc, _, err := GetTestOpenSession(t, logger)
+	assert.Nil(t, err)
+	vpcs.tracing = context.Background()
+	uc.WithContextReturns(uc)
+
+	volumeService := &volumeServiceFakes.VolumeService{}
+	uc.VolumeServiceReturns(volumeService)
+	volumeService.GetVolumeReturnsOnCall(0, nil, &models.Error{Trace: "trace-1", Errors: []models.ErrorItem{{Code: "internal_error", Message: "failed"}}})
+	volumeService.GetVolumeReturnsOnCall(1, &models.Volume{ID: "16f293bf-test-4bff-816f-e199c0c65db5", Zone: &models.Zone{Name: "test-zone"}}, nil)
+
+	volume, err := vpcs.GetVolume("16f293bf-test-4bff-816f-e199c0c65db5")
+	assert.Nil(t, err)
+	assert.NotNil(t, volume)
+
+	spans := exporter.GetSpans()
+	if assert.Equal(t, 3, len(spans)) {
+		failed, succeeded, operation := spans[0], spans[1], spans[2]
+		assert.Equal(t, "VPCSession.GetVolume", operation.Name)
+		assert.Contains(t, operation.Attributes, vpctracing.VolumeIDKey.String("16f293bf-test-4bff-816f-e199c0c65db5"))
+		assert.Equal(t, codes.Unset, operation.Status.Code)
+
+		// Each retry attempt is a child of the operation
+		for i, attempt := range []int{1, 2} {
+			assert.Equal(t, "attempt", spans[i].Name)
+			assert.Equal(t, operation.SpanContext.SpanID(), spans[i].Parent.SpanID())
+			assert.Contains(t, spans[i].Attributes, vpctracing.AttemptKey.Int(attempt))
+		}
+		assert.Equal(t, codes.Error, failed.Status.Code)
+		assert.Contains(t, failed.Attributes, vpctracing.TraceCodeKey.String("trace-1"))
+		assert.Equal(t, codes.Unset, succeeded.Status.Code)
+	}
+}
+
+func TestTraceConcurrentOperations(t *testing.T) {
+	logger, teardown := getTestSyncLogger(t)
+	defer teardown()
+
+	exporter := tracingtest.UseInMemoryExporter()
+	defer vpctracing.SetTracerProvider(nil)
+
+	vpcs, uc, _, err := GetTestOpenSession(t, logger)
+	assert.Nil(t, err)
+	vpcs.tracing = context.Background()
+
+	// Each operation sends its requests with the context of its own span
+	var mu sync.Mutex
+	clients := map[*fakes.RegionalAPI]context.Context{}
+	uc.WithContextStub = func(ctx context.Context) riaas.RegionalAPI {
+		volumeService := &volumeServiceFakes.VolumeService{}
+		volumeService.GetVolumeStub = func(volumeID string, ctxLogger *zap.Logger) (*models.Volume, error) {
+			return &models.Volume{ID: volumeID, Zone: &models.Zone{Name: "test-zone"}}, nil
+		}
+		client := &fakes.RegionalAPI{}
+		client.VolumeServiceReturns(volumeService)
+		mu.Lock()
+		clients[client] = ctx
+		mu.Unlock()
+		return client
+	}
+
+	var wg sync.WaitGroup
+	for _, volumeID := range []string{"16f293bf-test-4bff-816f-e199c0c65db1", "16f293bf-test-4bff-816f-e199c0c65db2"} {
+		wg.Add(1)
+		go func(volumeID string) {
+			defer wg.Done()
+			_, err := vpcs.GetVolume(volumeID)
+			assert.Nil(t, err)
+		}(volumeID)
+	}
+	wg.Wait()
+
+	operations := map[string]bool{}
+	for _, span := range exporter.GetSpans() {
+		if span.Name == "VPCSession.GetVolume" {
+			operations[span.SpanContext.SpanID().String()] = true
+		}
+	}
+	assert.Equal(t, 2, len(operations))
+	assert.Equal(t, 2, len(clients))
+	for _, ctx := range clients {
+		spanID := trace.SpanFromContext(ctx).SpanContext().SpanID().String()
+		assert.True(t, operations[spanID])
+		delete(operations, spanID)
+	}
+}
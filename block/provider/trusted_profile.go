@@ -0,0 +1,65 @@
+/**
+ * Copyright 2022 IBM Corp.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package provider ...
+package provider
+
+import (
+	"context"
+
+	"github.com/IBM/ibmcloud-volume-interface/lib/provider"
+	vpcauth "github.com/IBM/ibmcloud-volume-vpc/common/auth"
+	vpciam "github.com/IBM/ibmcloud-volume-vpc/common/iam"
+	"go.uber.org/zap"
+)
+
+// isTrustedProfileAuthType tells if the credentials of the auth type are exchanged for the access token of a
+// trusted profile
+func isTrustedProfileAuthType(authType provider.AuthType) bool {
+	return authType == vpciam.TrustedProfile || authType == vpciam.InstanceIdentityToken
+}
+
+// trustedProfileCredentials exchanges trusted profile credentials for IAM access token credentials. The credential
+// is the trusted profile ID, the one of the config is used if empty.
+func (vpcp *VPCBlockProvider) trustedProfileCredentials(ctx context.Context, contextCredentials provider.ContextCredentials, ctxLogger *zap.Logger) (provider.ContextCredentials, error) {
+	trustedProfileConfig := &vpciam.TrustedProfileConfiguration{}
+	if vpcp.Config.TrustedProfile != nil {
+		trustedProfileConfig = vpcauth.NewTrustedProfileConfiguration(vpcp.Config)
+	} else if vpcp.Config.VPCConfig != nil {
+		trustedProfileConfig.IamURL = vpcp.Config.VPCConfig.G2TokenExchangeURL
+	}
+	tokenExchangeService := vpciam.NewTokenExchangeTrustedProfileService(trustedProfileConfig, vpcp.httpClient)
+
+	accessToken, err := tokenExchangeService.ExchangeForAccessToken(ctx, contextCredentials.AuthType, contextCredentials.Credential, ctxLogger)
+	if err != nil {
+		ctxLogger.Error("Failed to exchange the trusted profile credentials", zap.Reflect("AuthType", contextCredentials.AuthType), zap.Error(err))
+		return provider.ContextCredentials{}, err
+	}
+
+	// Account of the session is the one of the profile, unless the credentials name it
+	iamAccountID := contextCredentials.IAMAccountID
+	if iamAccountID == "" {
+		iamAccountID, err = tokenExchangeService.GetIAMAccountIDFromAccessToken(*accessToken, ctxLogger)
+		if err != nil {
+			ctxLogger.Warn("Failed to read the account of the trusted profile access token", zap.Error(err))
+		}
+	}
+	return provider.ContextCredentials{
+		AuthType:     provider.IAMAccessToken,
+		IAMAccountID: iamAccountID,
+		Credential:   accessToken.Token,
+	}, nil
+}
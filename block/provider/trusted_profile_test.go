@@ -0,0 +1,98 @@
+/**
+ * Copyright 2022 IBM Corp.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package provider ...
+package provider
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/IBM/ibmcloud-volume-interface/lib/provider"
+	vpcconfig "github.com/IBM/ibmcloud-volume-vpc/block/vpcconfig"
+	vpciam "github.com/IBM/ibmcloud-volume-vpc/common/iam"
+	"github.com/IBM/ibmcloud-volume-vpc/common/vpcclient/riaas/fakes"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOpenSessionTrustedProfile(t *testing.T) {
+	logger, teardown := GetTestLogger(t)
+	defer teardown()
+
+	var exchanges int32
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = r.ParseForm()
+		if r.Form.Get("cr_token") != "cr-token" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		n := atomic.AddInt32(&exchanges, 1)
+		w.Header().Set("Content-Type", "application/json")
+		payload := fmt.Sprintf(`{"iam_id":"%s-%d","exp":%d,"account":{"bss":"account-1"}}`, r.Form.Get("profile_id"), n, time.Now().Add(time.Hour).Unix())
+		fmt.Fprintf(w, `{"access_token":"eyJhbGciOiJSUzI1NiJ9.%s.c2lnbmF0dXJl"}`, base64.RawURLEncoding.EncodeToString([]byte(payload)))
+	}))
+	defer s.Close()
+	tokenFile := filepath.Join(t.TempDir(), "token")
+	require.NoError(t, ioutil.WriteFile(tokenFile, []byte("cr-token"), 0600))
+
+	vpcp, _ := GetTestProvider(t, logger)
+	cp := &fakes.RegionalAPIClientProvider{}
+	uc := &fakes.RegionalAPI{}
+	cp.NewReturns(uc, nil)
+	vpcp.ClientProvider = cp
+	vpcp.Config.VPCConfig.G2TokenExchangeURL = s.URL
+	vpcp.Config.TrustedProfile = &vpcconfig.TrustedProfileConfig{ProfileID: "profile-1", ComputeResourceTokenFile: tokenFile}
+
+	// Profile of the config is used when the credentials name none
+	sessn, err := vpcp.OpenSession(context.Background(), provider.ContextCredentials{AuthType: vpciam.TrustedProfile}, logger)
+	require.NoError(t, err)
+	vpcSession := sessn.(*VPCSession)
+	assert.Equal(t, provider.IAMAccessToken, vpcSession.ContextCredentials.AuthType)
+	claims, err := vpciam.ParseAccessTokenClaims(vpcSession.ContextCredentials.Credential)
+	require.NoError(t, err)
+	assert.Equal(t, "profile-1-1", claims.IAMID)
+	// Account of the session is the one of the profile
+	assert.Equal(t, "account-1", vpcSession.VPCAccountID)
+
+	// Rejected token is refreshed with a new exchange of the profile
+	tokenSource := uc.LoginWithTokenSourceArgsForCall(0)
+	token, _ := tokenSource.Token()
+	assert.Equal(t, vpcSession.ContextCredentials.Credential, token)
+	refreshed, err := tokenSource.Refresh(token)
+	assert.NoError(t, err)
+	assert.NotEqual(t, token, refreshed)
+	assert.Equal(t, int32(2), atomic.LoadInt32(&exchanges))
+
+	// Credentials name the profile
+	sessn, err = vpcp.OpenSession(context.Background(), provider.ContextCredentials{AuthType: vpciam.TrustedProfile, Credential: "profile-2"}, logger)
+	require.NoError(t, err)
+	claims, _ = vpciam.ParseAccessTokenClaims(sessn.(*VPCSession).ContextCredentials.Credential)
+	assert.Equal(t, "profile-2-3", claims.IAMID)
+
+	// Failed exchange fails the session
+	require.NoError(t, ioutil.WriteFile(tokenFile, []byte("expired-cr-token"), 0600))
+	_, err = vpcp.OpenSession(context.Background(), provider.ContextCredentials{AuthType: vpciam.TrustedProfile}, logger)
+	assert.Error(t, err)
+}
@@ -18,6 +18,9 @@
 package provider
 
 import (
+	"context"
+	"errors"
+	"net/http"
 	"strconv"
 	"strings"
 	"time"
@@ -25,6 +28,8 @@ import (
 	"github.com/IBM/ibmcloud-volume-interface/lib/provider"
 	userError "github.com/IBM/ibmcloud-volume-vpc/common/messages"
 	"github.com/IBM/ibmcloud-volume-vpc/common/vpcclient/models"
+	"github.com/IBM/ibmcloud-volume-vpc/common/vpcmetrics"
+	"github.com/IBM/ibmcloud-volume-vpc/common/vpctracing"
 	"go.uber.org/zap"
 )
 
@@ -54,6 +59,9 @@ const (
 
 var volumeIDPartsCount = 5
 
+// maxRetryAfter caps the back off of the throttled requests
+const maxRetryAfter = 5 * time.Minute
+
 var skipErrorCodes = map[string]bool{
 	"validation_invalid_name":              true,
 	"volume_capacity_max":                  true,
@@ -85,23 +93,29 @@ var skipErrorCodes = map[string]bool{
 
 // retry ...
 func retry(logger *zap.Logger, retryfunc func() error) error {
+	return tracedRetry(nil, logger, retryfunc)
+}
+
+// tracedRetry is retry with a span per attempt, children of the span in the tracing context
+func tracedRetry(tracing context.Context, logger *zap.Logger, retryfunc func() error) error {
 	var err error
 	retryGap := 10
 	for i := 0; i < maxRetryAttempt; i++ {
 		if i > 0 {
-			time.Sleep(time.Duration(retryGap) * time.Second)
+			time.Sleep(retryDelay(err, retryGap))
 		}
+		end := startAttempt(tracing, i+1)
 		err = retryfunc()
+		end(err)
 		if err != nil {
 			logger.Info("err object is not nil", zap.Reflect("ERR", err))
 			//Skip retry for the below type of Errors
-			modelError, ok := err.(*models.Error)
-			if !ok {
-				continue
-			}
-			if skipRetry(modelError) {
+			if skipRetryForObviousErrors(err, false) {
 				break
 			}
+			if _, ok := err.(*models.Error); !ok {
+				continue
+			}
 			if i >= 1 {
 				retryGap = 2 * retryGap
 				if retryGap > maxRetryGap {
@@ -118,8 +132,20 @@ func retry(logger *zap.Logger, retryfunc func() error) error {
 	return err
 }
 
+// startAttempt starts the span of an attempt and counts the retries of the session operation in progress
+func startAttempt(tracing context.Context, attempt int) func(err error) {
+	if attempt > 1 {
+		vpcmetrics.Default().RetryAttempt(vpctracing.Operation(tracing))
+	}
+	_, end := vpctracing.Start(tracing, "attempt", vpctracing.AttemptKey.Int(attempt))
+	return end
+}
+
 // skipRetry skip retry as per listed error codes
 func skipRetry(err *models.Error) bool {
+	if skip, known := skipRetryForHTTPStatus(err); known {
+		return skip
+	}
 	for _, errorItem := range err.Errors {
 		skipStatus, ok := skipErrorCodes[string(errorItem.Code)]
 		if ok {
@@ -129,8 +155,21 @@ func skipRetry(err *models.Error) bool {
 	return false
 }
 
+// GetBackendUserError returns the user error of the code for a failed backend call. Calls fail fast
+// while the circuit of the endpoint is open, the error is EndpointUnavailable then.
+func GetBackendUserError(code string, err error, args ...interface{}) error {
+	var unavailable *models.EndpointUnavailableError
+	if errors.As(err, &unavailable) {
+		return userError.GetUserError(userError.EndpointUnavailable, err, unavailable.BaseURL, unavailable.RetryAt.Format(time.RFC3339))
+	}
+	return userError.GetUserError(code, err, args...)
+}
+
 // SkipRetryForIKS skip retry as per listed error codes
 func SkipRetryForIKS(err error) bool {
+	if skip, known := skipRetryForHTTPStatus(err); known {
+		return skip
+	}
 	iksError, iksok := err.(*models.IksError)
 	if iksok {
 		skipStatus, ok := skipErrorCodes[iksError.Code]
@@ -153,9 +192,88 @@ func skipRetryForObviousErrors(err error, isIKS bool) bool {
 	if ok {
 		return skipRetry(riaasError)
 	}
+	skip, _ := skipRetryForHTTPStatus(err)
+	return skip
+}
+
+// skipRetryForHTTPStatus classifies the error on the HTTP status of its response. Bad request, unauthorized,
+// forbidden and not found are never retried, throttled requests and server errors are always retried.
+// known is false for the other statuses and for the errors without response e.g network errors. Calls are not
+// retried either while the circuit of the endpoint is open, they fail fast.
+func skipRetryForHTTPStatus(err error) (skip bool, known bool) {
+	if models.IsEndpointUnavailable(err) {
+		return true, true
+	}
+	metadata := models.GetResponseMetadata(err)
+	if metadata == nil {
+		return false, false
+	}
+	switch metadata.StatusCode {
+	case http.StatusBadRequest, http.StatusUnauthorized, http.StatusForbidden, http.StatusNotFound:
+		return true, true
+	case http.StatusTooManyRequests:
+		return false, true
+	}
+	if metadata.StatusCode >= http.StatusInternalServerError {
+		return false, true
+	}
+	return false, false
+}
+
+// iksNotFoundErrorCodes are the IKS ms error codes of a resource which does not exist
+var iksNotFoundErrorCodes = map[string]bool{
+	"ST0005": true, // worker node could not be found
+	"ST0008": true, // resources not found
+	"P4106":  true, // instance not found
+	"P4109":  true, // volume attachment not found
+}
+
+// isNotFoundError is true when the error confirms that the resource does not exist. An open circuit, a rejected
+// token or any other status than not found does not tell anything about the resource.
+func isNotFoundError(err error) bool {
+	if err == nil || models.IsEndpointUnavailable(err) {
+		return false
+	}
+	if metadata := models.GetResponseMetadata(err); metadata != nil {
+		return metadata.StatusCode == http.StatusNotFound
+	}
+	var riaasError *models.Error
+	if errors.As(err, &riaasError) {
+		for _, errorItem := range riaasError.Errors {
+			if strings.HasSuffix(string(errorItem.Code), string(models.ErrorCodeNotFound)) {
+				return true
+			}
+		}
+		return false
+	}
+	var iksError *models.IksError
+	if errors.As(err, &iksError) {
+		return iksNotFoundErrorCodes[iksError.Code]
+	}
 	return false
 }
 
+// retryDelay returns the gap before the next attempt. Throttled (429) and unavailable (503) responses back off
+// for Retry-After, or twice the gap if the server did not send it.
+func retryDelay(err error, retryGap int) time.Duration {
+	delay := time.Duration(retryGap) * time.Second
+	metadata := models.GetResponseMetadata(err)
+	if metadata == nil || (metadata.StatusCode != http.StatusTooManyRequests && metadata.StatusCode != http.StatusServiceUnavailable) {
+		return delay
+	}
+	backoff := metadata.RetryAfter
+	if backoff == 0 {
+		backoff = 2 * delay
+	}
+	if backoff > maxRetryAfter {
+		backoff = maxRetryAfter
+	}
+	if backoff > delay {
+		return backoff
+	}
+	return delay
+}
+
 // FlexyRetry ...
 type FlexyRetry struct {
 	maxRetryAttempt       int
@@ -163,6 +281,7 @@ type FlexyRetry struct {
 	minVPCRetryGap        int
 	minVPCRetryGapAttempt int
 	maxVPCRetryAttempt    int
+	tracing               context.Context // Spans of the attempts are children of its span, nil means not traced
 }
 
 // NewFlexyRetryDefault ...
@@ -192,10 +311,12 @@ func (fRetry *FlexyRetry) FlexyRetry(logger *zap.Logger, funcToRetry func() (err
 	retryGap := 10
 	for i := 0; i < fRetry.maxRetryAttempt; i++ {
 		if i > 0 {
-			time.Sleep(time.Duration(retryGap) * time.Second)
+			time.Sleep(retryDelay(err, retryGap))
 		}
 		// Call function which required retry, retry is decided by function itself
+		end := startAttempt(fRetry.tracing, i+1)
 		err, stopRetry = funcToRetry()
+		end(err)
 		if stopRetry {
 			break
 		}
@@ -224,10 +345,12 @@ func (fRetry *FlexyRetry) FlexyRetryWithConstGap(logger *zap.Logger, funcToRetry
 	totalAttempt := fRetry.maxRetryAttempt * 4 // 40 time as per default values i.e 400 seconds
 	for i := 0; i < totalAttempt; i++ {
 		if i > 0 {
-			time.Sleep(time.Duration(ConstantRetryGap) * time.Second)
+			time.Sleep(retryDelay(err, ConstantRetryGap))
 		}
 		// Call function which required retry, retry is decided by function itself
+		end := startAttempt(fRetry.tracing, i+1)
 		err, stopRetry = funcToRetry()
+		end(err)
 		if stopRetry {
 			break
 		}
@@ -264,11 +387,13 @@ func (fRetry *FlexyRetry) FlexyRetryWithCustomGap(logger *zap.Logger, funcToRetr
 
 	for i := 0; i <= totalAttempt; i++ {
 		if i > 0 {
-			time.Sleep(time.Duration(retryGap) * time.Second)
+			time.Sleep(retryDelay(err, retryGap))
 		}
 
 		// Call function which required retry, retry is decided by function itself
+		end := startAttempt(fRetry.tracing, i+1)
 		err, stopRetry = funcToRetry()
+		end(err)
 		if stopRetry {
 			break
 		}
@@ -18,12 +18,18 @@
 package provider
 
 import (
+	"context"
 	"errors"
+	"net/http"
 	"os"
+	"strings"
 	"testing"
 	"time"
 
+	userError "github.com/IBM/ibmcloud-volume-vpc/common/messages"
 	"github.com/IBM/ibmcloud-volume-vpc/common/vpcclient/models"
+	"github.com/IBM/ibmcloud-volume-vpc/common/vpcmetrics"
+	"github.com/prometheus/client_golang/prometheus/testutil"
 	"github.com/stretchr/testify/assert"
 	"go.uber.org/zap"
 	"go.uber.org/zap/zapcore"
@@ -180,6 +186,75 @@ func TestSkipRetry(t *testing.T) {
 	assert.Equal(t, skip, false)
 }
 
+func TestSkipRetryForHTTPStatus(t *testing.T) {
+	testCases := []struct {
+		name       string
+		err        error
+		isIKS      bool
+		expectSkip bool
+	}{
+		{name: "bad request", err: &models.Error{ResponseMetadata: models.ResponseMetadata{StatusCode: http.StatusBadRequest}}, expectSkip: true},
+		{name: "unauthorized", err: &models.Error{ResponseMetadata: models.ResponseMetadata{StatusCode: http.StatusUnauthorized}}, expectSkip: true},
+		{name: "forbidden", err: &models.IksError{ResponseMetadata: models.ResponseMetadata{StatusCode: http.StatusForbidden}}, isIKS: true, expectSkip: true},
+		{name: "not found without known body", err: &models.HTTPError{ResponseMetadata: models.ResponseMetadata{StatusCode: http.StatusNotFound}, Err: errors.New("invalid body")}, expectSkip: true},
+		{name: "throttled", err: &models.Error{Errors: []models.ErrorItem{{Code: "not_found"}}, ResponseMetadata: models.ResponseMetadata{StatusCode: http.StatusTooManyRequests}}, expectSkip: false},
+		{name: "server error", err: &models.IksError{Code: "ST0008", ResponseMetadata: models.ResponseMetadata{StatusCode: http.StatusBadGateway}}, isIKS: true, expectSkip: false},
+		{name: "other status uses error code", err: &models.Error{Errors: []models.ErrorItem{{Code: "volume_id_invalid"}}, ResponseMetadata: models.ResponseMetadata{StatusCode: http.StatusConflict}}, expectSkip: true},
+		{name: "network error", err: errors.New("connection reset"), expectSkip: false},
+		{name: "endpoint unavailable", err: &models.EndpointUnavailableError{BaseURL: "https://us-south.iaas.cloud.ibm.com"}, expectSkip: true},
+		{name: "IKS endpoint unavailable", err: &models.EndpointUnavailableError{BaseURL: "https://storage-api"}, isIKS: true, expectSkip: true},
+	}
+
+	for _, testcase := range testCases {
+		t.Run(testcase.name, func(t *testing.T) {
+			assert.Equal(t, testcase.expectSkip, skipRetryForObviousErrors(testcase.err, testcase.isIKS))
+		})
+	}
+}
+
+func TestIsNotFoundError(t *testing.T) {
+	testCases := []struct {
+		name     string
+		err      error
+		notFound bool
+	}{
+		{name: "not found status", err: &models.HTTPError{ResponseMetadata: models.ResponseMetadata{StatusCode: http.StatusNotFound}, Err: errors.New("invalid body")}, notFound: true},
+		{name: "not found code", err: &models.Error{Errors: []models.ErrorItem{{Code: "volume_attachment_not_found"}}}, notFound: true},
+		{name: "IKS instance not found", err: &models.IksError{Code: "P4106"}, notFound: true},
+		{name: "unauthorized with not found code", err: &models.Error{Errors: []models.ErrorItem{{Code: "not_found"}}, ResponseMetadata: models.ResponseMetadata{StatusCode: http.StatusUnauthorized}}, notFound: false},
+		{name: "forbidden", err: &models.IksError{Code: "ST0008", ResponseMetadata: models.ResponseMetadata{StatusCode: http.StatusForbidden}}, notFound: false},
+		{name: "endpoint unavailable", err: &models.EndpointUnavailableError{BaseURL: "https://us-south.iaas.cloud.ibm.com"}, notFound: false},
+		{name: "network error", err: errors.New("connection reset"), notFound: false},
+	}
+
+	for _, testcase := range testCases {
+		t.Run(testcase.name, func(t *testing.T) {
+			assert.Equal(t, testcase.notFound, isNotFoundError(testcase.err))
+		})
+	}
+}
+
+func TestRetryDelay(t *testing.T) {
+	assert.Equal(t, 10*time.Second, retryDelay(errors.New("connection reset"), 10))
+	assert.Equal(t, 10*time.Second, retryDelay(&models.Error{ResponseMetadata: models.ResponseMetadata{StatusCode: http.StatusInternalServerError}}, 10))
+	assert.Equal(t, 20*time.Second, retryDelay(&models.Error{ResponseMetadata: models.ResponseMetadata{StatusCode: http.StatusTooManyRequests}}, 10))
+	assert.Equal(t, 30*time.Second, retryDelay(&models.Error{ResponseMetadata: models.ResponseMetadata{StatusCode: http.StatusServiceUnavailable, RetryAfter: 30 * time.Second}}, 10))
+	assert.Equal(t, 10*time.Second, retryDelay(&models.Error{ResponseMetadata: models.ResponseMetadata{StatusCode: http.StatusTooManyRequests, RetryAfter: time.Second}}, 10))
+	assert.Equal(t, maxRetryAfter, retryDelay(&models.Error{ResponseMetadata: models.ResponseMetadata{StatusCode: http.StatusTooManyRequests, RetryAfter: time.Hour}}, 10))
+}
+
+func TestRetryNotFound(t *testing.T) {
+	maxRetryAttempt = 3
+	logger, _ := GetTestContextLogger()
+	attempts := 0
+	err := retry(logger, func() error {
+		attempts++
+		return &models.HTTPError{ResponseMetadata: models.ResponseMetadata{StatusCode: http.StatusNotFound}, Err: errors.New("not found")}
+	})
+	assert.NotNil(t, err)
+	assert.Equal(t, 1, attempts)
+}
+
 func TestRetryWithError(t *testing.T) {
 	maxRetryAttempt = 2
 	maxRetryGap = 20
@@ -194,6 +269,29 @@ func TestRetryWithError(t *testing.T) {
 	})
 }
 
+func TestStartAttempt(t *testing.T) {
+	c := vpcmetrics.NewCollector()
+	vpcmetrics.SetDefault(c)
+	defer vpcmetrics.SetDefault(nil)
+
+	vpcs := &VPCSession{tracing: context.Background()}
+	traced, end := vpcs.trace("CreateVolume")
+	for attempt := 1; attempt <= 3; attempt++ {
+		startAttempt(traced.tracing, attempt)(nil)
+	}
+	end(nil)
+	startAttempt(nil, 2)(nil)
+
+	// Only the attempts after the first one are counted
+	expected := `
+# HELP ibmcloud_volume_vpc_retry_attempts_total Attempts made after a failed one, by session operation.
+# TYPE ibmcloud_volume_vpc_retry_attempts_total counter
+ibmcloud_volume_vpc_retry_attempts_total{operation="CreateVolume"} 2
+ibmcloud_volume_vpc_retry_attempts_total{operation="unknown"} 1
+`
+	assert.Nil(t, testutil.CollectAndCompare(c, strings.NewReader(expected), "ibmcloud_volume_vpc_retry_attempts_total"))
+}
+
 func TestFromProviderToLibVolume(t *testing.T) {
 	// Setup new style zap logger
 	logger, _ := GetTestContextLogger()
@@ -249,3 +347,19 @@ func TestIsValidVolumeIDFormat(t *testing.T) {
 	returnValue = IsValidVolumeIDFormat("34c3ad36-34d9-4d3a-8463-5a176c75801c")
 	assert.Equal(t, returnValue, true)
 }
+
+func TestGetBackendUserError(t *testing.T) {
+	userError.MessagesEn = userError.InitMessages()
+
+	err := GetBackendUserError("StorageFindFailedWithVolumeId", errors.New("volume not found"), "volume-1")
+	assert.Equal(t, "StorageFindFailedWithVolumeId", userError.GetUserErrorCode(err))
+
+	unavailable := &models.EndpointUnavailableError{BaseURL: "https://us-south.iaas.cloud.ibm.com", RetryAt: time.Now().Add(time.Minute)}
+	err = GetBackendUserError("StorageFindFailedWithVolumeId", unavailable, "volume-1")
+	assert.Equal(t, userError.EndpointUnavailable, userError.GetUserErrorCode(err))
+	assert.Contains(t, err.Error(), "https://us-south.iaas.cloud.ibm.com")
+
+	// The messages package does not map the backend errors
+	err = userError.GetUserError("StorageFindFailedWithVolumeId", unavailable, "volume-1")
+	assert.Equal(t, "StorageFindFailedWithVolumeId", userError.GetUserErrorCode(err))
+}
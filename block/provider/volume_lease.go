@@ -0,0 +1,438 @@
+/**
+ * Copyright 2022 IBM Corp.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package provider ...
+package provider
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	userError "github.com/IBM/ibmcloud-volume-vpc/common/messages"
+	"go.uber.org/zap"
+)
+
+const (
+	// leaseTagPrefix is the prefix of the volume tag holding a lease, i.e lock:<holder>:<nonce>:<unix-expiry>
+	leaseTagPrefix = "lock:"
+
+	// leaseNonceLength is the length of the hex nonce identifying one acquisition of the lease
+	leaseNonceLength = 16
+
+	// DefaultVolumeLeaseTTL is used when VolumeLeaseConfig.TTL is not set
+	DefaultVolumeLeaseTTL = 2 * time.Minute
+
+	// DefaultVolumeLeaseMaxHold is used when VolumeLeaseConfig.MaxHold is not set
+	DefaultVolumeLeaseMaxHold = 15 * time.Minute
+)
+
+// leaseNow is the clock used for lease expiry, replaced in unit tests
+var leaseNow = time.Now
+
+// VolumeLeaseConfig enables the volume tag based lease used to coordinate several
+// controller replicas running against the same account
+type VolumeLeaseConfig struct {
+	// Holder uniquely identifies this replica, e.g the pod name. Must not be empty
+	Holder string
+
+	// TTL is the life time of the lease. Defaults to DefaultVolumeLeaseTTL
+	TTL time.Duration
+
+	// RenewInterval is the interval to renew the lease while it is held. Defaults to TTL/3
+	RenewInterval time.Duration
+
+	// MaxHold is the longest time the lease is renewed, so that a lease never released
+	// e.g attach without a wait, expires by itself. Defaults to DefaultVolumeLeaseMaxHold
+	MaxHold time.Duration
+}
+
+func (lc *VolumeLeaseConfig) ttl() time.Duration {
+	if lc.TTL > 0 {
+		return lc.TTL
+	}
+	return DefaultVolumeLeaseTTL
+}
+
+func (lc *VolumeLeaseConfig) renewInterval() time.Duration {
+	if lc.RenewInterval > 0 && lc.RenewInterval < lc.ttl() {
+		return lc.RenewInterval
+	}
+	return lc.ttl() / 3
+}
+
+func (lc *VolumeLeaseConfig) maxHold() time.Duration {
+	if lc.MaxHold > 0 {
+		return lc.MaxHold
+	}
+	return DefaultVolumeLeaseMaxHold
+}
+
+// leaseTag is the parsed form of a lease tag
+type leaseTag struct {
+	name   string
+	holder string
+	nonce  string // Empty for the tags put before the nonce was introduced
+	expiry int64
+}
+
+func (lt leaseTag) expired(now time.Time) bool {
+	return lt.expiry <= now.Unix()
+}
+
+// newLeaseTag builds the tag for the given holder, acquisition nonce and expiry
+func newLeaseTag(holder string, nonce string, expiry time.Time) leaseTag {
+	return leaseTag{
+		name:   fmt.Sprintf("%s%s:%s:%d", leaseTagPrefix, holder, nonce, expiry.Unix()),
+		holder: holder,
+		nonce:  nonce,
+		expiry: expiry.Unix(),
+	}
+}
+
+// newLeaseNonce returns a random nonce identifying one acquisition of the lease
+func newLeaseNonce() (string, error) {
+	b := make([]byte, leaseNonceLength/2)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// parseLeaseTag parses lock:<holder>:<nonce>:<unix-expiry>, as well as lock:<holder>:<unix-expiry>
+// put by older versions. ok is false for any other tag.
+func parseLeaseTag(tag string) (lt leaseTag, ok bool) {
+	if !strings.HasPrefix(tag, leaseTagPrefix) {
+		return
+	}
+	rest := strings.TrimPrefix(tag, leaseTagPrefix)
+	idx := strings.LastIndex(rest, ":")
+	if idx <= 0 {
+		return
+	}
+	expiry, err := strconv.ParseInt(rest[idx+1:], 10, 64)
+	if err != nil {
+		return
+	}
+	holder, nonce := rest[:idx], ""
+	if idx = strings.LastIndex(holder, ":"); idx > 0 && isLeaseNonce(holder[idx+1:]) {
+		holder, nonce = holder[:idx], holder[idx+1:]
+	}
+	return leaseTag{name: tag, holder: holder, nonce: nonce, expiry: expiry}, true
+}
+
+// isLeaseNonce tells if s has the form of a nonce built by newLeaseNonce
+func isLeaseNonce(s string) bool {
+	if len(s) != leaseNonceLength {
+		return false
+	}
+	_, err := hex.DecodeString(s)
+	return err == nil
+}
+
+// VolumeLease is a lease held on a volume, obtained by AcquireVolumeLease
+type VolumeLease struct {
+	vpcs     *VPCSession
+	config   VolumeLeaseConfig
+	volumeID string
+
+	mu       sync.Mutex
+	nonce    string
+	tag      leaseTag
+	released bool
+	held     *heldVolumeLeases // Session holding the lease till the end of the wait, nil if not held
+
+	stop     chan struct{}
+	done     chan struct{}
+	stopOnce sync.Once // Release may be called concurrently e.g by a wait and by the batch holding the lease
+}
+
+// AcquireVolumeLease puts an owner tag with an expiry on the volume before mutating it.
+// Expired leases are taken over. The lease is renewed in the background until Release is
+// called or MaxHold is reached. An error with code VolumeLeaseHeld is returned if a live
+// lease is found on the volume, before or right after the tag is put, including one of
+// the same holder as each acquisition is identified by its own nonce.
+func (vpcs *VPCSession) AcquireVolumeLease(volumeID string, config VolumeLeaseConfig) (*VolumeLease, error) {
+	vpcs.Logger.Debug("Entry of AcquireVolumeLease method...", zap.String("volumeID", volumeID), zap.String("holder", config.Holder))
+	defer vpcs.Logger.Debug("Exit from AcquireVolumeLease method...")
+
+	if config.Holder == "" {
+		return nil, userError.GetUserError(string(userError.VolumeLeaseFailed), errors.New("lease holder is not configured"), volumeID)
+	}
+
+	tags, err := vpcs.listLeaseTags(volumeID)
+	if err != nil {
+		return nil, GetBackendUserError(string(userError.VolumeLeaseFailed), err, volumeID)
+	}
+
+	now := leaseNow()
+	for _, lt := range tags {
+		if lt.expired(now) {
+			// Take over the expired lease, failure just leaves a stale tag behind
+			vpcs.Logger.Info("Removing expired volume lease", zap.String("volumeID", volumeID), zap.String("tag", lt.name))
+			_ = vpcs.Apiclient.VolumeService().DeleteVolumeTag(volumeID, lt.name, vpcs.Logger)
+			continue
+		}
+		return nil, userError.GetUserError(string(userError.VolumeLeaseHeld), nil, volumeID, lt.holder, time.Unix(lt.expiry, 0).UTC().Format(time.RFC3339))
+	}
+
+	nonce, err := newLeaseNonce()
+	if err != nil {
+		return nil, GetBackendUserError(string(userError.VolumeLeaseFailed), err, volumeID)
+	}
+	lease := &VolumeLease{
+		vpcs:     vpcs,
+		config:   config,
+		volumeID: volumeID,
+		nonce:    nonce,
+	}
+	if err = lease.put(); err != nil {
+		return nil, err
+	}
+
+	// Check that no other acquisition has put its tag concurrently. The expiries come from the clocks of
+	// the replicas, in whole seconds, so they can not tell which tag was put first: the acquisition backs
+	// off as soon as another live tag is found, the racing ones all fail and are retried by their callers.
+	tags, err = vpcs.listLeaseTags(volumeID)
+	if err != nil {
+		lease.release()
+		return nil, GetBackendUserError(string(userError.VolumeLeaseFailed), err, volumeID)
+	}
+	now = leaseNow()
+	for _, lt := range tags {
+		if lt.name == lease.tag.name || lt.expired(now) {
+			continue
+		}
+		vpcs.Logger.Info("Backing off from concurrent volume lease", zap.String("volumeID", volumeID), zap.String("holder", lt.holder), zap.String("tag", lt.name))
+		lease.release()
+		return nil, userError.GetUserError(string(userError.VolumeLeaseHeld), nil, volumeID, lt.holder, time.Unix(lt.expiry, 0).UTC().Format(time.RFC3339))
+	}
+
+	lease.stop = make(chan struct{})
+	lease.done = make(chan struct{})
+	go lease.keepAlive()
+
+	vpcs.Logger.Info("Acquired volume lease", zap.String("volumeID", volumeID), zap.String("tag", lease.tag.name))
+	return lease, nil
+}
+
+// listLeaseTags returns the lease tags present on the volume
+func (vpcs *VPCSession) listLeaseTags(volumeID string) ([]leaseTag, error) {
+	var tags *[]string
+	err := tracedRetry(vpcs.tracing, vpcs.Logger, func() error {
+		var err error
+		tags, err = vpcs.Apiclient.VolumeService().ListVolumeTags(volumeID, vpcs.Logger)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var leaseTags []leaseTag
+	if tags != nil {
+		for _, tag := range *tags {
+			if lt, ok := parseLeaseTag(tag); ok {
+				leaseTags = append(leaseTags, lt)
+			}
+		}
+	}
+	return leaseTags, nil
+}
+
+// put sets a new lease tag with a fresh expiry and removes the previous one
+func (l *VolumeLease) put() error {
+	previous := l.tag
+	next := newLeaseTag(l.config.Holder, l.nonce, leaseNow().Add(l.config.ttl()))
+	if next.name == previous.name {
+		return nil
+	}
+
+	err := tracedRetry(l.vpcs.tracing, l.vpcs.Logger, func() error {
+		return l.vpcs.Apiclient.VolumeService().SetVolumeTag(l.volumeID, next.name, l.vpcs.Logger)
+	})
+	if err != nil {
+		return GetBackendUserError(string(userError.VolumeLeaseFailed), err, l.volumeID)
+	}
+	l.tag = next
+
+	if previous.name != "" {
+		if err = l.vpcs.Apiclient.VolumeService().DeleteVolumeTag(l.volumeID, previous.name, l.vpcs.Logger); err != nil {
+			l.vpcs.Logger.Warn("Failed to remove previous volume lease tag", zap.String("tag", previous.name), zap.Error(err))
+		}
+	}
+	return nil
+}
+
+// Renew extends the lease expiry by the configured TTL
+func (l *VolumeLease) Renew() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.released {
+		return userError.GetUserError(string(userError.VolumeLeaseFailed), errors.New("lease already released"), l.volumeID)
+	}
+	return l.put()
+}
+
+// Release stops the renewal and removes the lease tag from the volume
+func (l *VolumeLease) Release() {
+	if l == nil {
+		return
+	}
+	l.unhold()
+	l.stopOnce.Do(func() {
+		if l.stop != nil {
+			close(l.stop)
+			<-l.done
+		}
+	})
+	l.release()
+}
+
+// release removes the lease tag
+func (l *VolumeLease) release() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.released {
+		return
+	}
+	l.released = true
+	if l.tag.name == "" {
+		return
+	}
+	if err := l.vpcs.Apiclient.VolumeService().DeleteVolumeTag(l.volumeID, l.tag.name, l.vpcs.Logger); err != nil {
+		// The tag expires by itself, other holders take it over after that
+		l.vpcs.Logger.Warn("Failed to release volume lease", zap.String("volumeID", l.volumeID), zap.String("tag", l.tag.name), zap.Error(err))
+		return
+	}
+	l.vpcs.Logger.Info("Released volume lease", zap.String("volumeID", l.volumeID), zap.String("tag", l.tag.name))
+}
+
+// unhold drops the lease from the session holding it, if any
+func (l *VolumeLease) unhold() {
+	l.mu.Lock()
+	held := l.held
+	l.held = nil
+	l.mu.Unlock()
+	if held != nil {
+		held.remove(l)
+	}
+}
+
+// keepAlive renews the lease until it is released or held for MaxHold
+func (l *VolumeLease) keepAlive() {
+	defer close(l.done)
+	ticker := time.NewTicker(l.config.renewInterval())
+	defer ticker.Stop()
+	maxHold := time.NewTimer(l.config.maxHold())
+	defer maxHold.Stop()
+	for {
+		select {
+		case <-l.stop:
+			return
+		case <-maxHold.C:
+			l.vpcs.Logger.Warn("Volume lease held for too long, it is not renewed anymore", zap.String("volumeID", l.volumeID), zap.String("tag", l.tag.name))
+			// Session does not need to keep it any more, the wait may never be called
+			l.unhold()
+			return
+		case <-ticker.C:
+			if err := l.Renew(); err != nil {
+				l.vpcs.Logger.Warn("Failed to renew volume lease", zap.String("volumeID", l.volumeID), zap.Error(err))
+			}
+		}
+	}
+}
+
+// acquireSessionVolumeLease acquires the volume lease if leases are enabled for the session.
+// The returned lease may be nil, Release is safe to call on it.
+func (vpcs *VPCSession) acquireSessionVolumeLease(volumeID string) (*VolumeLease, error) {
+	if vpcs.VolumeLease == nil {
+		return nil, nil
+	}
+	return vpcs.AcquireVolumeLease(volumeID, *vpcs.VolumeLease)
+}
+
+// heldVolumeLeases keeps the leases of the attach and detach operations of a session till the end of their
+// wait, keyed by their acquisition nonce
+type heldVolumeLeases struct {
+	mu     sync.Mutex
+	leases map[string]*VolumeLease
+}
+
+func newHeldVolumeLeases() *heldVolumeLeases {
+	return &heldVolumeLeases{leases: map[string]*VolumeLease{}}
+}
+
+// add keeps the lease till it is released or stops being renewed
+func (h *heldVolumeLeases) add(lease *VolumeLease) {
+	h.mu.Lock()
+	h.leases[lease.nonce] = lease
+	h.mu.Unlock()
+	lease.mu.Lock()
+	lease.held = h
+	lease.mu.Unlock()
+}
+
+// remove drops the lease, if it is still held
+func (h *heldVolumeLeases) remove(lease *VolumeLease) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.leases[lease.nonce] == lease {
+		delete(h.leases, lease.nonce)
+	}
+}
+
+// take removes and returns the held leases of the volume, all of them if volumeID is empty
+func (h *heldVolumeLeases) take(volumeID string) []*VolumeLease {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	var taken []*VolumeLease
+	for nonce, lease := range h.leases {
+		if volumeID == "" || lease.volumeID == volumeID {
+			taken = append(taken, lease)
+			delete(h.leases, nonce)
+		}
+	}
+	return taken
+}
+
+// HoldVolumeLease keeps the lease of an attach or detach request of the session till the end of the wait
+// for the volume i.e ReleaseVolumeLease. Without a session to hold it, the lease is released right away.
+func (vpcs *VPCSession) HoldVolumeLease(lease *VolumeLease) {
+	if lease == nil {
+		return
+	}
+	if vpcs.heldLeases == nil {
+		lease.Release()
+		return
+	}
+	vpcs.heldLeases.add(lease)
+}
+
+// ReleaseVolumeLease releases the leases held for the volume by HoldVolumeLease of this session, if any.
+// The leases of the other sessions on the volume are left alone.
+func (vpcs *VPCSession) ReleaseVolumeLease(volumeID string) {
+	if vpcs.heldLeases == nil {
+		return
+	}
+	for _, lease := range vpcs.heldLeases.take(volumeID) {
+		lease.Release()
+	}
+}
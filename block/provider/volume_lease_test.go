@@ -0,0 +1,499 @@
+/**
+ * Copyright 2022 IBM Corp.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package provider ...
+package provider
+
+import (
+	"errors"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/IBM/ibmcloud-volume-interface/lib/provider"
+	userError "github.com/IBM/ibmcloud-volume-vpc/common/messages"
+	volumeAttachServiceFakes "github.com/IBM/ibmcloud-volume-vpc/common/vpcclient/instances/fakes"
+	"github.com/IBM/ibmcloud-volume-vpc/common/vpcclient/models"
+	volumeServiceFakes "github.com/IBM/ibmcloud-volume-vpc/common/vpcclient/vpcvolume/fakes"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+)
+
+// fakeTagStore backs the volume tag calls of the fake volume service
+type fakeTagStore struct {
+	mu   sync.Mutex
+	tags []string
+	// afterSet is called once a tag is set, to simulate a concurrent holder
+	afterSet func(store *fakeTagStore)
+}
+
+func (fs *fakeTagStore) install(volumeService *volumeServiceFakes.VolumeService) {
+	volumeService.ListVolumeTagsStub = func(volumeID string, logger *zap.Logger) (*[]string, error) {
+		fs.mu.Lock()
+		defer fs.mu.Unlock()
+		tags := append([]string{}, fs.tags...)
+		return &tags, nil
+	}
+	volumeService.SetVolumeTagStub = func(volumeID string, tag string, logger *zap.Logger) error {
+		fs.mu.Lock()
+		fs.tags = append(fs.tags, tag)
+		afterSet := fs.afterSet
+		fs.mu.Unlock()
+		if afterSet != nil {
+			afterSet(fs)
+		}
+		return nil
+	}
+	volumeService.DeleteVolumeTagStub = func(volumeID string, tag string, logger *zap.Logger) error {
+		fs.mu.Lock()
+		defer fs.mu.Unlock()
+		for i, t := range fs.tags {
+			if t == tag {
+				fs.tags = append(fs.tags[:i], fs.tags[i+1:]...)
+				break
+			}
+		}
+		return nil
+	}
+}
+
+func (fs *fakeTagStore) get() []string {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+	return append([]string{}, fs.tags...)
+}
+
+func TestParseLeaseTag(t *testing.T) {
+	lt, ok := parseLeaseTag("lock:replica-1:0123456789abcdef:1700000000")
+	assert.True(t, ok)
+	assert.Equal(t, "replica-1", lt.holder)
+	assert.Equal(t, "0123456789abcdef", lt.nonce)
+	assert.Equal(t, int64(1700000000), lt.expiry)
+
+	lt, ok = parseLeaseTag("lock:ns:pod:0123456789abcdef:1700000000")
+	assert.True(t, ok)
+	assert.Equal(t, "ns:pod", lt.holder)
+	assert.Equal(t, "0123456789abcdef", lt.nonce)
+
+	// Tags without nonce put by older versions
+	lt, ok = parseLeaseTag("lock:replica-1:1700000000")
+	assert.True(t, ok)
+	assert.Equal(t, "replica-1", lt.holder)
+	assert.Empty(t, lt.nonce)
+	assert.Equal(t, int64(1700000000), lt.expiry)
+
+	lt, ok = parseLeaseTag("lock:ns:pod:1700000000")
+	assert.True(t, ok)
+	assert.Equal(t, "ns:pod", lt.holder)
+	assert.Empty(t, lt.nonce)
+
+	for _, tag := range []string{"env:prod", "lock:replica-1", "lock::1700000000", "lock:replica-1:never"} {
+		_, ok = parseLeaseTag(tag)
+		assert.False(t, ok, tag)
+	}
+
+	assert.Equal(t, "lock:replica-1:0123456789abcdef:1700000000", newLeaseTag("replica-1", "0123456789abcdef", time.Unix(1700000000, 0)).name)
+
+	nonce, err := newLeaseNonce()
+	assert.Nil(t, err)
+	assert.True(t, isLeaseNonce(nonce))
+	other, _ := newLeaseNonce()
+	assert.NotEqual(t, nonce, other)
+}
+
+func TestAcquireVolumeLease(t *testing.T) {
+	userError.MessagesEn = userError.InitMessages()
+	logger, teardown := GetTestLogger(t)
+	defer teardown()
+
+	now := time.Unix(1700000000, 0)
+	leaseNow = func() time.Time { return now }
+	defer func() { leaseNow = time.Now }()
+
+	config := VolumeLeaseConfig{Holder: "replica-1", TTL: time.Minute}
+	// ownTag stands for the tag of the acquired lease, its nonce is random
+	ownTag := "own"
+
+	testCases := []struct {
+		testCaseName string
+		config       VolumeLeaseConfig
+		tags         []string
+		afterSet     func(store *fakeTagStore)
+
+		expectedErrCode string
+		expectedTags    []string
+	}{
+		{
+			testCaseName:    "Holder is not configured",
+			config:          VolumeLeaseConfig{},
+			expectedErrCode: userError.VolumeLeaseFailed,
+		}, {
+			testCaseName: "Volume is not leased",
+			config:       config,
+			tags:         []string{"env:prod"},
+			expectedTags: []string{"env:prod", ownTag},
+		}, {
+			testCaseName:    "Volume is leased by another holder",
+			config:          config,
+			tags:            []string{"lock:replica-2:1700000030"},
+			expectedErrCode: userError.VolumeLeaseHeld,
+			expectedTags:    []string{"lock:replica-2:1700000030"},
+		}, {
+			testCaseName: "Expired lease of another holder is taken over",
+			config:       config,
+			tags:         []string{"lock:replica-2:1699999990"},
+			expectedTags: []string{ownTag},
+		}, {
+			testCaseName:    "Volume is leased by another acquisition of the same holder",
+			config:          config,
+			tags:            []string{"lock:replica-1:0123456789abcdef:1700000030"},
+			expectedErrCode: userError.VolumeLeaseHeld,
+			expectedTags:    []string{"lock:replica-1:0123456789abcdef:1700000030"},
+		}, {
+			testCaseName:    "Volume is leased by the same holder without nonce",
+			config:          config,
+			tags:            []string{"lock:replica-1:1700000030"},
+			expectedErrCode: userError.VolumeLeaseHeld,
+			expectedTags:    []string{"lock:replica-1:1700000030"},
+		}, {
+			testCaseName: "Expired lease of the same holder is taken over",
+			config:       config,
+			tags:         []string{"lock:replica-1:0123456789abcdef:1699999990"},
+			expectedTags: []string{ownTag},
+		}, {
+			testCaseName: "Concurrent acquisition of the same holder makes it back off",
+			config:       config,
+			afterSet: func(store *fakeTagStore) {
+				store.tags = append(store.tags, "lock:replica-1:0123456789abcdef:1700000030")
+			},
+			expectedErrCode: userError.VolumeLeaseHeld,
+			expectedTags:    []string{"lock:replica-1:0123456789abcdef:1700000030"},
+		}, {
+			testCaseName: "Concurrent holder with older lease makes it back off",
+			config:       config,
+			afterSet: func(store *fakeTagStore) {
+				store.tags = append(store.tags, "lock:replica-2:1700000030")
+			},
+			expectedErrCode: userError.VolumeLeaseHeld,
+			expectedTags:    []string{"lock:replica-2:1700000030"},
+		}, {
+			// Expiry comes from the clock of the other replica, it does not tell which tag was put first
+			testCaseName: "Concurrent holder with newer lease makes it back off",
+			config:       config,
+			afterSet: func(store *fakeTagStore) {
+				store.tags = append(store.tags, "lock:replica-2:1700000090")
+			},
+			expectedErrCode: userError.VolumeLeaseHeld,
+			expectedTags:    []string{"lock:replica-2:1700000090"},
+		}, {
+			testCaseName: "Concurrent holder with expired lease is ignored",
+			config:       config,
+			afterSet: func(store *fakeTagStore) {
+				store.tags = append(store.tags, "lock:replica-2:1699999990")
+			},
+			expectedTags: []string{ownTag, "lock:replica-2:1699999990"},
+		},
+	}
+
+	for _, testcase := range testCases {
+		t.Run(testcase.testCaseName, func(t *testing.T) {
+			vpcs, uc, _, err := GetTestOpenSession(t, logger)
+			assert.Nil(t, err)
+
+			volumeService := &volumeServiceFakes.VolumeService{}
+			uc.VolumeServiceReturns(volumeService)
+			store := &fakeTagStore{tags: testcase.tags}
+			store.install(volumeService)
+			store.afterSet = func(fs *fakeTagStore) {
+				if testcase.afterSet != nil {
+					fs.mu.Lock()
+					testcase.afterSet(fs)
+					fs.mu.Unlock()
+				}
+			}
+
+			lease, err := vpcs.AcquireVolumeLease("volume-id1", testcase.config)
+			if testcase.expectedErrCode != "" {
+				assert.Nil(t, lease)
+				assert.NotNil(t, err)
+				assert.Equal(t, testcase.expectedErrCode, userError.GetUserErrorCode(err))
+			} else {
+				assert.Nil(t, err)
+				assert.NotNil(t, lease)
+			}
+			var expectedTags []string
+			for _, tag := range testcase.expectedTags {
+				if tag == ownTag {
+					tag = newLeaseTag("replica-1", lease.nonce, now.Add(time.Minute)).name
+				}
+				expectedTags = append(expectedTags, tag)
+			}
+			assert.ElementsMatch(t, expectedTags, store.get())
+
+			if lease != nil {
+				tag := lease.tag.name
+				lease.Release()
+				assert.NotContains(t, store.get(), tag)
+			}
+		})
+	}
+}
+
+func TestRenewVolumeLease(t *testing.T) {
+	userError.MessagesEn = userError.InitMessages()
+	logger, teardown := GetTestLogger(t)
+	defer teardown()
+
+	now := time.Unix(1700000000, 0)
+	leaseNow = func() time.Time { return now }
+	defer func() { leaseNow = time.Now }()
+
+	vpcs, uc, _, err := GetTestOpenSession(t, logger)
+	assert.Nil(t, err)
+	volumeService := &volumeServiceFakes.VolumeService{}
+	uc.VolumeServiceReturns(volumeService)
+	store := &fakeTagStore{}
+	store.install(volumeService)
+
+	lease, err := vpcs.AcquireVolumeLease("volume-id1", VolumeLeaseConfig{Holder: "replica-1", TTL: time.Hour})
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"lock:replica-1:" + lease.nonce + ":1700003600"}, store.get())
+
+	// Renewal keeps the nonce of the acquisition
+	now = now.Add(10 * time.Minute)
+	assert.Nil(t, lease.Renew())
+	assert.Equal(t, []string{"lock:replica-1:" + lease.nonce + ":1700004200"}, store.get())
+
+	lease.Release()
+	assert.Empty(t, store.get())
+	assert.NotNil(t, lease.Renew())
+
+	// Release is safe on a nil lease i.e leases disabled
+	var nilLease *VolumeLease
+	nilLease.Release()
+}
+
+func TestVolumeLeaseConcurrentRelease(t *testing.T) {
+	// keepAlive may log from its own goroutine while the lease is released
+	logger, teardown := getTestSyncLogger(t)
+	defer teardown()
+
+	vpcs, uc, _, err := GetTestOpenSession(t, logger)
+	assert.Nil(t, err)
+	volumeService := &volumeServiceFakes.VolumeService{}
+	uc.VolumeServiceReturns(volumeService)
+	store := &fakeTagStore{}
+	store.install(volumeService)
+
+	lease, err := vpcs.AcquireVolumeLease("volume-id1", VolumeLeaseConfig{Holder: "replica-1", TTL: time.Hour})
+	assert.Nil(t, err)
+
+	// e.g the wait of the item and the batch holding the lease release it at the same time
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			lease.Release()
+		}()
+	}
+	wg.Wait()
+
+	select {
+	case <-lease.done:
+	default:
+		t.Fatal("lease is still renewed after Release")
+	}
+	assert.Empty(t, store.get())
+	assert.Equal(t, 1, volumeService.DeleteVolumeTagCallCount())
+}
+
+func TestAttachVolumeWithLeaseHeld(t *testing.T) {
+	userError.MessagesEn = userError.InitMessages()
+	logger, teardown := GetTestLogger(t)
+	defer teardown()
+
+	vpcs, uc, _, err := GetTestOpenSession(t, logger)
+	assert.Nil(t, err)
+	vpcs.VolumeLease = &VolumeLeaseConfig{Holder: "replica-1"}
+
+	volumeService := &volumeServiceFakes.VolumeService{}
+	uc.VolumeServiceReturns(volumeService)
+	store := &fakeTagStore{tags: []string{newLeaseTag("replica-2", "0123456789abcdef", time.Now().Add(time.Minute)).name}}
+	store.install(volumeService)
+
+	volumeAttachService := &volumeAttachServiceFakes.VolumeAttachService{}
+	vpcs.APIClientVolAttachMgr = volumeAttachService
+
+	request := provider.VolumeAttachmentRequest{VolumeID: "volume-id1", InstanceID: "instance-id1"}
+	response, err := vpcs.AttachVolume(request)
+	assert.Nil(t, response)
+	assert.Equal(t, userError.VolumeLeaseHeld, userError.GetUserErrorCode(err))
+	assert.Equal(t, 0, volumeAttachService.AttachVolumeCallCount())
+
+	_, err = vpcs.DetachVolume(request)
+	assert.Equal(t, userError.VolumeLeaseHeld, userError.GetUserErrorCode(err))
+	assert.Equal(t, 0, volumeAttachService.DetachVolumeCallCount())
+}
+
+func TestVolumeLeaseMaxHold(t *testing.T) {
+	// keepAlive logs from its own goroutine once MaxHold is reached
+	logger, teardown := getTestSyncLogger(t)
+	defer teardown()
+
+	vpcs, uc, _, err := GetTestOpenSession(t, logger)
+	assert.Nil(t, err)
+	volumeService := &volumeServiceFakes.VolumeService{}
+	uc.VolumeServiceReturns(volumeService)
+	store := &fakeTagStore{}
+	store.install(volumeService)
+
+	lease, err := vpcs.AcquireVolumeLease("volume-id1", VolumeLeaseConfig{Holder: "replica-1", TTL: time.Hour, MaxHold: time.Millisecond})
+	assert.Nil(t, err)
+
+	// Renewal stops by itself, the tag is left to expire
+	select {
+	case <-lease.done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("lease is still renewed after MaxHold")
+	}
+	assert.Len(t, store.get(), 1)
+	lease.Release()
+	assert.Empty(t, store.get())
+}
+
+func TestVolumeLeaseHeldTillWait(t *testing.T) {
+	userError.MessagesEn = userError.InitMessages()
+	logger, teardown := GetTestLogger(t)
+	defer teardown()
+
+	testCases := []struct {
+		testCaseName string
+		attach       bool
+		attachErr    error
+		expectHeld   bool
+	}{
+		{
+			testCaseName: "Attach holds the lease till the wait",
+			attach:       true,
+			expectHeld:   true,
+		}, {
+			testCaseName: "Detach holds the lease till the wait",
+			expectHeld:   true,
+		}, {
+			testCaseName: "Failed attach releases the lease",
+			attach:       true,
+			attachErr:    errors.New("attach failed"),
+		},
+	}
+
+	for _, testcase := range testCases {
+		t.Run(testcase.testCaseName, func(t *testing.T) {
+			vpcs, uc, _, err := GetTestOpenSession(t, logger)
+			assert.Nil(t, err)
+			vpcs.VolumeLease = &VolumeLeaseConfig{Holder: "replica-1"}
+			vpcs.heldLeases = newHeldVolumeLeases()
+			vpcs.APIRetry = FlexyRetry{maxRetryAttempt: 1, maxRetryGap: 1}
+
+			volumeService := &volumeServiceFakes.VolumeService{}
+			uc.VolumeServiceReturns(volumeService)
+			store := &fakeTagStore{}
+			store.install(volumeService)
+
+			volumeAttachService := &volumeAttachServiceFakes.VolumeAttachService{}
+			vpcs.APIClientVolAttachMgr = volumeAttachService
+			attachment := models.VolumeAttachment{ID: "attachment-id1", Status: StatusAttached, Volume: &models.Volume{ID: "volume-id1"}}
+			attached := &models.VolumeAttachmentList{VolumeAttachments: []models.VolumeAttachment{attachment}}
+			volumeAttachService.ListVolumeAttachmentsReturns(attached, nil)
+			volumeAttachService.AttachVolumeReturns(&attachment, testcase.attachErr)
+			volumeAttachService.DetachVolumeReturns(&http.Response{StatusCode: http.StatusOK}, nil)
+
+			request := provider.VolumeAttachmentRequest{VolumeID: "volume-id1", InstanceID: "instance-id1"}
+			if testcase.attach {
+				volumeAttachService.ListVolumeAttachmentsReturns(&models.VolumeAttachmentList{}, nil)
+				_, err = vpcs.AttachVolume(request)
+			} else {
+				_, err = vpcs.DetachVolume(request) //nolint:bodyclose
+			}
+			assert.Equal(t, testcase.attachErr == nil, err == nil)
+			if !testcase.expectHeld {
+				assert.Empty(t, store.get())
+				return
+			}
+			assert.Len(t, store.get(), 1)
+
+			// Other operations on the volume are refused till the end of the wait
+			_, err = vpcs.AcquireVolumeLease("volume-id1", *vpcs.VolumeLease)
+			assert.Equal(t, userError.VolumeLeaseHeld, userError.GetUserErrorCode(err))
+
+			if testcase.attach {
+				volumeAttachService.ListVolumeAttachmentsReturns(attached, nil)
+				_, err = vpcs.WaitForAttachVolume(request)
+			} else {
+				volumeAttachService.ListVolumeAttachmentsReturns(&models.VolumeAttachmentList{}, nil)
+				err = vpcs.WaitForDetachVolume(request)
+			}
+			assert.Nil(t, err)
+			assert.Empty(t, store.get())
+		})
+	}
+}
+
+func TestVolumeLeaseHeldPerSession(t *testing.T) {
+	// keepAlive logs from its own goroutine once MaxHold is reached
+	logger, teardown := getTestSyncLogger(t)
+	defer teardown()
+
+	store := &fakeTagStore{}
+	var sessions []*VPCSession
+	for i := 0; i < 2; i++ {
+		vpcs, uc, _, err := GetTestOpenSession(t, logger)
+		assert.Nil(t, err)
+		vpcs.heldLeases = newHeldVolumeLeases()
+		volumeService := &volumeServiceFakes.VolumeService{}
+		uc.VolumeServiceReturns(volumeService)
+		store.install(volumeService)
+		sessions = append(sessions, vpcs)
+	}
+
+	lease, err := sessions[0].AcquireVolumeLease("volume-id1", VolumeLeaseConfig{Holder: "replica-1"})
+	assert.Nil(t, err)
+	sessions[0].HoldVolumeLease(lease)
+
+	// Wait of another session on the volume does not release the lease
+	sessions[1].ReleaseVolumeLease("volume-id1")
+	assert.Len(t, store.get(), 1)
+
+	sessions[0].ReleaseVolumeLease("volume-id1")
+	assert.Empty(t, store.get())
+	assert.Empty(t, sessions[0].heldLeases.leases)
+
+	// Lease which stops being renewed is not kept by the session any more
+	lease, err = sessions[0].AcquireVolumeLease("volume-id2", VolumeLeaseConfig{Holder: "replica-1", TTL: time.Hour, MaxHold: 50 * time.Millisecond})
+	assert.Nil(t, err)
+	sessions[0].HoldVolumeLease(lease)
+	select {
+	case <-lease.done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("lease is still renewed after MaxHold")
+	}
+	sessions[0].heldLeases.mu.Lock()
+	assert.Empty(t, sessions[0].heldLeases.leases)
+	sessions[0].heldLeases.mu.Unlock()
+	lease.Release()
+	assert.Empty(t, store.get())
+}
@@ -0,0 +1,481 @@
+/**
+ * Copyright 2022 IBM Corp.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package provider ...
+package provider
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/IBM/ibmcloud-volume-interface/lib/provider"
+	userError "github.com/IBM/ibmcloud-volume-vpc/common/messages"
+	"github.com/IBM/ibmcloud-volume-vpc/common/vpcclient/models"
+	"go.uber.org/zap"
+)
+
+// DefaultPollInterval is the interval between two polls of the shared volume poller
+const DefaultPollInterval = 5 * time.Second
+
+// VolumePollerConfig enables the volume poller shared by the sessions of a provider
+type VolumePollerConfig struct {
+	// Interval between two polls. Defaults to DefaultPollInterval
+	Interval time.Duration
+
+	// Timeout of a wait. Defaults to the retry budget of the waiting session, i.e the one of
+	// the same wait without poller
+	Timeout time.Duration
+}
+
+// VolumePoller serves the waits of the sessions of a provider. Waiters register their interest
+// per account and instance (or per account and volume), each poll lists the volume attachments
+// once per instance and gets each volume once, and the results are fanned out to all the waiters.
+// So the API calls per interval do not grow with the number of concurrent waits on the same instance.
+type VolumePoller struct {
+	mu        sync.Mutex
+	config    VolumePollerConfig
+	instances map[string]*instanceInterest
+	volumes   map[string]*volumeInterest
+	running   bool
+}
+
+// instanceInterest holds the waiters of an instance of an account
+type instanceInterest struct {
+	template models.VolumeAttachment
+	waiters  []*attachmentWaiter
+}
+
+// attachmentWaiter waits for a volume attachment to get attached or to be removed
+type attachmentWaiter struct {
+	vpcs     *VPCSession
+	request  provider.VolumeAttachmentRequest
+	attach   bool
+	deadline time.Time
+	result   chan attachmentWaitResult
+}
+
+type attachmentWaitResult struct {
+	response *provider.VolumeAttachmentResponse
+	err      error
+}
+
+// volumeInterest holds the waiters of a volume of an account
+type volumeInterest struct {
+	volumeID string
+	waiters  []*volumeWaiter
+}
+
+// volumeWaiter waits for a volume to get the valid (available) state
+type volumeWaiter struct {
+	vpcs     *VPCSession
+	deadline time.Time
+	result   chan volumeWaitResult
+}
+
+type volumeWaitResult struct {
+	volume *models.Volume
+	err    error
+}
+
+// NewVolumePoller creates a poller with the given config
+func NewVolumePoller(config VolumePollerConfig) *VolumePoller {
+	return &VolumePoller{
+		config:    config,
+		instances: map[string]*instanceInterest{},
+		volumes:   map[string]*volumeInterest{},
+	}
+}
+
+// configure updates the config of the poller, it applies to the next polls and waits
+func (p *VolumePoller) configure(config VolumePollerConfig) *VolumePoller {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.config = config
+	return p
+}
+
+// interval returns the interval of the next poll
+func (p *VolumePoller) interval() time.Duration {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.config.Interval > 0 {
+		return p.config.Interval
+	}
+	return DefaultPollInterval
+}
+
+// deadlineLocked returns the deadline of a wait starting now, p.mu must be held
+func (p *VolumePoller) deadlineLocked(budget time.Duration) time.Time {
+	if p.config.Timeout > 0 {
+		budget = p.config.Timeout
+	}
+	return time.Now().Add(budget)
+}
+
+// instanceKey identifies the instance of an attachment request, the cluster is part of it for IKS
+func instanceKey(template models.VolumeAttachment) string {
+	if template.ClusterID != nil {
+		return *template.ClusterID + "/" + *template.InstanceID
+	}
+	return *template.InstanceID
+}
+
+// pollKey keys the interest of a waiter by the account of its session and the polled resource. Waiters of an unknown
+// account are never coalesced, the sessions of all the accounts would share the polls and their results.
+func pollKey(vpcs *VPCSession, waiter interface{}, resource string) string {
+	if vpcs.VPCAccountID == "" {
+		return fmt.Sprintf("%p/%s", waiter, resource)
+	}
+	return vpcs.VPCAccountID + "/" + resource
+}
+
+// waitForAttachment blocks till the attachment is attached (attach true) or removed (attach false),
+// the wait ends with the deadline or the cancellation of the context of the session
+func (p *VolumePoller) waitForAttachment(vpcs *VPCSession, request provider.VolumeAttachmentRequest, attach bool) (*provider.VolumeAttachmentResponse, error) {
+	template := models.NewVolumeAttachment(request)
+	waiter := &attachmentWaiter{
+		vpcs:    vpcs,
+		request: request,
+		attach:  attach,
+		result:  make(chan attachmentWaitResult, 1),
+	}
+
+	p.mu.Lock()
+	waiter.deadline = p.deadlineLocked(vpcs.APIRetry.customGapBudget())
+	// Waiters of the same instance share the polls within their account only
+	key := pollKey(vpcs, waiter, instanceKey(template))
+	interest, ok := p.instances[key]
+	if !ok {
+		interest = &instanceInterest{template: template}
+		p.instances[key] = interest
+	}
+	interest.waiters = append(interest.waiters, waiter)
+	p.startLocked()
+	p.mu.Unlock()
+
+	select {
+	case result := <-waiter.result:
+		return result.response, result.err
+	case <-vpcs.context().Done():
+		p.cancelAttachmentWaiter(key, waiter)
+		vpcs.Logger.Info("Wait for volume attachment cancelled", zap.String("volumeID", request.VolumeID), zap.String("instanceID", request.InstanceID))
+		if attach {
+			return nil, userError.GetUserError(string(userError.VolumeAttachTimedOut), vpcs.context().Err(), request.VolumeID, request.InstanceID)
+		}
+		return nil, userError.GetUserError(string(userError.VolumeDetachTimedOut), vpcs.context().Err(), request.VolumeID, request.InstanceID)
+	}
+}
+
+// waitForVolume blocks till the volume gets the valid (available) state, the wait ends with the
+// deadline or the cancellation of the context of the session
+func (p *VolumePoller) waitForVolume(vpcs *VPCSession, volumeID string) (*models.Volume, error) {
+	waiter := &volumeWaiter{
+		vpcs:   vpcs,
+		result: make(chan volumeWaitResult, 1),
+	}
+
+	p.mu.Lock()
+	waiter.deadline = p.deadlineLocked(retryBudget())
+	key := pollKey(vpcs, waiter, volumeID)
+	interest, ok := p.volumes[key]
+	if !ok {
+		interest = &volumeInterest{volumeID: volumeID}
+		p.volumes[key] = interest
+	}
+	interest.waiters = append(interest.waiters, waiter)
+	p.startLocked()
+	p.mu.Unlock()
+
+	select {
+	case result := <-waiter.result:
+		return result.volume, result.err
+	case <-vpcs.context().Done():
+		p.cancelVolumeWaiter(key, waiter)
+		vpcs.Logger.Info("Wait for volume cancelled", zap.String("volumeID", volumeID))
+		return nil, vpcs.context().Err()
+	}
+}
+
+// cancelAttachmentWaiter removes the waiter of a cancelled wait
+func (p *VolumePoller) cancelAttachmentWaiter(key string, waiter *attachmentWaiter) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	interest, ok := p.instances[key]
+	if !ok {
+		return
+	}
+	var pending []*attachmentWaiter
+	for _, w := range interest.waiters {
+		if w != waiter {
+			pending = append(pending, w)
+		}
+	}
+	if len(pending) == 0 {
+		delete(p.instances, key)
+		return
+	}
+	interest.waiters = pending
+}
+
+// cancelVolumeWaiter removes the waiter of a cancelled wait
+func (p *VolumePoller) cancelVolumeWaiter(key string, waiter *volumeWaiter) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	interest, ok := p.volumes[key]
+	if !ok {
+		return
+	}
+	var pending []*volumeWaiter
+	for _, w := range interest.waiters {
+		if w != waiter {
+			pending = append(pending, w)
+		}
+	}
+	if len(pending) == 0 {
+		delete(p.volumes, key)
+		return
+	}
+	interest.waiters = pending
+}
+
+// startLocked starts the poll loop if it is not running, p.mu must be held
+func (p *VolumePoller) startLocked() {
+	if !p.running {
+		p.running = true
+		go p.run()
+	}
+}
+
+// run polls till there are no more waiters
+func (p *VolumePoller) run() {
+	for p.poll() {
+		time.Sleep(p.interval())
+	}
+}
+
+// poll makes one round of API calls and completes the waiters. It returns false and marks the
+// poller as stopped when no waiters are left. The calls are made with the session of the oldest
+// waiter of each interest, all the waiters of an interest belong to the same known account or
+// an interest has a single waiter.
+func (p *VolumePoller) poll() bool {
+	p.mu.Lock()
+	instances := make([]instanceInterest, 0, len(p.instances))
+	for _, interest := range p.instances {
+		instances = append(instances, *interest)
+	}
+	volumes := make([]volumeInterest, 0, len(p.volumes))
+	for _, interest := range p.volumes {
+		volumes = append(volumes, *interest)
+	}
+	p.mu.Unlock()
+
+	done := map[interface{}]bool{}
+	now := time.Now()
+	for _, interest := range instances {
+		template := interest.template
+		vpcs := interest.waiters[0].vpcs
+		volumeAttachmentList, err := vpcs.APIClientVolAttachMgr.ListVolumeAttachments(&template, vpcs.Logger)
+		vpcs.Logger.Debug("Polled volume attachments", zap.String("instanceID", *template.InstanceID), zap.Int("waiters", len(interest.waiters)), zap.Error(err))
+		for _, waiter := range interest.waiters {
+			if waiter.complete(volumeAttachmentList, err, now) {
+				done[waiter] = true
+			}
+		}
+	}
+	for _, interest := range volumes {
+		vpcs := interest.waiters[0].vpcs
+		volume, err := vpcs.Apiclient.VolumeService().GetVolume(interest.volumeID, vpcs.Logger)
+		vpcs.Logger.Debug("Polled volume", zap.String("volumeID", interest.volumeID), zap.Int("waiters", len(interest.waiters)), zap.Error(err))
+		for _, waiter := range interest.waiters {
+			if waiter.complete(volume, err, now) {
+				done[waiter] = true
+			}
+		}
+	}
+
+	// Drop the completed waiters, new waiters may have been added or cancelled meanwhile
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for key, interest := range p.instances {
+		var pending []*attachmentWaiter
+		for _, waiter := range interest.waiters {
+			if !done[waiter] {
+				pending = append(pending, waiter)
+			}
+		}
+		if len(pending) == 0 {
+			delete(p.instances, key)
+			continue
+		}
+		interest.waiters = pending
+	}
+	for key, interest := range p.volumes {
+		var pending []*volumeWaiter
+		for _, waiter := range interest.waiters {
+			if !done[waiter] {
+				pending = append(pending, waiter)
+			}
+		}
+		if len(pending) == 0 {
+			delete(p.volumes, key)
+			continue
+		}
+		interest.waiters = pending
+	}
+	if len(p.instances) == 0 && len(p.volumes) == 0 {
+		p.running = false
+		return false
+	}
+	return true
+}
+
+// complete checks the polled attachment list and sends the result if the wait is over
+func (w *attachmentWaiter) complete(volumeAttachmentList *models.VolumeAttachmentList, err error, now time.Time) bool {
+	vpcs := w.vpcs
+	if err != nil {
+		if skipRetryForObviousErrors(err, vpcs.Config.VPCConfig.IsIKS) {
+			switch {
+			case w.attach:
+				w.result <- attachmentWaitResult{err: GetBackendUserError(string(userError.VolumeAttachFindFailed), err, w.request.VolumeID, w.request.InstanceID)}
+			case isNotFoundError(err):
+				// The attachment or its instance does not exist any more, the detach is complete
+				vpcs.Logger.Info("Volume detachment is complete", zap.String("volumeID", w.request.VolumeID), zap.String("instanceID", w.request.InstanceID), zap.Error(err))
+				w.result <- attachmentWaitResult{}
+			default:
+				// Open circuit, rejected token... the detach is not confirmed
+				userErr := GetBackendUserError(string(userError.VolumeDetachTimedOut), err, w.request.VolumeID, w.request.InstanceID)
+				vpcs.Logger.Info("Wait for detach failed", zap.Error(userErr))
+				w.result <- attachmentWaitResult{err: userErr}
+			}
+			return true
+		}
+		return w.timedOut(now)
+	}
+
+	var found *models.VolumeAttachment
+	attachmentID := ""
+	if w.request.VPCVolumeAttachment != nil {
+		attachmentID = w.request.VPCVolumeAttachment.ID
+	}
+	for i, volumeAttachment := range volumeAttachmentList.VolumeAttachments {
+		if (attachmentID != "" && volumeAttachment.ID == attachmentID) ||
+			(attachmentID == "" && volumeAttachment.Volume != nil && volumeAttachment.Volume.ID == w.request.VolumeID) {
+			found = &volumeAttachmentList.VolumeAttachments[i]
+			break
+		}
+	}
+
+	switch {
+	case w.attach && found == nil:
+		// Attachment may not be listed yet right after the attach, keep waiting till the deadline
+		vpcs.Logger.Debug("Volume attachment not listed yet", zap.String("volumeID", w.request.VolumeID), zap.String("instanceID", w.request.InstanceID))
+	case w.attach && attachmentFailed(*found):
+		// No need to wait for an attachment which is being removed
+		w.result <- attachmentWaitResult{err: attachmentFailedError(w.request, *found)}
+		return true
+	case w.attach && found.Status == StatusAttached:
+		volumeAttachment := *found
+		volumeAttachment.InstanceID = &w.request.InstanceID
+		w.result <- attachmentWaitResult{response: volumeAttachment.ToVolumeAttachmentResponse(vpcs.Config.VPCConfig.VPCBlockProviderType)}
+		return true
+	case !w.attach && found == nil:
+		vpcs.Logger.Info("Volume detachment is complete", zap.String("volumeID", w.request.VolumeID), zap.String("instanceID", w.request.InstanceID))
+		w.result <- attachmentWaitResult{}
+		return true
+	}
+	return w.timedOut(now)
+}
+
+// timedOut sends the timeout error once the deadline has passed
+func (w *attachmentWaiter) timedOut(now time.Time) bool {
+	if now.Before(w.deadline) {
+		return false
+	}
+	var userErr error
+	if w.attach {
+		userErr = userError.GetUserError(string(userError.VolumeAttachTimedOut), nil, w.request.VolumeID, w.request.InstanceID)
+	} else {
+		userErr = userError.GetUserError(string(userError.VolumeDetachTimedOut), nil, w.request.VolumeID, w.request.InstanceID)
+	}
+	w.vpcs.Logger.Info("Wait for volume attachment timed out", zap.Error(userErr))
+	w.result <- attachmentWaitResult{err: userErr}
+	return true
+}
+
+// complete checks the polled volume and sends the result if the wait is over
+func (w *volumeWaiter) complete(volume *models.Volume, err error, now time.Time) bool {
+	if err != nil && skipRetryForObviousErrors(err, false) {
+		w.result <- volumeWaitResult{err: err}
+		return true
+	}
+	if err == nil && volume != nil && volume.Status == validVolumeStatus {
+		w.result <- volumeWaitResult{volume: volume}
+		return true
+	}
+	if now.Before(w.deadline) {
+		return false
+	}
+	if err == nil {
+		err = errors.New("volume did not get the valid (available) state in time")
+	}
+	w.result <- volumeWaitResult{err: err}
+	return true
+}
+
+// customGapBudget is the time FlexyRetryWithCustomGap sleeps over all its attempts
+func (fRetry *FlexyRetry) customGapBudget() time.Duration {
+	interimRetryGap := 2 * fRetry.minVPCRetryGap
+	if interimRetryGap > ConstantRetryGap {
+		interimRetryGap = ConstantRetryGap
+	}
+	maxInterimRetryGapAttempt := 3 * fRetry.minVPCRetryGapAttempt
+	retryGap := fRetry.minVPCRetryGap
+	budget := 0
+	for i := 0; i < fRetry.maxVPCRetryAttempt; i++ {
+		if i == fRetry.minVPCRetryGapAttempt {
+			retryGap = interimRetryGap
+		}
+		if i == maxInterimRetryGapAttempt {
+			retryGap = ConstantRetryGap
+		}
+		budget += retryGap
+	}
+	return time.Duration(budget) * time.Second
+}
+
+// retryBudget is the time retry sleeps over all its attempts
+func retryBudget() time.Duration {
+	budget, retryGap := 0, 10
+	for i := 1; i < maxRetryAttempt; i++ {
+		budget += retryGap
+		retryGap = 2 * retryGap
+		if retryGap > maxRetryGap {
+			retryGap = maxRetryGap
+		}
+	}
+	return time.Duration(budget) * time.Second
+}
+
+// context returns the context of the session, the background context if there is none
+func (vpcs *VPCSession) context() context.Context {
+	if vpcs.ctx != nil {
+		return vpcs.ctx
+	}
+	return context.Background()
+}
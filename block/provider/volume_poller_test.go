@@ -0,0 +1,369 @@
+/**
+ * Copyright 2022 IBM Corp.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package provider ...
+package provider
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/IBM/ibmcloud-volume-interface/lib/provider"
+	userError "github.com/IBM/ibmcloud-volume-vpc/common/messages"
+	volumeAttachServiceFakes "github.com/IBM/ibmcloud-volume-vpc/common/vpcclient/instances/fakes"
+	"github.com/IBM/ibmcloud-volume-vpc/common/vpcclient/models"
+	"github.com/IBM/ibmcloud-volume-vpc/common/vpcclient/riaas/fakes"
+	volumeServiceFakes "github.com/IBM/ibmcloud-volume-vpc/common/vpcclient/vpcvolume/fakes"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+)
+
+func TestVolumePollerWaitForAttachVolume(t *testing.T) {
+	userError.MessagesEn = userError.InitMessages()
+	logger, teardown := getTestSyncLogger(t)
+	defer teardown()
+
+	vpcs, _, _, err := GetTestOpenSession(t, logger)
+	assert.Nil(t, err)
+	vpcs.poller = NewVolumePoller(VolumePollerConfig{Interval: 20 * time.Millisecond, Timeout: time.Minute})
+
+	volumeAttachService := &volumeAttachServiceFakes.VolumeAttachService{}
+	vpcs.APIClientVolAttachMgr = volumeAttachService
+	instances := newFakeInstances()
+	instances.install(volumeAttachService)
+
+	const waits = 20
+	instances.attachments["instance-1"] = map[string]string{}
+	for i := 0; i < waits; i++ {
+		instances.attachments["instance-1"][fmt.Sprintf("volume-%d", i)] = StatusAttaching
+	}
+	// Volumes get attached once the waiters have registered
+	go func() {
+		time.Sleep(100 * time.Millisecond)
+		instances.mu.Lock()
+		defer instances.mu.Unlock()
+		for volumeID := range instances.attachments["instance-1"] {
+			instances.attachments["instance-1"][volumeID] = StatusAttached
+		}
+	}()
+
+	var wg sync.WaitGroup
+	responses := make([]*provider.VolumeAttachmentResponse, waits)
+	errs := make([]error, waits)
+	for i := 0; i < waits; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			responses[i], errs[i] = vpcs.WaitForAttachVolume(provider.VolumeAttachmentRequest{VolumeID: fmt.Sprintf("volume-%d", i), InstanceID: "instance-1"})
+		}(i)
+	}
+	wg.Wait()
+
+	for i := 0; i < waits; i++ {
+		assert.Nil(t, errs[i])
+		if assert.NotNil(t, responses[i]) {
+			assert.Equal(t, StatusAttached, responses[i].Status)
+			assert.Equal(t, fmt.Sprintf("volume-%d", i), responses[i].VolumeID)
+			assert.Equal(t, "instance-1", responses[i].InstanceID)
+		}
+	}
+	// One list call per poll, not per waiter
+	assert.Less(t, volumeAttachService.ListVolumeAttachmentsCallCount(), waits)
+
+	// Poller stops once there are no more waiters
+	vpcs.poller.mu.Lock()
+	assert.False(t, vpcs.poller.running)
+	vpcs.poller.mu.Unlock()
+}
+
+func TestVolumePollerWaitForAttachment(t *testing.T) {
+	userError.MessagesEn = userError.InitMessages()
+	logger, teardown := getTestSyncLogger(t)
+	defer teardown()
+
+	request := provider.VolumeAttachmentRequest{VolumeID: "volume-1", InstanceID: "instance-1"}
+	testCases := []struct {
+		testCaseName string
+		attach       bool
+		attachments  []models.VolumeAttachment
+		listErr      error
+
+		expectedErrCode string
+	}{
+		{
+			testCaseName:    "Attachment does not exist",
+			attach:          true,
+			expectedErrCode: userError.VolumeAttachTimedOut,
+		}, {
+			testCaseName:    "Attachment is never attached",
+			attach:          true,
+			attachments:     []models.VolumeAttachment{{ID: "attachment-1", Status: StatusAttaching, Volume: &models.Volume{ID: "volume-1"}}},
+			expectedErrCode: userError.VolumeAttachTimedOut,
+		}, {
+			testCaseName:    "Instance not found while waiting for attach",
+			attach:          true,
+			listErr:         &models.Error{Errors: []models.ErrorItem{{Code: "not_found"}}},
+			expectedErrCode: userError.VolumeAttachFindFailed,
+		}, {
+			testCaseName: "Attachment is removed",
+			attachments:  []models.VolumeAttachment{{ID: "attachment-2", Status: StatusAttached, Volume: &models.Volume{ID: "volume-2"}}},
+		}, {
+			testCaseName: "Instance not found while waiting for detach",
+			listErr:      &models.Error{Errors: []models.ErrorItem{{Code: "not_found"}}},
+		}, {
+			testCaseName:    "Token rejected while waiting for detach",
+			listErr:         &models.Error{Errors: []models.ErrorItem{{Code: "token_invalid"}}, ResponseMetadata: models.ResponseMetadata{StatusCode: http.StatusUnauthorized}},
+			expectedErrCode: userError.VolumeDetachTimedOut,
+		}, {
+			testCaseName:    "Endpoint unavailable while waiting for detach",
+			listErr:         &models.EndpointUnavailableError{BaseURL: "https://us-south.iaas.cloud.ibm.com"},
+			expectedErrCode: userError.EndpointUnavailable,
+		}, {
+			testCaseName:    "Attachment is never removed",
+			attachments:     []models.VolumeAttachment{{ID: "attachment-1", Status: StatusDetaching, Volume: &models.Volume{ID: "volume-1"}}},
+			expectedErrCode: userError.VolumeDetachTimedOut,
+		}, {
+			testCaseName:    "Attachment list keeps failing",
+			listErr:         &models.Error{Errors: []models.ErrorItem{{Code: "internal_error"}}},
+			expectedErrCode: userError.VolumeDetachTimedOut,
+		},
+	}
+
+	for _, testcase := range testCases {
+		t.Run(testcase.testCaseName, func(t *testing.T) {
+			vpcs, _, _, err := GetTestOpenSession(t, logger)
+			assert.Nil(t, err)
+			vpcs.poller = NewVolumePoller(VolumePollerConfig{Interval: 10 * time.Millisecond, Timeout: 50 * time.Millisecond})
+
+			volumeAttachService := &volumeAttachServiceFakes.VolumeAttachService{}
+			vpcs.APIClientVolAttachMgr = volumeAttachService
+			volumeAttachService.ListVolumeAttachmentsReturns(&models.VolumeAttachmentList{VolumeAttachments: testcase.attachments}, testcase.listErr)
+
+			if testcase.attach {
+				response, err := vpcs.WaitForAttachVolume(request)
+				assert.Nil(t, response)
+				assert.Equal(t, testcase.expectedErrCode, userError.GetUserErrorCode(err))
+			} else {
+				err = vpcs.WaitForDetachVolume(request)
+				if testcase.expectedErrCode == "" {
+					assert.Nil(t, err)
+				} else {
+					assert.Equal(t, testcase.expectedErrCode, userError.GetUserErrorCode(err))
+				}
+			}
+		})
+	}
+}
+
+func TestVolumePollerWaitForValidVolumeState(t *testing.T) {
+	userError.MessagesEn = userError.InitMessages()
+	logger, teardown := getTestSyncLogger(t)
+	defer teardown()
+
+	vpcs, uc, _, err := GetTestOpenSession(t, logger)
+	assert.Nil(t, err)
+	vpcs.poller = NewVolumePoller(VolumePollerConfig{Interval: 20 * time.Millisecond, Timeout: time.Minute})
+
+	volumeService := &volumeServiceFakes.VolumeService{}
+	uc.VolumeServiceReturns(volumeService)
+	volumeService.GetVolumeStub = func(volumeID string, logger *zap.Logger) (*models.Volume, error) {
+		status := "pending"
+		if volumeService.GetVolumeCallCount() > 2 {
+			status = validVolumeStatus
+		}
+		return &models.Volume{ID: volumeID, Status: models.StatusType(status), SourceSnapshot: &models.Snapshot{ID: "snapshot-1"}}, nil
+	}
+
+	const waits = 10
+	var wg sync.WaitGroup
+	volumes := make([]*models.Volume, waits)
+	errs := make([]error, waits)
+	for i := 0; i < waits; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			volumes[i] = &models.Volume{ID: "volume-1"}
+			errs[i] = WaitForValidVolumeState(vpcs, volumes[i])
+		}(i)
+	}
+	wg.Wait()
+
+	for i := 0; i < waits; i++ {
+		assert.Nil(t, errs[i])
+		assert.Equal(t, "snapshot-1", volumes[i].SourceSnapshot.ID)
+	}
+	// One get call per poll, not per waiter
+	assert.Less(t, volumeService.GetVolumeCallCount(), waits)
+}
+
+func TestVolumePollerAttachmentListedLate(t *testing.T) {
+	userError.MessagesEn = userError.InitMessages()
+	logger, teardown := getTestSyncLogger(t)
+	defer teardown()
+
+	vpcs, _, _, err := GetTestOpenSession(t, logger)
+	assert.Nil(t, err)
+	vpcs.poller = NewVolumePoller(VolumePollerConfig{Interval: 10 * time.Millisecond, Timeout: time.Minute})
+
+	volumeAttachService := &volumeAttachServiceFakes.VolumeAttachService{}
+	vpcs.APIClientVolAttachMgr = volumeAttachService
+	instances := newFakeInstances()
+	instances.install(volumeAttachService)
+	// Attachment shows up after a few polls only
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		instances.mu.Lock()
+		defer instances.mu.Unlock()
+		instances.attachments["instance-1"] = map[string]string{"volume-1": StatusAttached}
+	}()
+
+	response, err := vpcs.WaitForAttachVolume(provider.VolumeAttachmentRequest{VolumeID: "volume-1", InstanceID: "instance-1"})
+	assert.Nil(t, err)
+	if assert.NotNil(t, response) {
+		assert.Equal(t, StatusAttached, response.Status)
+	}
+}
+
+func TestVolumePollerAccounts(t *testing.T) {
+	userError.MessagesEn = userError.InitMessages()
+	logger, teardown := getTestSyncLogger(t)
+	defer teardown()
+
+	poller := NewVolumePoller(VolumePollerConfig{Interval: 10 * time.Millisecond, Timeout: time.Minute})
+	var wg sync.WaitGroup
+	services := map[string]*volumeAttachServiceFakes.VolumeAttachService{}
+	// Sessions whose account is unknown are never coalesced, whatever their credentials
+	for i, accountID := range []string{"account-1", "account-2", "", ""} {
+		session := fmt.Sprintf("session-%d", i)
+		vpcs, _, _, err := GetTestOpenSession(t, logger)
+		assert.Nil(t, err)
+		vpcs.VPCAccountID = accountID
+		vpcs.poller = poller
+
+		// Same instance ID, but each session only sees its own attachments
+		volumeAttachService := &volumeAttachServiceFakes.VolumeAttachService{}
+		vpcs.APIClientVolAttachMgr = volumeAttachService
+		instances := newFakeInstances()
+		instances.attachments["instance-1"] = map[string]string{"volume-" + session: StatusAttached}
+		instances.install(volumeAttachService)
+		services[session] = volumeAttachService
+
+		wg.Add(1)
+		go func(vpcs *VPCSession, volumeID string) {
+			defer wg.Done()
+			response, err := vpcs.WaitForAttachVolume(provider.VolumeAttachmentRequest{VolumeID: volumeID, InstanceID: "instance-1"})
+			assert.Nil(t, err)
+			assert.NotNil(t, response)
+		}(vpcs, "volume-"+session)
+	}
+	wg.Wait()
+
+	// Each account, and each session of an unknown account, is polled with its own session
+	for session, volumeAttachService := range services {
+		assert.NotZero(t, volumeAttachService.ListVolumeAttachmentsCallCount(), session)
+	}
+}
+
+func TestVolumePollerCancel(t *testing.T) {
+	userError.MessagesEn = userError.InitMessages()
+	logger, teardown := getTestSyncLogger(t)
+	defer teardown()
+
+	vpcs, uc, _, err := GetTestOpenSession(t, logger)
+	assert.Nil(t, err)
+	vpcs.poller = NewVolumePoller(VolumePollerConfig{Interval: 10 * time.Millisecond, Timeout: time.Minute})
+	ctx, cancel := context.WithCancel(context.Background())
+	vpcs.ctx = ctx
+
+	volumeAttachService := &volumeAttachServiceFakes.VolumeAttachService{}
+	vpcs.APIClientVolAttachMgr = volumeAttachService
+	volumeAttachService.ListVolumeAttachmentsReturns(&models.VolumeAttachmentList{}, nil)
+	volumeService := &volumeServiceFakes.VolumeService{}
+	uc.VolumeServiceReturns(volumeService)
+	volumeService.GetVolumeReturns(&models.Volume{ID: "volume-1", Status: models.StatusType("pending")}, nil)
+
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		cancel()
+	}()
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		response, err := vpcs.WaitForAttachVolume(provider.VolumeAttachmentRequest{VolumeID: "volume-1", InstanceID: "instance-1"})
+		assert.Nil(t, response)
+		assert.Equal(t, userError.VolumeAttachTimedOut, userError.GetUserErrorCode(err))
+	}()
+	go func() {
+		defer wg.Done()
+		err := WaitForValidVolumeState(vpcs, &models.Volume{ID: "volume-1"})
+		assert.Equal(t, "VolumeNotInValidState", userError.GetUserErrorCode(err))
+	}()
+	wg.Wait()
+
+	// Cancelled waiters are not polled any more
+	vpcs.poller.mu.Lock()
+	assert.Empty(t, vpcs.poller.instances)
+	assert.Empty(t, vpcs.poller.volumes)
+	vpcs.poller.mu.Unlock()
+}
+
+func TestVolumePollerBudget(t *testing.T) {
+	// Default budget of FlexyRetryWithCustomGap, 3 times 3 sec + 6 times 6 sec + 37 times 10 sec
+	fRetry := NewFlexyRetryDefault()
+	assert.Equal(t, 415*time.Second, fRetry.customGapBudget())
+
+	defer func(attempts, gap int) { maxRetryAttempt, maxRetryGap = attempts, gap }(maxRetryAttempt, maxRetryGap)
+	maxRetryAttempt, maxRetryGap = 4, 30
+	assert.Equal(t, (10+20+30)*time.Second, retryBudget())
+
+	// Waits without timeout get the retry budget of the session
+	poller := NewVolumePoller(VolumePollerConfig{})
+	deadline := poller.deadlineLocked(fRetry.customGapBudget())
+	assert.WithinDuration(t, time.Now().Add(415*time.Second), deadline, time.Second)
+	poller.configure(VolumePollerConfig{Timeout: time.Minute})
+	deadline = poller.deadlineLocked(fRetry.customGapBudget())
+	assert.WithinDuration(t, time.Now().Add(time.Minute), deadline, time.Second)
+	assert.Equal(t, DefaultPollInterval, poller.interval())
+}
+
+func TestOpenSessionVolumePoller(t *testing.T) {
+	logger, teardown := GetTestLogger(t)
+	defer teardown()
+
+	vpcp, err := GetTestProvider(t, logger)
+	assert.Nil(t, err)
+	// Same as NewProvider
+	vpcp.poller = NewVolumePoller(VolumePollerConfig{})
+	cp := &fakes.RegionalAPIClientProvider{}
+	cp.NewReturns(&fakes.RegionalAPI{}, nil)
+	vpcp.ClientProvider = cp
+
+	// Poller is disabled by default
+	sessn, err := vpcp.OpenSession(context.Background(), provider.ContextCredentials{AuthType: provider.IAMAccessToken, Credential: TestProviderAccessToken}, logger)
+	assert.Nil(t, err)
+	assert.Nil(t, sessn.(*VPCSession).poller)
+
+	vpcp.VolumePoller = &VolumePollerConfig{Interval: time.Second}
+	sessn, err = vpcp.OpenSession(context.Background(), provider.ContextCredentials{AuthType: provider.IAMAccessToken, Credential: TestProviderAccessToken}, logger)
+	assert.Nil(t, err)
+	assert.Equal(t, vpcp.poller, sessn.(*VPCSession).poller)
+	assert.Equal(t, time.Second, vpcp.poller.interval())
+}
@@ -23,15 +23,20 @@ import (
 	"github.com/IBM/ibmcloud-volume-interface/lib/metrics"
 	"github.com/IBM/ibmcloud-volume-interface/lib/provider"
 	userError "github.com/IBM/ibmcloud-volume-vpc/common/messages"
+	"github.com/IBM/ibmcloud-volume-vpc/common/vpcmetrics"
 	"go.uber.org/zap"
 )
 
 // WaitForAttachVolume waits for volume to be attached to node. e.g waits till status becomes attached
-func (vpcs *VPCSession) WaitForAttachVolume(volumeAttachmentTemplate provider.VolumeAttachmentRequest) (*provider.VolumeAttachmentResponse, error) {
+func (vpcs *VPCSession) WaitForAttachVolume(volumeAttachmentTemplate provider.VolumeAttachmentRequest) (_ *provider.VolumeAttachmentResponse, err error) {
 	vpcs.Logger.Debug("Entry of WaitForAttachVolume method...")
 	defer vpcs.Logger.Debug("Exit from WaitForAttachVolume method...")
+	vpcs, end := vpcs.trace("WaitForAttachVolume", attachmentAttributes(volumeAttachmentTemplate)...)
+	defer func() { end(err) }()
 	defer metrics.UpdateDurationFromStart(vpcs.Logger, "WaitForAttachVolume", time.Now())
-	var err error
+	defer func(start time.Time) { vpcmetrics.Default().ObserveWait(vpcmetrics.WaitAttach, start, err) }(time.Now())
+	// Lease taken by the attach or detach of the volume ends with the wait
+	defer vpcs.ReleaseVolumeLease(volumeAttachmentTemplate.VolumeID)
 
 	//check if ServiceSession is valid
 	if err = isValidServiceSession(vpcs); err != nil {
@@ -44,6 +49,11 @@ func (vpcs *VPCSession) WaitForAttachVolume(volumeAttachmentTemplate provider.Vo
 		return nil, err
 	}
 
+	// Shared poller lists the attachments of the instance once for all its waiters
+	if vpcs.poller != nil {
+		return vpcs.poller.waitForAttachment(vpcs, volumeAttachmentTemplate, true)
+	}
+
 	var currentVolAttachment *provider.VolumeAttachmentResponse
 	err = vpcs.APIRetry.FlexyRetryWithCustomGap(vpcs.Logger, func() (error, bool) {
 		currentVolAttachment, err = vpcs.GetVolumeAttachment(volumeAttachmentTemplate)
@@ -24,15 +24,20 @@ import (
 	"github.com/IBM/ibmcloud-volume-interface/lib/provider"
 	util "github.com/IBM/ibmcloud-volume-interface/lib/utils"
 	userError "github.com/IBM/ibmcloud-volume-vpc/common/messages"
+	"github.com/IBM/ibmcloud-volume-vpc/common/vpcmetrics"
 	"go.uber.org/zap"
 )
 
 // WaitForDetachVolume waits for volume to be detached from node. e.g waits till no volume attachment is found
-func (vpcs *VPCSession) WaitForDetachVolume(volumeAttachmentTemplate provider.VolumeAttachmentRequest) error {
+func (vpcs *VPCSession) WaitForDetachVolume(volumeAttachmentTemplate provider.VolumeAttachmentRequest) (err error) {
 	vpcs.Logger.Debug("Entry of WaitForDetachVolume method...")
 	defer vpcs.Logger.Debug("Exit from WaitForDetachVolume method...")
+	vpcs, end := vpcs.trace("WaitForDetachVolume", attachmentAttributes(volumeAttachmentTemplate)...)
+	defer func() { end(err) }()
 	defer metrics.UpdateDurationFromStart(vpcs.Logger, "WaitForDetachVolume", time.Now())
-	var err error
+	defer func(start time.Time) { vpcmetrics.Default().ObserveWait(vpcmetrics.WaitDetach, start, err) }(time.Now())
+	// Lease taken by the attach or detach of the volume ends with the wait
+	defer vpcs.ReleaseVolumeLease(volumeAttachmentTemplate.VolumeID)
 
 	//check if ServiceSession is valid
 	if err = isValidServiceSession(vpcs); err != nil {
@@ -45,6 +50,12 @@ func (vpcs *VPCSession) WaitForDetachVolume(volumeAttachmentTemplate provider.Vo
 		return err
 	}
 
+	// Shared poller lists the attachments of the instance once for all its waiters
+	if vpcs.poller != nil {
+		_, err = vpcs.poller.waitForAttachment(vpcs, volumeAttachmentTemplate, false)
+		return err
+	}
+
 	err = vpcs.APIRetry.FlexyRetryWithCustomGap(vpcs.Logger, func() (error, bool) {
 		_, err := vpcs.GetVolumeAttachment(volumeAttachmentTemplate)
 		// In case of error we should not retry as there are two conditions for error
@@ -66,7 +77,7 @@ func (vpcs *VPCSession) WaitForDetachVolume(volumeAttachmentTemplate provider.Vo
 		}
 	}
 
-	userErr := userError.GetUserError(string(userError.VolumeDetachTimedOut), err, volumeAttachmentTemplate.VolumeID, volumeAttachmentTemplate.InstanceID)
+	userErr := GetBackendUserError(string(userError.VolumeDetachTimedOut), err, volumeAttachmentTemplate.VolumeID, volumeAttachmentTemplate.InstanceID)
 	vpcs.Logger.Info("Wait for detach timed out", zap.Error(userErr))
 	return userErr
 }
@@ -21,8 +21,9 @@ import (
 	"time"
 
 	"github.com/IBM/ibmcloud-volume-interface/lib/metrics"
-	userError "github.com/IBM/ibmcloud-volume-vpc/common/messages"
 	"github.com/IBM/ibmcloud-volume-vpc/common/vpcclient/models"
+	"github.com/IBM/ibmcloud-volume-vpc/common/vpcmetrics"
+	"github.com/IBM/ibmcloud-volume-vpc/common/vpctracing"
 	"go.uber.org/zap"
 )
 
@@ -34,16 +35,34 @@ const (
 func WaitForValidVolumeState(vpcs *VPCSession, volumeObj *models.Volume) (err error) {
 	vpcs.Logger.Debug("Entry of WaitForValidVolumeState method...")
 	defer vpcs.Logger.Debug("Exit from WaitForValidVolumeState method...")
+	vpcs, end := vpcs.trace("WaitForValidVolumeState")
+	defer func() { end(err) }()
 	defer metrics.UpdateDurationFromStart(vpcs.Logger, "WaitForValidVolumeState", time.Now())
+	defer func(start time.Time) { vpcmetrics.Default().ObserveWait(vpcmetrics.WaitVolumeReady, start, err) }(time.Now())
 
 	var volumeID string
 	var volume *models.Volume
 
 	if volumeObj != nil {
 		volumeID = volumeObj.ID
+		vpctracing.SetAttributes(vpcs.tracing, vpctracing.VolumeIDKey.String(volumeID))
 		vpcs.Logger.Info("Getting volume details from VPC provider...", zap.Reflect("VolumeID", volumeID))
 	}
-	err = retry(vpcs.Logger, func() error {
+	// Shared poller gets the volume once for all its waiters
+	if vpcs.poller != nil {
+		volume, err = vpcs.poller.waitForVolume(vpcs, volumeID)
+		if err != nil {
+			vpcs.Logger.Info("Volume could not get valid (available) state", zap.String("volumeID", volumeID), zap.Error(err))
+			return GetBackendUserError("VolumeNotInValidState", err, volumeID)
+		}
+		vpcs.Logger.Info("Volume got valid (available) state", zap.Reflect("VolumeDetails", volume))
+		if volumeObj != nil && volume.SourceSnapshot != nil {
+			volumeObj.SourceSnapshot = volume.SourceSnapshot
+		}
+		return nil
+	}
+
+	err = tracedRetry(vpcs.tracing, vpcs.Logger, func() error {
 		volume, err = vpcs.Apiclient.VolumeService().GetVolume(volumeID, vpcs.Logger)
 		if err != nil {
 			return err
@@ -56,12 +75,12 @@ func WaitForValidVolumeState(vpcs *VPCSession, volumeObj *models.Volume) (err er
 			}
 			return nil
 		}
-		return userError.GetUserError("VolumeNotInValidState", err, volumeID)
+		return GetBackendUserError("VolumeNotInValidState", err, volumeID)
 	})
 
 	if err != nil {
 		vpcs.Logger.Info("Volume could not get valid (available) state", zap.Reflect("VolumeDetails", volume))
-		return userError.GetUserError("VolumeNotInValidState", err, volumeID)
+		return GetBackendUserError("VolumeNotInValidState", err, volumeID)
 	}
 
 	return nil
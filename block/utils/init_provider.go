@@ -19,6 +19,7 @@ package utils
 
 import (
 	"errors"
+	"strings"
 
 	"github.com/IBM/ibmcloud-volume-interface/lib/provider"
 	util "github.com/IBM/ibmcloud-volume-interface/lib/utils"
@@ -47,6 +48,13 @@ func InitProviders(conf *vpcconfig.VPCBlockConfig, k8sClient *k8s_utils.Kubernet
 		}
 		providerRegistry.Register(conf.VPCConfig.VPCBlockProviderName, prov)
 		haveProviders = true
+
+		// Providers of the accounts of the multi-account mode share the HTTP transport of the VPC provider
+		if vpcProvider, ok := prov.(*vpc_provider.VPCBlockProvider); ok {
+			if err = vpcProvider.RegisterAccountProviders(providerRegistry, conf.VPCConfig.VPCBlockProviderName, logger); err != nil {
+				return nil, err
+			}
+		}
 	}
 
 	// IKS provider registration
@@ -83,7 +91,7 @@ func OpenProviderSessionWithContext(ctx context.Context, prov local.Provider, vp
 		return nil, true, err
 	}
 	ctxLogger.Info("Calling provider/utils/init_provider.go GenerateContextCredentials")
-	contextCredentials, err := GenerateContextCredentials(vpcBlockConfig, providerID, ccf, ctxLogger)
+	contextCredentials, err := GenerateContextCredentials(currentConfig(prov, vpcBlockConfig), providerID, ccf, ctxLogger)
 	if err != nil {
 		ctxLogger.Error("Unable to generate credentials", local.ZapError(err))
 		return nil, true, err
@@ -99,6 +107,31 @@ func OpenProviderSessionWithContext(ctx context.Context, prov local.Provider, vp
 	return session, false, nil
 }
 
+// apiKeyProvider is a provider whose API key can be rotated without restart, see VPCBlockProvider.ReloadCredentials
+type apiKeyProvider interface {
+	APIKey() string
+}
+
+var _ apiKeyProvider = &vpc_provider.VPCBlockProvider{}
+
+// currentConfig returns the config with the current API key of the provider, which replaces the one of the config
+// once rotated. The config is shared, a copy is returned.
+func currentConfig(prov local.Provider, conf *vpcconfig.VPCBlockConfig) *vpcconfig.VPCBlockConfig {
+	keyProvider, ok := prov.(apiKeyProvider)
+	if !ok || conf.VPCConfig == nil {
+		return conf
+	}
+	apiKey := keyProvider.APIKey()
+	if apiKey == "" || apiKey == conf.VPCConfig.G2APIKey {
+		return conf
+	}
+	current := *conf
+	vpcConfig := *conf.VPCConfig
+	vpcConfig.G2APIKey = apiKey
+	current.VPCConfig = &vpcConfig
+	return &current
+}
+
 // GenerateContextCredentials ...
 func GenerateContextCredentials(conf *vpcconfig.VPCBlockConfig, providerID string, contextCredentialsFactory local.ContextCredentialsFactory, ctxLogger *zap.Logger) (provider.ContextCredentials, error) {
 	ctxLogger.Info("Generating generateContextCredentials for ", zap.String("Provider ID", providerID))
@@ -109,6 +142,9 @@ func GenerateContextCredentials(conf *vpcconfig.VPCBlockConfig, providerID strin
 		ctxLogger.Info("Calling provider/init_provider.go ForIAMAccessToken")
 		return contextCredentialsFactory.ForIAMAccessToken(conf.VPCConfig.G2APIKey, ctxLogger)
 
+	case (conf.VPCConfig != nil && strings.HasPrefix(providerID, vpc_provider.AccountProviderID(conf.VPCConfig.VPCBlockProviderName, ""))):
+		return provider.ContextCredentials{}, nil // Account providers get the credentials of their account in OpenSession
+
 	case (conf.IKSConfig != nil && providerID == conf.IKSConfig.IKSBlockProviderName):
 		return provider.ContextCredentials{}, nil // Get credentials  in OpenSession method
 
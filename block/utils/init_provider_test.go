@@ -0,0 +1,73 @@
+/**
+ * Copyright 2020 IBM Corp.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package utils ...
+package utils
+
+import (
+	"testing"
+
+	"github.com/IBM/ibmcloud-volume-interface/config"
+	"github.com/IBM/ibmcloud-volume-interface/lib/provider"
+	localFakes "github.com/IBM/ibmcloud-volume-interface/provider/local/fakes"
+	vpcconfig "github.com/IBM/ibmcloud-volume-vpc/block/vpcconfig"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"golang.org/x/net/context"
+)
+
+// rotatingProvider is a provider whose API key is rotated like the one of VPCBlockProvider.ReloadCredentials
+type rotatingProvider struct {
+	*localFakes.Provider
+	apiKey string
+}
+
+func (p *rotatingProvider) APIKey() string {
+	return p.apiKey
+}
+
+func TestOpenProviderSessionRotatedAPIKey(t *testing.T) {
+	logger := zap.NewNop()
+	conf := &vpcconfig.VPCBlockConfig{
+		VPCConfig: &config.VPCProviderConfig{
+			VPCBlockProviderName: "vpc-classic",
+			G2APIKey:             "startup-key",
+		},
+	}
+	ccf := &localFakes.ContextCredentialsFactory{}
+	ccf.ForIAMAccessTokenReturns(provider.ContextCredentials{AuthType: provider.IAMAccessToken, Credential: "token"}, nil)
+	prov := &rotatingProvider{Provider: &localFakes.Provider{}, apiKey: "startup-key"}
+	prov.ContextCredentialsFactoryReturns(ccf, nil)
+
+	// Sessions opened before the rotation exchange the API key of the config
+	_, fatal, err := OpenProviderSessionWithContext(context.TODO(), prov, conf, "vpc-classic", logger)
+	assert.Nil(t, err)
+	assert.False(t, fatal)
+	apiKey, _ := ccf.ForIAMAccessTokenArgsForCall(0)
+	assert.Equal(t, "startup-key", apiKey)
+
+	// Sessions opened after the rotation exchange the current API key of the provider
+	prov.apiKey = "rotated-key"
+	_, _, err = OpenProviderSession(prov, conf, nil, "vpc-classic", logger)
+	assert.Nil(t, err)
+	apiKey, _ = ccf.ForIAMAccessTokenArgsForCall(1)
+	assert.Equal(t, "rotated-key", apiKey)
+	_, credentials, _ := prov.OpenSessionArgsForCall(1)
+	assert.Equal(t, "token", credentials.Credential)
+
+	// Config shared by the callers is not modified
+	assert.Equal(t, "startup-key", conf.VPCConfig.G2APIKey)
+}
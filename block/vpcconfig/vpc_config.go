@@ -18,6 +18,8 @@
 package utils
 
 import (
+	"time"
+
 	"github.com/IBM/ibmcloud-volume-interface/config"
 )
 
@@ -27,4 +29,77 @@ type VPCBlockConfig struct {
 	IKSConfig    *config.IKSConfig
 	APIConfig    *config.APIConfig
 	ServerConfig *config.ServerConfig
+
+	// TrustedProfile authenticates with an IAM trusted profile instead of the API key, if set
+	TrustedProfile *TrustedProfileConfig
+
+	// TokenKeys is the key directory of the service token generator, the etc directory of the sources if nil
+	TokenKeys *TokenKeysConfig
+
+	// AccountID is the account the access tokens of the sessions must belong to, not verified if empty
+	AccountID string
+
+	// Accounts are the credentials of the tenant accounts of the multi-account mode, the sessions of a request
+	// naming an account or resource group use its credentials
+	Accounts []AccountConfig
+
+	// ResourceManagerURL is the Resource Manager endpoint resolving the resource group names, the public one if
+	// empty. It can be the address of a resourcemanager.Emulator to resolve the names locally.
+	ResourceManagerURL string
+
+	// KeyManagementURL is the Key Protect endpoint checking that the encryption keys of the volumes are enabled
+	// before the volumes are created, as a template whose {region} and {instance} are replaced with the region and
+	// the instance of each key CRN, e.g. keymanagement.KeyProtectURL. Only the CRN of the keys is checked if it is
+	// empty and there is no KeyManagementInstanceURLs. It can be the address of a keymanagement.Emulator to check
+	// the keys locally.
+	KeyManagementURL string
+
+	// KeyManagementInstanceURLs is the endpoint of the key management instances which have their own, e.g. HPCS,
+	// by instance ID. They take precedence over KeyManagementURL.
+	KeyManagementInstanceURLs map[string]string
+
+	// KeyManagementPolicyURL is the IAM policy management endpoint checking that Block Storage is authorized to use
+	// the keys of the instance of the encryption keys, e.g. keymanagement.IAMPolicyURL. The authorization is not
+	// checked if it is empty, or if the keys are not checked. It can be the address of a keymanagement.Emulator.
+	KeyManagementPolicyURL string
+}
+
+// AccountConfig is the credentials of a tenant account, or of a resource group of the account
+type AccountConfig struct {
+	// AccountID is the account of the API key, the access tokens of its sessions must belong to it
+	AccountID string
+
+	// ResourceGroupID is the resource group of the volumes of the credentials, the one of the VPC config if empty
+	ResourceGroupID string
+
+	// APIKey is the API key exchanged for the access tokens of the sessions
+	APIKey string
+}
+
+// TrustedProfileConfig authenticates the VPC and IKS token exchanges with an IAM trusted profile, so that no API key
+// has to be kept in the cluster secrets
+type TrustedProfileConfig struct {
+	// ProfileID is the ID of the trusted profile
+	ProfileID string
+
+	// ComputeResourceTokenFile is the path of the projected compute resource token exchanged for the access token.
+	// The instance metadata identity token is exchanged when empty.
+	ComputeResourceTokenFile string
+
+	// MetadataURL is the endpoint of the instance metadata service, the link-local default if empty
+	MetadataURL string
+}
+
+// TokenKeysConfig locates the RSA keys signing the service tokens. Each PEM file of the directory is a key, its
+// kid is the file name without the .pem extension.
+type TokenKeysConfig struct {
+	// Dir is the directory of the keys, usually a mounted secret
+	Dir string
+
+	// ActiveKID is the kid of the key signing the tokens. It is read from the file named active in Dir when empty,
+	// so that the signing key can be rotated along with the keys.
+	ActiveKID string
+
+	// CheckInterval is the interval of the checks for changed keys, 30 seconds if zero
+	CheckInterval time.Duration
 }
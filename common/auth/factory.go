@@ -18,6 +18,8 @@
 package auth
 
 import (
+	"net/http"
+
 	"github.com/IBM/ibmcloud-volume-interface/provider/auth"
 	"github.com/IBM/ibmcloud-volume-interface/provider/iam"
 	vpcconfig "github.com/IBM/ibmcloud-volume-vpc/block/vpcconfig"
@@ -25,8 +27,25 @@ import (
 	"github.com/IBM/secret-utils-lib/pkg/k8s_utils"
 )
 
-// NewVPCContextCredentialsFactory ...
-func NewVPCContextCredentialsFactory(config *vpcconfig.VPCBlockConfig, k8sClient *k8s_utils.KubernetesClient) (*auth.ContextCredentialsFactory, error) {
+// NewVPCContextCredentialsFactory returns the factory of the config, the trusted profile exchanges use the HTTP client
+// of the provider
+func NewVPCContextCredentialsFactory(config *vpcconfig.VPCBlockConfig, k8sClient *k8s_utils.KubernetesClient, httpClient *http.Client) (*auth.ContextCredentialsFactory, error) {
+	if config.TrustedProfile != nil {
+		// Private clusters keep the IKS private route, the secret provider exchanges the trusted profile of the
+		// storage secret with the private IAM endpoint
+		if config.VPCConfig.IKSTokenExchangePrivateURL != "" {
+			iksService, err := newIKSTokenExchangeService(config, k8sClient)
+			if err != nil {
+				return nil, err
+			}
+			return &auth.ContextCredentialsFactory{TokenExchangeService: vpciam.WithTokenExchangeMetrics(iksService)}, nil
+		}
+		// The trusted profile replaces the API key exchange
+		trustedProfileService := vpciam.NewTokenExchangeTrustedProfileService(NewTrustedProfileConfiguration(config), httpClient)
+		return &auth.ContextCredentialsFactory{
+			TokenExchangeService: vpciam.WithTokenExchangeMetrics(trustedProfileService),
+		}, nil
+	}
 	authConfig := &iam.AuthConfiguration{
 		IamURL:          config.VPCConfig.G2TokenExchangeURL,
 		IamClientID:     config.VPCConfig.IamClientID,
@@ -37,15 +56,47 @@ func NewVPCContextCredentialsFactory(config *vpcconfig.VPCBlockConfig, k8sClient
 		return nil, err
 	}
 	if config.VPCConfig.IKSTokenExchangePrivateURL != "" {
-		authIKSConfig := &vpciam.IksAuthConfiguration{
-			IamAPIKey:       config.VPCConfig.G2APIKey,
-			PrivateAPIRoute: config.VPCConfig.IKSTokenExchangePrivateURL, // Only for private cluster
-			CSRFToken:       config.APIConfig.PassthroughSecret,          // required for private cluster
-		}
-		ccf.TokenExchangeService, err = vpciam.NewTokenExchangeIKSService(authIKSConfig, k8sClient)
+		ccf.TokenExchangeService, err = newIKSTokenExchangeService(config, k8sClient)
 		if err != nil {
 			return nil, err
 		}
 	}
+	ccf.TokenExchangeService = vpciam.WithTokenExchangeMetrics(ccf.TokenExchangeService)
 	return ccf, nil
 }
+
+// newIKSTokenExchangeService returns the token exchange of private clusters, through the IKS private route
+func newIKSTokenExchangeService(config *vpcconfig.VPCBlockConfig, k8sClient *k8s_utils.KubernetesClient) (iam.TokenExchangeService, error) {
+	authIKSConfig := &vpciam.IksAuthConfiguration{
+		IamAPIKey:       config.VPCConfig.G2APIKey,
+		PrivateAPIRoute: config.VPCConfig.IKSTokenExchangePrivateURL, // Only for private cluster
+		CSRFToken:       config.APIConfig.PassthroughSecret,          // required for private cluster
+		IamURL:          config.VPCConfig.G2TokenExchangeURL,
+		IamClientID:     config.VPCConfig.IamClientID,
+		IamClientSecret: config.VPCConfig.IamClientSecret,
+	}
+	return vpciam.NewTokenExchangeIKSService(authIKSConfig, k8sClient)
+}
+
+// NewAPIKeyContextCredentialsFactory returns a factory exchanging the API key it is given with IAM, for the
+// accounts of the multi-account mode. The factories of the accounts share the HTTP client, not their tokens.
+func NewAPIKeyContextCredentialsFactory(config *vpcconfig.VPCBlockConfig, httpClient *http.Client) *auth.ContextCredentialsFactory {
+	authConfig := &iam.AuthConfiguration{
+		IamURL:          config.VPCConfig.G2TokenExchangeURL,
+		IamClientID:     config.VPCConfig.IamClientID,
+		IamClientSecret: config.VPCConfig.IamClientSecret,
+	}
+	return &auth.ContextCredentialsFactory{
+		TokenExchangeService: vpciam.WithTokenExchangeMetrics(vpciam.NewTokenExchangeAPIKeyService(authConfig, httpClient)),
+	}
+}
+
+// NewTrustedProfileConfiguration ...
+func NewTrustedProfileConfiguration(config *vpcconfig.VPCBlockConfig) *vpciam.TrustedProfileConfiguration {
+	return &vpciam.TrustedProfileConfiguration{
+		IamURL:                   config.VPCConfig.G2TokenExchangeURL,
+		ProfileID:                config.TrustedProfile.ProfileID,
+		ComputeResourceTokenFile: config.TrustedProfile.ComputeResourceTokenFile,
+		MetadataURL:              config.TrustedProfile.MetadataURL,
+	}
+}
@@ -18,14 +18,18 @@
 package auth
 
 import (
+	"io/ioutil"
+	"net/http"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 
 	"github.com/IBM/ibmcloud-volume-interface/config"
 	vpcconfig "github.com/IBM/ibmcloud-volume-vpc/block/vpcconfig"
 	"github.com/IBM/secret-utils-lib/pkg/k8s_utils"
 	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
 )
 
 func TestNewContextCredentialsFactory(t *testing.T) {
@@ -47,6 +51,75 @@ func TestNewContextCredentialsFactory(t *testing.T) {
 	pwd, _ := os.Getwd()
 	file := filepath.Join(pwd, "..", "..", "etc", "libconfig.toml")
 	_ = k8s_utils.FakeCreateSecret(kc, "DEFAULT", file)
-	_, err := NewVPCContextCredentialsFactory(conf, &kc)
+	_, err := NewVPCContextCredentialsFactory(conf, &kc, nil)
 	assert.Nil(t, err)
 }
+
+// recordingTransport answers the requests with an access token and records their URL
+type recordingTransport struct {
+	urls []string
+}
+
+func (rt *recordingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	rt.urls = append(rt.urls, req.URL.String())
+	return &http.Response{
+		StatusCode: http.StatusOK,
+		Header:     http.Header{"Content-Type": []string{"application/json"}},
+		Body:       ioutil.NopCloser(strings.NewReader(`{"access_token":"profile-token"}`)),
+		Request:    req,
+	}, nil
+}
+
+func TestNewContextCredentialsFactoryTrustedProfile(t *testing.T) {
+	tokenFile := filepath.Join(t.TempDir(), "vault-token")
+	assert.Nil(t, ioutil.WriteFile(tokenFile, []byte("cr-token"), 0600))
+	conf := &vpcconfig.VPCBlockConfig{
+		VPCConfig: &config.VPCProviderConfig{
+			Enabled:            true,
+			G2TokenExchangeURL: "http://test-iam-url",
+		},
+		APIConfig: &config.APIConfig{},
+		TrustedProfile: &vpcconfig.TrustedProfileConfig{
+			ProfileID:                "profile-1",
+			ComputeResourceTokenFile: tokenFile,
+		},
+	}
+
+	// No secret is needed, the profile replaces the API key, exchanged with the HTTP client of the provider
+	transport := &recordingTransport{}
+	ccf, err := NewVPCContextCredentialsFactory(conf, nil, &http.Client{Transport: transport})
+	assert.Nil(t, err)
+	assert.NotNil(t, ccf.TokenExchangeService)
+	accessToken, err := ccf.TokenExchangeService.ExchangeIAMAPIKeyForAccessToken("", zap.NewNop())
+	assert.Nil(t, err)
+	assert.Equal(t, "profile-token", accessToken.Token)
+	assert.Equal(t, []string{"http://test-iam-url/identity/token"}, transport.urls)
+
+	trustedProfileConfig := NewTrustedProfileConfiguration(conf)
+	assert.Equal(t, "http://test-iam-url", trustedProfileConfig.IamURL)
+	assert.Equal(t, "profile-1", trustedProfileConfig.ProfileID)
+	assert.Equal(t, tokenFile, trustedProfileConfig.ComputeResourceTokenFile)
+
+	// Private clusters keep the IKS private route, whose secret provider exchanges the profile of the secret
+	conf.VPCConfig.IKSTokenExchangePrivateURL = "token-exchange-private-URL"
+	kc, _ := k8s_utils.FakeGetk8sClientSet()
+	_, err = NewVPCContextCredentialsFactory(conf, &kc, &http.Client{Transport: transport})
+	assert.NotNil(t, err)
+	pwd, _ := os.Getwd()
+	_ = k8s_utils.FakeCreateSecret(kc, "DEFAULT", filepath.Join(pwd, "..", "..", "etc", "libconfig.toml"))
+	ccf, err = NewVPCContextCredentialsFactory(conf, &kc, &http.Client{Transport: transport})
+	assert.Nil(t, err)
+	assert.NotNil(t, ccf.TokenExchangeService)
+}
+
+func TestNewAPIKeyContextCredentialsFactory(t *testing.T) {
+	conf := &vpcconfig.VPCBlockConfig{
+		VPCConfig: &config.VPCProviderConfig{
+			G2TokenExchangeURL: "test-iam-url",
+		},
+	}
+
+	// No secret is needed, the API key of the account is exchanged
+	ccf := NewAPIKeyContextCredentialsFactory(conf, nil)
+	assert.NotNil(t, ccf.TokenExchangeService)
+}
@@ -0,0 +1,65 @@
+/**
+ * Copyright 2022 IBM Corp.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package iam ...
+package iam
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"strings"
+	"time"
+)
+
+// AccessTokenClaims are the claims of an IAM access token used by the provider
+type AccessTokenClaims struct {
+	IAMID   string `json:"iam_id"`
+	Exp     int64  `json:"exp"`
+	Account struct {
+		BSS string `json:"bss"`
+	} `json:"account"`
+}
+
+// ParseAccessTokenClaims reads the claims of a JWT access token. The signature is not verified, the token is
+// only trusted as much as the exchange which returned it.
+func ParseAccessTokenClaims(accessToken string) (*AccessTokenClaims, error) {
+	parts := strings.Split(accessToken, ".")
+	if len(parts) != 3 {
+		return nil, errors.New("access token is not a JWT")
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(parts[1], "="))
+	if err != nil {
+		return nil, err
+	}
+	var claims AccessTokenClaims
+	if err = json.Unmarshal(payload, &claims); err != nil {
+		return nil, err
+	}
+	return &claims, nil
+}
+
+// AccessTokenExpiry returns the expiry time of a JWT access token, from its exp claim
+func AccessTokenExpiry(accessToken string) (time.Time, error) {
+	claims, err := ParseAccessTokenClaims(accessToken)
+	if err != nil {
+		return time.Time{}, err
+	}
+	if claims.Exp <= 0 {
+		return time.Time{}, errors.New("access token has no exp claim")
+	}
+	return time.Unix(claims.Exp, 0), nil
+}
@@ -0,0 +1,85 @@
+/**
+ * Copyright 2022 IBM Corp.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package iam ...
+package iam
+
+import (
+	"encoding/base64"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// testJWT returns an unsigned JWT with the payload
+func testJWT(payload string) string {
+	return "eyJhbGciOiJSUzI1NiJ9." + base64.RawURLEncoding.EncodeToString([]byte(payload)) + ".c2lnbmF0dXJl"
+}
+
+func TestAccessTokenExpiry(t *testing.T) {
+	testCases := []struct {
+		name        string
+		token       string
+		expectedExp time.Time
+		expectErr   bool
+	}{
+		{
+			name:        "exp claim",
+			token:       testJWT(`{"iam_id":"test","exp":1700000000}`),
+			expectedExp: time.Unix(1700000000, 0),
+		}, {
+			name:        "padded payload",
+			token:       "eyJhbGciOiJSUzI1NiJ9." + base64.URLEncoding.EncodeToString([]byte(`{"exp":1700000000}`)) + ".c2lnbmF0dXJl",
+			expectedExp: time.Unix(1700000000, 0),
+		}, {
+			name:      "no exp claim",
+			token:     testJWT(`{"iam_id":"test"}`),
+			expectErr: true,
+		}, {
+			name:      "not a JWT",
+			token:     "access-token",
+			expectErr: true,
+		}, {
+			name:      "payload not JSON",
+			token:     testJWT(`not-json`),
+			expectErr: true,
+		},
+	}
+
+	for _, testcase := range testCases {
+		t.Run(testcase.name, func(t *testing.T) {
+			exp, err := AccessTokenExpiry(testcase.token)
+			if testcase.expectErr {
+				assert.NotNil(t, err)
+				return
+			}
+			assert.Nil(t, err)
+			assert.True(t, testcase.expectedExp.Equal(exp))
+		})
+	}
+}
+
+func TestParseAccessTokenClaims(t *testing.T) {
+	claims, err := ParseAccessTokenClaims(testJWT(`{"iam_id":"iam-ServiceId-1","exp":1700000000,"account":{"bss":"account-1"}}`))
+	assert.Nil(t, err)
+	assert.Equal(t, "iam-ServiceId-1", claims.IAMID)
+	assert.Equal(t, "account-1", claims.Account.BSS)
+	assert.Equal(t, int64(1700000000), claims.Exp)
+
+	_, err = ParseAccessTokenClaims("access-token")
+	assert.NotNil(t, err)
+}
@@ -0,0 +1,260 @@
+/**
+ * Copyright 2022 IBM Corp.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package iam ...
+package iam
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/IBM-Cloud/ibm-cloud-cli-sdk/common/rest"
+	"github.com/IBM/ibmcloud-volume-interface/lib/provider"
+	util "github.com/IBM/ibmcloud-volume-interface/lib/utils"
+	"github.com/IBM/ibmcloud-volume-interface/lib/utils/reasoncode"
+	"github.com/IBM/ibmcloud-volume-interface/provider/iam"
+	"go.uber.org/zap"
+)
+
+const (
+	// ErrorUnsupportedTokenExchange is the reason code of the exchanges a token exchange service does not support
+	ErrorUnsupportedTokenExchange reasoncode.ReasonCode = "ErrorUnsupportedTokenExchange"
+
+	// exchangeRetryAttempts is the number of attempts of the exchanges failing with connection errors
+	exchangeRetryAttempts = 40
+
+	// exchangeRetryInterval is the interval between the attempts of the exchanges
+	exchangeRetryInterval = 3 * time.Second
+
+	// tokenCacheExpiryMargin is the time before their expiry when the cached tokens are exchanged again
+	tokenCacheExpiryMargin = time.Minute
+
+	// exchangeCancelledProperty marks the errors of the exchanges which gave up as their context was done
+	exchangeCancelledProperty = "exchangeCancelled"
+)
+
+// NewUnsupportedExchangeError returns the error of an exchange the token exchange service does not support
+func NewUnsupportedExchangeError(exchange string) error {
+	return util.NewError(ErrorUnsupportedTokenExchange, exchange+" is not supported by the token exchange service")
+}
+
+// IsUnsupportedExchange tells if the error is the one of an exchange the token exchange service does not support
+func IsUnsupportedExchange(err error) bool {
+	return err != nil && util.ErrorReasonCode(err) == ErrorUnsupportedTokenExchange
+}
+
+// IsExchangeCancelled tells if the exchange gave up retrying as its context was done, rather than failed by itself
+func IsExchangeCancelled(err error) bool {
+	var perr provider.Error
+	return errors.As(err, &perr) && perr.Properties()[exchangeCancelledProperty] == "true"
+}
+
+// exchangeRetrier retries the exchanges failing with connection errors, like util.ErrorRetrier, and gives up as
+// soon as the context is done
+type exchangeRetrier struct {
+	attempts int
+	interval time.Duration
+	logger   *zap.Logger
+}
+
+// newExchangeRetrier ...
+func newExchangeRetrier(logger *zap.Logger) *exchangeRetrier {
+	return &exchangeRetrier{attempts: exchangeRetryAttempts, interval: exchangeRetryInterval, logger: logger}
+}
+
+// retry calls exchange until it succeeds, fails with another error than a connection error, the attempts are
+// exhausted or the context is done
+func (er *exchangeRetrier) retry(ctx context.Context, exchange func() error) error {
+	var err error
+	for attempt := 1; ; attempt++ {
+		err = exchange()
+		if err == nil || !iam.IsConnectionError(err) || attempt >= er.attempts {
+			return err
+		}
+		er.logger.Warn("Token exchange failed with a connection error, retrying", zap.Int("attempt", attempt), zap.Error(err))
+		timer := time.NewTimer(er.interval)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			er.logger.Error("Token exchange cancelled", zap.Error(ctx.Err()))
+			return util.NewErrorWithProperties("Timeout", "Token exchange cancelled: "+ctx.Err().Error(), map[string]string{exchangeCancelledProperty: "true"}, err)
+		case <-timer.C:
+		}
+	}
+}
+
+// iamTokenGrant is a grant request of the token endpoint of IAM
+type iamTokenGrant struct {
+	IamURL          string
+	IamClientID     string // bx if empty
+	IamClientSecret string
+	Fields          map[string]string
+}
+
+// exchange sends the grant request, with retries on connection errors until the context is done
+func (g iamTokenGrant) exchange(ctx context.Context, httpClient *http.Client, logger *zap.Logger) (*iam.AccessToken, error) {
+	clientID, clientSecret := g.IamClientID, g.IamClientSecret
+	if clientID == "" {
+		clientID, clientSecret = "bx", "bx"
+	}
+	client := rest.NewClient()
+	client.HTTPClient = httpClient
+
+	var accessToken *iam.AccessToken
+	err := newExchangeRetrier(logger).retry(ctx, func() error {
+		request := rest.PostRequest(fmt.Sprintf("%s/identity/token", g.IamURL)).
+			Set("Authorization", "Basic "+base64.StdEncoding.EncodeToString([]byte(clientID+":"+clientSecret))).
+			Set("Accept", "application/json")
+		for name, value := range g.Fields {
+			request = request.Field(name, value)
+		}
+
+		var successV struct {
+			AccessToken string `json:"access_token"`
+		}
+		var errorV struct {
+			ErrorCode    string `json:"errorCode"`
+			ErrorMessage string `json:"errorMessage"`
+		}
+		logger.Info("Sending token exchange request to IAM", zap.String("grantType", g.Fields["grant_type"]))
+		resp, err := client.Do(request, &successV, &errorV)
+		if err != nil {
+			logger.Error("IAM token exchange request failed", zap.Reflect("Response", resp), zap.Error(err))
+			return exchangeRequestError(err)
+		}
+		if errorV.ErrorMessage != "" {
+			logger.Error("IAM token exchange request failed with message", zap.Int("StatusCode", resp.StatusCode), zap.Reflect("Error", errorV))
+			return util.NewError("ErrorFailedTokenExchange", "IAM token exchange request failed: "+errorV.ErrorMessage, errors.New(errorV.ErrorCode))
+		}
+		if successV.AccessToken == "" {
+			return util.NewError("ErrorUnclassified", "Unexpected IAM token exchange response")
+		}
+		accessToken = &iam.AccessToken{Token: successV.AccessToken}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return accessToken, nil
+}
+
+// TokenInvalidator is a token exchange service caching the access tokens it exchanged. An access token rejected by
+// an API is invalidated, so that the next exchange does not return it again.
+type TokenInvalidator interface {
+	InvalidateAccessToken(token string)
+}
+
+// InvalidateAccessToken invalidates the access token in the cache of the token exchange service, if it has one
+func InvalidateAccessToken(tes iam.TokenExchangeService, token string) {
+	if invalidator, ok := tes.(TokenInvalidator); ok {
+		invalidator.InvalidateAccessToken(token)
+	}
+}
+
+// ContextTokenExchangeService is a token exchange service whose exchanges stop retrying once the context is done,
+// e.g once the request of the session is cancelled
+type ContextTokenExchangeService interface {
+	ExchangeIAMAPIKeyForAccessTokenWithContext(ctx context.Context, iamAPIKey string, logger *zap.Logger) (*iam.AccessToken, error)
+	ExchangeRefreshTokenForAccessTokenWithContext(ctx context.Context, refreshToken string, logger *zap.Logger) (*iam.AccessToken, error)
+}
+
+// ExchangeIAMAPIKeyForAccessTokenWithContext exchanges the API key with the token exchange service, until the
+// context is done if the service supports it
+func ExchangeIAMAPIKeyForAccessTokenWithContext(ctx context.Context, tes iam.TokenExchangeService, iamAPIKey string, logger *zap.Logger) (*iam.AccessToken, error) {
+	if contextTES, ok := tes.(ContextTokenExchangeService); ok {
+		return contextTES.ExchangeIAMAPIKeyForAccessTokenWithContext(ctx, iamAPIKey, logger)
+	}
+	return tes.ExchangeIAMAPIKeyForAccessToken(iamAPIKey, logger)
+}
+
+// ExchangeRefreshTokenForAccessTokenWithContext exchanges the refresh token with the token exchange service, until
+// the context is done if the service supports it
+func ExchangeRefreshTokenForAccessTokenWithContext(ctx context.Context, tes iam.TokenExchangeService, refreshToken string, logger *zap.Logger) (*iam.AccessToken, error) {
+	if contextTES, ok := tes.(ContextTokenExchangeService); ok {
+		return contextTES.ExchangeRefreshTokenForAccessTokenWithContext(ctx, refreshToken, logger)
+	}
+	return tes.ExchangeRefreshTokenForAccessToken(refreshToken, logger)
+}
+
+// tokenCache caches the exchanged access tokens by credentials until their expiry
+type tokenCache struct {
+	mu     sync.Mutex
+	tokens map[string]cachedToken
+}
+
+// cachedToken ...
+type cachedToken struct {
+	token   iam.AccessToken
+	expires time.Time
+}
+
+// newTokenCache ...
+func newTokenCache() *tokenCache {
+	return &tokenCache{tokens: map[string]cachedToken{}}
+}
+
+// tokenCacheKey returns the cache key of the credentials, which are not kept in memory as they are
+func tokenCacheKey(kind, credential string) string {
+	sum := sha256.Sum256([]byte(kind + "\x00" + credential))
+	return hex.EncodeToString(sum[:])
+}
+
+// exchange returns the cached token of the key, or the token of the exchange which is cached until its expiry.
+// Tokens without exp claim are not cached.
+func (c *tokenCache) exchange(key string, exchange func() (*iam.AccessToken, error)) (*iam.AccessToken, error) {
+	if c == nil {
+		return exchange()
+	}
+	c.mu.Lock()
+	cached, ok := c.tokens[key]
+	c.mu.Unlock()
+	if ok && time.Now().Before(cached.expires.Add(-tokenCacheExpiryMargin)) {
+		token := cached.token
+		return &token, nil
+	}
+
+	token, err := exchange()
+	if err != nil {
+		return nil, err
+	}
+	if expires, err := AccessTokenExpiry(token.Token); err == nil {
+		c.mu.Lock()
+		c.tokens[key] = cachedToken{token: *token, expires: expires}
+		c.mu.Unlock()
+	}
+	return token, nil
+}
+
+// invalidate drops the token from the cache, whatever credentials it was exchanged for
+func (c *tokenCache) invalidate(token string) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for key, cached := range c.tokens {
+		if cached.token.Token == token {
+			delete(c.tokens, key)
+		}
+	}
+}
@@ -0,0 +1,162 @@
+/**
+ * Copyright 2022 IBM Corp.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package iam ...
+package iam
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	util "github.com/IBM/ibmcloud-volume-interface/lib/utils"
+	"github.com/IBM/ibmcloud-volume-interface/lib/utils/reasoncode"
+	"github.com/IBM/ibmcloud-volume-interface/provider/iam"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIsUnsupportedExchange(t *testing.T) {
+	assert.True(t, IsUnsupportedExchange(NewUnsupportedExchangeError("IMS token exchange")))
+	assert.False(t, IsUnsupportedExchange(util.NewError("ErrorUnclassified", "failed")))
+	assert.False(t, IsUnsupportedExchange(nil))
+}
+
+func TestExchangeRetrier(t *testing.T) {
+	connectionErr := util.NewError("ErrorUnclassified", "IAM token exchange request failed", errors.New("dial tcp: connection refused"))
+
+	testCases := []struct {
+		name             string
+		errs             []error
+		cancel           bool
+		expectedAttempts int
+		expectedCode     reasoncode.ReasonCode
+	}{
+		{
+			name:             "success after connection errors",
+			errs:             []error{connectionErr, connectionErr, nil},
+			expectedAttempts: 3,
+		}, {
+			name:             "other errors are not retried",
+			errs:             []error{util.NewError("ErrorFailedTokenExchange", "rejected")},
+			expectedAttempts: 1,
+			expectedCode:     "ErrorFailedTokenExchange",
+		}, {
+			name:             "attempts exhausted",
+			errs:             []error{connectionErr, connectionErr, connectionErr, connectionErr},
+			expectedAttempts: 3,
+			expectedCode:     "ErrorUnclassified",
+		}, {
+			name:             "context cancelled",
+			errs:             []error{connectionErr, connectionErr},
+			cancel:           true,
+			expectedAttempts: 1,
+			expectedCode:     "Timeout",
+		},
+	}
+
+	for _, testcase := range testCases {
+		t.Run(testcase.name, func(t *testing.T) {
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			retrier := newExchangeRetrier(logger)
+			retrier.attempts = 3
+			retrier.interval = time.Millisecond
+			if testcase.cancel {
+				retrier.interval = time.Hour
+				cancel()
+			}
+
+			attempts := 0
+			err := retrier.retry(ctx, func() error {
+				err := testcase.errs[attempts]
+				attempts++
+				return err
+			})
+			assert.Equal(t, testcase.expectedAttempts, attempts)
+			if testcase.expectedCode == "" {
+				assert.Nil(t, err)
+				return
+			}
+			assert.Equal(t, testcase.expectedCode, util.ErrorReasonCode(err))
+			assert.Equal(t, testcase.cancel, IsExchangeCancelled(err))
+		})
+	}
+}
+
+func TestTokenCache(t *testing.T) {
+	testCases := []struct {
+		name              string
+		token             string
+		expectedExchanges int
+	}{
+		{
+			name:              "token cached until its expiry",
+			token:             testJWT(fmt.Sprintf(`{"exp":%d}`, time.Now().Add(time.Hour).Unix())),
+			expectedExchanges: 1,
+		}, {
+			name:              "token expiring soon",
+			token:             testJWT(fmt.Sprintf(`{"exp":%d}`, time.Now().Add(tokenCacheExpiryMargin/2).Unix())),
+			expectedExchanges: 2,
+		}, {
+			name:              "token without expiry",
+			token:             "opaque-token",
+			expectedExchanges: 2,
+		},
+	}
+
+	for _, testcase := range testCases {
+		t.Run(testcase.name, func(t *testing.T) {
+			cache := newTokenCache()
+			exchanges := 0
+			exchange := func() (*iam.AccessToken, error) {
+				exchanges++
+				return &iam.AccessToken{Token: testcase.token}, nil
+			}
+			for i := 0; i < 2; i++ {
+				token, err := cache.exchange(tokenCacheKey("apikey", "key-1"), exchange)
+				assert.Nil(t, err)
+				assert.Equal(t, testcase.token, token.Token)
+			}
+			assert.Equal(t, testcase.expectedExchanges, exchanges)
+		})
+	}
+
+	// Invalidated token is exchanged again, for all the credentials it was cached for
+	token := testJWT(fmt.Sprintf(`{"exp":%d}`, time.Now().Add(time.Hour).Unix()))
+	exchanges := 0
+	exchange := func() (*iam.AccessToken, error) {
+		exchanges++
+		return &iam.AccessToken{Token: token}, nil
+	}
+	validCache := newTokenCache()
+	_, _ = validCache.exchange(tokenCacheKey("apikey", "key-1"), exchange)
+	_, _ = validCache.exchange(tokenCacheKey("refresh_token", "refresh-1"), exchange)
+	validCache.invalidate("other-token")
+	_, _ = validCache.exchange(tokenCacheKey("apikey", "key-1"), exchange)
+	assert.Equal(t, 2, exchanges)
+	validCache.invalidate(token)
+	_, _ = validCache.exchange(tokenCacheKey("apikey", "key-1"), exchange)
+	_, _ = validCache.exchange(tokenCacheKey("refresh_token", "refresh-1"), exchange)
+	assert.Equal(t, 4, exchanges)
+
+	// Failed exchanges are not cached, and a nil cache always exchanges
+	var cache *tokenCache
+	cache.invalidate(token)
+	_, err := cache.exchange("key", func() (*iam.AccessToken, error) { return nil, errors.New("failed") })
+	assert.NotNil(t, err)
+}
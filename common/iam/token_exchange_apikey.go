@@ -0,0 +1,114 @@
+/**
+ * Copyright 2022 IBM Corp.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package iam ...
+package iam
+
+import (
+	"context"
+	"net/http"
+
+	util "github.com/IBM/ibmcloud-volume-interface/lib/utils"
+	"github.com/IBM/ibmcloud-volume-interface/provider/iam"
+	"go.uber.org/zap"
+)
+
+// APIKeyTokenExchangeService exchanges the API key it is given with IAM, unlike the VPC token exchange of the
+// interface which uses the API key of the secret store. Each service caches its own tokens, so that the accounts
+// of the multi-account mode do not share them.
+type APIKeyTokenExchangeService struct {
+	authConfig *iam.AuthConfiguration
+	httpClient *http.Client
+	tokens     *tokenCache
+}
+
+// TokenExchangeService ...
+var _ iam.TokenExchangeService = &APIKeyTokenExchangeService{}
+var _ ContextTokenExchangeService = &APIKeyTokenExchangeService{}
+
+// NewTokenExchangeAPIKeyService ...
+func NewTokenExchangeAPIKeyService(authConfig *iam.AuthConfiguration, httpClient *http.Client) *APIKeyTokenExchangeService {
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	return &APIKeyTokenExchangeService{
+		authConfig: authConfig,
+		httpClient: httpClient,
+		tokens:     newTokenCache(),
+	}
+}
+
+// ExchangeIAMAPIKeyForAccessToken exchanges the API key, the access token is cached until its expiry
+func (tes *APIKeyTokenExchangeService) ExchangeIAMAPIKeyForAccessToken(iamAPIKey string, logger *zap.Logger) (*iam.AccessToken, error) {
+	return tes.ExchangeIAMAPIKeyForAccessTokenWithContext(context.Background(), iamAPIKey, logger)
+}
+
+// ExchangeIAMAPIKeyForAccessTokenWithContext exchanges the API key, retries stop once the context is done
+func (tes *APIKeyTokenExchangeService) ExchangeIAMAPIKeyForAccessTokenWithContext(ctx context.Context, iamAPIKey string, logger *zap.Logger) (*iam.AccessToken, error) {
+	if iamAPIKey == "" {
+		return nil, util.NewError("ErrorInsufficientAuthentication", "No API key to exchange for an access token")
+	}
+	return tes.tokens.exchange(tokenCacheKey("apikey", iamAPIKey), func() (*iam.AccessToken, error) {
+		return tes.grant(map[string]string{"grant_type": "urn:ibm:params:oauth:grant-type:apikey", "apikey": iamAPIKey}).exchange(ctx, tes.httpClient, logger)
+	})
+}
+
+// ExchangeRefreshTokenForAccessToken exchanges the refresh token, the access token is cached until its expiry
+func (tes *APIKeyTokenExchangeService) ExchangeRefreshTokenForAccessToken(refreshToken string, logger *zap.Logger) (*iam.AccessToken, error) {
+	return tes.ExchangeRefreshTokenForAccessTokenWithContext(context.Background(), refreshToken, logger)
+}
+
+// ExchangeRefreshTokenForAccessTokenWithContext exchanges the refresh token, retries stop once the context is done
+func (tes *APIKeyTokenExchangeService) ExchangeRefreshTokenForAccessTokenWithContext(ctx context.Context, refreshToken string, logger *zap.Logger) (*iam.AccessToken, error) {
+	return tes.tokens.exchange(tokenCacheKey("refresh_token", refreshToken), func() (*iam.AccessToken, error) {
+		return tes.grant(map[string]string{"grant_type": "refresh_token", "refresh_token": refreshToken}).exchange(ctx, tes.httpClient, logger)
+	})
+}
+
+// InvalidateAccessToken drops the access token from the cache of the service
+func (tes *APIKeyTokenExchangeService) InvalidateAccessToken(token string) {
+	tes.tokens.invalidate(token)
+}
+
+// ExchangeAccessTokenForIMSToken ...
+func (tes *APIKeyTokenExchangeService) ExchangeAccessTokenForIMSToken(accessToken iam.AccessToken, logger *zap.Logger) (*iam.IMSToken, error) {
+	return nil, NewUnsupportedExchangeError("Access token exchange for IMS token")
+}
+
+// ExchangeIAMAPIKeyForIMSToken ...
+func (tes *APIKeyTokenExchangeService) ExchangeIAMAPIKeyForIMSToken(iamAPIKey string, logger *zap.Logger) (*iam.IMSToken, error) {
+	return nil, NewUnsupportedExchangeError("IAM API key exchange for IMS token")
+}
+
+// GetIAMAccountIDFromAccessToken returns the account of the access token
+func (tes *APIKeyTokenExchangeService) GetIAMAccountIDFromAccessToken(accessToken iam.AccessToken, logger *zap.Logger) (string, error) {
+	claims, err := ParseAccessTokenClaims(accessToken.Token)
+	if err != nil {
+		logger.Error("Failed to parse the claims of the access token", zap.Error(err))
+		return "", util.NewError("ErrorUnclassified", "Failed to parse the claims of the access token", err)
+	}
+	return claims.Account.BSS, nil
+}
+
+// grant ...
+func (tes *APIKeyTokenExchangeService) grant(fields map[string]string) iamTokenGrant {
+	return iamTokenGrant{
+		IamURL:          tes.authConfig.IamURL,
+		IamClientID:     tes.authConfig.IamClientID,
+		IamClientSecret: tes.authConfig.IamClientSecret,
+		Fields:          fields,
+	}
+}
@@ -18,11 +18,12 @@
 package iam
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"net/http"
 	"strings"
-	"time"
+	"sync/atomic"
 
 	"github.com/IBM-Cloud/ibm-cloud-cli-sdk/common/rest"
 	"github.com/IBM/ibmcloud-volume-interface/config"
@@ -39,6 +40,10 @@ type tokenExchangeIKSService struct {
 	iksAuthConfig *IksAuthConfiguration
 	httpClient    *http.Client
 	spObject      sp.SecretProviderInterface
+	tokens        *tokenCache
+
+	// freshToken is set once an access token is invalidated, the secret provider caches its token as well
+	freshToken int32
 }
 
 // IksAuthConfiguration ...
@@ -46,10 +51,17 @@ type IksAuthConfiguration struct {
 	PrivateAPIRoute string
 	IamAPIKey       string
 	CSRFToken       string
+
+	// IamURL, IamClientID and IamClientSecret configure the refresh token exchange with IAM, refresh tokens are
+	// exchanged for the API key of the config with the container API server without IamURL
+	IamURL          string
+	IamClientID     string
+	IamClientSecret string
 }
 
 // TokenExchangeService ...
 var _ iam.TokenExchangeService = &tokenExchangeIKSService{}
+var _ ContextTokenExchangeService = &tokenExchangeIKSService{}
 
 // NewTokenExchangeIKSService ...
 func NewTokenExchangeIKSService(iksAuthConfig *IksAuthConfiguration, k8sClient *k8s_utils.KubernetesClient) (iam.TokenExchangeService, error) {
@@ -61,20 +73,24 @@ func NewTokenExchangeIKSService(iksAuthConfig *IksAuthConfiguration, k8sClient *
 		secret_provider.ProviderType: secret_provider.VPC,
 	}
 	spObject, err := secret_provider.NewSecretProvider(k8sClient, providerType)
+	if err != nil {
+		return nil, err
+	}
 	return &tokenExchangeIKSService{
 		iksAuthConfig: iksAuthConfig,
 		httpClient:    httpClient,
 		spObject:      spObject,
+		tokens:        newTokenCache(),
 	}, nil
 }
 
 // tokenExchangeIKSRequest ...
 type tokenExchangeIKSRequest struct {
-	tes          *tokenExchangeIKSService
-	request      *rest.Request
-	client       *rest.Client
-	logger       *zap.Logger
-	errorRetrier *util.ErrorRetrier
+	tes     *tokenExchangeIKSService
+	request *rest.Request
+	client  *rest.Client
+	logger  *zap.Logger
+	retrier *exchangeRetrier
 }
 
 // tokenExchangeIKSResponse ...
@@ -85,57 +101,102 @@ type tokenExchangeIKSResponse struct {
 
 // ExchangeRefreshTokenForAccessToken ...
 func (tes *tokenExchangeIKSService) ExchangeRefreshTokenForAccessToken(refreshToken string, logger *zap.Logger) (*iam.AccessToken, error) {
-	r := tes.newTokenExchangeRequest(logger)
-	return r.exchangeForAccessToken()
+	return tes.ExchangeRefreshTokenForAccessTokenWithContext(context.Background(), refreshToken, logger)
 }
 
-// ExchangeIAMAPIKeyForAccessToken ...
-func (tes *tokenExchangeIKSService) ExchangeIAMAPIKeyForAccessToken(iamAPIKey string, logger *zap.Logger) (*iam.AccessToken, error) {
-	logger.Info("Fetching using secret provider")
-	token, _, err := tes.spObject.GetDefaultIAMToken(false)
-	if err != nil {
-		logger.Error("Error fetching iam token", zap.Error(err))
-		return nil, err
+// ExchangeRefreshTokenForAccessTokenWithContext exchanges the refresh token with IAM, which requires the config to
+// have an IAM URL. Without refresh token, the API key of the config is exchanged with the container API server.
+// Retries stop once the context is done, the access token is cached until its expiry.
+func (tes *tokenExchangeIKSService) ExchangeRefreshTokenForAccessTokenWithContext(ctx context.Context, refreshToken string, logger *zap.Logger) (*iam.AccessToken, error) {
+	if refreshToken != "" {
+		// The container API server only exchanges the API key of the config, not the refresh token
+		if tes.iksAuthConfig.IamURL == "" {
+			return nil, NewUnsupportedExchangeError("Refresh token exchange without IAM URL")
+		}
+		return tes.tokens.exchange(tokenCacheKey("refresh_token", refreshToken), func() (*iam.AccessToken, error) {
+			grant := iamTokenGrant{
+				IamURL:          tes.iksAuthConfig.IamURL,
+				IamClientID:     tes.iksAuthConfig.IamClientID,
+				IamClientSecret: tes.iksAuthConfig.IamClientSecret,
+				Fields:          map[string]string{"grant_type": "refresh_token", "refresh_token": refreshToken},
+			}
+			return grant.exchange(ctx, tes.httpClient, logger)
+		})
 	}
-	logger.Info("Successfully fetched iam token")
-	return &iam.AccessToken{Token: token}, nil
+	return tes.tokens.exchange(tokenCacheKey("apikey", tes.iksAuthConfig.IamAPIKey), func() (*iam.AccessToken, error) {
+		r := tes.newTokenExchangeRequest(logger)
+		return r.exchangeForAccessToken(ctx, r.sendTokenExchangeRequest)
+	})
+}
+
+// ExchangeIAMAPIKeyForAccessTokenWithContext is ExchangeIAMAPIKeyForAccessToken, the secret provider does not
+// retry so there is nothing to stop once the context is done
+func (tes *tokenExchangeIKSService) ExchangeIAMAPIKeyForAccessTokenWithContext(ctx context.Context, iamAPIKey string, logger *zap.Logger) (*iam.AccessToken, error) {
+	return tes.ExchangeIAMAPIKeyForAccessToken(iamAPIKey, logger)
+}
+
+// ExchangeIAMAPIKeyForAccessToken fetches the token with the secret provider, the access token is cached until
+// its expiry
+func (tes *tokenExchangeIKSService) ExchangeIAMAPIKeyForAccessToken(iamAPIKey string, logger *zap.Logger) (*iam.AccessToken, error) {
+	return tes.tokens.exchange(tokenCacheKey("secret_provider", iamAPIKey), func() (*iam.AccessToken, error) {
+		logger.Info("Fetching using secret provider")
+		token, _, err := tes.spObject.GetDefaultIAMToken(atomic.SwapInt32(&tes.freshToken, 0) == 1)
+		if err != nil {
+			logger.Error("Error fetching iam token", zap.Error(err))
+			return nil, err
+		}
+		logger.Info("Successfully fetched iam token")
+		return &iam.AccessToken{Token: token}, nil
+	})
+}
+
+// InvalidateAccessToken drops the access token from the cache of the service, the next token of the secret
+// provider is a fresh one
+func (tes *tokenExchangeIKSService) InvalidateAccessToken(token string) {
+	tes.tokens.invalidate(token)
+	atomic.StoreInt32(&tes.freshToken, 1)
 }
 
 // newTokenExchangeRequest ...
 func (tes *tokenExchangeIKSService) newTokenExchangeRequest(logger *zap.Logger) *tokenExchangeIKSRequest {
 	client := rest.NewClient()
 	client.HTTPClient = tes.httpClient
-	retyrInterval, _ := time.ParseDuration("3s")
 	return &tokenExchangeIKSRequest{
-		tes:          tes,
-		request:      rest.PostRequest(fmt.Sprintf("%s/v1/iam/apikey", tes.iksAuthConfig.PrivateAPIRoute)),
-		client:       client,
-		logger:       logger,
-		errorRetrier: util.NewErrorRetrier(40, retyrInterval, logger),
+		tes:     tes,
+		request: rest.PostRequest(fmt.Sprintf("%s/v1/iam/apikey", tes.iksAuthConfig.PrivateAPIRoute)),
+		client:  client,
+		logger:  logger,
+		retrier: newExchangeRetrier(logger),
 	}
 }
 
-// ExchangeAccessTokenForIMSToken ...
+// ExchangeAccessTokenForIMSToken is not supported, IKS clusters do not use IMS tokens
 func (tes *tokenExchangeIKSService) ExchangeAccessTokenForIMSToken(accessToken iam.AccessToken, logger *zap.Logger) (*iam.IMSToken, error) {
-	return nil, nil
+	return nil, NewUnsupportedExchangeError("Access token exchange for IMS token")
 }
 
-// ExchangeIAMAPIKeyForIMSToken ...
+// ExchangeIAMAPIKeyForIMSToken is not supported, IKS clusters do not use IMS tokens
 func (tes *tokenExchangeIKSService) ExchangeIAMAPIKeyForIMSToken(iamAPIKey string, logger *zap.Logger) (*iam.IMSToken, error) {
-	return nil, nil
+	return nil, NewUnsupportedExchangeError("IAM API key exchange for IMS token")
 }
 
+// GetIAMAccountIDFromAccessToken returns the account of the access token, from its account.bss claim
 func (tes *tokenExchangeIKSService) GetIAMAccountIDFromAccessToken(accessToken iam.AccessToken, logger *zap.Logger) (accountID string, err error) {
-	return "Not required to implement", nil
+	claims, err := ParseAccessTokenClaims(accessToken.Token)
+	if err != nil {
+		logger.Error("Failed to parse the claims of the access token", zap.Error(err))
+		return "", util.NewError("ErrorUnclassified", "Failed to parse the claims of the access token", err)
+	}
+	return claims.Account.BSS, nil
 }
 
-// exchangeForAccessToken ...
-func (r *tokenExchangeIKSRequest) exchangeForAccessToken() (*iam.AccessToken, error) {
+// exchangeForAccessToken sends the request with retries on connection errors
+func (r *tokenExchangeIKSRequest) exchangeForAccessToken(ctx context.Context, send func() (*tokenExchangeIKSResponse, error)) (*iam.AccessToken, error) {
 	var iamResp *tokenExchangeIKSResponse
-	var err error
-	err = r.errorRetrier.ErrorRetry(func() (error, bool) {
-		iamResp, err = r.sendTokenExchangeRequest()
-		return err, !iam.IsConnectionError(err) // Skip retry if its not connection error
+	err := r.retrier.retry(ctx, func() error {
+		var err error
+		iamResp, err = send()
+		return err
 	})
 	if err != nil {
 		return nil, err
@@ -25,6 +25,7 @@ import (
 	"os"
 	"path/filepath"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"go.uber.org/zap"
@@ -83,7 +84,7 @@ func Test_IKSExchangeRefreshTokenForAccessToken_Success(t *testing.T) {
 	tes.iksAuthConfig = iksAuthConfig
 	tes.spObject = new(sp.FakeSecretProvider)
 
-	r, err := tes.ExchangeRefreshTokenForAccessToken("testrefreshtoken", logger)
+	r, err := tes.ExchangeRefreshTokenForAccessToken("", logger)
 	assert.Nil(t, err)
 	if assert.NotNil(t, r) {
 		assert.Equal(t, (*r).Token, "at_success")
@@ -125,7 +126,7 @@ func Test_IKSExchangeRefreshTokenForAccessToken_FailedDuringRequest(t *testing.T
 	tes.iksAuthConfig = iksAuthConfig
 	tes.spObject = new(sp.FakeSecretProvider)
 
-	r, err := tes.ExchangeRefreshTokenForAccessToken("badrefreshtoken", logger)
+	r, err := tes.ExchangeRefreshTokenForAccessToken("", logger)
 	assert.Nil(t, r)
 	if assert.NotNil(t, err) {
 		assert.Equal(t, "IAM token exchange request failed: did not work", err.Error())
@@ -158,7 +159,7 @@ func Test_IKSExchangeRefreshTokenForAccessToken_FailedDuringRequest_no_message(t
 	tes.iksAuthConfig = iksAuthConfig
 	tes.spObject = new(sp.FakeSecretProvider)
 
-	r, err := tes.ExchangeRefreshTokenForAccessToken("badrefreshtoken", logger)
+	r, err := tes.ExchangeRefreshTokenForAccessToken("", logger)
 	assert.Nil(t, r)
 	if assert.NotNil(t, err) {
 		assert.Equal(t, "Unexpected IAM token exchange response", err.Error())
@@ -192,7 +193,7 @@ func Test_IKSExchangeRefreshTokenForAccessToken_FailedWrongApiUrl(t *testing.T)
 	tes.iksAuthConfig = iksAuthConfig
 	tes.spObject = new(sp.FakeSecretProvider)
 
-	r, err := tes.ExchangeRefreshTokenForAccessToken("testrefreshtoken", logger)
+	r, err := tes.ExchangeRefreshTokenForAccessToken("", logger)
 	assert.Nil(t, r)
 
 	if assert.NotNil(t, err) {
@@ -225,7 +226,7 @@ func Test_IKSExchangeRefreshTokenForAccessToken_FailedRequesting_unclassified_er
 	tes, err := iam.NewTokenExchangeService(iksAuthConfig)
 	assert.NoError(t, err)
 
-	r, err := tes.ExchangeRefreshTokenForAccessToken("badrefreshtoken", logger)
+	r, err := tes.ExchangeRefreshTokenForAccessToken("", logger)
 	assert.Nil(t, r)
 
 	if assert.NotNil(t, err) {
@@ -274,6 +275,50 @@ func Test_IKSExchangeIAMAPIKeyForAccessToken(t *testing.T) {
 	}
 }
 
+func TestIKSInvalidateAccessToken(t *testing.T) {
+	tokens := []string{
+		testJWT(fmt.Sprintf(`{"iam_id":"rejected","exp":%d}`, time.Now().Add(time.Hour).Unix())),
+		testJWT(fmt.Sprintf(`{"iam_id":"fresh","exp":%d}`, time.Now().Add(time.Hour).Unix())),
+	}
+	requests := 0
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, `{"token":%q}`, tokens[requests])
+		requests++
+	}))
+	defer s.Close()
+
+	tes := &tokenExchangeIKSService{
+		iksAuthConfig: &IksAuthConfiguration{PrivateAPIRoute: s.URL, IamAPIKey: "apikey1"},
+		httpClient:    http.DefaultClient,
+		spObject:      new(sp.FakeSecretProvider),
+		tokens:        newTokenCache(),
+	}
+	var service iam.TokenExchangeService = WithTokenExchangeMetrics(tes)
+
+	// Cached token rejected by an API is exchanged again through the private route
+	token, err := service.ExchangeRefreshTokenForAccessToken("", logger)
+	assert.Nil(t, err)
+	assert.Equal(t, tokens[0], token.Token)
+	token, _ = service.ExchangeRefreshTokenForAccessToken("", logger)
+	assert.Equal(t, tokens[0], token.Token)
+	InvalidateAccessToken(service, tokens[0])
+	token, err = service.ExchangeRefreshTokenForAccessToken("", logger)
+	assert.Nil(t, err)
+	assert.Equal(t, tokens[1], token.Token)
+	assert.Equal(t, 2, requests)
+
+	// Secret provider is asked for a fresh token once after a token is invalidated
+	token, err = service.ExchangeIAMAPIKeyForAccessToken("apikey1", logger)
+	assert.Nil(t, err)
+	assert.Equal(t, "token", token.Token)
+	_, err = service.ExchangeIAMAPIKeyForAccessToken("apikey1", logger)
+	assert.NotNil(t, err)
+	InvalidateAccessToken(service, "token")
+	token, err = service.ExchangeIAMAPIKeyForAccessToken("apikey1", logger)
+	assert.Nil(t, err)
+	assert.Equal(t, "token", token.Token)
+}
+
 func TestNewTokenExchangeIKSService(t *testing.T) {
 	iksAuthConfig := &IksAuthConfiguration{
 		PrivateAPIRoute: server.URL,
@@ -284,25 +329,125 @@ func TestNewTokenExchangeIKSService(t *testing.T) {
 	_ = k8s_utils.FakeCreateSecret(kc, "DEFAULT", file)
 	_, err := NewTokenExchangeIKSService(iksAuthConfig, &kc)
 	assert.Nil(t, err)
+
+	// Secret provider errors are returned
+	kc, _ = k8s_utils.FakeGetk8sClientSet()
+	_, err = NewTokenExchangeIKSService(iksAuthConfig, &kc)
+	assert.NotNil(t, err)
+}
+
+func TestIKSExchangeRefreshTokenWithIAM(t *testing.T) {
+	accessToken := testJWT(fmt.Sprintf(`{"iam_id":"iam-ServiceId-1","exp":%d}`, time.Now().Add(time.Hour).Unix()))
+	testCases := []struct {
+		name          string
+		refreshToken  string
+		status        int
+		response      string
+		expectedToken string
+		expectedErr   string
+	}{
+		{
+			name:          "access token of the refresh token",
+			refreshToken:  "refresh-1",
+			status:        http.StatusOK,
+			response:      fmt.Sprintf(`{"access_token":%q,"refresh_token":"refresh-2"}`, accessToken),
+			expectedToken: accessToken,
+		}, {
+			name:         "refresh token rejected",
+			refreshToken: "refresh-1",
+			status:       http.StatusBadRequest,
+			response:     `{"errorCode":"BXNIM0407E","errorMessage":"Provided refresh token is invalid"}`,
+			expectedErr:  "IAM token exchange request failed: Provided refresh token is invalid",
+		}, {
+			name:         "no access token",
+			refreshToken: "refresh-1",
+			status:       http.StatusOK,
+			response:     `{}`,
+			expectedErr:  "Unexpected IAM token exchange response",
+		},
+	}
+
+	for _, testcase := range testCases {
+		t.Run(testcase.name, func(t *testing.T) {
+			requests := 0
+			s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				requests++
+				assert.Equal(t, "/identity/token", r.URL.Path)
+				assert.Nil(t, r.ParseForm())
+				assert.Equal(t, "refresh_token", r.PostForm.Get("grant_type"))
+				assert.Equal(t, testcase.refreshToken, r.PostForm.Get("refresh_token"))
+				user, password, _ := r.BasicAuth()
+				assert.Equal(t, "bx:bx", user+":"+password)
+				w.WriteHeader(testcase.status)
+				fmt.Fprint(w, testcase.response)
+			}))
+			defer s.Close()
+
+			tes := &tokenExchangeIKSService{
+				iksAuthConfig: &IksAuthConfiguration{IamURL: s.URL},
+				httpClient:    http.DefaultClient,
+				tokens:        newTokenCache(),
+			}
+			token, err := tes.ExchangeRefreshTokenForAccessToken(testcase.refreshToken, logger)
+			if testcase.expectedErr != "" {
+				if assert.NotNil(t, err) {
+					assert.Equal(t, testcase.expectedErr, err.Error())
+				}
+				return
+			}
+			assert.Nil(t, err)
+			assert.Equal(t, testcase.expectedToken, token.Token)
+
+			// Token is cached until its expiry
+			token, err = tes.ExchangeRefreshTokenForAccessToken(testcase.refreshToken, logger)
+			assert.Nil(t, err)
+			assert.Equal(t, testcase.expectedToken, token.Token)
+			assert.Equal(t, 1, requests)
+		})
+	}
+}
+
+func TestIKSExchangeRefreshTokenWithoutIAM(t *testing.T) {
+	requests := 0
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		fmt.Fprint(w, `{"token":"at_success"}`)
+	}))
+	defer s.Close()
+
+	// Refresh token is not swapped for the API key of the config
+	tes := &tokenExchangeIKSService{
+		iksAuthConfig: &IksAuthConfiguration{PrivateAPIRoute: s.URL, IamAPIKey: "apikey1"},
+		httpClient:    http.DefaultClient,
+		tokens:        newTokenCache(),
+	}
+	token, err := tes.ExchangeRefreshTokenForAccessToken("refresh-1", logger)
+	assert.Nil(t, token)
+	assert.True(t, IsUnsupportedExchange(err))
+	assert.Equal(t, 0, requests)
 }
 
 func TestExchangeAccessTokenForIMSToken(t *testing.T) {
 	tes := new(tokenExchangeIKSService)
 	logger = zap.New(zapcore.NewCore(zapcore.NewJSONEncoder(zap.NewDevelopmentEncoderConfig()), consoleDebugging, lowPriority), zap.AddCaller())
 	_, err := tes.ExchangeAccessTokenForIMSToken(iam.AccessToken{}, logger)
-	assert.Nil(t, err)
+	assert.True(t, IsUnsupportedExchange(err))
 }
 
 func TestExchangeIAMAPIKeyForIMSToken(t *testing.T) {
 	tes := new(tokenExchangeIKSService)
 	logger = zap.New(zapcore.NewCore(zapcore.NewJSONEncoder(zap.NewDevelopmentEncoderConfig()), consoleDebugging, lowPriority), zap.AddCaller())
 	_, err := tes.ExchangeIAMAPIKeyForIMSToken("", logger)
-	assert.Nil(t, err)
+	assert.True(t, IsUnsupportedExchange(err))
 }
 
 func TestGetIAMAccountIDFromAccessToken(t *testing.T) {
 	tes := new(tokenExchangeIKSService)
 	logger = zap.New(zapcore.NewCore(zapcore.NewJSONEncoder(zap.NewDevelopmentEncoderConfig()), consoleDebugging, lowPriority), zap.AddCaller())
 	_, err := tes.GetIAMAccountIDFromAccessToken(iam.AccessToken{}, logger)
+	assert.NotNil(t, err)
+
+	accountID, err := tes.GetIAMAccountIDFromAccessToken(iam.AccessToken{Token: testJWT(`{"iam_id":"iam-ServiceId-1","account":{"bss":"account-1"}}`)}, logger)
 	assert.Nil(t, err)
+	assert.Equal(t, "account-1", accountID)
 }
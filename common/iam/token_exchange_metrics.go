@@ -0,0 +1,90 @@
+/**
+ * Copyright 2022 IBM Corp.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package iam ...
+package iam
+
+import (
+	"context"
+
+	"github.com/IBM/ibmcloud-volume-interface/provider/iam"
+	"github.com/IBM/ibmcloud-volume-vpc/common/vpcmetrics"
+	"go.uber.org/zap"
+)
+
+// tokenExchangeMetrics counts the token exchanges of the wrapped service
+type tokenExchangeMetrics struct {
+	iam.TokenExchangeService
+}
+
+// TokenExchangeService ...
+var _ iam.TokenExchangeService = &tokenExchangeMetrics{}
+var _ ContextTokenExchangeService = &tokenExchangeMetrics{}
+
+// WithTokenExchangeMetrics counts the token exchanges and their failures in the default vpcmetrics collector
+func WithTokenExchangeMetrics(tes iam.TokenExchangeService) iam.TokenExchangeService {
+	if _, ok := tes.(*tokenExchangeMetrics); ok || tes == nil {
+		return tes
+	}
+	return &tokenExchangeMetrics{TokenExchangeService: tes}
+}
+
+// InvalidateAccessToken invalidates the access token in the cache of the wrapped service
+func (tem *tokenExchangeMetrics) InvalidateAccessToken(token string) {
+	InvalidateAccessToken(tem.TokenExchangeService, token)
+}
+
+// ExchangeRefreshTokenForAccessToken ...
+func (tem *tokenExchangeMetrics) ExchangeRefreshTokenForAccessToken(refreshToken string, logger *zap.Logger) (*iam.AccessToken, error) {
+	token, err := tem.TokenExchangeService.ExchangeRefreshTokenForAccessToken(refreshToken, logger)
+	vpcmetrics.Default().TokenExchange(vpcmetrics.TokenExchangeRefreshToken, err)
+	return token, err
+}
+
+// ExchangeIAMAPIKeyForAccessToken ...
+func (tem *tokenExchangeMetrics) ExchangeIAMAPIKeyForAccessToken(iamAPIKey string, logger *zap.Logger) (*iam.AccessToken, error) {
+	token, err := tem.TokenExchangeService.ExchangeIAMAPIKeyForAccessToken(iamAPIKey, logger)
+	vpcmetrics.Default().TokenExchange(vpcmetrics.TokenExchangeAPIKey, err)
+	return token, err
+}
+
+// ExchangeRefreshTokenForAccessTokenWithContext ...
+func (tem *tokenExchangeMetrics) ExchangeRefreshTokenForAccessTokenWithContext(ctx context.Context, refreshToken string, logger *zap.Logger) (*iam.AccessToken, error) {
+	token, err := ExchangeRefreshTokenForAccessTokenWithContext(ctx, tem.TokenExchangeService, refreshToken, logger)
+	vpcmetrics.Default().TokenExchange(vpcmetrics.TokenExchangeRefreshToken, err)
+	return token, err
+}
+
+// ExchangeIAMAPIKeyForAccessTokenWithContext ...
+func (tem *tokenExchangeMetrics) ExchangeIAMAPIKeyForAccessTokenWithContext(ctx context.Context, iamAPIKey string, logger *zap.Logger) (*iam.AccessToken, error) {
+	token, err := ExchangeIAMAPIKeyForAccessTokenWithContext(ctx, tem.TokenExchangeService, iamAPIKey, logger)
+	vpcmetrics.Default().TokenExchange(vpcmetrics.TokenExchangeAPIKey, err)
+	return token, err
+}
+
+// ExchangeAccessTokenForIMSToken ...
+func (tem *tokenExchangeMetrics) ExchangeAccessTokenForIMSToken(accessToken iam.AccessToken, logger *zap.Logger) (*iam.IMSToken, error) {
+	token, err := tem.TokenExchangeService.ExchangeAccessTokenForIMSToken(accessToken, logger)
+	vpcmetrics.Default().TokenExchange(vpcmetrics.TokenExchangeIMSToken, err)
+	return token, err
+}
+
+// ExchangeIAMAPIKeyForIMSToken ...
+func (tem *tokenExchangeMetrics) ExchangeIAMAPIKeyForIMSToken(iamAPIKey string, logger *zap.Logger) (*iam.IMSToken, error) {
+	token, err := tem.TokenExchangeService.ExchangeIAMAPIKeyForIMSToken(iamAPIKey, logger)
+	vpcmetrics.Default().TokenExchange(vpcmetrics.TokenExchangeIMSToken, err)
+	return token, err
+}
@@ -0,0 +1,77 @@
+/**
+ * Copyright 2022 IBM Corp.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package iam ...
+package iam
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/IBM/ibmcloud-volume-interface/provider/iam"
+	"github.com/IBM/ibmcloud-volume-vpc/common/vpcmetrics"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+)
+
+// stubTokenExchangeService fails the exchanges when err is set
+type stubTokenExchangeService struct {
+	iam.TokenExchangeService
+	err error
+}
+
+func (s *stubTokenExchangeService) ExchangeIAMAPIKeyForAccessToken(iamAPIKey string, logger *zap.Logger) (*iam.AccessToken, error) {
+	if s.err != nil {
+		return nil, s.err
+	}
+	return &iam.AccessToken{Token: "access-token"}, nil
+}
+
+func (s *stubTokenExchangeService) ExchangeRefreshTokenForAccessToken(refreshToken string, logger *zap.Logger) (*iam.AccessToken, error) {
+	return s.ExchangeIAMAPIKeyForAccessToken(refreshToken, logger)
+}
+
+func TestWithTokenExchangeMetrics(t *testing.T) {
+	c := vpcmetrics.NewCollector()
+	vpcmetrics.SetDefault(c)
+	defer vpcmetrics.SetDefault(nil)
+
+	stub := &stubTokenExchangeService{}
+	tes := WithTokenExchangeMetrics(stub)
+	assert.Equal(t, tes, WithTokenExchangeMetrics(tes))
+
+	token, err := tes.ExchangeIAMAPIKeyForAccessToken("api-key", logger)
+	assert.Nil(t, err)
+	assert.Equal(t, "access-token", token.Token)
+	_, _ = tes.ExchangeRefreshTokenForAccessToken("refresh-token", logger)
+
+	stub.err = errors.New("exchange failed")
+	_, err = tes.ExchangeIAMAPIKeyForAccessToken("api-key", logger)
+	assert.EqualError(t, err, "exchange failed")
+
+	expected := `
+# HELP ibmcloud_volume_vpc_token_exchanges_total Token exchanges, by method.
+# TYPE ibmcloud_volume_vpc_token_exchanges_total counter
+ibmcloud_volume_vpc_token_exchanges_total{method="api_key"} 2
+ibmcloud_volume_vpc_token_exchanges_total{method="refresh_token"} 1
+# HELP ibmcloud_volume_vpc_token_exchange_failures_total Failed token exchanges, by method.
+# TYPE ibmcloud_volume_vpc_token_exchange_failures_total counter
+ibmcloud_volume_vpc_token_exchange_failures_total{method="api_key"} 1
+`
+	assert.Nil(t, testutil.CollectAndCompare(c, strings.NewReader(expected), "ibmcloud_volume_vpc_token_exchanges_total", "ibmcloud_volume_vpc_token_exchange_failures_total"))
+}
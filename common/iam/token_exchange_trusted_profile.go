@@ -0,0 +1,274 @@
+/**
+ * Copyright 2022 IBM Corp.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package iam ...
+package iam
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+
+	"github.com/IBM-Cloud/ibm-cloud-cli-sdk/common/rest"
+	"github.com/IBM/ibmcloud-volume-interface/lib/provider"
+	util "github.com/IBM/ibmcloud-volume-interface/lib/utils"
+	"github.com/IBM/ibmcloud-volume-interface/provider/iam"
+	"go.uber.org/zap"
+)
+
+const (
+	// TrustedProfile is the auth type of the credentials exchanged for an access token with the compute resource
+	// token of the config. The credential is the trusted profile ID, the one of the config if empty.
+	TrustedProfile provider.AuthType = "TRUSTED_PROFILE"
+
+	// InstanceIdentityToken is the auth type of the credentials exchanged for an access token with the instance
+	// metadata identity token. The credential is the trusted profile ID, the one of the config if empty.
+	InstanceIdentityToken provider.AuthType = "INSTANCE_IDENTITY_TOKEN"
+)
+
+const (
+	// DefaultMetadataURL is the endpoint of the instance metadata service
+	DefaultMetadataURL = "http://169.254.169.254"
+
+	// metadataAPIVersion is the version of the instance metadata API
+	metadataAPIVersion = "2022-03-01"
+
+	// instanceIdentityTokenExpiry is the life time in seconds of the identity tokens requested, they are
+	// exchanged right away
+	instanceIdentityTokenExpiry = 300
+)
+
+// TrustedProfileConfiguration ...
+type TrustedProfileConfiguration struct {
+	IamURL                   string
+	ProfileID                string
+	ComputeResourceTokenFile string
+	MetadataURL              string
+}
+
+// TrustedProfileTokenExchangeService exchanges compute resource tokens or instance identity tokens for the access
+// tokens of a trusted profile. The API keys given to its TokenExchangeService methods are ignored.
+type TrustedProfileTokenExchangeService struct {
+	config     *TrustedProfileConfiguration
+	httpClient *http.Client
+}
+
+// TokenExchangeService ...
+var _ iam.TokenExchangeService = &TrustedProfileTokenExchangeService{}
+var _ ContextTokenExchangeService = &TrustedProfileTokenExchangeService{}
+
+// NewTokenExchangeTrustedProfileService ...
+func NewTokenExchangeTrustedProfileService(config *TrustedProfileConfiguration, httpClient *http.Client) *TrustedProfileTokenExchangeService {
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	return &TrustedProfileTokenExchangeService{config: config, httpClient: httpClient}
+}
+
+// AuthType returns the auth type of the exchanges of the config
+func (tes *TrustedProfileTokenExchangeService) AuthType() provider.AuthType {
+	if tes.config.ComputeResourceTokenFile != "" {
+		return TrustedProfile
+	}
+	return InstanceIdentityToken
+}
+
+// ExchangeForAccessToken exchanges the token of the auth type for an access token of the trusted profile,
+// the one of the config if profileID is empty. Retries stop once the context is done.
+func (tes *TrustedProfileTokenExchangeService) ExchangeForAccessToken(ctx context.Context, authType provider.AuthType, profileID string, logger *zap.Logger) (*iam.AccessToken, error) {
+	if profileID == "" {
+		profileID = tes.config.ProfileID
+	}
+	var accessToken *iam.AccessToken
+	err := newExchangeRetrier(logger).retry(ctx, func() error {
+		var err error
+		switch authType {
+		case TrustedProfile:
+			accessToken, err = tes.exchangeComputeResourceToken(profileID, logger)
+		case InstanceIdentityToken:
+			accessToken, err = tes.exchangeInstanceIdentityToken(profileID, logger)
+		default:
+			err = util.NewError("ErrorUnclassified", "Unknown trusted profile auth type "+string(authType))
+		}
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return accessToken, nil
+}
+
+// ExchangeIAMAPIKeyForAccessToken exchanges the token of the config, the API key is ignored
+func (tes *TrustedProfileTokenExchangeService) ExchangeIAMAPIKeyForAccessToken(iamAPIKey string, logger *zap.Logger) (*iam.AccessToken, error) {
+	return tes.ExchangeForAccessToken(context.Background(), tes.AuthType(), "", logger)
+}
+
+// ExchangeRefreshTokenForAccessToken exchanges the token of the config, trusted profiles have no refresh token
+func (tes *TrustedProfileTokenExchangeService) ExchangeRefreshTokenForAccessToken(refreshToken string, logger *zap.Logger) (*iam.AccessToken, error) {
+	return tes.ExchangeForAccessToken(context.Background(), tes.AuthType(), "", logger)
+}
+
+// ExchangeIAMAPIKeyForAccessTokenWithContext exchanges the token of the config until the context is done
+func (tes *TrustedProfileTokenExchangeService) ExchangeIAMAPIKeyForAccessTokenWithContext(ctx context.Context, iamAPIKey string, logger *zap.Logger) (*iam.AccessToken, error) {
+	return tes.ExchangeForAccessToken(ctx, tes.AuthType(), "", logger)
+}
+
+// ExchangeRefreshTokenForAccessTokenWithContext exchanges the token of the config until the context is done
+func (tes *TrustedProfileTokenExchangeService) ExchangeRefreshTokenForAccessTokenWithContext(ctx context.Context, refreshToken string, logger *zap.Logger) (*iam.AccessToken, error) {
+	return tes.ExchangeForAccessToken(ctx, tes.AuthType(), "", logger)
+}
+
+// ExchangeAccessTokenForIMSToken ...
+func (tes *TrustedProfileTokenExchangeService) ExchangeAccessTokenForIMSToken(accessToken iam.AccessToken, logger *zap.Logger) (*iam.IMSToken, error) {
+	return nil, NewUnsupportedExchangeError("Access token exchange for IMS token")
+}
+
+// ExchangeIAMAPIKeyForIMSToken ...
+func (tes *TrustedProfileTokenExchangeService) ExchangeIAMAPIKeyForIMSToken(iamAPIKey string, logger *zap.Logger) (*iam.IMSToken, error) {
+	return nil, NewUnsupportedExchangeError("IAM API key exchange for IMS token")
+}
+
+// GetIAMAccountIDFromAccessToken returns the account of the access token
+func (tes *TrustedProfileTokenExchangeService) GetIAMAccountIDFromAccessToken(accessToken iam.AccessToken, logger *zap.Logger) (string, error) {
+	claims, err := ParseAccessTokenClaims(accessToken.Token)
+	if err != nil {
+		return "", err
+	}
+	return claims.Account.BSS, nil
+}
+
+// trustedProfileTokenResponse ...
+type trustedProfileTokenResponse struct {
+	AccessToken string `json:"access_token"`
+}
+
+// exchangeComputeResourceToken exchanges the projected compute resource token, read again for each exchange as
+// it is rotated
+func (tes *TrustedProfileTokenExchangeService) exchangeComputeResourceToken(profileID string, logger *zap.Logger) (*iam.AccessToken, error) {
+	crToken, err := ioutil.ReadFile(tes.config.ComputeResourceTokenFile)
+	if err != nil {
+		logger.Error("Failed to read the compute resource token", zap.String("file", tes.config.ComputeResourceTokenFile), zap.Error(err))
+		return nil, util.NewError("ErrorUnclassified", "Failed to read the compute resource token", err)
+	}
+
+	request := rest.PostRequest(fmt.Sprintf("%s/identity/token", tes.config.IamURL)).
+		Set("Accept", "application/json").
+		Field("grant_type", "urn:ibm:params:oauth:grant-type:cr-token").
+		Field("cr_token", strings.TrimSpace(string(crToken))).
+		Field("profile_id", profileID)
+
+	var successV trustedProfileTokenResponse
+	var errorV = struct {
+		ErrorMessage string `json:"errorMessage"`
+		ErrorCode    string `json:"errorCode"`
+	}{}
+	logger.Info("Sending compute resource token exchange request", zap.String("profileID", profileID))
+	resp, err := tes.client().Do(request, &successV, &errorV)
+	if err != nil {
+		logger.Error("Compute resource token exchange request failed", zap.Error(err))
+		return nil, exchangeRequestError(err)
+	}
+	if resp.StatusCode == http.StatusOK && successV.AccessToken != "" {
+		return &iam.AccessToken{Token: successV.AccessToken}, nil
+	}
+	logger.Error("Compute resource token exchange request failed with message", zap.Int("StatusCode", resp.StatusCode), zap.Reflect("Error", errorV))
+	return nil, util.NewError("ErrorFailedTokenExchange", "Compute resource token exchange request failed: "+errorV.ErrorCode+" "+errorV.ErrorMessage)
+}
+
+// metadataErrorResponse ...
+type metadataErrorResponse struct {
+	Errors []struct {
+		Code    string `json:"code"`
+		Message string `json:"message"`
+	} `json:"errors"`
+}
+
+func (e metadataErrorResponse) String() string {
+	messages := []string{}
+	for _, item := range e.Errors {
+		messages = append(messages, item.Code+" "+item.Message)
+	}
+	return strings.Join(messages, ", ")
+}
+
+// exchangeInstanceIdentityToken gets an identity token from the instance metadata service and exchanges it for an
+// access token of the trusted profile, the one linked to the instance if profileID is empty
+func (tes *TrustedProfileTokenExchangeService) exchangeInstanceIdentityToken(profileID string, logger *zap.Logger) (*iam.AccessToken, error) {
+	metadataURL := tes.config.MetadataURL
+	if metadataURL == "" {
+		metadataURL = DefaultMetadataURL
+	}
+
+	identityRequest := rest.PutRequest(metadataURL+"/instance_identity/v1/token").
+		Query("version", metadataAPIVersion).
+		Set("Metadata-Flavor", "ibm").
+		Set("Accept", "application/json").
+		Body(map[string]int{"expires_in": instanceIdentityTokenExpiry})
+	var identityV trustedProfileTokenResponse
+	var errorV metadataErrorResponse
+	logger.Info("Requesting instance identity token")
+	resp, err := tes.client().Do(identityRequest, &identityV, &errorV)
+	if err != nil {
+		logger.Error("Instance identity token request failed", zap.Error(err))
+		return nil, exchangeRequestError(err)
+	}
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusCreated || identityV.AccessToken == "" {
+		logger.Error("Instance identity token request failed with message", zap.Int("StatusCode", resp.StatusCode), zap.Reflect("Error", errorV))
+		return nil, util.NewError("ErrorFailedTokenExchange", "Instance identity token request failed: "+errorV.String())
+	}
+
+	iamRequest := rest.PostRequest(metadataURL+"/instance_identity/v1/iam_token").
+		Query("version", metadataAPIVersion).
+		Set("Authorization", "Bearer "+identityV.AccessToken).
+		Set("Accept", "application/json")
+	if profileID != "" {
+		iamRequest = iamRequest.Body(map[string]map[string]string{"trusted_profile": {"id": profileID}})
+	}
+	var successV trustedProfileTokenResponse
+	errorV = metadataErrorResponse{}
+	logger.Info("Sending instance identity token exchange request", zap.String("profileID", profileID))
+	resp, err = tes.client().Do(iamRequest, &successV, &errorV)
+	if err != nil {
+		logger.Error("Instance identity token exchange request failed", zap.Error(err))
+		return nil, exchangeRequestError(err)
+	}
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusCreated || successV.AccessToken == "" {
+		logger.Error("Instance identity token exchange request failed with message", zap.Int("StatusCode", resp.StatusCode), zap.Reflect("Error", errorV))
+		return nil, util.NewError("ErrorFailedTokenExchange", "Instance identity token exchange request failed: "+errorV.String())
+	}
+	return &iam.AccessToken{Token: successV.AccessToken}, nil
+}
+
+func (tes *TrustedProfileTokenExchangeService) client() *rest.Client {
+	client := rest.NewClient()
+	client.HTTPClient = tes.httpClient
+	return client
+}
+
+// exchangeRequestError classifies the errors of the exchange requests as the IKS token exchange does
+func exchangeRequestError(err error) error {
+	errString := err.Error()
+	switch {
+	case strings.Contains(errString, "no such host"):
+		return util.NewError("EndpointNotReachable", errString)
+	case strings.Contains(errString, "Timeout"):
+		return util.NewError("Timeout", errString)
+	default:
+		return util.NewError("ErrorUnclassified", "IAM token exchange request failed", err)
+	}
+}
@@ -0,0 +1,149 @@
+/**
+ * Copyright 2022 IBM Corp.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package iam ...
+package iam
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+
+	"github.com/IBM/ibmcloud-volume-interface/provider/iam"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTrustedProfileComputeResourceToken(t *testing.T) {
+	accessToken := testJWT(`{"iam_id":"iam-Profile-1","exp":1700000000,"account":{"bss":"account-1"}}`)
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = r.ParseForm()
+		if r.URL.Path != "/identity/token" || r.Form.Get("grant_type") != "urn:ibm:params:oauth:grant-type:cr-token" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		if r.Form.Get("cr_token") != "cr-token" {
+			w.WriteHeader(http.StatusBadRequest)
+			fmt.Fprint(w, `{"errorCode":"BXNIM0415E","errorMessage":"Provided compute resource token is invalid"}`)
+			return
+		}
+		fmt.Fprintf(w, `{"access_token":%q,"profile_id":%q}`, accessToken, r.Form.Get("profile_id"))
+	}))
+	defer s.Close()
+
+	tokenFile := filepath.Join(t.TempDir(), "token")
+	assert.Nil(t, ioutil.WriteFile(tokenFile, []byte("cr-token\n"), 0600))
+	tes := NewTokenExchangeTrustedProfileService(&TrustedProfileConfiguration{IamURL: s.URL, ProfileID: "profile-1", ComputeResourceTokenFile: tokenFile}, nil)
+	assert.Equal(t, TrustedProfile, tes.AuthType())
+
+	token, err := tes.ExchangeIAMAPIKeyForAccessToken("", logger)
+	assert.Nil(t, err)
+	assert.Equal(t, accessToken, token.Token)
+
+	accountID, err := tes.GetIAMAccountIDFromAccessToken(*token, logger)
+	assert.Nil(t, err)
+	assert.Equal(t, "account-1", accountID)
+
+	// Token file is read again for each exchange
+	assert.Nil(t, ioutil.WriteFile(tokenFile, []byte("rotated-cr-token"), 0600))
+	_, err = tes.ExchangeForAccessToken(context.Background(), TrustedProfile, "profile-2", logger)
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), "BXNIM0415E")
+
+	tes = NewTokenExchangeTrustedProfileService(&TrustedProfileConfiguration{IamURL: s.URL, ComputeResourceTokenFile: filepath.Join(t.TempDir(), "missing")}, nil)
+	_, err = tes.ExchangeIAMAPIKeyForAccessToken("", logger)
+	assert.NotNil(t, err)
+
+	_, err = tes.ExchangeIAMAPIKeyForIMSToken("", logger)
+	assert.NotNil(t, err)
+	_, err = tes.ExchangeAccessTokenForIMSToken(iam.AccessToken{}, logger)
+	assert.NotNil(t, err)
+}
+
+func TestTrustedProfileInstanceIdentityToken(t *testing.T) {
+	testCases := []struct {
+		name              string
+		profileID         string
+		identityStatus    int
+		expectedProfileID string
+		expectErr         bool
+	}{
+		{
+			name:              "profile of the config",
+			identityStatus:    http.StatusOK,
+			expectedProfileID: "profile-1",
+		}, {
+			name:              "profile of the credentials",
+			profileID:         "profile-2",
+			identityStatus:    http.StatusOK,
+			expectedProfileID: "profile-2",
+		}, {
+			name:           "identity token request fails",
+			identityStatus: http.StatusForbidden,
+			expectErr:      true,
+		},
+	}
+
+	for _, testcase := range testCases {
+		t.Run(testcase.name, func(t *testing.T) {
+			var profileID string
+			s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", "application/json")
+				if r.URL.Query().Get("version") != metadataAPIVersion {
+					w.WriteHeader(http.StatusBadRequest)
+					return
+				}
+				switch {
+				case r.Method == http.MethodPut && r.URL.Path == "/instance_identity/v1/token" && r.Header.Get("Metadata-Flavor") == "ibm":
+					w.WriteHeader(testcase.identityStatus)
+					if testcase.identityStatus != http.StatusOK {
+						fmt.Fprint(w, `{"errors":[{"code":"forbidden","message":"Metadata service is disabled"}]}`)
+						return
+					}
+					fmt.Fprint(w, `{"access_token":"identity-token"}`)
+				case r.Method == http.MethodPost && r.URL.Path == "/instance_identity/v1/iam_token" && r.Header.Get("Authorization") == "Bearer identity-token":
+					var body struct {
+						TrustedProfile struct {
+							ID string `json:"id"`
+						} `json:"trusted_profile"`
+					}
+					_ = json.NewDecoder(r.Body).Decode(&body)
+					profileID = body.TrustedProfile.ID
+					fmt.Fprint(w, `{"access_token":"access-token"}`)
+				default:
+					w.WriteHeader(http.StatusNotFound)
+				}
+			}))
+			defer s.Close()
+
+			tes := NewTokenExchangeTrustedProfileService(&TrustedProfileConfiguration{ProfileID: "profile-1", MetadataURL: s.URL}, nil)
+			assert.Equal(t, InstanceIdentityToken, tes.AuthType())
+			token, err := tes.ExchangeForAccessToken(context.Background(), InstanceIdentityToken, testcase.profileID, logger)
+			if testcase.expectErr {
+				assert.NotNil(t, err)
+				return
+			}
+			assert.Nil(t, err)
+			assert.Equal(t, "access-token", token.Token)
+			assert.Equal(t, testcase.expectedProfileID, profileID)
+		})
+	}
+}
@@ -275,6 +275,118 @@ var messagesEn = map[string]util.Message{
 		RC:          500,
 		Action:      "Please retry again after some time.",
 	},
+	"VolumeLeaseHeld": {
+		Code:        VolumeLeaseHeld,
+		Description: "The volume ID '%s' is leased by '%s' until %s.",
+		Type:        util.UpdateFailed,
+		RC:          409,
+		Action:      "Another controller replica is updating the volume. Wait for the lease to be released or to expire and try again.",
+	},
+	"VolumeLeaseFailed": {
+		Code:        VolumeLeaseFailed,
+		Description: "Failed to acquire the lease on the volume ID '%s'.",
+		Type:        util.UpdateFailed,
+		RC:          500,
+		Action:      "Verify that the volume ID exists and that its tags can be updated. Run 'ibmcloud is volume VOLUME_ID' to check the volume.",
+	},
+	"EndpointUnavailable": {
+		Code:        EndpointUnavailable,
+		Description: "The endpoint '%s' is unavailable after consecutive failures, requests are not sent until %s.",
+		Type:        util.RetrivalFailed,
+		RC:          503,
+		Action:      "Wait for a few minutes and try again. If the error persists, check the status of the region at https://cloud.ibm.com/status.",
+	},
+	"CredentialsReloadFailed": {
+		Code:        CredentialsReloadFailed,
+		Description: "The rotated credentials could not be validated, the provider keeps using the previous credentials.",
+		Type:        util.FailedAccessToken,
+		RC:          400,
+		Action:      "Verify that the API key in the storage secret store is valid and has access to the VPC infrastructure service.",
+	},
+	"AccountMismatch": {
+		Code:        AccountMismatch,
+		Description: "The access token belongs to the account '%s', but the account '%s' is expected.",
+		Type:        util.PermissionDenied,
+		RC:          403,
+		Action:      "Verify that the API key or trusted profile in the storage secret store belongs to the account of the cluster.",
+	},
+	"AccountNotConfigured": {
+		Code:        AccountNotConfigured,
+		Description: "No credentials are configured for the account or resource group '%s'.",
+		Type:        util.Unauthenticated,
+		RC:          401,
+		Action:      "Add the API key of the account to the accounts of the provider config, or verify the account or resource group of the request.",
+	},
+	"ResourceGroupNotFound": {
+		Code:        ResourceGroupNotFound,
+		Description: "The resource group '%s' could not be found in the account.",
+		Type:        util.InvalidRequest,
+		RC:          400,
+		Action:      "Provide the name or ID of a resource group of the account. Run 'ibmcloud resource groups' to list the resource groups that you have access to.",
+	},
+	"ResourceGroupNameAmbiguous": {
+		Code:        ResourceGroupNameAmbiguous,
+		Description: "The resource group name '%s' matches several resource groups: %s.",
+		Type:        util.InvalidRequest,
+		RC:          400,
+		Action:      "Provide the ID of the resource group instead of its name. Run 'ibmcloud resource groups' to list the resource groups and their IDs.",
+	},
+	"ResourceGroupLookupFailed": {
+		Code:        ResourceGroupLookupFailed,
+		Description: "Failed to find the ID of the resource group '%s'.",
+		Type:        util.RetrivalFailed,
+		RC:          500,
+		Action:      "Wait for a few minutes and try again, or provide the ID of the resource group instead of its name. If the error persists, check the status of the Resource Manager at https://cloud.ibm.com/status.",
+	},
+	"InvalidEncryptionKeyCRN": {
+		Code:        InvalidEncryptionKeyCRN,
+		Description: "The encryption key CRN '%s' is not valid, %s.",
+		Type:        util.InvalidRequest,
+		RC:          400,
+		Action:      "Provide the CRN of a root key of Key Protect or Hyper Protect Crypto Services. Run 'ibmcloud kp keys --output json' to list the keys and their CRNs.",
+	},
+	"EncryptionKeyRegionMismatch": {
+		Code:        EncryptionKeyRegionMismatch,
+		Description: "The encryption key is in the region '%s', but the volume zone '%s' is in the region '%s'.",
+		Type:        util.InvalidRequest,
+		RC:          400,
+		Action:      "Provide a root key of a key management instance in the region of the volume zone, or create the volume in a zone of the region of the key.",
+	},
+	"EncryptionKeyNotFound": {
+		Code:        EncryptionKeyNotFound,
+		Description: "The encryption key '%s' could not be found in the key management instance '%s'.",
+		Type:        util.InvalidRequest,
+		RC:          400,
+		Action:      "Verify the CRN of the root key. Run 'ibmcloud kp keys --instance-ID INSTANCE_ID' to list the keys of the instance.",
+	},
+	"EncryptionKeyNotEnabled": {
+		Code:        EncryptionKeyNotEnabled,
+		Description: "The encryption key '%s' is not enabled, its state is '%s'.",
+		Type:        util.InvalidRequest,
+		RC:          400,
+		Action:      "Enable the root key, or provide another root key in the active state. Run 'ibmcloud kp key show KEY_ID --instance-ID INSTANCE_ID' to check the state of the key.",
+	},
+	"EncryptionKeyAccessDenied": {
+		Code:        EncryptionKeyAccessDenied,
+		Description: "The credentials of the provider cannot access the key management instance '%s' of the encryption key.",
+		Type:        util.PermissionDenied,
+		RC:          403,
+		Action:      "Grant the Reader role on the key management instance to the credentials of the provider. Run 'ibmcloud iam user-policy-create USER --roles Reader --service-name kms --service-instance INSTANCE_ID' to assign the role.",
+	},
+	"EncryptionKeyNotAuthorized": {
+		Code:        EncryptionKeyNotAuthorized,
+		Description: "Block Storage is not authorized to use the keys of the key management instance '%s' of the encryption key.",
+		Type:        util.PermissionDenied,
+		RC:          403,
+		Action:      "Authorize Cloud Block Storage to read the keys of the instance. Run 'ibmcloud iam authorization-policy-create server-protect %s Reader --target-service-instance-id INSTANCE_ID' to create the authorization.",
+	},
+	"EncryptionKeyCheckFailed": {
+		Code:        EncryptionKeyCheckFailed,
+		Description: "Failed to check the encryption key '%s' with the key management service.",
+		Type:        util.RetrivalFailed,
+		RC:          500,
+		Action:      "Wait for a few minutes and try again. If the error persists, check the status of the key management service at https://cloud.ibm.com/status.",
+	},
 }
 
 // InitMessages ...
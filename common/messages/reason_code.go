@@ -36,4 +36,36 @@ const (
 	VolumeDetachTimedOut = "VolumeDetachTimedOut"
 	//InvalidServiceSession indicates that there is some issue with IAM token exchange request for container service
 	InvalidServiceSession = "InvalidServiceSession"
+	//VolumeLeaseHeld indicates that the volume is leased by another holder
+	VolumeLeaseHeld = "VolumeLeaseHeld"
+	//VolumeLeaseFailed indicates that the volume lease could not be acquired or renewed
+	VolumeLeaseFailed = "VolumeLeaseFailed"
+	//EndpointUnavailable indicates that the circuit of the endpoint is open after consecutive failures
+	EndpointUnavailable = "EndpointUnavailable"
+	//CredentialsReloadFailed indicates that the rotated credentials failed the validation exchange
+	CredentialsReloadFailed = "CredentialsReloadFailed"
+	//AccountMismatch indicates that the access token belongs to another account than the expected one
+	AccountMismatch = "AccountMismatch"
+	//AccountNotConfigured indicates that no credentials are configured for the account of the request
+	AccountNotConfigured = "AccountNotConfigured"
+	//ResourceGroupNotFound indicates that no resource group of the account has the name of the request
+	ResourceGroupNotFound = "ResourceGroupNotFound"
+	//ResourceGroupNameAmbiguous indicates that several resource groups of the account have the name of the request
+	ResourceGroupNameAmbiguous = "ResourceGroupNameAmbiguous"
+	//ResourceGroupLookupFailed indicates that the Resource Manager request resolving a resource group name failed
+	ResourceGroupLookupFailed = "ResourceGroupLookupFailed"
+	//InvalidEncryptionKeyCRN indicates that the encryption key CRN of the volume is not a Key Protect or HPCS key CRN
+	InvalidEncryptionKeyCRN = "InvalidEncryptionKeyCRN"
+	//EncryptionKeyRegionMismatch indicates that the encryption key is not in the region of the volume zone
+	EncryptionKeyRegionMismatch = "EncryptionKeyRegionMismatch"
+	//EncryptionKeyNotFound indicates that the encryption key is not found in its key management instance
+	EncryptionKeyNotFound = "EncryptionKeyNotFound"
+	//EncryptionKeyNotEnabled indicates that the encryption key is not in the active state
+	EncryptionKeyNotEnabled = "EncryptionKeyNotEnabled"
+	//EncryptionKeyAccessDenied indicates that the credentials of the provider cannot access the key management instance of the encryption key
+	EncryptionKeyAccessDenied = "EncryptionKeyAccessDenied"
+	//EncryptionKeyNotAuthorized indicates that Block Storage is not authorized to use the keys of the key management instance of the encryption key
+	EncryptionKeyNotAuthorized = "EncryptionKeyNotAuthorized"
+	//EncryptionKeyCheckFailed indicates that the key management request checking the encryption key failed
+	EncryptionKeyCheckFailed = "EncryptionKeyCheckFailed"
 )
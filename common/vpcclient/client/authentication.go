@@ -18,25 +18,115 @@
 package client
 
 import (
+	"bytes"
+	"encoding/json"
 	"errors"
+	"io"
+	"io/ioutil"
+	"net/http"
+
+	"github.com/IBM/ibmcloud-volume-vpc/common/vpcclient/models"
 )
 
 // ErrAuthenticationRequired is returned if a request is made before an authentication
 // token has been provided to the client
 var ErrAuthenticationRequired = errors.New("authentication token required")
 
+// TokenSource provides the authentication token of the requests, and a new one once the API rejected it.
+// It is shared by the concurrent requests of a session.
+type TokenSource interface {
+	// Token returns the current token
+	Token() (string, error)
+
+	// Refresh returns a token replacing the rejected one. Concurrent callers rejected with the same token
+	// should share a single refresh.
+	Refresh(rejected string) (string, error)
+}
+
 type authenticationHandler struct {
-	authToken     string
-	resourceGroup string
+	authToken   string
+	tokenSource TokenSource
 }
 
-// Before is called before each request
-func (a *authenticationHandler) Before(request *Request) error {
-	request.resourceGroup = a.resourceGroup
+// Wrap sets the Authorization header before each request. With a token source, a request rejected as
+// token_invalid is replayed once with a refreshed token.
+func (a *authenticationHandler) Wrap(next Handler) Handler {
+	return func(request *Request, httpRequest *http.Request) (*http.Response, error) {
+		if a.tokenSource != nil {
+			return a.invokeWithTokenSource(next, request, httpRequest)
+		}
+		if a.authToken == "" {
+			return nil, ErrAuthenticationRequired
+		}
+		httpRequest.Header.Set("Authorization", "Bearer "+a.authToken)
+		return next(request, httpRequest)
+	}
+}
+
+func (a *authenticationHandler) invokeWithTokenSource(next Handler, request *Request, httpRequest *http.Request) (*http.Response, error) {
+	token, err := a.tokenSource.Token()
+	if err != nil {
+		return nil, err
+	}
+	if token == "" {
+		return nil, ErrAuthenticationRequired
+	}
+	// Body is consumed by the first attempt, only requests whose body can be read again are replayed
+	replayable := httpRequest.Body == nil || httpRequest.Body == http.NoBody || httpRequest.GetBody != nil
+	replay := httpRequest.Clone(httpRequest.Context())
+
+	httpRequest.Header.Set("Authorization", "Bearer "+token)
+	resp, err := next(request, httpRequest)
+	if err != nil || !replayable || resp.StatusCode != http.StatusUnauthorized || !isTokenInvalid(resp) {
+		return resp, err
+	}
 
-	if a.authToken == "" {
-		return ErrAuthenticationRequired
+	refreshed, refreshErr := a.tokenSource.Refresh(token)
+	if refreshErr != nil || refreshed == "" || refreshed == token {
+		// Caller gets the rejection of the API
+		return resp, nil
 	}
-	request.headers.Set("Authorization", "Bearer "+a.authToken)
-	return nil
+	if httpRequest.GetBody != nil {
+		if replay.Body, err = httpRequest.GetBody(); err != nil {
+			return resp, nil
+		}
+	}
+	_ = resp.Body.Close()
+	replay.Header.Set("Authorization", "Bearer "+refreshed)
+	return next(request, replay)
+}
+
+// maxTokenErrorBodySize is the size of the 401 body read for the error code
+const maxTokenErrorBodySize = 64 * 1024
+
+// isTokenInvalid tells whether the API rejected the token of the request as token_invalid. It reads the error
+// code from the body, and puts the body back for the error consumer.
+func isTokenInvalid(resp *http.Response) bool {
+	if resp.Body == nil {
+		return false
+	}
+	body, err := ioutil.ReadAll(io.LimitReader(resp.Body, maxTokenErrorBodySize))
+	resp.Body = struct {
+		io.Reader
+		io.Closer
+	}{io.MultiReader(bytes.NewReader(body), resp.Body), resp.Body}
+	if err != nil {
+		return false
+	}
+	var apiErr models.Error
+	if json.Unmarshal(body, &apiErr) != nil {
+		return false
+	}
+	for _, item := range apiErr.Errors {
+		if item.Code == models.ErrorCodeTokenInvalid {
+			return true
+		}
+	}
+	return false
+}
+
+// IsTokenInvalid tells whether the response is the rejection of the token of the request as token_invalid.
+// The body is left for the error consumer.
+func IsTokenInvalid(resp *http.Response) bool {
+	return resp != nil && resp.StatusCode == http.StatusUnauthorized && isTokenInvalid(resp)
 }
@@ -0,0 +1,165 @@
+/**
+ * Copyright 2022 IBM Corp.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package client_test ...
+package client_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/IBM/ibmcloud-volume-vpc/common/vpcclient/client"
+	"github.com/IBM/ibmcloud-volume-vpc/common/vpcclient/models"
+)
+
+// stubTokenSource hands out token, and refreshed on refresh
+type stubTokenSource struct {
+	mu         sync.Mutex
+	token      string
+	refreshed  string
+	refreshErr error
+	refreshes  int
+}
+
+func (s *stubTokenSource) Token() (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.token, nil
+}
+
+func (s *stubTokenSource) Refresh(rejected string) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.refreshes++
+	if s.refreshErr != nil {
+		return "", s.refreshErr
+	}
+	s.token = s.refreshed
+	return s.token, nil
+}
+
+func TestWithTokenSource(t *testing.T) {
+	var (
+		mu            sync.Mutex
+		authorization []string
+		bodies        []string
+	)
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		mu.Lock()
+		authorization = append(authorization, r.Header.Get("Authorization"))
+		bodies = append(bodies, string(body))
+		mu.Unlock()
+
+		w.Header().Set("Content-Type", "application/json")
+		switch r.Header.Get("Authorization") {
+		case "Bearer fresh-token":
+			fmt.Fprint(w, `{"id":"volume-1"}`)
+		case "Bearer unauthorized-token":
+			w.WriteHeader(http.StatusUnauthorized)
+			fmt.Fprint(w, `{"errors":[{"code":"not_authorized","message":"Not authorized"}],"trace":"trace-2"}`)
+		default:
+			w.WriteHeader(http.StatusUnauthorized)
+			fmt.Fprint(w, `{"errors":[{"code":"token_invalid","message":"Token expired"}],"trace":"trace-1"}`)
+		}
+	}))
+	defer s.Close()
+
+	testcases := []struct {
+		name                  string
+		tokenSource           *stubTokenSource
+		operation             *client.Operation
+		expectedErr           string
+		expectedRefreshes     int
+		expectedAuthorization []string
+	}{
+		{
+			name:                  "rejected token is refreshed and the request replayed",
+			tokenSource:           &stubTokenSource{token: "expired-token", refreshed: "fresh-token"},
+			operation:             getOperation,
+			expectedRefreshes:     1,
+			expectedAuthorization: []string{"Bearer expired-token", "Bearer fresh-token"},
+		}, {
+			name:                  "body is replayed",
+			tokenSource:           &stubTokenSource{token: "expired-token", refreshed: "fresh-token"},
+			operation:             postOperation,
+			expectedRefreshes:     1,
+			expectedAuthorization: []string{"Bearer expired-token", "Bearer fresh-token"},
+		}, {
+			name:                  "request is replayed once",
+			tokenSource:           &stubTokenSource{token: "expired-token", refreshed: "still-expired-token"},
+			operation:             getOperation,
+			expectedErr:           "Trace Code:trace-1, Token expired Please check ",
+			expectedRefreshes:     1,
+			expectedAuthorization: []string{"Bearer expired-token", "Bearer still-expired-token"},
+		}, {
+			name:                  "failed refresh returns the rejection",
+			tokenSource:           &stubTokenSource{token: "expired-token", refreshErr: errors.New("exchange failed")},
+			operation:             getOperation,
+			expectedErr:           "Trace Code:trace-1, Token expired Please check ",
+			expectedRefreshes:     1,
+			expectedAuthorization: []string{"Bearer expired-token"},
+		}, {
+			name:                  "other authorization errors are not refreshed",
+			tokenSource:           &stubTokenSource{token: "unauthorized-token", refreshed: "fresh-token"},
+			operation:             getOperation,
+			expectedErr:           "Trace Code:trace-2, Not authorized Please check ",
+			expectedAuthorization: []string{"Bearer unauthorized-token"},
+		}, {
+			name:        "no token",
+			tokenSource: &stubTokenSource{},
+			operation:   getOperation,
+			expectedErr: client.ErrAuthenticationRequired.Error(),
+		},
+	}
+
+	for _, testcase := range testcases {
+		t.Run(testcase.name, func(t *testing.T) {
+			authorization, bodies = nil, nil
+			c := client.New(context.Background(), s.URL, nil, http.DefaultClient, "test-context", "").WithTokenSource(testcase.tokenSource)
+
+			var result struct {
+				ID string `json:"id"`
+			}
+			var errResult models.Error
+			request := c.NewRequest(testcase.operation).JSONSuccess(&result).JSONError(&errResult)
+			if testcase.operation.Method == http.MethodPost {
+				request = request.JSONBody(map[string]string{"name": "volume"})
+			}
+			_, err := request.Invoke()
+
+			if testcase.expectedErr != "" {
+				assert.EqualError(t, err, testcase.expectedErr)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, "volume-1", result.ID)
+			}
+			assert.Equal(t, testcase.expectedRefreshes, testcase.tokenSource.refreshes)
+			assert.Equal(t, testcase.expectedAuthorization, authorization)
+			if testcase.operation.Method == http.MethodPost {
+				assert.Equal(t, []string{"{\"name\":\"volume\"}\n", "{\"name\":\"volume\"}\n"}, bodies)
+			}
+		})
+	}
+}
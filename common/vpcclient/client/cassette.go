@@ -0,0 +1,321 @@
+/**
+ * Copyright 2022 IBM Corp.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package client ...
+package client
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"mime"
+	"net/http"
+	"net/url"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// CassetteMode tells whether a cassette records or replays the exchanges
+type CassetteMode int
+
+const (
+	// CassetteRecord sends the requests and records the exchanges
+	CassetteRecord CassetteMode = iota
+	// CassetteReplay serves the recorded exchanges without sending anything
+	CassetteReplay
+)
+
+// Interaction is a recorded request and its response
+type Interaction struct {
+	Request  RecordedRequest  `json:"request"`
+	Response RecordedResponse `json:"response"`
+}
+
+// RecordedRequest is the sanitized request of an interaction
+type RecordedRequest struct {
+	Method string      `json:"method"`
+	Path   string      `json:"path"`
+	Query  string      `json:"query,omitempty"`
+	Header http.Header `json:"header,omitempty"`
+	Body   string      `json:"body,omitempty"`
+}
+
+// RecordedResponse is the sanitized response of an interaction
+type RecordedResponse struct {
+	StatusCode int         `json:"status_code"`
+	Header     http.Header `json:"header,omitempty"`
+	Body       string      `json:"body,omitempty"`
+}
+
+// Cassette is an http.RoundTripper that records the exchanges to a file, or replays them from it.
+// Replayed requests are matched on method, path and normalized query, in the order they were recorded.
+type Cassette struct {
+	mode      CassetteMode
+	path      string
+	transport http.RoundTripper
+
+	mu           sync.Mutex
+	interactions []Interaction
+	replayed     map[int]bool
+}
+
+var _ http.RoundTripper = &Cassette{}
+
+// NewCassetteRecorder creates a cassette recording the exchanges sent through transport, http.DefaultTransport if nil.
+// Save writes them to path.
+func NewCassetteRecorder(path string, transport http.RoundTripper) *Cassette {
+	if transport == nil {
+		transport = http.DefaultTransport
+	}
+	return &Cassette{mode: CassetteRecord, path: path, transport: transport}
+}
+
+// NewCassetteReplayer creates a cassette replaying the exchanges recorded in path
+func NewCassetteReplayer(path string) (*Cassette, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var interactions []Interaction
+	if err = json.Unmarshal(data, &interactions); err != nil {
+		return nil, fmt.Errorf("cassette %s: %v", path, err)
+	}
+	return &Cassette{mode: CassetteReplay, path: path, interactions: interactions, replayed: map[int]bool{}}, nil
+}
+
+// Mode returns the mode of the cassette
+func (c *Cassette) Mode() CassetteMode {
+	return c.mode
+}
+
+// Interactions returns the interactions recorded so far, or loaded for replay
+func (c *Cassette) Interactions() []Interaction {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([]Interaction(nil), c.interactions...)
+}
+
+// Remaining returns the count of the recorded interactions not replayed yet
+func (c *Cassette) Remaining() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.interactions) - len(c.replayed)
+}
+
+// Save writes the recorded interactions to the file of the cassette
+func (c *Cassette) Save() error {
+	if c.mode != CassetteRecord {
+		return nil
+	}
+	data, err := json.MarshalIndent(c.Interactions(), "", "  ")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(c.path, append(data, '\n'), 0644)
+}
+
+// RoundTrip implements http.RoundTripper
+func (c *Cassette) RoundTrip(req *http.Request) (*http.Response, error) {
+	if c.mode == CassetteReplay {
+		return c.replay(req)
+	}
+	return c.record(req)
+}
+
+func (c *Cassette) record(req *http.Request) (*http.Response, error) {
+	var body []byte
+	if req.Body != nil {
+		var err error
+		if body, err = ioutil.ReadAll(req.Body); err != nil {
+			return nil, err
+		}
+		_ = req.Body.Close()
+		req.Body = ioutil.NopCloser(bytes.NewReader(body))
+	}
+
+	resp, err := c.transport.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	respBody, err := ioutil.ReadAll(resp.Body)
+	_ = resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = ioutil.NopCloser(bytes.NewReader(respBody))
+
+	interaction := Interaction{
+		Request: RecordedRequest{
+			Method: req.Method,
+			Path:   req.URL.Path,
+			Query:  recordedQuery(req.URL.Query()),
+			Header: sanitizeHeader(req.Header),
+			Body:   sanitizeBody(body, req.Header.Get("Content-Type")),
+		},
+		Response: RecordedResponse{
+			StatusCode: resp.StatusCode,
+			Header:     sanitizeHeader(resp.Header),
+			Body:       sanitizeBody(respBody, resp.Header.Get("Content-Type")),
+		},
+	}
+	c.mu.Lock()
+	c.interactions = append(c.interactions, interaction)
+	c.mu.Unlock()
+	return resp, nil
+}
+
+func (c *Cassette) replay(req *http.Request) (*http.Response, error) {
+	if req.Body != nil {
+		_ = req.Body.Close()
+	}
+	query := recordedQuery(req.URL.Query())
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for i, interaction := range c.interactions {
+		recorded := interaction.Request
+		if c.replayed[i] || recorded.Method != req.Method || recorded.Path != req.URL.Path || recorded.Query != query {
+			continue
+		}
+		c.replayed[i] = true
+		header := http.Header{}
+		for name, values := range interaction.Response.Header {
+			header[name] = append([]string(nil), values...)
+		}
+		return &http.Response{
+			Status:        fmt.Sprintf("%d %s", interaction.Response.StatusCode, http.StatusText(interaction.Response.StatusCode)),
+			StatusCode:    interaction.Response.StatusCode,
+			Proto:         "HTTP/1.1",
+			ProtoMajor:    1,
+			ProtoMinor:    1,
+			Header:        header,
+			Body:          ioutil.NopCloser(strings.NewReader(interaction.Response.Body)),
+			ContentLength: int64(len(interaction.Response.Body)),
+			Request:       req,
+		}, nil
+	}
+	return nil, fmt.Errorf("cassette %s: no recorded interaction left for %s %s?%s", c.path, req.Method, req.URL.Path, query)
+}
+
+// recordedQuery is the query as recorded in the cassette, its sensitive values redacted and normalized.
+// Replayed requests are matched on it.
+func recordedQuery(query url.Values) string {
+	return normalizeQuery(redactValues(query))
+}
+
+// normalizeQuery encodes the query sorted by key and value
+func normalizeQuery(query url.Values) string {
+	for _, values := range query {
+		sort.Strings(values)
+	}
+	return query.Encode()
+}
+
+// sensitiveHeaders are redacted from the cassettes, in addition to the ones sanitize redacts
+var sensitiveHeaders = []string{"Cookie", "Set-Cookie", "X-Csrf-Token", "Refresh-Token"}
+
+// tokenPattern matches the bearer tokens and JWTs left in the headers and bodies
+var tokenPattern = regexp.MustCompile(`(?i)bearer\s+[^\s",]+|eyJ[\w-]*\.[\w-]*\.[\w-]*`)
+
+func sanitizeHeader(header http.Header) http.Header {
+	sanitized := http.Header{}
+	for name, values := range header {
+		for _, value := range values {
+			line := sanitize([]byte(name + ": " + value))
+			sanitized.Add(name, scrubTokens(strings.TrimPrefix(line, name+": ")))
+		}
+	}
+	for _, name := range sensitiveHeaders {
+		if sanitized.Get(name) != "" {
+			sanitized.Set(name, RedactedFillin)
+		}
+	}
+	return sanitized
+}
+
+// sanitizeBody redacts the sensitive properties of a JSON body and the sensitive values of a form body,
+// other bodies are sanitized as text
+func sanitizeBody(body []byte, contentType string) string {
+	if len(body) == 0 {
+		return ""
+	}
+	var document interface{}
+	if err := json.Unmarshal(body, &document); err != nil {
+		if mediaType, _, _ := mime.ParseMediaType(contentType); mediaType == "application/x-www-form-urlencoded" {
+			if form, err := url.ParseQuery(string(body)); err == nil {
+				return scrubTokens(redactValues(form).Encode())
+			}
+		}
+		return scrubTokens(sanitize(body))
+	}
+	sanitized, err := json.Marshal(redactJSON(document))
+	if err != nil {
+		return scrubTokens(sanitize(body))
+	}
+	return scrubTokens(string(sanitized))
+}
+
+// sensitiveProperties are the property substrings whose string values are redacted, as sanitize does
+var sensitiveProperties = []string{"key", "password", "passphrase", "token", "secret"}
+
+func redactJSON(document interface{}) interface{} {
+	switch value := document.(type) {
+	case map[string]interface{}:
+		for name, property := range value {
+			if _, isString := property.(string); isString && isSensitiveProperty(name) {
+				value[name] = RedactedFillin
+				continue
+			}
+			value[name] = redactJSON(property)
+		}
+	case []interface{}:
+		for i := range value {
+			value[i] = redactJSON(value[i])
+		}
+	}
+	return document
+}
+
+// redactValues redacts the values of the sensitive keys of a query or form, as redactJSON does for JSON
+func redactValues(values url.Values) url.Values {
+	redacted := url.Values{}
+	for name, list := range values {
+		for _, value := range list {
+			if isSensitiveProperty(name) {
+				value = RedactedFillin
+			}
+			redacted.Add(name, value)
+		}
+	}
+	return redacted
+}
+
+func isSensitiveProperty(name string) bool {
+	name = strings.ToLower(name)
+	for _, property := range sensitiveProperties {
+		if strings.Contains(name, property) {
+			return true
+		}
+	}
+	return false
+}
+
+func scrubTokens(input string) string {
+	return tokenPattern.ReplaceAllString(input, RedactedFillin)
+}
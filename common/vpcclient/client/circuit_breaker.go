@@ -0,0 +1,181 @@
+/**
+ * Copyright 2022 IBM Corp.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package client ...
+package client
+
+import (
+	"sync"
+	"time"
+
+	"github.com/IBM/ibmcloud-volume-vpc/common/vpcclient/models"
+)
+
+const (
+	// DefaultFailureThreshold is the number of consecutive failures which open the circuit
+	DefaultFailureThreshold = 5
+
+	// DefaultCoolDown is the time the circuit stays open before a trial request is let through
+	DefaultCoolDown = 30 * time.Second
+)
+
+// CircuitState is the state of a circuit breaker
+type CircuitState int
+
+// Circuit states
+const (
+	CircuitClosed CircuitState = iota
+	CircuitOpen
+	CircuitHalfOpen
+)
+
+func (s CircuitState) String() string {
+	switch s {
+	case CircuitOpen:
+		return "open"
+	case CircuitHalfOpen:
+		return "half-open"
+	}
+	return "closed"
+}
+
+// CircuitBreakerConfig configures the circuit breaker of an endpoint
+type CircuitBreakerConfig struct {
+	FailureThreshold int           // Consecutive failures which open the circuit, defaults to DefaultFailureThreshold
+	CoolDown         time.Duration // Time the circuit stays open, defaults to DefaultCoolDown
+}
+
+// CircuitBreaker stops sending requests to an endpoint after consecutive network errors or 5xx responses.
+// Once the cool down is over one trial request is let through (half-open), which closes the circuit on
+// success or opens it again on failure.
+type CircuitBreaker struct {
+	baseURL          string
+	failureThreshold int
+	coolDown         time.Duration
+
+	mu       sync.Mutex
+	state    CircuitState
+	failures int
+	openedAt time.Time
+	trial    bool // Trial request of the half-open circuit is in flight
+}
+
+// circuitResult is the outcome of a request for the circuit breaker
+type circuitResult int
+
+const (
+	circuitSuccess circuitResult = iota
+	circuitFailure
+	circuitIgnored // e.g request cancelled by the caller, says nothing about the endpoint
+)
+
+// NewCircuitBreaker creates a closed circuit breaker for the endpoint
+func NewCircuitBreaker(baseURL string, config CircuitBreakerConfig) *CircuitBreaker {
+	cb := &CircuitBreaker{
+		baseURL:          baseURL,
+		failureThreshold: config.FailureThreshold,
+		coolDown:         config.CoolDown,
+	}
+	if cb.failureThreshold <= 0 {
+		cb.failureThreshold = DefaultFailureThreshold
+	}
+	if cb.coolDown <= 0 {
+		cb.coolDown = DefaultCoolDown
+	}
+	return cb
+}
+
+var (
+	sharedCircuitBreakersMu sync.Mutex
+	sharedCircuitBreakers   = map[string]*CircuitBreaker{}
+)
+
+// SharedCircuitBreaker returns the circuit breaker of the endpoint, so that all the clients of the endpoint
+// share the same circuit. The config is used when the circuit breaker is created.
+func SharedCircuitBreaker(baseURL string, config CircuitBreakerConfig) *CircuitBreaker {
+	sharedCircuitBreakersMu.Lock()
+	defer sharedCircuitBreakersMu.Unlock()
+	cb, ok := sharedCircuitBreakers[baseURL]
+	if !ok {
+		cb = NewCircuitBreaker(baseURL, config)
+		sharedCircuitBreakers[baseURL] = cb
+	}
+	return cb
+}
+
+// CircuitBreakerStates returns the state of the shared circuit breakers keyed on base URL, for health checks
+func CircuitBreakerStates() map[string]CircuitState {
+	sharedCircuitBreakersMu.Lock()
+	defer sharedCircuitBreakersMu.Unlock()
+	states := make(map[string]CircuitState, len(sharedCircuitBreakers))
+	for baseURL, cb := range sharedCircuitBreakers {
+		states[baseURL] = cb.State()
+	}
+	return states
+}
+
+// State returns the current state of the circuit
+func (cb *CircuitBreaker) State() CircuitState {
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+	if cb.state == CircuitOpen && !time.Now().Before(cb.openedAt.Add(cb.coolDown)) {
+		return CircuitHalfOpen
+	}
+	return cb.state
+}
+
+// allow returns EndpointUnavailableError if the request must not be sent. Every allowed request must be
+// followed by record.
+func (cb *CircuitBreaker) allow() error {
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+	switch cb.state {
+	case CircuitOpen:
+		retryAt := cb.openedAt.Add(cb.coolDown)
+		if time.Now().Before(retryAt) {
+			return &models.EndpointUnavailableError{BaseURL: cb.baseURL, RetryAt: retryAt}
+		}
+		cb.state = CircuitHalfOpen
+		cb.trial = true
+	case CircuitHalfOpen:
+		if cb.trial {
+			return &models.EndpointUnavailableError{BaseURL: cb.baseURL, RetryAt: time.Now().Add(cb.coolDown)}
+		}
+		cb.trial = true
+	}
+	return nil
+}
+
+// record updates the circuit with the outcome of an allowed request
+func (cb *CircuitBreaker) record(result circuitResult) {
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+	switch result {
+	case circuitSuccess:
+		cb.state = CircuitClosed
+		cb.failures = 0
+		cb.trial = false
+	case circuitFailure:
+		cb.failures++
+		if cb.state == CircuitHalfOpen || cb.failures >= cb.failureThreshold {
+			cb.state = CircuitOpen
+			cb.openedAt = time.Now()
+			cb.trial = false
+		}
+	case circuitIgnored:
+		cb.trial = false
+	}
+}
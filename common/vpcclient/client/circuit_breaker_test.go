@@ -0,0 +1,106 @@
+/**
+ * Copyright 2022 IBM Corp.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package client_test ...
+package client_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/IBM/ibmcloud-volume-vpc/common/vpcclient/client"
+	"github.com/IBM/ibmcloud-volume-vpc/common/vpcclient/models"
+)
+
+func TestCircuitBreaker(t *testing.T) {
+	var hits int32
+	var status int32 = http.StatusServiceUnavailable
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&hits, 1)
+		w.WriteHeader(int(atomic.LoadInt32(&status)))
+	}))
+	defer server.Close()
+
+	cb := client.SharedCircuitBreaker(server.URL, client.CircuitBreakerConfig{FailureThreshold: 2, CoolDown: 100 * time.Millisecond})
+	assert.Same(t, cb, client.SharedCircuitBreaker(server.URL, client.CircuitBreakerConfig{}))
+	c := client.New(context.Background(), server.URL, nil, http.DefaultClient, "test-context", "default", client.WithCircuitBreaker(cb)).WithAuthToken("auth-token")
+	invoke := func() error {
+		var errResult models.Error
+		_, err := c.NewRequest(getOperation).JSONError(&errResult).Invoke()
+		return err
+	}
+
+	// Consecutive 5xx responses open the circuit
+	assert.Equal(t, client.CircuitClosed, cb.State())
+	_ = invoke()
+	assert.Equal(t, client.CircuitClosed, cb.State())
+	_ = invoke()
+	assert.Equal(t, client.CircuitOpen, cb.State())
+	assert.Equal(t, client.CircuitOpen, client.CircuitBreakerStates()[server.URL])
+
+	// Open circuit fails fast without sending the request
+	err := invoke()
+	assert.True(t, models.IsEndpointUnavailable(err))
+	assert.Equal(t, int32(2), atomic.LoadInt32(&hits))
+
+	// Failed trial request after the cool down opens the circuit again
+	time.Sleep(110 * time.Millisecond)
+	assert.Equal(t, client.CircuitHalfOpen, cb.State())
+	_ = invoke()
+	assert.Equal(t, int32(3), atomic.LoadInt32(&hits))
+	assert.Equal(t, client.CircuitOpen, cb.State())
+
+	// Successful trial request closes the circuit
+	time.Sleep(110 * time.Millisecond)
+	atomic.StoreInt32(&status, http.StatusNotFound)
+	err = invoke()
+	assert.False(t, models.IsEndpointUnavailable(err))
+	assert.Equal(t, client.CircuitClosed, cb.State())
+	assert.Equal(t, "closed", client.CircuitBreakerStates()[server.URL].String())
+}
+
+func TestCircuitBreakerNetworkErrors(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	baseURL := server.URL
+	server.Close()
+
+	cb := client.NewCircuitBreaker(baseURL, client.CircuitBreakerConfig{FailureThreshold: 1, CoolDown: time.Minute})
+	c := client.New(context.Background(), baseURL, nil, http.DefaultClient, "test-context", "default", client.WithCircuitBreaker(cb)).WithAuthToken("auth-token")
+
+	_, err := c.NewRequest(getOperation).Invoke()
+	assert.NotNil(t, err)
+	assert.False(t, models.IsEndpointUnavailable(err))
+	assert.Equal(t, client.CircuitOpen, cb.State())
+
+	_, err = c.NewRequest(getOperation).Invoke()
+	assert.True(t, models.IsEndpointUnavailable(err))
+	assert.Contains(t, err.Error(), baseURL)
+
+	// Cancelled requests say nothing about the endpoint
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	cb = client.NewCircuitBreaker(baseURL, client.CircuitBreakerConfig{FailureThreshold: 1})
+	c = client.New(ctx, baseURL, nil, http.DefaultClient, "test-context", "default", client.WithCircuitBreaker(cb)).WithAuthToken("auth-token")
+	_, err = c.NewRequest(getOperation).Invoke()
+	assert.NotNil(t, err)
+	assert.Equal(t, client.CircuitClosed, cb.State())
+}
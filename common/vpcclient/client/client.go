@@ -26,36 +26,62 @@ import (
 	"github.com/IBM/ibmcloud-volume-vpc/common/vpcclient/models"
 )
 
-// handler ...
-type handler interface {
-	Before(request *Request) error
-}
-
 // SessionClient provides an interface for a REST API client
 // go:generate counterfeiter -o fakes/client.go --fake-name SessionClient . SessionClient
 type SessionClient interface {
 	NewRequest(operation *Operation) *Request
 	WithDebug(writer io.Writer) SessionClient
 	WithAuthToken(authToken string) SessionClient
+	WithTokenSource(tokenSource TokenSource) SessionClient
 	WithPathParameter(name, value string) SessionClient
 	WithQueryValue(name, value string) SessionClient
+	WithMiddleware(middlewares ...Middleware) SessionClient
+	WithContext(ctx context.Context) SessionClient
 }
 
 type client struct {
-	baseURL       string
-	httpClient    *http.Client
-	pathParams    Params
-	queryValues   url.Values
-	authenHandler handler
-	debugWriter   io.Writer
-	resourceGroup string
-	contextID     string
-	context       context.Context
+	baseURL        string
+	httpClient     *http.Client
+	pathParams     Params
+	queryValues    url.Values
+	authenHandler  Middleware
+	middlewares    []Middleware
+	debugWriter    io.Writer
+	resourceGroup  string
+	contextID      string
+	context        context.Context
+	rateLimiter    *RateLimiter
+	circuitBreaker *CircuitBreaker
+	coalescer      *Coalescer
+}
+
+// Option configures optional features of the client
+type Option func(c *client)
+
+// WithRateLimiter makes the client wait for the rate limiter before sending each request
+func WithRateLimiter(rateLimiter *RateLimiter) Option {
+	return func(c *client) {
+		c.rateLimiter = rateLimiter
+	}
+}
+
+// WithCircuitBreaker makes the client fail fast while the circuit of the endpoint is open
+func WithCircuitBreaker(circuitBreaker *CircuitBreaker) Option {
+	return func(c *client) {
+		c.circuitBreaker = circuitBreaker
+	}
+}
+
+// WithCoalescer lets the identical concurrent reads of the client, see Request.InvokeCoalesced, share one request
+func WithCoalescer(coalescer *Coalescer) Option {
+	return func(c *client) {
+		c.coalescer = coalescer
+	}
 }
 
 // New creates a new instance of a SessionClient
-func New(ctx context.Context, baseURL string, queryValues url.Values, httpClient *http.Client, contextID string, resourceGroupID string) SessionClient {
-	return &client{
+func New(ctx context.Context, baseURL string, queryValues url.Values, httpClient *http.Client, contextID string, resourceGroupID string, opts ...Option) SessionClient {
+	c := &client{
 		baseURL:       baseURL,
 		httpClient:    httpClient,
 		pathParams:    Params{},
@@ -65,6 +91,10 @@ func New(ctx context.Context, baseURL string, queryValues url.Values, httpClient
 		context:       ctx,
 		resourceGroup: resourceGroupID,
 	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
 }
 
 // NewRequest creates a request and configures it with the supplied operation
@@ -81,48 +111,102 @@ func (c *client) NewRequest(operation *Operation) *Request {
 		headers.Set("X-Auth-Resource-Group-ID", c.resourceGroup)
 	}
 
-	// Copy the query values to a new map
-	qv := url.Values{}
-	for k, v := range c.queryValues {
-		qv[k] = v
+	// Copy the query values, requests add their own
+	qv := copyValues(c.queryValues)
+
+	// Middlewares of the caller wrap the built-in ones, so that the debug dump shows what is sent
+	middlewares := append([]Middleware{}, c.middlewares...)
+	middlewares = append(middlewares, c.authenHandler)
+	if c.debugWriter != nil {
+		middlewares = append(middlewares, DebugMiddleware(c.debugWriter))
 	}
 
 	return &Request{
-		httpClient:    c.httpClient,
-		context:       c.context,
-		baseURL:       c.baseURL,
-		operation:     operation,
-		pathParams:    c.pathParams.Copy(),
-		authenHandler: c.authenHandler,
-		headers:       headers,
-		debugWriter:   c.debugWriter,
-		resourceGroup: c.resourceGroup,
-		queryValues:   qv,
+		httpClient:     c.httpClient,
+		context:        c.context,
+		baseURL:        c.baseURL,
+		operation:      operation,
+		pathParams:     c.pathParams.Copy(),
+		authenHandler:  c.authenHandler,
+		headers:        headers,
+		middlewares:    middlewares,
+		queryValues:    qv,
+		rateLimiter:    c.rateLimiter,
+		circuitBreaker: c.circuitBreaker,
+		coalescer:      c.coalescer,
 	}
 }
 
-// WithDebug enables debug for this SessionClient, outputting to the supplied writer
+// WithDebug returns a copy of this SessionClient with debug enabled, outputting to the supplied writer
 func (c *client) WithDebug(writer io.Writer) SessionClient {
-	c.debugWriter = writer
-	return c
+	derived := c.copy()
+	derived.debugWriter = writer
+	return derived
 }
 
-// WithAuthToken supplies the authentication token to use for all requests made by this session
+// WithAuthToken returns a copy of this SessionClient using the authentication token for all its requests
 func (c *client) WithAuthToken(authToken string) SessionClient {
-	c.authenHandler = &authenticationHandler{
+	derived := c.copy()
+	derived.authenHandler = &authenticationHandler{
 		authToken: authToken,
 	}
-	return c
+	return derived
+}
+
+// WithTokenSource returns a copy of this SessionClient getting the authentication token of its requests from the
+// token source, requests rejected as token_invalid are replayed once with a refreshed token
+func (c *client) WithTokenSource(tokenSource TokenSource) SessionClient {
+	derived := c.copy()
+	derived.authenHandler = &authenticationHandler{
+		tokenSource: tokenSource,
+	}
+	return derived
 }
 
-// WithPathParameter adds a path parameter to the request
+// WithMiddleware returns a copy of this SessionClient whose requests are all wrapped by the middlewares
+func (c *client) WithMiddleware(middlewares ...Middleware) SessionClient {
+	derived := c.copy()
+	derived.middlewares = append(derived.middlewares, middlewares...)
+	return derived
+}
+
+// WithPathParameter returns a copy of this SessionClient adding the path parameter to its requests
 func (c *client) WithPathParameter(name, value string) SessionClient {
-	c.pathParams[name] = value
-	return c
+	derived := c.copy()
+	derived.pathParams[name] = value
+	return derived
 }
 
-// WithQueryValue adds a query parameter to the request
+// WithQueryValue returns a copy of this SessionClient adding the query parameter to its requests
 func (c *client) WithQueryValue(name, value string) SessionClient {
-	c.queryValues.Set(name, value)
-	return c
+	derived := c.copy()
+	derived.queryValues.Set(name, value)
+	return derived
+}
+
+// WithContext returns a copy of this SessionClient sending its requests with the context, e.g the one
+// carrying the span of the operation in progress
+func (c *client) WithContext(ctx context.Context) SessionClient {
+	derived := c.copy()
+	derived.context = ctx
+	return derived
+}
+
+// copy returns a copy of the client that can be changed without affecting the client, which may be in use
+// by concurrent requests. Rate limiter, circuit breaker and coalescer are shared.
+func (c *client) copy() *client {
+	derived := *c
+	derived.pathParams = c.pathParams.Copy()
+	derived.queryValues = copyValues(c.queryValues)
+	derived.middlewares = append([]Middleware(nil), c.middlewares...)
+	return &derived
+}
+
+// copyValues returns a deep copy of the values
+func copyValues(values url.Values) url.Values {
+	copied := url.Values{}
+	for k, v := range values {
+		copied[k] = append([]string(nil), v...)
+	}
+	return copied
 }
@@ -20,6 +20,7 @@ package client_test
 import (
 	"bytes"
 	"context"
+	"errors"
 	"fmt"
 	"io/ioutil"
 	"net/http"
@@ -28,6 +29,7 @@ import (
 	"strconv"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 
@@ -360,3 +362,111 @@ func TestWithQueryValue(t *testing.T) {
 	assert.NotNil(t, riaas)
 	defer s.Close()
 }
+
+func TestDerivedClients(t *testing.T) {
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprintf(w, `{"worker":%q,"authorization":%q,"path":%q}`, r.URL.Query().Get("worker"), r.Header.Get("Authorization"), r.URL.Path)
+	}))
+	defer s.Close()
+
+	queryValues := map[string][]string{"version": {models.APIVersion}}
+	base := client.New(context.Background(), s.URL, queryValues, http.DefaultClient, "test-context", "default").WithAuthToken("base-token")
+	operation := &client.Operation{Name: "GetWorker", Method: http.MethodGet, PathPattern: "/workers/{worker}"}
+
+	type result struct {
+		Worker        string `json:"worker"`
+		Authorization string `json:"authorization"`
+		Path          string `json:"path"`
+	}
+
+	// Clients derived concurrently from the same client do not see each other values
+	const workers = 50
+	results := make([]result, workers)
+	errs := make([]error, workers)
+	done := make(chan struct{})
+	for i := 0; i < workers; i++ {
+		go func(i int) {
+			defer func() { done <- struct{}{} }()
+			worker := strconv.Itoa(i)
+			derived := base.WithQueryValue("worker", worker).WithPathParameter("worker", worker).WithAuthToken("token-" + worker)
+			_, errs[i] = derived.NewRequest(operation).JSONSuccess(&results[i]).Invoke()
+		}(i)
+	}
+	for i := 0; i < workers; i++ {
+		<-done
+	}
+	for i := 0; i < workers; i++ {
+		worker := strconv.Itoa(i)
+		assert.NoError(t, errs[i])
+		assert.Equal(t, result{Worker: worker, Authorization: "Bearer token-" + worker, Path: "/workers/" + worker}, results[i])
+	}
+
+	// The client they were derived from is unchanged
+	var baseResult result
+	_, err := base.WithPathParameter("worker", "base").NewRequest(operation).JSONSuccess(&baseResult).Invoke()
+	assert.NoError(t, err)
+	assert.Equal(t, result{Authorization: "Bearer base-token", Path: "/workers/base"}, baseResult)
+	assert.Equal(t, []string{models.APIVersion}, queryValues["version"])
+}
+
+func TestErrorResponseMetadata(t *testing.T) {
+	testcases := []struct {
+		name         string
+		responseBody string
+		responseCode int
+		retryAfter   string
+		requestID    string
+
+		expectErr        string
+		expectRequestID  string
+		expectRetryAfter time.Duration
+	}{
+		{
+			name:             "throttled response with Retry-After",
+			responseBody:     `{"errors":[{"message":"Too many requests","code":"too_many_requests"}],"trace":"trace-1"}`,
+			responseCode:     http.StatusTooManyRequests,
+			retryAfter:       "7",
+			requestID:        "server-request-id",
+			expectErr:        "Trace Code:trace-1, Too many requests Please check ",
+			expectRequestID:  "server-request-id",
+			expectRetryAfter: 7 * time.Second,
+		}, {
+			name:            "body is not an error",
+			responseBody:    `<html>Service Unavailable</html>`,
+			responseCode:    http.StatusServiceUnavailable,
+			expectErr:       "invalid character '<' looking for beginning of value",
+			expectRequestID: "test-context",
+		},
+	}
+
+	for _, testcase := range testcases {
+		t.Run(testcase.name, func(t *testing.T) {
+			s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if testcase.retryAfter != "" {
+					w.Header().Set("Retry-After", testcase.retryAfter)
+				}
+				if testcase.requestID != "" {
+					w.Header().Set("X-Request-ID", testcase.requestID)
+				}
+				w.WriteHeader(testcase.responseCode)
+				fmt.Fprint(w, testcase.responseBody)
+			}))
+			defer s.Close()
+
+			var errResult models.Error
+			c := client.New(context.Background(), s.URL, nil, http.DefaultClient, "test-context", "default").WithAuthToken("auth-token")
+			_, err := c.NewRequest(getOperation).JSONError(&errResult).Invoke()
+			if assert.EqualError(t, err, testcase.expectErr) {
+				metadata := models.GetResponseMetadata(err)
+				assert.NotNil(t, metadata)
+				assert.Equal(t, testcase.responseCode, metadata.StatusCode)
+				assert.Equal(t, testcase.expectRequestID, metadata.RequestID)
+				assert.Equal(t, testcase.expectRetryAfter, metadata.RetryAfter)
+				assert.Equal(t, testcase.retryAfter, metadata.Header.Get("Retry-After"))
+			}
+		})
+	}
+
+	assert.Nil(t, models.GetResponseMetadata(errors.New("connection refused")))
+}
@@ -0,0 +1,197 @@
+/**
+ * Copyright 2022 IBM Corp.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package client ...
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sync"
+	"sync/atomic"
+
+	"github.com/IBM/ibmcloud-volume-vpc/common/vpcclient/models"
+)
+
+// Coalescer lets concurrent identical reads share one in-flight request (singleflight style).
+// It is enabled once created, clients use it if configured WithCoalescer.
+type Coalescer struct {
+	disabled int32
+	calls    uint64
+	saved    uint64
+
+	mu       sync.Mutex
+	inflight map[string]*coalescedCall
+}
+
+// CoalesceStats are the counters of a Coalescer
+type CoalesceStats struct {
+	Calls uint64 // Number of calls made through Do
+	Saved uint64 // Number of calls which shared the result of an in-flight request
+}
+
+// coalescedCall is an in-flight request
+type coalescedCall struct {
+	wg  sync.WaitGroup
+	val interface{}
+	err error
+}
+
+// NewCoalescer creates an enabled Coalescer
+func NewCoalescer() *Coalescer {
+	return &Coalescer{inflight: map[string]*coalescedCall{}}
+}
+
+// SetEnabled enables or disables the coalescing, when disabled each call makes its own request
+func (c *Coalescer) SetEnabled(enabled bool) {
+	if enabled {
+		atomic.StoreInt32(&c.disabled, 0)
+	} else {
+		atomic.StoreInt32(&c.disabled, 1)
+	}
+}
+
+// Enabled tells if the coalescing is enabled
+func (c *Coalescer) Enabled() bool {
+	return atomic.LoadInt32(&c.disabled) == 0
+}
+
+// Stats returns the counters
+func (c *Coalescer) Stats() CoalesceStats {
+	return CoalesceStats{
+		Calls: atomic.LoadUint64(&c.calls),
+		Saved: atomic.LoadUint64(&c.saved),
+	}
+}
+
+// Do calls fn, unless a call with the same key is already in flight. In that case it waits for
+// that call and returns its result with shared true. Callers must not modify a shared result.
+func (c *Coalescer) Do(key string, fn func() (interface{}, error)) (val interface{}, shared bool, err error) {
+	atomic.AddUint64(&c.calls, 1)
+	if !c.Enabled() {
+		val, err = fn()
+		return val, false, err
+	}
+
+	c.mu.Lock()
+	if call, ok := c.inflight[key]; ok {
+		c.mu.Unlock()
+		atomic.AddUint64(&c.saved, 1)
+		call.wg.Wait()
+		return call.val, true, call.err
+	}
+	call := &coalescedCall{}
+	call.wg.Add(1)
+	c.inflight[key] = call
+	c.mu.Unlock()
+
+	defer func() {
+		c.mu.Lock()
+		delete(c.inflight, key)
+		c.mu.Unlock()
+		call.wg.Done()
+	}()
+	call.val, call.err = fn()
+	return call.val, false, call.err
+}
+
+// coalescedError is the API error of a coalesced request, so that each follower decodes its own copy
+type coalescedError struct {
+	body     []byte
+	metadata *models.ResponseMetadata
+}
+
+// InvokeCoalesced performs the request like Invoke, unless an identical request is in flight on the
+// coalescer of the client. In that case it waits for that request and its response is decoded again
+// into the success or error receiver of this request, so that callers never share the same objects.
+// A request whose in-flight request was cancelled or timed out by its own context is performed again,
+// unless its own context is done too. Without coalescer, or for requests whose credentials can not be
+// told apart, it is the same as Invoke.
+func (r *Request) InvokeCoalesced() (shared bool, err error) {
+	key := r.CoalesceKey()
+	if r.coalescer == nil || r.successConsumer == nil || key == "" {
+		_, err = r.Invoke()
+		return false, err
+	}
+
+	val, shared, err := r.coalescer.Do(key, func() (interface{}, error) {
+		if _, err := r.Invoke(); err != nil {
+			return r.coalescedError(err), err
+		}
+		return json.Marshal(r.successConsumer.Receiver())
+	})
+	if !shared {
+		return false, err
+	}
+	if err != nil {
+		return r.sharedError(val, err)
+	}
+	return true, json.Unmarshal(val.([]byte), r.successConsumer.Receiver())
+}
+
+// coalescedError encodes the API error decoded by the error receiver of the request, nil for the other errors
+func (r *Request) coalescedError(err error) interface{} {
+	if r.errorConsumer == nil || err != r.errorConsumer.Receiver() {
+		return nil
+	}
+	body, marshalErr := json.Marshal(err)
+	if marshalErr != nil {
+		return nil
+	}
+	shared := coalescedError{body: body}
+	if metadata := models.GetResponseMetadata(err); metadata != nil {
+		copied := *metadata
+		copied.Header = metadata.Header.Clone()
+		shared.metadata = &copied
+	}
+	return shared
+}
+
+// sharedError returns the error of the in-flight request to a request which waited for it
+func (r *Request) sharedError(val interface{}, err error) (bool, error) {
+	// Cancellation or timeout of the in-flight request does not apply to this request
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		if r.context == nil || r.context.Err() == nil {
+			_, err = r.Invoke()
+			return false, err
+		}
+		return true, r.context.Err()
+	}
+
+	shared, ok := val.(coalescedError)
+	if !ok || r.errorConsumer == nil {
+		return true, err
+	}
+	receiver := r.errorConsumer.Receiver()
+	if unmarshalErr := json.Unmarshal(shared.body, receiver); unmarshalErr != nil {
+		return true, err
+	}
+	ownErr, ok := receiver.(error)
+	if !ok {
+		return true, err
+	}
+	if shared.metadata != nil {
+		metadata := *shared.metadata
+		metadata.Header = shared.metadata.Header.Clone()
+		if setter, ok := ownErr.(interface {
+			SetResponseMetadata(models.ResponseMetadata)
+		}); ok {
+			setter.SetResponseMetadata(metadata)
+		}
+	}
+	return true, ownErr
+}
@@ -0,0 +1,237 @@
+/**
+ * Copyright 2022 IBM Corp.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package client_test ...
+package client_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/IBM/ibmcloud-volume-vpc/common/vpcclient/client"
+	"github.com/IBM/ibmcloud-volume-vpc/common/vpcclient/models"
+)
+
+func TestCoalescer(t *testing.T) {
+	coalescer := client.NewCoalescer()
+	assert.True(t, coalescer.Enabled())
+
+	var calls int32
+	release := make(chan struct{})
+	fn := func() (interface{}, error) {
+		atomic.AddInt32(&calls, 1)
+		<-release
+		return "result", errors.New("failed")
+	}
+
+	const callers = 5
+	var wg sync.WaitGroup
+	var sharedCount int32
+	for i := 0; i < callers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			val, shared, err := coalescer.Do("key", fn)
+			assert.Equal(t, "result", val)
+			assert.EqualError(t, err, "failed")
+			if shared {
+				atomic.AddInt32(&sharedCount, 1)
+			}
+		}()
+	}
+	// Wait till all the callers have joined the in-flight call
+	for coalescer.Stats().Saved < callers-1 {
+		runtime.Gosched()
+	}
+	close(release)
+	wg.Wait()
+
+	assert.Equal(t, int32(1), calls)
+	assert.Equal(t, int32(callers-1), sharedCount)
+	assert.Equal(t, client.CoalesceStats{Calls: callers, Saved: callers - 1}, coalescer.Stats())
+
+	// Different keys and finished calls are not shared
+	_, shared, _ := coalescer.Do("other-key", func() (interface{}, error) { return nil, nil })
+	assert.False(t, shared)
+
+	// Disabled coalescer calls the function every time
+	coalescer.SetEnabled(false)
+	assert.False(t, coalescer.Enabled())
+	_, shared, _ = coalescer.Do("key", func() (interface{}, error) { return nil, nil })
+	assert.False(t, shared)
+	assert.Equal(t, uint64(callers+2), coalescer.Stats().Calls)
+	assert.Equal(t, uint64(callers-1), coalescer.Stats().Saved)
+}
+
+func TestCoalesceKey(t *testing.T) {
+	newRequest := func(token string, volumeID string) *client.Request {
+		c := client.New(context.Background(), "http://127.0.0.1", nil, http.DefaultClient, "test-context", "default").WithAuthToken(token)
+		return c.NewRequest(getOperation).PathParameter("id", volumeID)
+	}
+
+	key := newRequest("token-1", "volume-1").CoalesceKey()
+	assert.Equal(t, key, newRequest("token-1", "volume-1").CoalesceKey())
+	assert.NotEqual(t, key, newRequest("token-2", "volume-1").CoalesceKey())
+	assert.NotContains(t, key, "token-1")
+
+	c := client.New(context.Background(), "http://127.0.0.1", nil, http.DefaultClient, "test-context", "default").WithAuthToken("token-1")
+	assert.NotEqual(t, key, c.NewRequest(postOperation).CoalesceKey())
+
+	// Token sources are keyed by their current token, requests without token are not coalesced
+	newTokenSourceRequest := func(tokenSource client.TokenSource) *client.Request {
+		c := client.New(context.Background(), "http://127.0.0.1", nil, http.DefaultClient, "test-context", "default").WithTokenSource(tokenSource)
+		return c.NewRequest(getOperation).PathParameter("id", "volume-1")
+	}
+	key = newTokenSourceRequest(&stubTokenSource{token: "token-1"}).CoalesceKey()
+	assert.Equal(t, key, newTokenSourceRequest(&stubTokenSource{token: "token-1"}).CoalesceKey())
+	assert.NotEqual(t, key, newTokenSourceRequest(&stubTokenSource{token: "token-2"}).CoalesceKey())
+	assert.Equal(t, "", newTokenSourceRequest(&stubTokenSource{}).CoalesceKey())
+	assert.Equal(t, "", newRequest("", "volume-1").CoalesceKey())
+}
+
+func TestInvokeCoalescedTokenSources(t *testing.T) {
+	arrived := make(chan struct{}, 2)
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		arrived <- struct{}{}
+		<-release
+		_, _ = w.Write([]byte(`{"name":"` + r.Header.Get("Authorization") + `"}`))
+	}))
+	defer server.Close()
+
+	// Sessions of two accounts read the same resource through a shared coalescer
+	coalescer := client.NewCoalescer()
+	results := make([]map[string]string, 2)
+	var wg sync.WaitGroup
+	for i, token := range []string{"account-1-token", "account-2-token"} {
+		wg.Add(1)
+		go func(i int, token string) {
+			defer wg.Done()
+			c := client.New(context.Background(), server.URL, nil, http.DefaultClient, "test-context", "default", client.WithCoalescer(coalescer)).WithTokenSource(&stubTokenSource{token: token})
+			shared, err := c.NewRequest(getOperation).JSONSuccess(&results[i]).InvokeCoalesced()
+			assert.Nil(t, err)
+			assert.False(t, shared)
+		}(i, token)
+	}
+	for i := 0; i < 2; i++ {
+		select {
+		case <-arrived:
+		case <-time.After(5 * time.Second):
+			t.Fatal("request of the second session was coalesced")
+		}
+	}
+	close(release)
+	wg.Wait()
+
+	assert.Equal(t, "Bearer account-1-token", results[0]["name"])
+	assert.Equal(t, "Bearer account-2-token", results[1]["name"])
+	assert.Equal(t, uint64(0), coalescer.Stats().Saved)
+}
+
+func TestInvokeCoalescedLeaderCancelled(t *testing.T) {
+	var requests int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// First request hangs till its caller gives up
+		if atomic.AddInt32(&requests, 1) == 1 {
+			<-r.Context().Done()
+			return
+		}
+		_, _ = w.Write([]byte(`{"name":"volume-1"}`))
+	}))
+	defer server.Close()
+
+	coalescer := client.NewCoalescer()
+	leaderCtx, cancel := context.WithCancel(context.Background())
+	leaderDone := make(chan error)
+	go func() {
+		c := client.New(leaderCtx, server.URL, nil, http.DefaultClient, "test-context", "default", client.WithCoalescer(coalescer)).WithAuthToken("token-1")
+		_, err := c.NewRequest(getOperation).JSONSuccess(&map[string]string{}).InvokeCoalesced()
+		leaderDone <- err
+	}()
+	for atomic.LoadInt32(&requests) == 0 {
+		runtime.Gosched()
+	}
+
+	followerDone := make(chan error)
+	result := map[string]string{}
+	var shared bool
+	go func() {
+		c := client.New(context.Background(), server.URL, nil, http.DefaultClient, "test-context", "default", client.WithCoalescer(coalescer)).WithAuthToken("token-1")
+		var err error
+		shared, err = c.NewRequest(getOperation).JSONSuccess(&result).InvokeCoalesced()
+		followerDone <- err
+	}()
+	for coalescer.Stats().Saved == 0 {
+		runtime.Gosched()
+	}
+	cancel()
+
+	assert.True(t, errors.Is(<-leaderDone, context.Canceled))
+	// Follower whose context is live makes its own request
+	assert.Nil(t, <-followerDone)
+	assert.False(t, shared)
+	assert.Equal(t, "volume-1", result["name"])
+	assert.Equal(t, int32(2), atomic.LoadInt32(&requests))
+}
+
+func TestInvokeCoalescedError(t *testing.T) {
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusNotFound)
+		_, _ = w.Write([]byte(`{"errors":[{"code":"not_found","message":"Volume not found"}],"trace":"trace-1"}`))
+	}))
+	defer server.Close()
+
+	coalescer := client.NewCoalescer()
+	const callers = 3
+	errs := make([]error, callers)
+	apiErrs := make([]models.Error, callers)
+	var wg sync.WaitGroup
+	for i := 0; i < callers; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			c := client.New(context.Background(), server.URL, nil, http.DefaultClient, "test-context", "default", client.WithCoalescer(coalescer)).WithAuthToken("token-1")
+			_, errs[i] = c.NewRequest(getOperation).JSONSuccess(&map[string]string{}).JSONError(&apiErrs[i]).InvokeCoalesced()
+		}(i)
+	}
+	for coalescer.Stats().Saved < callers-1 {
+		runtime.Gosched()
+	}
+	close(release)
+	wg.Wait()
+
+	// Each caller gets the error in its own receiver
+	for i := 0; i < callers; i++ {
+		assert.Same(t, &apiErrs[i], errs[i])
+		assert.Equal(t, models.ErrorCodeNotFound, apiErrs[i].Errors[0].Code)
+		assert.Equal(t, "trace-1", apiErrs[i].Trace)
+		assert.Equal(t, http.StatusNotFound, apiErrs[i].StatusCode)
+	}
+	apiErrs[0].Header.Set("X-Test", "changed")
+	assert.Empty(t, apiErrs[1].Header.Get("X-Test"))
+}
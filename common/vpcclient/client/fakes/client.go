@@ -2,6 +2,7 @@
 package fakes
 
 import (
+	"context"
 	"io"
 	"sync"
 
@@ -31,6 +32,17 @@ type SessionClient struct {
 	withAuthTokenReturnsOnCall map[int]struct {
 		result1 client.SessionClient
 	}
+	WithContextStub        func(context.Context) client.SessionClient
+	withContextMutex       sync.RWMutex
+	withContextArgsForCall []struct {
+		arg1 context.Context
+	}
+	withContextReturns struct {
+		result1 client.SessionClient
+	}
+	withContextReturnsOnCall map[int]struct {
+		result1 client.SessionClient
+	}
 	WithDebugStub        func(io.Writer) client.SessionClient
 	withDebugMutex       sync.RWMutex
 	withDebugArgsForCall []struct {
@@ -42,6 +54,17 @@ type SessionClient struct {
 	withDebugReturnsOnCall map[int]struct {
 		result1 client.SessionClient
 	}
+	WithMiddlewareStub        func(...client.Middleware) client.SessionClient
+	withMiddlewareMutex       sync.RWMutex
+	withMiddlewareArgsForCall []struct {
+		arg1 []client.Middleware
+	}
+	withMiddlewareReturns struct {
+		result1 client.SessionClient
+	}
+	withMiddlewareReturnsOnCall map[int]struct {
+		result1 client.SessionClient
+	}
 	WithPathParameterStub        func(string, string) client.SessionClient
 	withPathParameterMutex       sync.RWMutex
 	withPathParameterArgsForCall []struct {
@@ -66,6 +89,17 @@ type SessionClient struct {
 	withQueryValueReturnsOnCall map[int]struct {
 		result1 client.SessionClient
 	}
+	WithTokenSourceStub        func(client.TokenSource) client.SessionClient
+	withTokenSourceMutex       sync.RWMutex
+	withTokenSourceArgsForCall []struct {
+		arg1 client.TokenSource
+	}
+	withTokenSourceReturns struct {
+		result1 client.SessionClient
+	}
+	withTokenSourceReturnsOnCall map[int]struct {
+		result1 client.SessionClient
+	}
 	invocations      map[string][][]interface{}
 	invocationsMutex sync.RWMutex
 }
@@ -76,15 +110,16 @@ func (fake *SessionClient) NewRequest(arg1 *client.Operation) *client.Request {
 	fake.newRequestArgsForCall = append(fake.newRequestArgsForCall, struct {
 		arg1 *client.Operation
 	}{arg1})
+	stub := fake.NewRequestStub
+	fakeReturns := fake.newRequestReturns
 	fake.recordInvocation("NewRequest", []interface{}{arg1})
 	fake.newRequestMutex.Unlock()
-	if fake.NewRequestStub != nil {
-		return fake.NewRequestStub(arg1)
+	if stub != nil {
+		return stub(arg1)
 	}
 	if specificReturn {
 		return ret.result1
 	}
-	fakeReturns := fake.newRequestReturns
 	return fakeReturns.result1
 }
 
@@ -136,15 +171,16 @@ func (fake *SessionClient) WithAuthToken(arg1 string) client.SessionClient {
 	fake.withAuthTokenArgsForCall = append(fake.withAuthTokenArgsForCall, struct {
 		arg1 string
 	}{arg1})
+	stub := fake.WithAuthTokenStub
+	fakeReturns := fake.withAuthTokenReturns
 	fake.recordInvocation("WithAuthToken", []interface{}{arg1})
 	fake.withAuthTokenMutex.Unlock()
-	if fake.WithAuthTokenStub != nil {
-		return fake.WithAuthTokenStub(arg1)
+	if stub != nil {
+		return stub(arg1)
 	}
 	if specificReturn {
 		return ret.result1
 	}
-	fakeReturns := fake.withAuthTokenReturns
 	return fakeReturns.result1
 }
 
@@ -190,21 +226,83 @@ func (fake *SessionClient) WithAuthTokenReturnsOnCall(i int, result1 client.Sess
 	}{result1}
 }
 
+func (fake *SessionClient) WithContext(arg1 context.Context) client.SessionClient {
+	fake.withContextMutex.Lock()
+	ret, specificReturn := fake.withContextReturnsOnCall[len(fake.withContextArgsForCall)]
+	fake.withContextArgsForCall = append(fake.withContextArgsForCall, struct {
+		arg1 context.Context
+	}{arg1})
+	stub := fake.WithContextStub
+	fakeReturns := fake.withContextReturns
+	fake.recordInvocation("WithContext", []interface{}{arg1})
+	fake.withContextMutex.Unlock()
+	if stub != nil {
+		return stub(arg1)
+	}
+	if specificReturn {
+		return ret.result1
+	}
+	return fakeReturns.result1
+}
+
+func (fake *SessionClient) WithContextCallCount() int {
+	fake.withContextMutex.RLock()
+	defer fake.withContextMutex.RUnlock()
+	return len(fake.withContextArgsForCall)
+}
+
+func (fake *SessionClient) WithContextCalls(stub func(context.Context) client.SessionClient) {
+	fake.withContextMutex.Lock()
+	defer fake.withContextMutex.Unlock()
+	fake.WithContextStub = stub
+}
+
+func (fake *SessionClient) WithContextArgsForCall(i int) context.Context {
+	fake.withContextMutex.RLock()
+	defer fake.withContextMutex.RUnlock()
+	argsForCall := fake.withContextArgsForCall[i]
+	return argsForCall.arg1
+}
+
+func (fake *SessionClient) WithContextReturns(result1 client.SessionClient) {
+	fake.withContextMutex.Lock()
+	defer fake.withContextMutex.Unlock()
+	fake.WithContextStub = nil
+	fake.withContextReturns = struct {
+		result1 client.SessionClient
+	}{result1}
+}
+
+func (fake *SessionClient) WithContextReturnsOnCall(i int, result1 client.SessionClient) {
+	fake.withContextMutex.Lock()
+	defer fake.withContextMutex.Unlock()
+	fake.WithContextStub = nil
+	if fake.withContextReturnsOnCall == nil {
+		fake.withContextReturnsOnCall = make(map[int]struct {
+			result1 client.SessionClient
+		})
+	}
+	fake.withContextReturnsOnCall[i] = struct {
+		result1 client.SessionClient
+	}{result1}
+}
+
 func (fake *SessionClient) WithDebug(arg1 io.Writer) client.SessionClient {
 	fake.withDebugMutex.Lock()
 	ret, specificReturn := fake.withDebugReturnsOnCall[len(fake.withDebugArgsForCall)]
 	fake.withDebugArgsForCall = append(fake.withDebugArgsForCall, struct {
 		arg1 io.Writer
 	}{arg1})
+	stub := fake.WithDebugStub
+	fakeReturns := fake.withDebugReturns
 	fake.recordInvocation("WithDebug", []interface{}{arg1})
 	fake.withDebugMutex.Unlock()
-	if fake.WithDebugStub != nil {
-		return fake.WithDebugStub(arg1)
+	if stub != nil {
+		return stub(arg1)
 	}
 	if specificReturn {
 		return ret.result1
 	}
-	fakeReturns := fake.withDebugReturns
 	return fakeReturns.result1
 }
 
@@ -250,6 +348,67 @@ func (fake *SessionClient) WithDebugReturnsOnCall(i int, result1 client.SessionC
 	}{result1}
 }
 
+func (fake *SessionClient) WithMiddleware(arg1 ...client.Middleware) client.SessionClient {
+	fake.withMiddlewareMutex.Lock()
+	ret, specificReturn := fake.withMiddlewareReturnsOnCall[len(fake.withMiddlewareArgsForCall)]
+	fake.withMiddlewareArgsForCall = append(fake.withMiddlewareArgsForCall, struct {
+		arg1 []client.Middleware
+	}{arg1})
+	stub := fake.WithMiddlewareStub
+	fakeReturns := fake.withMiddlewareReturns
+	fake.recordInvocation("WithMiddleware", []interface{}{arg1})
+	fake.withMiddlewareMutex.Unlock()
+	if stub != nil {
+		return stub(arg1...)
+	}
+	if specificReturn {
+		return ret.result1
+	}
+	return fakeReturns.result1
+}
+
+func (fake *SessionClient) WithMiddlewareCallCount() int {
+	fake.withMiddlewareMutex.RLock()
+	defer fake.withMiddlewareMutex.RUnlock()
+	return len(fake.withMiddlewareArgsForCall)
+}
+
+func (fake *SessionClient) WithMiddlewareCalls(stub func(...client.Middleware) client.SessionClient) {
+	fake.withMiddlewareMutex.Lock()
+	defer fake.withMiddlewareMutex.Unlock()
+	fake.WithMiddlewareStub = stub
+}
+
+func (fake *SessionClient) WithMiddlewareArgsForCall(i int) []client.Middleware {
+	fake.withMiddlewareMutex.RLock()
+	defer fake.withMiddlewareMutex.RUnlock()
+	argsForCall := fake.withMiddlewareArgsForCall[i]
+	return argsForCall.arg1
+}
+
+func (fake *SessionClient) WithMiddlewareReturns(result1 client.SessionClient) {
+	fake.withMiddlewareMutex.Lock()
+	defer fake.withMiddlewareMutex.Unlock()
+	fake.WithMiddlewareStub = nil
+	fake.withMiddlewareReturns = struct {
+		result1 client.SessionClient
+	}{result1}
+}
+
+func (fake *SessionClient) WithMiddlewareReturnsOnCall(i int, result1 client.SessionClient) {
+	fake.withMiddlewareMutex.Lock()
+	defer fake.withMiddlewareMutex.Unlock()
+	fake.WithMiddlewareStub = nil
+	if fake.withMiddlewareReturnsOnCall == nil {
+		fake.withMiddlewareReturnsOnCall = make(map[int]struct {
+			result1 client.SessionClient
+		})
+	}
+	fake.withMiddlewareReturnsOnCall[i] = struct {
+		result1 client.SessionClient
+	}{result1}
+}
+
 func (fake *SessionClient) WithPathParameter(arg1 string, arg2 string) client.SessionClient {
 	fake.withPathParameterMutex.Lock()
 	ret, specificReturn := fake.withPathParameterReturnsOnCall[len(fake.withPathParameterArgsForCall)]
@@ -257,15 +416,16 @@ func (fake *SessionClient) WithPathParameter(arg1 string, arg2 string) client.Se
 		arg1 string
 		arg2 string
 	}{arg1, arg2})
+	stub := fake.WithPathParameterStub
+	fakeReturns := fake.withPathParameterReturns
 	fake.recordInvocation("WithPathParameter", []interface{}{arg1, arg2})
 	fake.withPathParameterMutex.Unlock()
-	if fake.WithPathParameterStub != nil {
-		return fake.WithPathParameterStub(arg1, arg2)
+	if stub != nil {
+		return stub(arg1, arg2)
 	}
 	if specificReturn {
 		return ret.result1
 	}
-	fakeReturns := fake.withPathParameterReturns
 	return fakeReturns.result1
 }
 
@@ -318,15 +478,16 @@ func (fake *SessionClient) WithQueryValue(arg1 string, arg2 string) client.Sessi
 		arg1 string
 		arg2 string
 	}{arg1, arg2})
+	stub := fake.WithQueryValueStub
+	fakeReturns := fake.withQueryValueReturns
 	fake.recordInvocation("WithQueryValue", []interface{}{arg1, arg2})
 	fake.withQueryValueMutex.Unlock()
-	if fake.WithQueryValueStub != nil {
-		return fake.WithQueryValueStub(arg1, arg2)
+	if stub != nil {
+		return stub(arg1, arg2)
 	}
 	if specificReturn {
 		return ret.result1
 	}
-	fakeReturns := fake.withQueryValueReturns
 	return fakeReturns.result1
 }
 
@@ -372,6 +533,67 @@ func (fake *SessionClient) WithQueryValueReturnsOnCall(i int, result1 client.Ses
 	}{result1}
 }
 
+func (fake *SessionClient) WithTokenSource(arg1 client.TokenSource) client.SessionClient {
+	fake.withTokenSourceMutex.Lock()
+	ret, specificReturn := fake.withTokenSourceReturnsOnCall[len(fake.withTokenSourceArgsForCall)]
+	fake.withTokenSourceArgsForCall = append(fake.withTokenSourceArgsForCall, struct {
+		arg1 client.TokenSource
+	}{arg1})
+	stub := fake.WithTokenSourceStub
+	fakeReturns := fake.withTokenSourceReturns
+	fake.recordInvocation("WithTokenSource", []interface{}{arg1})
+	fake.withTokenSourceMutex.Unlock()
+	if stub != nil {
+		return stub(arg1)
+	}
+	if specificReturn {
+		return ret.result1
+	}
+	return fakeReturns.result1
+}
+
+func (fake *SessionClient) WithTokenSourceCallCount() int {
+	fake.withTokenSourceMutex.RLock()
+	defer fake.withTokenSourceMutex.RUnlock()
+	return len(fake.withTokenSourceArgsForCall)
+}
+
+func (fake *SessionClient) WithTokenSourceCalls(stub func(client.TokenSource) client.SessionClient) {
+	fake.withTokenSourceMutex.Lock()
+	defer fake.withTokenSourceMutex.Unlock()
+	fake.WithTokenSourceStub = stub
+}
+
+func (fake *SessionClient) WithTokenSourceArgsForCall(i int) client.TokenSource {
+	fake.withTokenSourceMutex.RLock()
+	defer fake.withTokenSourceMutex.RUnlock()
+	argsForCall := fake.withTokenSourceArgsForCall[i]
+	return argsForCall.arg1
+}
+
+func (fake *SessionClient) WithTokenSourceReturns(result1 client.SessionClient) {
+	fake.withTokenSourceMutex.Lock()
+	defer fake.withTokenSourceMutex.Unlock()
+	fake.WithTokenSourceStub = nil
+	fake.withTokenSourceReturns = struct {
+		result1 client.SessionClient
+	}{result1}
+}
+
+func (fake *SessionClient) WithTokenSourceReturnsOnCall(i int, result1 client.SessionClient) {
+	fake.withTokenSourceMutex.Lock()
+	defer fake.withTokenSourceMutex.Unlock()
+	fake.WithTokenSourceStub = nil
+	if fake.withTokenSourceReturnsOnCall == nil {
+		fake.withTokenSourceReturnsOnCall = make(map[int]struct {
+			result1 client.SessionClient
+		})
+	}
+	fake.withTokenSourceReturnsOnCall[i] = struct {
+		result1 client.SessionClient
+	}{result1}
+}
+
 func (fake *SessionClient) Invocations() map[string][][]interface{} {
 	fake.invocationsMutex.RLock()
 	defer fake.invocationsMutex.RUnlock()
@@ -379,12 +601,18 @@ func (fake *SessionClient) Invocations() map[string][][]interface{} {
 	defer fake.newRequestMutex.RUnlock()
 	fake.withAuthTokenMutex.RLock()
 	defer fake.withAuthTokenMutex.RUnlock()
+	fake.withContextMutex.RLock()
+	defer fake.withContextMutex.RUnlock()
 	fake.withDebugMutex.RLock()
 	defer fake.withDebugMutex.RUnlock()
+	fake.withMiddlewareMutex.RLock()
+	defer fake.withMiddlewareMutex.RUnlock()
 	fake.withPathParameterMutex.RLock()
 	defer fake.withPathParameterMutex.RUnlock()
 	fake.withQueryValueMutex.RLock()
 	defer fake.withQueryValueMutex.RUnlock()
+	fake.withTokenSourceMutex.RLock()
+	defer fake.withTokenSourceMutex.RUnlock()
 	copiedInvocations := map[string][][]interface{}{}
 	for key, value := range fake.invocations {
 		copiedInvocations[key] = value
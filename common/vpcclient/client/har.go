@@ -0,0 +1,218 @@
+/**
+ * Copyright 2022 IBM Corp.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package client ...
+package client
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+)
+
+// DefaultMaxHAREntries is the count of entries a HARRecorder keeps when MaxEntries is not set
+const DefaultMaxHAREntries = 1000
+
+// HARRecorder keeps the logged HTTP exchanges for export in the HTTP Archive (HAR 1.2) format, e.g to open them
+// in a browser developer tools. Only the latest MaxEntries are kept.
+type HARRecorder struct {
+	MaxEntries int
+
+	mu      sync.Mutex
+	entries []harEntry
+}
+
+type harLog struct {
+	Log struct {
+		Version string     `json:"version"`
+		Creator harCreator `json:"creator"`
+		Entries []harEntry `json:"entries"`
+	} `json:"log"`
+}
+
+type harCreator struct {
+	Name    string `json:"name"`
+	Version string `json:"version"`
+}
+
+type harEntry struct {
+	StartedDateTime string      `json:"startedDateTime"`
+	Time            float64     `json:"time"`
+	Request         harRequest  `json:"request"`
+	Response        harResponse `json:"response"`
+	Cache           struct{}    `json:"cache"`
+	Timings         harTimings  `json:"timings"`
+	Comment         string      `json:"comment,omitempty"`
+}
+
+type harRequest struct {
+	Method      string         `json:"method"`
+	URL         string         `json:"url"`
+	HTTPVersion string         `json:"httpVersion"`
+	Headers     []harNameValue `json:"headers"`
+	QueryString []harNameValue `json:"queryString"`
+	Cookies     []harNameValue `json:"cookies"`
+	HeadersSize int            `json:"headersSize"`
+	BodySize    int            `json:"bodySize"`
+	PostData    *harPostData   `json:"postData,omitempty"`
+}
+
+type harResponse struct {
+	Status      int            `json:"status"`
+	StatusText  string         `json:"statusText"`
+	HTTPVersion string         `json:"httpVersion"`
+	Headers     []harNameValue `json:"headers"`
+	Cookies     []harNameValue `json:"cookies"`
+	Content     harContent     `json:"content"`
+	RedirectURL string         `json:"redirectURL"`
+	HeadersSize int            `json:"headersSize"`
+	BodySize    int            `json:"bodySize"`
+}
+
+type harNameValue struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+type harPostData struct {
+	MimeType string `json:"mimeType"`
+	Text     string `json:"text"`
+}
+
+type harContent struct {
+	Size     int    `json:"size"`
+	MimeType string `json:"mimeType"`
+	Text     string `json:"text,omitempty"`
+}
+
+type harTimings struct {
+	Send    float64 `json:"send"`
+	Wait    float64 `json:"wait"`
+	Receive float64 `json:"receive"`
+}
+
+// NewHARRecorder creates an empty HAR recorder
+func NewHARRecorder() *HARRecorder {
+	return &HARRecorder{}
+}
+
+// Len returns the count of the recorded entries
+func (h *HARRecorder) Len() int {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return len(h.entries)
+}
+
+// WriteTo writes the recorded entries as a HAR document
+func (h *HARRecorder) WriteTo(w io.Writer) (int64, error) {
+	var document harLog
+	document.Log.Version = "1.2"
+	document.Log.Creator = harCreator{Name: "ibmcloud-volume-vpc", Version: "1.0"}
+	h.mu.Lock()
+	document.Log.Entries = append([]harEntry{}, h.entries...)
+	h.mu.Unlock()
+
+	data, err := json.MarshalIndent(document, "", "  ")
+	if err != nil {
+		return 0, err
+	}
+	n, err := w.Write(append(data, '\n'))
+	return int64(n), err
+}
+
+// Save writes the recorded entries as a HAR document to the file at path
+func (h *HARRecorder) Save(path string) error {
+	var document bytes.Buffer
+	if _, err := h.WriteTo(&document); err != nil {
+		return err
+	}
+	return ioutil.WriteFile(path, document.Bytes(), 0600)
+}
+
+func (h *HARRecorder) add(exchange *httpExchange) {
+	milliseconds := float64(exchange.duration) / float64(time.Millisecond)
+	entry := harEntry{
+		StartedDateTime: exchange.started.Format(time.RFC3339Nano),
+		Time:            milliseconds,
+		Request: harRequest{
+			Method:      exchange.request.Method,
+			URL:         scrubTokens(exchange.request.URL.String()),
+			HTTPVersion: "HTTP/1.1",
+			Headers:     harHeaders(sanitizeHeader(exchange.request.Header)),
+			QueryString: []harNameValue{},
+			Cookies:     []harNameValue{},
+			HeadersSize: -1,
+			BodySize:    exchange.requestBodySize,
+		},
+		Response: harResponse{
+			HTTPVersion: "HTTP/1.1",
+			Headers:     []harNameValue{},
+			Cookies:     []harNameValue{},
+			HeadersSize: -1,
+			BodySize:    -1,
+		},
+		Timings: harTimings{Send: 0, Wait: milliseconds, Receive: 0},
+	}
+	for name, values := range exchange.request.URL.Query() {
+		for _, value := range values {
+			entry.Request.QueryString = append(entry.Request.QueryString, harNameValue{Name: name, Value: scrubTokens(value)})
+		}
+	}
+	sort.Slice(entry.Request.QueryString, func(i, j int) bool {
+		return entry.Request.QueryString[i].Name < entry.Request.QueryString[j].Name
+	})
+	if exchange.requestBody != "" {
+		entry.Request.PostData = &harPostData{MimeType: exchange.request.Header.Get("Content-Type"), Text: exchange.requestBody}
+	}
+	if exchange.err != nil {
+		entry.Comment = exchange.err.Error()
+	}
+	if resp := exchange.response; resp != nil {
+		entry.Response.Status = resp.StatusCode
+		entry.Response.StatusText = http.StatusText(resp.StatusCode)
+		entry.Response.Headers = harHeaders(sanitizeHeader(resp.Header))
+		entry.Response.Content = harContent{Size: len(exchange.responseBody), MimeType: resp.Header.Get("Content-Type"), Text: exchange.responseBody}
+	}
+
+	maxEntries := h.MaxEntries
+	if maxEntries <= 0 {
+		maxEntries = DefaultMaxHAREntries
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.entries = append(h.entries, entry)
+	if len(h.entries) > maxEntries {
+		h.entries = h.entries[len(h.entries)-maxEntries:]
+	}
+}
+
+func harHeaders(header http.Header) []harNameValue {
+	headers := []harNameValue{}
+	for name, values := range header {
+		for _, value := range values {
+			headers = append(headers, harNameValue{Name: name, Value: value})
+		}
+	}
+	sort.Slice(headers, func(i, j int) bool {
+		return headers[i].Name < headers[j].Name
+	})
+	return headers
+}
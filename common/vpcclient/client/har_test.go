@@ -0,0 +1,100 @@
+/**
+ * Copyright 2022 IBM Corp.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package client_test ...
+package client_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+
+	"github.com/IBM/ibmcloud-volume-vpc/common/vpcclient/client"
+)
+
+func TestHARRecorder(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `{"id":"volume-1"}`)
+	}))
+	defer server.Close()
+
+	// HAR is recorded even when debug events are not logged
+	har := &client.HARRecorder{MaxEntries: 2}
+	queryValues := url.Values{"version": []string{"2022-11-01"}}
+	c := client.New(context.Background(), server.URL, queryValues, http.DefaultClient, "test-context", "default").WithAuthToken(testJWT)
+	c = c.WithMiddleware(client.HTTPLogMiddleware(zap.NewNop(), client.HTTPLogConfig{HAR: har}))
+
+	for i := 0; i < 3; i++ {
+		_, err := c.NewRequest(postOperation).JSONBody(map[string]string{"name": fmt.Sprintf("volume-%d", i)}).Invoke()
+		assert.Nil(t, err)
+	}
+	assert.Equal(t, 2, har.Len())
+
+	path := filepath.Join(t.TempDir(), "session.har")
+	assert.Nil(t, har.Save(path))
+	data, err := ioutil.ReadFile(path)
+	assert.Nil(t, err)
+	assert.NotContains(t, string(data), testJWT)
+
+	var document struct {
+		Log struct {
+			Version string `json:"version"`
+			Entries []struct {
+				Request struct {
+					Method      string `json:"method"`
+					URL         string `json:"url"`
+					QueryString []struct {
+						Name  string `json:"name"`
+						Value string `json:"value"`
+					} `json:"queryString"`
+					PostData struct {
+						Text string `json:"text"`
+					} `json:"postData"`
+				} `json:"request"`
+				Response struct {
+					Status  int `json:"status"`
+					Content struct {
+						MimeType string `json:"mimeType"`
+						Text     string `json:"text"`
+					} `json:"content"`
+				} `json:"response"`
+			} `json:"entries"`
+		} `json:"log"`
+	}
+	assert.Nil(t, json.Unmarshal(data, &document))
+	assert.Equal(t, "1.2", document.Log.Version)
+	if assert.Equal(t, 2, len(document.Log.Entries)) {
+		// Oldest entries are dropped
+		entry := document.Log.Entries[0]
+		assert.Equal(t, "POST", entry.Request.Method)
+		assert.Equal(t, server.URL+"/resource?version=2022-11-01", entry.Request.URL)
+		assert.Equal(t, "version", entry.Request.QueryString[0].Name)
+		assert.Equal(t, `{"name":"volume-1"}`, entry.Request.PostData.Text)
+		assert.Equal(t, http.StatusOK, entry.Response.Status)
+		assert.Equal(t, "application/json", entry.Response.Content.MimeType)
+		assert.Equal(t, `{"id":"volume-1"}`, entry.Response.Content.Text)
+	}
+}
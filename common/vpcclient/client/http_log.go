@@ -0,0 +1,187 @@
+/**
+ * Copyright 2022 IBM Corp.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package client ...
+package client
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"math/rand"
+	"net/http"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// DefaultMaxLoggedBodySize is the size of the bodies logged when HTTPLogConfig.MaxBodySize is not set
+const DefaultMaxLoggedBodySize = 4096
+
+// HTTPLogConfig configures the HTTP debug events
+type HTTPLogConfig struct {
+	// MaxBodySize is the size of the bodies logged, DefaultMaxLoggedBodySize if 0. Negative omits the bodies.
+	MaxBodySize int
+
+	// SampleRates is the fraction, between 0 and 1, of the successful exchanges logged by operation name.
+	// Operations not listed are all logged, failed exchanges are always logged.
+	SampleRates map[string]float64
+
+	// HAR records the logged exchanges for export, if set
+	HAR *HARRecorder
+}
+
+func (c HTTPLogConfig) maxBodySize() int {
+	if c.MaxBodySize == 0 {
+		return DefaultMaxLoggedBodySize
+	}
+	return c.MaxBodySize
+}
+
+// sampled tells whether a successful exchange of the operation is logged
+func (c HTTPLogConfig) sampled(operation string) bool {
+	rate, ok := c.SampleRates[operation]
+	if !ok || rate >= 1 {
+		return true
+	}
+	return rate > 0 && rand.Float64() < rate // #nosec G404 sampling does not need a secure random
+}
+
+// httpExchange is the sanitized request and response logged
+type httpExchange struct {
+	operation       string
+	started         time.Time
+	duration        time.Duration
+	request         *http.Request
+	requestBody     string
+	requestBodySize int
+	response        *http.Response
+	responseBody    string
+	err             error
+}
+
+// HTTPLogMiddleware logs the requests and responses as structured debug events of the logger, with the headers and
+// bodies redacted as in the debug dumps and truncated to config.MaxBodySize
+func HTTPLogMiddleware(logger *zap.Logger, config HTTPLogConfig) Middleware {
+	return MiddlewareFunc(func(next Handler) Handler {
+		return func(request *Request, httpRequest *http.Request) (*http.Response, error) {
+			if !logger.Core().Enabled(zapcore.DebugLevel) && config.HAR == nil {
+				return next(request, httpRequest)
+			}
+
+			exchange := &httpExchange{operation: request.Operation().Name, started: time.Now(), request: httpRequest}
+			exchange.requestBody, exchange.requestBodySize = peekRequestBody(httpRequest, config.maxBodySize())
+			resp, err := next(request, httpRequest)
+			exchange.duration = time.Since(exchange.started)
+			exchange.response, exchange.err = resp, err
+
+			failed := err != nil || resp == nil || resp.StatusCode >= http.StatusBadRequest
+			if !failed && !config.sampled(exchange.operation) {
+				return resp, err
+			}
+			if resp != nil {
+				exchange.responseBody = peekResponseBody(resp, config.maxBodySize())
+			}
+			logger.Debug("HTTP exchange", exchange.fields()...)
+			if config.HAR != nil {
+				config.HAR.add(exchange)
+			}
+			return resp, err
+		}
+	})
+}
+
+func (e *httpExchange) fields() []zap.Field {
+	fields := []zap.Field{
+		zap.String("operation", e.operation),
+		zap.String("method", e.request.Method),
+		zap.String("url", scrubTokens(e.request.URL.String())),
+		zap.Duration("duration", e.duration),
+		zap.Any("requestHeaders", sanitizeHeader(e.request.Header)),
+	}
+	if e.requestBody != "" {
+		fields = append(fields, zap.String("requestBody", e.requestBody))
+	}
+	if e.err != nil {
+		return append(fields, zap.Error(e.err))
+	}
+	if e.response != nil {
+		fields = append(fields, zap.Int("status", e.response.StatusCode), zap.Any("responseHeaders", sanitizeHeader(e.response.Header)))
+		if e.responseBody != "" {
+			fields = append(fields, zap.String("responseBody", e.responseBody))
+		}
+	}
+	return fields
+}
+
+// peekRequestBody returns the sanitized and truncated body of the request, and its size, leaving the body unread
+func peekRequestBody(req *http.Request, limit int) (string, int) {
+	if req.Body == nil || req.Body == http.NoBody {
+		return "", 0
+	}
+	var body []byte
+	if req.GetBody != nil {
+		reader, err := req.GetBody()
+		if err != nil {
+			return "", 0
+		}
+		body, err = ioutil.ReadAll(reader)
+		_ = reader.Close()
+		if err != nil {
+			return "", 0
+		}
+	} else {
+		var err error
+		body, err = ioutil.ReadAll(req.Body)
+		_ = req.Body.Close()
+		req.Body = ioutil.NopCloser(bytes.NewReader(body))
+		if err != nil {
+			return "", 0
+		}
+	}
+	return truncateBody(sanitizeBody(body, req.Header.Get("Content-Type")), limit), len(body)
+}
+
+// peekResponseBody returns the sanitized and truncated body of the response, and puts the body back for the consumer
+func peekResponseBody(resp *http.Response, limit int) string {
+	if resp.Body == nil || limit < 0 {
+		return ""
+	}
+	// Bodies are redacted before being truncated, read a bit more than needed so that JSON bodies stay parsable
+	body, err := ioutil.ReadAll(io.LimitReader(resp.Body, int64(4*limit)))
+	resp.Body = struct {
+		io.Reader
+		io.Closer
+	}{io.MultiReader(bytes.NewReader(body), resp.Body), resp.Body}
+	if err != nil {
+		return ""
+	}
+	return truncateBody(sanitizeBody(body, resp.Header.Get("Content-Type")), limit)
+}
+
+// TruncatedFillin is appended to the logged bodies that were truncated
+const TruncatedFillin = "...[TRUNCATED]"
+
+func truncateBody(body string, limit int) string {
+	if limit < 0 {
+		return ""
+	}
+	if len(body) > limit {
+		return body[:limit] + TruncatedFillin
+	}
+	return body
+}
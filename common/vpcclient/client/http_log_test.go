@@ -0,0 +1,132 @@
+/**
+ * Copyright 2022 IBM Corp.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package client_test ...
+package client_test
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+
+	"github.com/IBM/ibmcloud-volume-vpc/common/vpcclient/client"
+	"github.com/IBM/ibmcloud-volume-vpc/common/vpcclient/models"
+)
+
+func TestHTTPLogMiddleware(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if r.Method == http.MethodPost {
+			w.WriteHeader(http.StatusBadRequest)
+			fmt.Fprint(w, `{"errors":[{"code":"bad_request","message":"Bad request"}],"trace":"trace-1"}`)
+			return
+		}
+		fmt.Fprintf(w, `{"id":"volume-1","description":"%s"}`, strings.Repeat("x", 100))
+	}))
+	defer server.Close()
+
+	testCases := []struct {
+		testCaseName string
+		config       client.HTTPLogConfig
+		level        zapcore.Level
+		expectedLogs int
+		verify       func(t *testing.T, logs []observer.LoggedEntry)
+	}{
+		{
+			testCaseName: "Exchanges are logged with redacted headers and bodies",
+			config:       client.HTTPLogConfig{},
+			level:        zapcore.DebugLevel,
+			expectedLogs: 2,
+			verify: func(t *testing.T, logs []observer.LoggedEntry) {
+				get := logs[0].ContextMap()
+				assert.Equal(t, "HTTP exchange", logs[0].Message)
+				assert.Equal(t, "GetOperation", get["operation"])
+				assert.Equal(t, "GET", get["method"])
+				assert.Equal(t, int64(http.StatusOK), get["status"])
+				assert.Contains(t, get["responseBody"], `"id":"volume-1"`)
+				assert.NotNil(t, get["duration"])
+
+				post := logs[1].ContextMap()
+				assert.Equal(t, int64(http.StatusBadRequest), post["status"])
+				assert.Equal(t, `{"api_key":"[REDACTED]","name":"volume"}`, post["requestBody"])
+				assert.Contains(t, post["responseBody"], "trace-1")
+				assert.Equal(t, "application/json", post["responseHeaders"].(http.Header).Get("Content-Type"))
+				assert.NotContains(t, fmt.Sprint(post), "secret-api-key")
+			},
+		}, {
+			testCaseName: "Bodies are truncated",
+			config:       client.HTTPLogConfig{MaxBodySize: 20},
+			level:        zapcore.DebugLevel,
+			expectedLogs: 2,
+			verify: func(t *testing.T, logs []observer.LoggedEntry) {
+				assert.Equal(t, `{"id":"volume-1","de`+client.TruncatedFillin, logs[0].ContextMap()["responseBody"])
+			},
+		}, {
+			testCaseName: "Bodies are omitted",
+			config:       client.HTTPLogConfig{MaxBodySize: -1},
+			level:        zapcore.DebugLevel,
+			expectedLogs: 2,
+			verify: func(t *testing.T, logs []observer.LoggedEntry) {
+				assert.NotContains(t, logs[0].ContextMap(), "responseBody")
+				assert.NotContains(t, logs[1].ContextMap(), "requestBody")
+			},
+		}, {
+			testCaseName: "Successful exchanges are sampled, failed ones are always logged",
+			config:       client.HTTPLogConfig{SampleRates: map[string]float64{"GetOperation": 0, "PostOperation": 0}},
+			level:        zapcore.DebugLevel,
+			expectedLogs: 1,
+			verify: func(t *testing.T, logs []observer.LoggedEntry) {
+				assert.Equal(t, "PostOperation", logs[0].ContextMap()["operation"])
+			},
+		}, {
+			testCaseName: "Nothing is logged above debug level",
+			config:       client.HTTPLogConfig{},
+			level:        zapcore.InfoLevel,
+			expectedLogs: 0,
+		},
+	}
+
+	for _, testcase := range testCases {
+		t.Run(testcase.testCaseName, func(t *testing.T) {
+			core, logs := observer.New(testcase.level)
+			c := client.New(context.Background(), server.URL, nil, http.DefaultClient, "test-context", "default").WithAuthToken("auth-token")
+			c = c.WithMiddleware(client.HTTPLogMiddleware(zap.New(core), testcase.config))
+
+			// Consumers still read the bodies
+			var result map[string]string
+			_, err := c.NewRequest(getOperation).JSONSuccess(&result).Invoke()
+			assert.Nil(t, err)
+			assert.Equal(t, "volume-1", result["id"])
+
+			var errResult models.Error
+			_, err = c.NewRequest(postOperation).JSONBody(map[string]string{"name": "volume", "api_key": "secret-api-key"}).JSONError(&errResult).Invoke()
+			assert.EqualError(t, err, "Trace Code:trace-1, Bad request Please check ")
+
+			assert.Equal(t, testcase.expectedLogs, logs.Len())
+			if testcase.verify != nil && logs.Len() == testcase.expectedLogs {
+				testcase.verify(t, logs.All())
+			}
+		})
+	}
+}
@@ -0,0 +1,59 @@
+/**
+ * Copyright 2022 IBM Corp.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package client ...
+package client
+
+import (
+	"io"
+	"net/http"
+)
+
+// Handler sends the HTTP request of an operation and returns the response
+type Handler func(request *Request, httpRequest *http.Request) (*http.Response, error)
+
+// Middleware wraps the handler which sends the requests of a SessionClient. It sees the operation, URL,
+// headers and body of each request and the resulting response or error. It can change them, or respond
+// without calling the next handler e.g from a cache or to inject faults.
+type Middleware interface {
+	Wrap(next Handler) Handler
+}
+
+// MiddlewareFunc adapts a function to a Middleware
+type MiddlewareFunc func(next Handler) Handler
+
+// Wrap ...
+func (f MiddlewareFunc) Wrap(next Handler) Handler {
+	return f(next)
+}
+
+// AuthMiddleware sets the bearer token on the requests. Requests fail with ErrAuthenticationRequired if the token is empty.
+func AuthMiddleware(authToken string) Middleware {
+	return &authenticationHandler{authToken: authToken}
+}
+
+// DebugMiddleware dumps the requests and responses to the writer, with the credentials redacted
+func DebugMiddleware(writer io.Writer) Middleware {
+	return &debugHandler{writer: writer}
+}
+
+// chain wraps the handler with the middlewares, the first middleware is the outermost
+func chain(middlewares []Middleware, handler Handler) Handler {
+	for i := len(middlewares) - 1; i >= 0; i-- {
+		handler = middlewares[i].Wrap(handler)
+	}
+	return handler
+}
@@ -0,0 +1,134 @@
+/**
+ * Copyright 2022 IBM Corp.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package client_test ...
+package client_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/IBM/ibmcloud-volume-vpc/common/vpcclient/client"
+)
+
+func TestMiddleware(t *testing.T) {
+	var received *http.Request
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received = r
+		w.WriteHeader(http.StatusOK)
+		_, _ = w.Write([]byte(`{"id":"volume-1"}`))
+	}))
+	defer server.Close()
+
+	var calls []string
+	recorder := func(name string) client.Middleware {
+		return client.MiddlewareFunc(func(next client.Handler) client.Handler {
+			return func(request *client.Request, httpRequest *http.Request) (*http.Response, error) {
+				calls = append(calls, name+" "+request.Operation().Name+" "+httpRequest.URL.Path)
+				httpRequest.Header.Set("X-"+name, "injected")
+				resp, err := next(request, httpRequest)
+				if resp != nil {
+					calls = append(calls, name+" "+resp.Status)
+				}
+				return resp, err
+			}
+		})
+	}
+
+	log := &bytes.Buffer{}
+	c := client.New(context.Background(), server.URL, nil, http.DefaultClient, "test-context", "default").WithDebug(log).WithAuthToken("auth-token")
+	c = c.WithMiddleware(recorder("Outer"), recorder("Inner"))
+
+	var result map[string]string
+	resp, err := c.NewRequest(postOperation).JSONBody(map[string]string{"name": "volume"}).JSONSuccess(&result).Invoke()
+	assert.Nil(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "volume-1", result["id"])
+
+	// Middlewares are called in order and see the request and the response
+	assert.Equal(t, []string{"Outer PostOperation /resource", "Inner PostOperation /resource", "Inner 200 OK", "Outer 200 OK"}, calls)
+	assert.Equal(t, "injected", received.Header.Get("X-Outer"))
+	assert.Equal(t, "Bearer auth-token", received.Header.Get("Authorization"))
+
+	// Built-in debug middleware dumps the injected headers and redacts the token
+	assert.Contains(t, log.String(), "X-Inner: injected")
+	assert.Contains(t, log.String(), "Authorization: [REDACTED]")
+	assert.NotContains(t, log.String(), "auth-token")
+}
+
+func TestMiddlewareShortCircuit(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Error("request must not be sent")
+	}))
+	defer server.Close()
+
+	// Respond from the middleware, e.g a cache
+	cache := client.MiddlewareFunc(func(next client.Handler) client.Handler {
+		return func(request *client.Request, httpRequest *http.Request) (*http.Response, error) {
+			return &http.Response{StatusCode: http.StatusOK, Body: ioutil.NopCloser(strings.NewReader(`{"id":"cached"}`))}, nil
+		}
+	})
+	c := client.New(context.Background(), server.URL, nil, http.DefaultClient, "test-context", "default").WithAuthToken("auth-token").WithMiddleware(cache)
+	var result map[string]string
+	_, err := c.NewRequest(getOperation).JSONSuccess(&result).Invoke()
+	assert.Nil(t, err)
+	assert.Equal(t, "cached", result["id"])
+
+	// Inject a fault
+	fault := client.MiddlewareFunc(func(next client.Handler) client.Handler {
+		return func(request *client.Request, httpRequest *http.Request) (*http.Response, error) {
+			return nil, errors.New("injected fault")
+		}
+	})
+	c = client.New(context.Background(), server.URL, nil, http.DefaultClient, "test-context", "default").WithAuthToken("auth-token").WithMiddleware(fault)
+	_, err = c.NewRequest(getOperation).Invoke()
+	assert.EqualError(t, err, "injected fault")
+
+	// Built-in auth middleware needs the token
+	var authErr error
+	observer := client.MiddlewareFunc(func(next client.Handler) client.Handler {
+		return func(request *client.Request, httpRequest *http.Request) (*http.Response, error) {
+			resp, err := next(request, httpRequest)
+			authErr = err
+			return resp, err
+		}
+	})
+	c = client.New(context.Background(), server.URL, nil, http.DefaultClient, "test-context", "default").WithMiddleware(observer)
+	_, err = c.NewRequest(getOperation).Invoke()
+	assert.Equal(t, client.ErrAuthenticationRequired, err)
+	assert.Equal(t, client.ErrAuthenticationRequired, authErr)
+
+	// Built-in auth middleware can be used on its own
+	var authorization string
+	checker := client.MiddlewareFunc(func(next client.Handler) client.Handler {
+		return func(request *client.Request, httpRequest *http.Request) (*http.Response, error) {
+			authorization = httpRequest.Header.Get("Authorization")
+			return cache.Wrap(next)(request, httpRequest)
+		}
+	})
+	c = client.New(context.Background(), server.URL, nil, http.DefaultClient, "test-context", "default").WithMiddleware(client.AuthMiddleware("other-token"), checker)
+	_, err = c.NewRequest(getOperation).Invoke()
+	assert.Nil(t, err)
+	assert.Equal(t, "Bearer other-token", authorization)
+}
@@ -0,0 +1,229 @@
+/**
+ * Copyright 2022 IBM Corp.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package client ...
+package client
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+const (
+	// minRateFactor is the lowest fraction of the configured rate the adaptive decrease goes to
+	minRateFactor = 0.1
+
+	// recoverRateFactor is the fraction of the configured rate recovered on each successful request
+	recoverRateFactor = 0.05
+)
+
+// RateLimit is the rate of a token bucket
+type RateLimit struct {
+	Rate  float64 // Requests per second, zero means unlimited
+	Burst int     // Bucket size, defaults to 1
+}
+
+// RateLimitConfig configures the client side rate limiter
+type RateLimitConfig struct {
+	Read       RateLimit            // Bucket for GET and HEAD requests
+	Write      RateLimit            // Bucket for all other requests
+	Operations map[string]RateLimit // Own buckets for some operations, keyed on Operation.Name
+}
+
+// RateLimiter limits the requests with token buckets. On 429 the rate of the bucket is halved and
+// it is paused for Retry-After, then the rate recovers gradually with the successful requests.
+type RateLimiter struct {
+	mu         sync.RWMutex
+	read       *adaptiveBucket
+	write      *adaptiveBucket
+	operations map[string]*adaptiveBucket
+}
+
+// adaptiveBucket is a token bucket whose rate adapts to the throttling of the server
+type adaptiveBucket struct {
+	limit      RateLimit
+	limiter    *rate.Limiter
+	configured rate.Limit
+
+	mu          sync.Mutex
+	pausedUntil time.Time
+}
+
+// NewRateLimiter creates a rate limiter from the config
+func NewRateLimiter(config RateLimitConfig) *RateLimiter {
+	rl := &RateLimiter{
+		read:       newAdaptiveBucket(config.Read),
+		write:      newAdaptiveBucket(config.Write),
+		operations: map[string]*adaptiveBucket{},
+	}
+	for name, limit := range config.Operations {
+		rl.operations[name] = newAdaptiveBucket(limit)
+	}
+	return rl
+}
+
+var (
+	sharedRateLimitersMu sync.Mutex
+	sharedRateLimiters   = map[string]*RateLimiter{}
+)
+
+// SharedRateLimiter returns the rate limiter of the account and endpoint, so that all the clients
+// of an account share the same buckets. The latest config wins: if it differs from the config the
+// limiter has, the limiter is reconfigured for all its clients, see Reconfigure.
+func SharedRateLimiter(accountID string, baseURL string, config RateLimitConfig) *RateLimiter {
+	key := accountID + " " + baseURL
+	sharedRateLimitersMu.Lock()
+	defer sharedRateLimitersMu.Unlock()
+	rl, ok := sharedRateLimiters[key]
+	if !ok {
+		rl = NewRateLimiter(config)
+		sharedRateLimiters[key] = rl
+		return rl
+	}
+	rl.Reconfigure(config)
+	return rl
+}
+
+// Reconfigure applies the config to the limiter. The buckets whose limit is unchanged keep their
+// adapted rate and pause, the others are created anew.
+func (rl *RateLimiter) Reconfigure(config RateLimitConfig) {
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+	rl.read = reconfigureBucket(rl.read, config.Read)
+	rl.write = reconfigureBucket(rl.write, config.Write)
+	operations := map[string]*adaptiveBucket{}
+	for name, limit := range config.Operations {
+		operations[name] = reconfigureBucket(rl.operations[name], limit)
+	}
+	rl.operations = operations
+}
+
+func reconfigureBucket(b *adaptiveBucket, limit RateLimit) *adaptiveBucket {
+	if b != nil && b.limit == limit {
+		return b
+	}
+	return newAdaptiveBucket(limit)
+}
+
+func newAdaptiveBucket(limit RateLimit) *adaptiveBucket {
+	if limit.Rate <= 0 {
+		return nil
+	}
+	burst := limit.Burst
+	if burst <= 0 {
+		burst = 1
+	}
+	return &adaptiveBucket{
+		limit:      limit,
+		limiter:    rate.NewLimiter(rate.Limit(limit.Rate), burst),
+		configured: rate.Limit(limit.Rate),
+	}
+}
+
+// bucket returns the bucket of the operation, nil if it is not limited
+func (rl *RateLimiter) bucket(operation *Operation) *adaptiveBucket {
+	rl.mu.RLock()
+	defer rl.mu.RUnlock()
+	if b, ok := rl.operations[operation.Name]; ok {
+		return b
+	}
+	if operation.Method == http.MethodGet || operation.Method == http.MethodHead {
+		return rl.read
+	}
+	return rl.write
+}
+
+// Wait blocks till the operation is allowed to be sent or the context is done
+func (rl *RateLimiter) Wait(ctx context.Context, operation *Operation) error {
+	b := rl.bucket(operation)
+	if b == nil {
+		return nil
+	}
+	b.mu.Lock()
+	pause := time.Until(b.pausedUntil)
+	b.mu.Unlock()
+	if pause > 0 {
+		timer := time.NewTimer(pause)
+		defer timer.Stop()
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-timer.C:
+		}
+	}
+	return b.limiter.Wait(ctx)
+}
+
+// Observe adapts the rate of the operation's bucket to the response
+func (rl *RateLimiter) Observe(operation *Operation, resp *http.Response) {
+	b := rl.bucket(operation)
+	if b == nil || resp == nil {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	now := time.Now()
+	current := b.limiter.Limit()
+	switch {
+	case resp.StatusCode == http.StatusTooManyRequests:
+		decreased := current / 2
+		if decreased < b.configured*minRateFactor {
+			decreased = b.configured * minRateFactor
+		}
+		b.limiter.SetLimitAt(now, decreased)
+		if until := now.Add(ParseRetryAfter(resp.Header.Get("Retry-After"), now)); until.After(b.pausedUntil) {
+			b.pausedUntil = until
+		}
+	case resp.StatusCode < 400 && current < b.configured:
+		increased := current + b.configured*recoverRateFactor
+		if increased > b.configured {
+			increased = b.configured
+		}
+		b.limiter.SetLimitAt(now, increased)
+	}
+}
+
+// Rate returns the current rate of the operation's bucket in requests per second, zero if not limited
+func (rl *RateLimiter) Rate(operation *Operation) float64 {
+	b := rl.bucket(operation)
+	if b == nil {
+		return 0
+	}
+	return float64(b.limiter.Limit())
+}
+
+// ParseRetryAfter parses the Retry-After header, either delay seconds or an HTTP date.
+// It returns zero if the header is empty or invalid.
+func ParseRetryAfter(value string, now time.Time) time.Duration {
+	if value == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(value); err == nil {
+		if seconds < 0 {
+			return 0
+		}
+		return time.Duration(seconds) * time.Second
+	}
+	if date, err := http.ParseTime(value); err == nil && date.After(now) {
+		return date.Sub(now)
+	}
+	return 0
+}
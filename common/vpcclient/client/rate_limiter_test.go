@@ -0,0 +1,138 @@
+/**
+ * Copyright 2022 IBM Corp.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package client_test ...
+package client_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/IBM/ibmcloud-volume-vpc/common/vpcclient/client"
+)
+
+func TestRateLimiterBuckets(t *testing.T) {
+	rl := client.NewRateLimiter(client.RateLimitConfig{
+		Read:       client.RateLimit{Rate: 20},
+		Write:      client.RateLimit{Rate: 5},
+		Operations: map[string]client.RateLimit{"GetOperation": {Rate: 50, Burst: 10}},
+	})
+
+	assert.Equal(t, float64(20), rl.Rate(&client.Operation{Name: "GetVolume", Method: http.MethodGet}))
+	assert.Equal(t, float64(5), rl.Rate(&client.Operation{Name: "CreateVolume", Method: http.MethodPost}))
+	assert.Equal(t, float64(50), rl.Rate(getOperation))
+
+	// Unlimited bucket
+	assert.Equal(t, float64(0), client.NewRateLimiter(client.RateLimitConfig{}).Rate(getOperation))
+	assert.Nil(t, client.NewRateLimiter(client.RateLimitConfig{}).Wait(context.Background(), getOperation))
+
+	// Requests beyond the burst wait for the tokens
+	readOperation := &client.Operation{Name: "GetVolume", Method: http.MethodGet}
+	start := time.Now()
+	for i := 0; i < 5; i++ {
+		assert.Nil(t, rl.Wait(context.Background(), readOperation))
+	}
+	assert.True(t, time.Since(start) >= 150*time.Millisecond)
+
+	// Waiting stops with the context
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	assert.NotNil(t, rl.Wait(ctx, &client.Operation{Name: "CreateVolume", Method: http.MethodPost}))
+}
+
+func TestRateLimiterAdaptive(t *testing.T) {
+	rl := client.NewRateLimiter(client.RateLimitConfig{Read: client.RateLimit{Rate: 100, Burst: 100}})
+
+	throttled := &http.Response{StatusCode: http.StatusTooManyRequests, Header: http.Header{"Retry-After": []string{"1"}}}
+	rl.Observe(getOperation, throttled)
+	assert.Equal(t, float64(50), rl.Rate(getOperation))
+
+	// Bucket is paused till Retry-After
+	start := time.Now()
+	assert.Nil(t, rl.Wait(context.Background(), getOperation))
+	assert.True(t, time.Since(start) >= 900*time.Millisecond)
+
+	// Rate does not go below 10% of the configured one
+	for i := 0; i < 10; i++ {
+		rl.Observe(getOperation, &http.Response{StatusCode: http.StatusTooManyRequests, Header: http.Header{}})
+	}
+	assert.Equal(t, float64(10), rl.Rate(getOperation))
+
+	// Successful requests recover the rate up to the configured one
+	for i := 0; i < 30; i++ {
+		rl.Observe(getOperation, &http.Response{StatusCode: http.StatusOK})
+	}
+	assert.Equal(t, float64(100), rl.Rate(getOperation))
+}
+
+func TestRateLimitedClient(t *testing.T) {
+	var hits int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&hits, 1) == 1 {
+			w.Header().Set("Retry-After", "0")
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	config := client.RateLimitConfig{Read: client.RateLimit{Rate: 100}}
+	rl := client.SharedRateLimiter("account-1", server.URL, config)
+	assert.Same(t, rl, client.SharedRateLimiter("account-1", server.URL, config))
+	assert.NotSame(t, rl, client.SharedRateLimiter("account-2", server.URL, config))
+
+	c := client.New(context.Background(), server.URL, nil, http.DefaultClient, "test-context", "default", client.WithRateLimiter(rl)).WithAuthToken("auth-token")
+	resp, _ := c.NewRequest(getOperation).Invoke()
+	assert.Equal(t, http.StatusTooManyRequests, resp.StatusCode)
+	assert.Equal(t, float64(50), rl.Rate(getOperation))
+
+	resp, err := c.NewRequest(getOperation).Invoke()
+	assert.Nil(t, err)
+	assert.Equal(t, http.StatusNoContent, resp.StatusCode)
+	assert.Equal(t, float64(55), rl.Rate(getOperation))
+}
+
+func TestSharedRateLimiterReconfigure(t *testing.T) {
+	rl := client.SharedRateLimiter("account-1", "https://reconfigure.example.com", client.RateLimitConfig{Read: client.RateLimit{Rate: 100}, Write: client.RateLimit{Rate: 10}})
+	rl.Observe(getOperation, &http.Response{StatusCode: http.StatusTooManyRequests, Header: http.Header{}})
+	assert.Equal(t, float64(50), rl.Rate(getOperation))
+
+	// A later config replaces the changed buckets and keeps the adapted rate of the others
+	same := client.SharedRateLimiter("account-1", "https://reconfigure.example.com", client.RateLimitConfig{Read: client.RateLimit{Rate: 100}, Write: client.RateLimit{Rate: 20}})
+	assert.Same(t, rl, same)
+	assert.Equal(t, float64(50), rl.Rate(getOperation))
+	assert.Equal(t, float64(20), rl.Rate(&client.Operation{Name: "PostOperation", Method: http.MethodPost}))
+
+	client.SharedRateLimiter("account-1", "https://reconfigure.example.com", client.RateLimitConfig{})
+	assert.Equal(t, float64(0), rl.Rate(getOperation))
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	now := time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC)
+	assert.Equal(t, 5*time.Second, client.ParseRetryAfter("5", now))
+	assert.Equal(t, 30*time.Second, client.ParseRetryAfter(now.Add(30*time.Second).Format(http.TimeFormat), now))
+	assert.Equal(t, time.Duration(0), client.ParseRetryAfter("", now))
+	assert.Equal(t, time.Duration(0), client.ParseRetryAfter("-1", now))
+	assert.Equal(t, time.Duration(0), client.ParseRetryAfter("soon", now))
+	assert.Equal(t, time.Duration(0), client.ParseRetryAfter(now.Add(-time.Minute).Format(http.TimeFormat), now))
+}
@@ -19,18 +19,18 @@ package client
 
 import (
 	"context"
+	"crypto/sha256"
 	"fmt"
 	"io"
 	"net/http"
 	"net/http/httputil"
 	"net/url"
-	"reflect"
 	"regexp"
 	"strings"
 	"time"
 
 	"github.com/IBM/ibmcloud-volume-vpc/common/vpcclient/client/payload"
-	"github.com/fatih/structs"
+	"github.com/IBM/ibmcloud-volume-vpc/common/vpcclient/models"
 )
 
 // Operation defines the API operation to be invoked
@@ -45,7 +45,8 @@ type Operation struct {
 type Request struct {
 	httpClient    *http.Client
 	baseURL       string
-	authenHandler handler
+	authenHandler Middleware
+	middlewares   []Middleware
 
 	context context.Context
 
@@ -53,13 +54,13 @@ type Request struct {
 	pathParams Params
 	headers    http.Header
 
-	debugWriter io.Writer
-
 	queryValues     url.Values
 	bodyProvider    BodyProvider
 	successConsumer ResponseConsumer
 	errorConsumer   ResponseConsumer
-	resourceGroup   string
+	rateLimiter     *RateLimiter
+	circuitBreaker  *CircuitBreaker
+	coalescer       *Coalescer
 }
 
 // BodyProvider declares an interface that describes an HTTP body, for
@@ -102,6 +103,33 @@ func (r *Request) URL() string {
 	return resolvedURL.String()
 }
 
+// CoalesceKey identifies identical requests i.e same operation, URL, resource group and credentials.
+// The credentials are the current token of the request, hashed so that it is not kept as is. Requests
+// without a token can not be told apart, their key is empty.
+func (r *Request) CoalesceKey() string {
+	auth, ok := r.authenHandler.(*authenticationHandler)
+	if !ok {
+		return ""
+	}
+	token := auth.authToken
+	if auth.tokenSource != nil {
+		var err error
+		if token, err = auth.tokenSource.Token(); err != nil {
+			return ""
+		}
+	}
+	if token == "" {
+		return ""
+	}
+	credentials := fmt.Sprintf("%x", sha256.Sum256([]byte(token)))
+	return strings.Join([]string{r.operation.Name, r.operation.Method, r.URL(), r.headers.Get("X-Auth-Resource-Group-ID"), credentials}, " ")
+}
+
+// Operation returns the operation of the request
+func (r *Request) Operation() *Operation {
+	return r.operation
+}
+
 // PathParameter sets a path parameter to be resolved on invocation of a request
 func (r *Request) PathParameter(name, value string) *Request {
 	r.pathParams[name] = value
@@ -126,20 +154,26 @@ func (r *Request) SetQueryValue(key, value string) *Request {
 	return r
 }
 
-// JSONBody converts the supplied argument to JSON to use as the body of a request
-func (r *Request) JSONBody(p interface{}) *Request {
-	if r.operation.Method == http.MethodPost && reflect.ValueOf(p).Kind() == reflect.Struct {
-		structs.DefaultTagName = "json"
-		m := structs.Map(p)
-
-		if r.resourceGroup != "" {
-			m["resourceGroup"] = r.resourceGroup
-		}
+// SetHeader sets a header of the request, replacing the one of the client if any
+func (r *Request) SetHeader(key, value string) *Request {
+	r.headers.Set(key, value)
+	return r
+}
 
-		r.bodyProvider = payload.NewJSONBodyProvider(m)
-	} else {
-		r.bodyProvider = payload.NewJSONBodyProvider(p)
+// ResourceGroup sets the resource group of the request in its header, instead of the one of the client. Empty
+// keeps the one of the client.
+func (r *Request) ResourceGroup(resourceGroupID string) *Request {
+	if resourceGroupID == "" {
+		return r
 	}
+	r.headers.Set("X-Auth-Resource-Group-ID", resourceGroupID)
+	return r
+}
+
+// JSONBody converts the supplied argument to JSON to use as the body of a request. The body is sent as it is, the
+// resource group of a create is set explicitly in its template.
+func (r *Request) JSONBody(p interface{}) *Request {
+	r.bodyProvider = payload.NewJSONBodyProvider(p)
 	return r
 }
 
@@ -166,11 +200,7 @@ func (r *Request) JSONError(receiver error) *Request {
 
 // Invoke performs the request, and populates the response or error as appropriate
 func (r *Request) Invoke() (*http.Response, error) {
-	err := r.authenHandler.Before(r)
-	if err != nil {
-		return nil, err
-	}
-
+	var err error
 	var body io.Reader
 	if r.bodyProvider != nil {
 		body, err = r.bodyProvider.Body()
@@ -183,6 +213,13 @@ func (r *Request) Invoke() (*http.Response, error) {
 		}
 	}
 
+	// Client side rate limit, shared by the clients of the account and endpoint
+	if r.rateLimiter != nil {
+		if err = r.rateLimiter.Wait(r.context, r.operation); err != nil {
+			return nil, err
+		}
+	}
+
 	httpRequest, err := http.NewRequest(r.operation.Method, r.URL(), body)
 	if err != nil {
 		return nil, err
@@ -192,16 +229,12 @@ func (r *Request) Invoke() (*http.Response, error) {
 		httpRequest.Header[k] = v
 	}
 
-	r.debugRequest(httpRequest)
-
-	resp, err := r.httpClient.Do(httpRequest.WithContext(r.context))
+	resp, err := chain(r.middlewares, send)(r, httpRequest.WithContext(r.context))
 	if err != nil {
 		return nil, err
 	}
 	defer resp.Body.Close()
 
-	r.debugResponse(resp)
-
 	switch {
 	case resp.StatusCode == http.StatusNoContent:
 		break
@@ -216,47 +249,118 @@ func (r *Request) Invoke() (*http.Response, error) {
 			err = r.errorConsumer.Consume(resp.Body)
 			if err == nil {
 				err = r.errorConsumer.Receiver().(error)
+			} else {
+				err = &models.HTTPError{Err: err}
 			}
+			setResponseMetadata(err, resp)
 		}
 	}
 
 	return resp, err
 }
 
-func (r *Request) debugRequest(req *http.Request) {
-	if r.debugWriter == nil {
+// send is the innermost handler, it sends the request to the endpoint
+func send(r *Request, httpRequest *http.Request) (*http.Response, error) {
+	// Fail fast while the endpoint is known to be down
+	if r.circuitBreaker != nil {
+		if err := r.circuitBreaker.allow(); err != nil {
+			return nil, err
+		}
+	}
+
+	resp, err := r.httpClient.Do(httpRequest)
+	if r.circuitBreaker != nil {
+		r.circuitBreaker.record(r.circuitResult(resp, err))
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if r.rateLimiter != nil {
+		r.rateLimiter.Observe(r.operation, resp)
+	}
+	return resp, nil
+}
+
+// circuitResult classifies the outcome of the request for the circuit breaker. Network errors and 5xx
+// responses are failures of the endpoint, the other responses are successes.
+func (r *Request) circuitResult(resp *http.Response, err error) circuitResult {
+	if err != nil {
+		if r.context != nil && r.context.Err() != nil {
+			return circuitIgnored
+		}
+		return circuitFailure
+	}
+	if resp.StatusCode >= http.StatusInternalServerError {
+		return circuitFailure
+	}
+	return circuitSuccess
+}
+
+// setResponseMetadata attaches the status, headers, Retry-After and request ID of the response to the error
+func setResponseMetadata(err error, resp *http.Response) {
+	setter, ok := err.(interface {
+		SetResponseMetadata(models.ResponseMetadata)
+	})
+	if !ok {
 		return
 	}
+	requestID := resp.Header.Get("X-Request-ID")
+	if requestID == "" && resp.Request != nil {
+		requestID = resp.Request.Header.Get("X-Request-ID")
+	}
+	setter.SetResponseMetadata(models.ResponseMetadata{
+		StatusCode: resp.StatusCode,
+		Header:     resp.Header.Clone(),
+		RetryAfter: ParseRetryAfter(resp.Header.Get("Retry-After"), time.Now()),
+		RequestID:  requestID,
+	})
+}
 
+// debugHandler dumps the requests and responses
+type debugHandler struct {
+	writer io.Writer
+}
+
+// Wrap dumps the request before sending it and the response once received
+func (d *debugHandler) Wrap(next Handler) Handler {
+	return func(request *Request, httpRequest *http.Request) (*http.Response, error) {
+		d.debugRequest(httpRequest)
+		resp, err := next(request, httpRequest)
+		if err != nil {
+			return nil, err
+		}
+		d.debugResponse(resp)
+		return resp, nil
+	}
+}
+
+func (d *debugHandler) debugRequest(req *http.Request) {
 	multipart := strings.Contains(req.Header.Get("Content-Type"), "multipart/form-data")
 	dumpedRequest, err := httputil.DumpRequest(req, !multipart)
 	if err != nil {
-		r.debugf("Error dumping request\n%s\n", err)
+		d.debugf("Error dumping request\n%s\n", err)
 		return
 	}
 
-	r.debugf("\nREQUEST: [%s]\n%s\n", time.Now().Format(time.RFC3339), sanitize(dumpedRequest))
+	d.debugf("\nREQUEST: [%s]\n%s\n", time.Now().Format(time.RFC3339), sanitize(dumpedRequest))
 	if multipart {
-		r.debugf("[MULTIPART/FORM-DATA CONTENT HIDDEN]\n")
+		d.debugf("[MULTIPART/FORM-DATA CONTENT HIDDEN]\n")
 	}
 }
 
-func (r *Request) debugResponse(resp *http.Response) {
-	if r.debugWriter == nil {
-		return
-	}
-
+func (d *debugHandler) debugResponse(resp *http.Response) {
 	dumpedResponse, err := httputil.DumpResponse(resp, true)
 	if err != nil {
-		fmt.Fprintf(r.debugWriter, "Error dumping response\n%s\n", err)
+		fmt.Fprintf(d.writer, "Error dumping response\n%s\n", err)
 		return
 	}
 
-	r.debugf("\nRESPONSE: [%s]\n%s\n", time.Now().Format(time.RFC3339), sanitize(dumpedResponse))
+	d.debugf("\nRESPONSE: [%s]\n%s\n", time.Now().Format(time.RFC3339), sanitize(dumpedResponse))
 }
 
-func (r *Request) debugf(format string, args ...interface{}) {
-	fmt.Fprintf(r.debugWriter, format, args...)
+func (d *debugHandler) debugf(format string, args ...interface{}) {
+	fmt.Fprintf(d.writer, format, args...)
 }
 
 // RedactedFillin used as a replacement string in debug logs for sensitive data
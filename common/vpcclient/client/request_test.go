@@ -0,0 +1,99 @@
+/**
+ * Copyright 2022 IBM Corp.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package client_test ...
+package client_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/IBM/ibmcloud-volume-vpc/common/vpcclient/client"
+	"github.com/IBM/ibmcloud-volume-vpc/common/vpcclient/models"
+)
+
+func TestRequestResourceGroup(t *testing.T) {
+	testcases := []struct {
+		name           string
+		body           interface{}
+		resourceGroup  string
+		expectedHeader string
+		expectedBody   map[string]interface{}
+	}{
+		{
+			name:           "resource group of the client",
+			body:           &models.Snapshot{Name: "snapshot"},
+			expectedHeader: "rg-client",
+			expectedBody:   map[string]interface{}{"name": "snapshot"},
+		}, {
+			name:           "resource group of the request",
+			body:           &models.Snapshot{Name: "snapshot"},
+			resourceGroup:  "rg-request",
+			expectedHeader: "rg-request",
+			expectedBody:   map[string]interface{}{"name": "snapshot"},
+		}, {
+			name:           "explicit resource group of the body other than the one of the request",
+			body:           &models.Snapshot{Name: "snapshot", ResourceGroup: &models.ResourceGroup{ID: "rg-body"}},
+			resourceGroup:  "rg-request",
+			expectedHeader: "rg-request",
+			expectedBody:   map[string]interface{}{"name": "snapshot", "resource_group": map[string]interface{}{"id": "rg-body"}},
+		}, {
+			name:           "struct body is sent as it is",
+			body:           models.Snapshot{Name: "snapshot"},
+			resourceGroup:  "rg-request",
+			expectedHeader: "rg-request",
+			expectedBody:   map[string]interface{}{"name": "snapshot"},
+		},
+	}
+
+	for _, testcase := range testcases {
+		t.Run(testcase.name, func(t *testing.T) {
+			var header string
+			var body map[string]interface{}
+			s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				header = r.Header.Get("X-Auth-Resource-Group-ID")
+				assert.Nil(t, json.NewDecoder(r.Body).Decode(&body))
+				w.WriteHeader(http.StatusOK)
+			}))
+			defer s.Close()
+
+			c := client.New(context.Background(), s.URL, nil, http.DefaultClient, "test-context", "rg-client").WithAuthToken("auth-token")
+			_, err := c.NewRequest(postOperation).ResourceGroup(testcase.resourceGroup).JSONBody(testcase.body).Invoke()
+			assert.Nil(t, err)
+			assert.Equal(t, testcase.expectedHeader, header)
+			assert.Equal(t, testcase.expectedBody, body)
+		})
+	}
+}
+
+func TestRequestSetHeader(t *testing.T) {
+	var header string
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		header = r.Header.Get("Bluemix-Instance")
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer s.Close()
+
+	c := client.New(context.Background(), s.URL, nil, http.DefaultClient, "test-context", "rg-client").WithAuthToken("auth-token")
+	_, err := c.NewRequest(getOperation).SetHeader("bluemix-instance", "instance-1").Invoke()
+	assert.Nil(t, err)
+	assert.Equal(t, "instance-1", header)
+}
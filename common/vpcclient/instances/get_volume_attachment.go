@@ -48,11 +48,12 @@ func (vs *VolumeAttachService) GetVolumeAttachment(volumeAttachmentTemplate *mod
 	operationRequest = vs.populatePathPrefixParameters(operationRequest, volumeAttachmentTemplate)
 	operationRequest = operationRequest.PathParameter(attachmentIDParam, volumeAttachmentTemplate.ID)
 
-	_, err := operationRequest.JSONSuccess(&volumeAttachment).JSONError(apiErr).Invoke()
+	// Concurrent identical calls share one request
+	shared, err := operationRequest.JSONSuccess(&volumeAttachment).JSONError(apiErr).InvokeCoalesced()
 	if err != nil {
 		ctxLogger.Error("Error occurred while getting volume attachment", zap.Error(err))
 		return nil, err
 	}
-	ctxLogger.Info("Successfully retrieved the volume attachment", zap.Reflect("volumeAttachment", volumeAttachment))
+	ctxLogger.Info("Successfully retrieved the volume attachment", zap.Reflect("volumeAttachment", volumeAttachment), zap.Bool("shared", shared))
 	return &volumeAttachment, err
 }
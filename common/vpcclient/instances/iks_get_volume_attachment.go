@@ -46,11 +46,12 @@ func (vs *IKSVolumeAttachService) GetVolumeAttachment(volumeAttachmentTemplate *
 
 	ctxLogger.Info("Equivalent curl command and query parameters", zap.Reflect("URL", operationRequest.URL()), zap.Reflect(IksClusterQueryKey, *volumeAttachmentTemplate.ClusterID), zap.Reflect(IksWorkerQueryKey, *volumeAttachmentTemplate.InstanceID), zap.Reflect(IksVolumeAttachmentIDQueryKey, volumeAttachmentTemplate.ID))
 
-	_, err := operationRequest.JSONSuccess(&volumeAttachment).JSONError(apiErr).Invoke()
+	// Concurrent identical calls share one request
+	shared, err := operationRequest.JSONSuccess(&volumeAttachment).JSONError(apiErr).InvokeCoalesced()
 	if err != nil {
 		ctxLogger.Error("Error occurred while getting volume attachment", zap.Error(err))
 		return nil, err
 	}
-	ctxLogger.Info("Successfully retrieved the volume attachment", zap.Reflect("volumeAttachment", volumeAttachment))
+	ctxLogger.Info("Successfully retrieved the volume attachment", zap.Reflect("volumeAttachment", volumeAttachment), zap.Bool("shared", shared))
 	return &volumeAttachment, err
 }
@@ -39,18 +39,19 @@ func (vs *IKSVolumeAttachService) ListVolumeAttachments(volumeAttachmentTemplate
 	var volumeAttachmentList models.VolumeAttachmentList
 
 	apiErr := vs.receiverError
-	vs.client = vs.client.WithQueryValue(IksClusterQueryKey, *volumeAttachmentTemplate.ClusterID)
-	vs.client = vs.client.WithQueryValue(IksWorkerQueryKey, *volumeAttachmentTemplate.InstanceID)
+	// Query values are set on a derived client, the service client is shared by the concurrent calls
+	attachmentsClient := vs.client.WithQueryValue(IksClusterQueryKey, *volumeAttachmentTemplate.ClusterID).WithQueryValue(IksWorkerQueryKey, *volumeAttachmentTemplate.InstanceID)
 
-	operationRequest := vs.client.NewRequest(operation)
+	operationRequest := attachmentsClient.NewRequest(operation)
 
 	ctxLogger.Info("Equivalent curl command and query parameters", zap.Reflect("URL", operationRequest.URL()), zap.Reflect("volumeAttachmentTemplate", volumeAttachmentTemplate), zap.Reflect("Operation", operation), zap.Reflect(IksClusterQueryKey, *volumeAttachmentTemplate.ClusterID), zap.Reflect(IksWorkerQueryKey, *volumeAttachmentTemplate.InstanceID))
 
-	_, err := operationRequest.JSONSuccess(&volumeAttachmentList).JSONError(apiErr).Invoke()
+	// Concurrent identical calls share one request
+	shared, err := operationRequest.JSONSuccess(&volumeAttachmentList).JSONError(apiErr).InvokeCoalesced()
 	if err != nil {
 		ctxLogger.Error("Error occurred while getting volume attachments list", zap.Error(err))
 		return nil, err
 	}
-	ctxLogger.Info("Successfully retrieved the volume attachments")
+	ctxLogger.Info("Successfully retrieved the volume attachments", zap.Bool("shared", shared))
 	return &volumeAttachmentList, nil
 }
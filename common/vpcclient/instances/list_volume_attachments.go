@@ -47,11 +47,12 @@ func (vs *VolumeAttachService) ListVolumeAttachments(volumeAttachmentTemplate *m
 	ctxLogger.Info("Equivalent curl command details", zap.Reflect("URL", operationRequest.URL()), zap.Reflect("volumeAttachmentTemplate", volumeAttachmentTemplate), zap.Reflect("Operation", operation))
 	operationRequest = vs.populatePathPrefixParameters(operationRequest, volumeAttachmentTemplate)
 
-	_, err := operationRequest.JSONSuccess(&volumeAttachmentList).JSONError(apiErr).Invoke()
+	// Concurrent identical calls share one request
+	shared, err := operationRequest.JSONSuccess(&volumeAttachmentList).JSONError(apiErr).InvokeCoalesced()
 	if err != nil {
 		ctxLogger.Error("Error occurred while getting volume attachments list", zap.Error(err))
 		return nil, err
 	}
-	ctxLogger.Info("Successfully retrieved the volume attachments")
+	ctxLogger.Info("Successfully retrieved the volume attachments", zap.Bool("shared", shared))
 	return &volumeAttachmentList, nil
 }
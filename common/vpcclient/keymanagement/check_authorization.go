@@ -0,0 +1,103 @@
+/**
+ * Copyright 2020 IBM Corp.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package keymanagement ...
+package keymanagement
+
+import (
+	"time"
+
+	util "github.com/IBM/ibmcloud-volume-interface/lib/utils"
+	"github.com/IBM/ibmcloud-volume-vpc/common/vpcclient/client"
+	"github.com/IBM/ibmcloud-volume-vpc/common/vpcclient/models"
+	"go.uber.org/zap"
+)
+
+const (
+	policiesPath = "/v1/policies"
+
+	// authorizationPolicyType is the type of the policies authorizing a service to use another one
+	authorizationPolicyType = "authorization"
+)
+
+// BlockStorageService is the IAM service name of Block Storage, the source of the authorizations to the key
+// management instances
+const BlockStorageService = "server-protect"
+
+// CheckServiceAuthorization GETs /v1/policies?account_id={account}&type=authorization of the IAM policy management,
+// and looks for an authorization of Block Storage to the instance of the key
+func (ks *KeyService) CheckServiceAuthorization(keyCRN KeyCRN, ctxLogger *zap.Logger) (bool, bool, error) {
+	ctxLogger.Debug("Entry Backend CheckServiceAuthorization")
+	defer ctxLogger.Debug("Exit Backend CheckServiceAuthorization")
+
+	if ks.policyClient == nil {
+		ctxLogger.Debug("No IAM policy endpoint, the service authorization is not checked")
+		return false, false, nil
+	}
+
+	defer util.TimeTracker("CheckServiceAuthorization", time.Now())
+
+	operation := &client.Operation{
+		Name:        "ListPolicies",
+		Method:      "GET",
+		PathPattern: policiesPath,
+	}
+
+	var policies models.PolicyList
+	var apiErr models.Error
+
+	request := ks.policyClient().NewRequest(operation)
+	req := request.AddQueryValue("account_id", keyCRN.AccountID).AddQueryValue("type", authorizationPolicyType)
+	ctxLogger.Info("Equivalent curl command", zap.Reflect("URL", req.URL()), zap.Reflect("Operation", operation))
+
+	_, err := req.JSONSuccess(&policies).JSONError(&apiErr).Invoke()
+	if err != nil {
+		return false, true, err
+	}
+
+	for _, policy := range policies.Policies {
+		if authorizesBlockStorage(policy, keyCRN) {
+			return true, true, nil
+		}
+	}
+	return false, true, nil
+}
+
+// authorizesBlockStorage tells if the policy authorizes Block Storage to use the keys of the instance. The
+// policies without instance authorize all the instances of the service.
+func authorizesBlockStorage(policy models.Policy, keyCRN KeyCRN) bool {
+	if policy.Type != authorizationPolicyType || len(policy.Roles) == 0 {
+		return false
+	}
+	subject := false
+	for _, s := range policy.Subjects {
+		if models.PolicyAttributeValue(s.Attributes, models.PolicyServiceNameAttribute) == BlockStorageService {
+			subject = true
+		}
+	}
+	if !subject {
+		return false
+	}
+	for _, resource := range policy.Resources {
+		if models.PolicyAttributeValue(resource.Attributes, models.PolicyServiceNameAttribute) != keyCRN.ServiceName {
+			continue
+		}
+		if instanceID := models.PolicyAttributeValue(resource.Attributes, models.PolicyServiceInstanceAttribute); instanceID == "" || instanceID == keyCRN.InstanceID {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,102 @@
+/**
+ * Copyright 2020 IBM Corp.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package keymanagement_test ...
+package keymanagement_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/IBM/ibmcloud-volume-vpc/common/vpcclient/keymanagement"
+	"github.com/IBM/ibmcloud-volume-vpc/common/vpcclient/models"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+)
+
+func TestCheckServiceAuthorization(t *testing.T) {
+	emulator := keymanagement.NewEmulator()
+	emulator.AuthorizeBlockStorage("account-1", keymanagement.KeyProtectService, "instance-1")
+	emulator.AuthorizeBlockStorage("account-2", keymanagement.HPCSService, "")
+	s := httptest.NewServer(emulator)
+	defer s.Close()
+
+	testCases := []struct {
+		name       string
+		keyCRN     keymanagement.KeyCRN
+		authorized bool
+	}{
+		{
+			name:       "authorization to the instance",
+			keyCRN:     keymanagement.KeyCRN{ServiceName: keymanagement.KeyProtectService, AccountID: "account-1", InstanceID: "instance-1", KeyID: "key-1"},
+			authorized: true,
+		}, {
+			name:   "authorization to another instance",
+			keyCRN: keymanagement.KeyCRN{ServiceName: keymanagement.KeyProtectService, AccountID: "account-1", InstanceID: "instance-2", KeyID: "key-1"},
+		}, {
+			name:   "authorization to another service",
+			keyCRN: keymanagement.KeyCRN{ServiceName: keymanagement.HPCSService, AccountID: "account-1", InstanceID: "instance-1", KeyID: "key-1"},
+		}, {
+			name:       "authorization to all the instances of the service",
+			keyCRN:     keymanagement.KeyCRN{ServiceName: keymanagement.HPCSService, AccountID: "account-2", InstanceID: "instance-3", KeyID: "key-1"},
+			authorized: true,
+		}, {
+			name:   "no authorization in the account",
+			keyCRN: keymanagement.KeyCRN{ServiceName: keymanagement.KeyProtectService, AccountID: "account-3", InstanceID: "instance-1", KeyID: "key-1"},
+		},
+	}
+
+	for _, testcase := range testCases {
+		t.Run(testcase.name, func(t *testing.T) {
+			session := keymanagement.New(keymanagement.Config{BaseURL: s.URL, PolicyURL: s.URL})
+			session.Login("auth-token")
+			authorized, checked, err := session.KeyService().CheckServiceAuthorization(testcase.keyCRN, zap.NewNop())
+			assert.Nil(t, err)
+			assert.True(t, checked)
+			assert.Equal(t, testcase.authorized, authorized)
+		})
+	}
+}
+
+func TestCheckServiceAuthorizationNotConfigured(t *testing.T) {
+	// Without policy endpoint, the authorization is not checked and no request is sent
+	session := keymanagement.New(keymanagement.Config{BaseURL: "http://127.0.0.1:1"})
+	session.Login("auth-token")
+	authorized, checked, err := session.KeyService().CheckServiceAuthorization(keymanagement.KeyCRN{AccountID: "account-1", InstanceID: "instance-1"}, zap.NewNop())
+	assert.Nil(t, err)
+	assert.False(t, checked)
+	assert.False(t, authorized)
+}
+
+func TestCheckServiceAuthorizationError(t *testing.T) {
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/v1/policies", r.URL.Path)
+		assert.Equal(t, "account-1", r.URL.Query().Get("account_id"))
+		assert.Equal(t, "authorization", r.URL.Query().Get("type"))
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusForbidden)
+		_, _ = w.Write([]byte(`{"trace":"trace-1","errors":[{"code":"insufficent_permissions","message":"You are not allowed to list the policies"}],"status_code":403}`))
+	}))
+	defer s.Close()
+
+	session := keymanagement.New(keymanagement.Config{PolicyURL: s.URL})
+	session.Login("auth-token")
+	_, checked, err := session.KeyService().CheckServiceAuthorization(keymanagement.KeyCRN{AccountID: "account-1", InstanceID: "instance-1"}, zap.NewNop())
+	assert.True(t, checked)
+	assert.NotNil(t, err)
+	assert.Equal(t, http.StatusForbidden, models.GetResponseMetadata(err).StatusCode)
+}
@@ -0,0 +1,94 @@
+/**
+ * Copyright 2020 IBM Corp.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package keymanagement ...
+package keymanagement
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// Service names of the key CRNs
+const (
+	KeyProtectService = "kms"
+	HPCSService       = "hs-crypto"
+)
+
+// crnSegments is the number of segments of a CRN,
+// crn:version:cname:ctype:service-name:location:scope:service-instance:resource-type:resource
+const crnSegments = 10
+
+// zoneSuffix is the zone number suffix of the zone names e.g -1 of us-south-1
+var zoneSuffix = regexp.MustCompile(`-[0-9]+$`)
+
+// KeyCRN is the CRN of a root key of Key Protect or Hyper Protect Crypto Services, e.g
+// crn:v1:bluemix:public:kms:us-south:a/<account>:<instance>:key:<key>
+type KeyCRN struct {
+	CRN         string
+	ServiceName string
+	Region      string
+	AccountID   string
+	InstanceID  string
+	KeyID       string
+}
+
+// ParseKeyCRN parses and validates the CRN of a Key Protect or Hyper Protect Crypto Services key
+func ParseKeyCRN(crn string) (KeyCRN, error) {
+	segments := strings.Split(crn, ":")
+	if len(segments) != crnSegments {
+		return KeyCRN{}, fmt.Errorf("a CRN has %d segments separated by ':', found %d", crnSegments, len(segments))
+	}
+	if segments[0] != "crn" || segments[1] != "v1" {
+		return KeyCRN{}, errors.New("a CRN starts with 'crn:v1:'")
+	}
+	keyCRN := KeyCRN{
+		CRN:         crn,
+		ServiceName: segments[4],
+		Region:      segments[5],
+		InstanceID:  segments[7],
+		KeyID:       segments[9],
+	}
+	if keyCRN.ServiceName != KeyProtectService && keyCRN.ServiceName != HPCSService {
+		return KeyCRN{}, fmt.Errorf("the service '%s' is not Key Protect (%s) or Hyper Protect Crypto Services (%s)", keyCRN.ServiceName, KeyProtectService, HPCSService)
+	}
+	if keyCRN.Region == "" {
+		return KeyCRN{}, errors.New("the CRN has no region")
+	}
+	if !strings.HasPrefix(segments[6], "a/") || len(segments[6]) == len("a/") {
+		return KeyCRN{}, errors.New("the CRN has no account scope 'a/<account>'")
+	}
+	keyCRN.AccountID = strings.TrimPrefix(segments[6], "a/")
+	if keyCRN.InstanceID == "" {
+		return KeyCRN{}, errors.New("the CRN has no service instance")
+	}
+	if segments[8] != "key" || keyCRN.KeyID == "" {
+		return KeyCRN{}, errors.New("the CRN is not the one of a key 'key:<key>'")
+	}
+	return keyCRN, nil
+}
+
+// ZoneRegion returns the region of the zone e.g us-south of us-south-1
+func ZoneRegion(zone string) string {
+	return zoneSuffix.ReplaceAllString(zone, "")
+}
+
+// CompatibleWithZone returns whether the key can encrypt the volumes of the zone, which is in the region of the key
+func (k KeyCRN) CompatibleWithZone(zone string) bool {
+	return zone == "" || k.Region == ZoneRegion(zone)
+}
@@ -0,0 +1,111 @@
+/**
+ * Copyright 2020 IBM Corp.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package keymanagement_test ...
+package keymanagement_test
+
+import (
+	"testing"
+
+	"github.com/IBM/ibmcloud-volume-vpc/common/vpcclient/keymanagement"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseKeyCRN(t *testing.T) {
+	testCases := []struct {
+		name        string
+		crn         string
+		expectedCRN keymanagement.KeyCRN
+		expectErr   string
+	}{
+		{
+			name: "Key Protect key",
+			crn:  "crn:v1:bluemix:public:kms:us-south:a/account-1:instance-1:key:key-1",
+			expectedCRN: keymanagement.KeyCRN{
+				CRN:         "crn:v1:bluemix:public:kms:us-south:a/account-1:instance-1:key:key-1",
+				ServiceName: keymanagement.KeyProtectService,
+				Region:      "us-south",
+				AccountID:   "account-1",
+				InstanceID:  "instance-1",
+				KeyID:       "key-1",
+			},
+		}, {
+			name: "Hyper Protect Crypto Services key",
+			crn:  "crn:v1:bluemix:public:hs-crypto:eu-de:a/account-1:instance-1:key:key-1",
+			expectedCRN: keymanagement.KeyCRN{
+				CRN:         "crn:v1:bluemix:public:hs-crypto:eu-de:a/account-1:instance-1:key:key-1",
+				ServiceName: keymanagement.HPCSService,
+				Region:      "eu-de",
+				AccountID:   "account-1",
+				InstanceID:  "instance-1",
+				KeyID:       "key-1",
+			},
+		}, {
+			name:      "not a CRN",
+			crn:       "key-1",
+			expectErr: "a CRN has 10 segments separated by ':', found 1",
+		}, {
+			name:      "unknown version",
+			crn:       "crn:v2:bluemix:public:kms:us-south:a/account-1:instance-1:key:key-1",
+			expectErr: "a CRN starts with 'crn:v1:'",
+		}, {
+			name:      "not a key management service",
+			crn:       "crn:v1:bluemix:public:cloud-object-storage:global:a/account-1:instance-1:bucket:bucket-1",
+			expectErr: "the service 'cloud-object-storage' is not Key Protect (kms) or Hyper Protect Crypto Services (hs-crypto)",
+		}, {
+			name:      "no region",
+			crn:       "crn:v1:bluemix:public:kms::a/account-1:instance-1:key:key-1",
+			expectErr: "the CRN has no region",
+		}, {
+			name:      "no account",
+			crn:       "crn:v1:bluemix:public:kms:us-south::instance-1:key:key-1",
+			expectErr: "the CRN has no account scope 'a/<account>'",
+		}, {
+			name:      "no instance",
+			crn:       "crn:v1:bluemix:public:kms:us-south:a/account-1::key:key-1",
+			expectErr: "the CRN has no service instance",
+		}, {
+			name:      "not a key",
+			crn:       "crn:v1:bluemix:public:kms:us-south:a/account-1:instance-1:policy:policy-1",
+			expectErr: "the CRN is not the one of a key 'key:<key>'",
+		}, {
+			name:      "no key",
+			crn:       "crn:v1:bluemix:public:kms:us-south:a/account-1:instance-1:key:",
+			expectErr: "the CRN is not the one of a key 'key:<key>'",
+		},
+	}
+
+	for _, testcase := range testCases {
+		t.Run(testcase.name, func(t *testing.T) {
+			keyCRN, err := keymanagement.ParseKeyCRN(testcase.crn)
+			if testcase.expectErr != "" {
+				assert.EqualError(t, err, testcase.expectErr)
+				return
+			}
+			assert.Nil(t, err)
+			assert.Equal(t, testcase.expectedCRN, keyCRN)
+		})
+	}
+}
+
+func TestCompatibleWithZone(t *testing.T) {
+	keyCRN := keymanagement.KeyCRN{Region: "us-south"}
+	assert.Equal(t, "us-south", keymanagement.ZoneRegion("us-south-1"))
+	assert.Equal(t, "us-south", keymanagement.ZoneRegion("us-south"))
+	assert.True(t, keyCRN.CompatibleWithZone("us-south-3"))
+	assert.True(t, keyCRN.CompatibleWithZone(""))
+	assert.False(t, keyCRN.CompatibleWithZone("us-east-1"))
+}
@@ -0,0 +1,166 @@
+/**
+ * Copyright 2020 IBM Corp.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package keymanagement ...
+package keymanagement
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/IBM/ibmcloud-volume-vpc/common/vpcclient/models"
+)
+
+// Emulator is a local stand-in of the key management API of Key Protect and Hyper Protect Crypto Services, it
+// serves the keys it is given. It also serves the service authorizations it is given as the IAM policy management.
+// Set the key management URL and the policy URL of the config to the address of the emulator to check the
+// encryption keys of the volumes without an IBM Cloud account e.g in development clusters and tests.
+type Emulator struct {
+	mu           sync.RWMutex
+	keys         map[emulatorKey]models.Key
+	unauthorized map[string]bool
+	policies     map[string][]models.Policy // Authorization policies by account ID
+}
+
+type emulatorKey struct {
+	instanceID string
+	keyID      string
+}
+
+var _ http.Handler = &Emulator{}
+
+// NewEmulator returns an emulator serving no keys
+func NewEmulator() *Emulator {
+	return &Emulator{
+		keys:         map[emulatorKey]models.Key{},
+		unauthorized: map[string]bool{},
+		policies:     map[string][]models.Policy{},
+	}
+}
+
+// AddKey adds the key to the instance
+func (e *Emulator) AddKey(instanceID string, key models.Key) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.keys[emulatorKey{instanceID: instanceID, keyID: key.ID}] = key
+}
+
+// Unauthorize rejects the requests for the keys of the instance, as without access to the instance
+func (e *Emulator) Unauthorize(instanceID string) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.unauthorized[instanceID] = true
+}
+
+// AuthorizeBlockStorage authorizes Block Storage to use the keys of the instance of the service of the account, as
+// by the IAM authorization created by the owner of the instance. Empty instance authorizes all the instances.
+func (e *Emulator) AuthorizeBlockStorage(accountID string, serviceName string, instanceID string) {
+	resource := []models.PolicyAttribute{
+		{Name: models.PolicyAccountIDAttribute, Value: accountID},
+		{Name: models.PolicyServiceNameAttribute, Value: serviceName},
+	}
+	if instanceID != "" {
+		resource = append(resource, models.PolicyAttribute{Name: models.PolicyServiceInstanceAttribute, Value: instanceID})
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.policies[accountID] = append(e.policies[accountID], models.Policy{
+		ID:   fmt.Sprintf("policy-%d", len(e.policies[accountID])+1),
+		Type: authorizationPolicyType,
+		Subjects: []models.PolicySubject{{Attributes: []models.PolicyAttribute{
+			{Name: models.PolicyAccountIDAttribute, Value: accountID},
+			{Name: models.PolicyServiceNameAttribute, Value: BlockStorageService},
+		}}},
+		Roles:     []models.PolicyRole{{RoleID: "crn:v1:bluemix:public:iam::::serviceRole:Reader"}},
+		Resources: []models.PolicyResource{{Attributes: resource}},
+	})
+}
+
+// ServeHTTP serves GET /api/v2/keys/{key-id} of the instance of the bluemix-instance header, and GET /v1/policies
+// of the account_id query parameter
+func (e *Emulator) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	authorization := r.Header.Get("Authorization")
+	instanceID := r.Header.Get(instanceHeader)
+	keyID := strings.TrimPrefix(r.URL.Path, keysPath+"/")
+	switch {
+	case !strings.HasPrefix(authorization, "Bearer ") || strings.TrimSpace(strings.TrimPrefix(authorization, "Bearer ")) == "":
+		writeEmulatorError(w, http.StatusUnauthorized, "Unauthorized: The user does not have access to the specified resource")
+		return
+	case r.URL.Path == policiesPath:
+		e.servePolicies(w, r)
+		return
+	case !strings.HasPrefix(r.URL.Path, keysPath+"/") || keyID == "" || strings.Contains(keyID, "/"):
+		writeEmulatorError(w, http.StatusNotFound, "Not Found: The resource could not be found")
+		return
+	case r.Method != http.MethodGet:
+		writeEmulatorError(w, http.StatusMethodNotAllowed, "Method Not Allowed")
+		return
+	case instanceID == "":
+		writeEmulatorError(w, http.StatusBadRequest, "Bad Request: The bluemix-instance header is required")
+		return
+	}
+
+	e.mu.RLock()
+	unauthorized := e.unauthorized[instanceID]
+	key, ok := e.keys[emulatorKey{instanceID: instanceID, keyID: keyID}]
+	e.mu.RUnlock()
+	switch {
+	case unauthorized:
+		writeEmulatorError(w, http.StatusForbidden, "Forbidden: The user does not have access to the specified resource")
+	case !ok:
+		writeEmulatorError(w, http.StatusNotFound, "Not Found: The key could not be found")
+	default:
+		writeEmulatorResponse(w, http.StatusOK, models.KeyList{Resources: []models.Key{key}})
+	}
+}
+
+// servePolicies serves the authorization policies of the account
+func (e *Emulator) servePolicies(w http.ResponseWriter, r *http.Request) {
+	accountID := r.URL.Query().Get("account_id")
+	switch {
+	case r.Method != http.MethodGet:
+		writeEmulatorResponse(w, http.StatusMethodNotAllowed, models.Error{Errors: []models.ErrorItem{{Code: "method_not_allowed", Message: "Method Not Allowed"}}})
+		return
+	case accountID == "":
+		writeEmulatorResponse(w, http.StatusBadRequest, models.Error{Errors: []models.ErrorItem{{Code: "invalid_query_parameter", Message: "The account_id query parameter is required"}}})
+		return
+	}
+
+	policies := models.PolicyList{Policies: []models.Policy{}}
+	e.mu.RLock()
+	for _, policy := range e.policies[accountID] {
+		if policyType := r.URL.Query().Get("type"); policyType == "" || policyType == policy.Type {
+			policies.Policies = append(policies.Policies, policy)
+		}
+	}
+	e.mu.RUnlock()
+	writeEmulatorResponse(w, http.StatusOK, policies)
+}
+
+// writeEmulatorError ...
+func writeEmulatorError(w http.ResponseWriter, statusCode int, errorMsg string) {
+	writeEmulatorResponse(w, statusCode, models.KeyManagementError{Resources: []models.KeyManagementErrorItem{{ErrorMsg: errorMsg}}})
+}
+
+// writeEmulatorResponse ...
+func writeEmulatorResponse(w http.ResponseWriter, statusCode int, body interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	_ = json.NewEncoder(w).Encode(body)
+}
@@ -0,0 +1,83 @@
+/**
+ * Copyright 2020 IBM Corp.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package keymanagement_test ...
+package keymanagement_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/IBM/ibmcloud-volume-vpc/common/vpcclient/keymanagement"
+	"github.com/IBM/ibmcloud-volume-vpc/common/vpcclient/models"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+)
+
+func TestEmulator(t *testing.T) {
+	emulator := keymanagement.NewEmulator()
+	emulator.AddKey("instance-1", models.Key{ID: "key-1", State: models.KeyStateActive})
+	emulator.AddKey("instance-2", models.Key{ID: "key-2", State: models.KeyStateActive})
+	emulator.Unauthorize("instance-2")
+	s := httptest.NewServer(emulator)
+	defer s.Close()
+
+	testCases := []struct {
+		name         string
+		keyCRN       keymanagement.KeyCRN
+		expectedKey  *models.Key
+		expectedCode int
+	}{
+		{
+			name:        "key of the instance",
+			keyCRN:      keymanagement.KeyCRN{InstanceID: "instance-1", KeyID: "key-1"},
+			expectedKey: &models.Key{ID: "key-1", State: models.KeyStateActive},
+		}, {
+			name:         "key of another instance",
+			keyCRN:       keymanagement.KeyCRN{InstanceID: "instance-3", KeyID: "key-1"},
+			expectedCode: http.StatusNotFound,
+		}, {
+			name:         "unknown key",
+			keyCRN:       keymanagement.KeyCRN{InstanceID: "instance-1", KeyID: "key-3"},
+			expectedCode: http.StatusNotFound,
+		}, {
+			name:         "instance without access",
+			keyCRN:       keymanagement.KeyCRN{InstanceID: "instance-2", KeyID: "key-2"},
+			expectedCode: http.StatusForbidden,
+		},
+	}
+
+	for _, testcase := range testCases {
+		t.Run(testcase.name, func(t *testing.T) {
+			session := keymanagement.New(keymanagement.Config{BaseURL: s.URL})
+			session.Login("auth-token")
+			key, err := session.KeyService().GetKey(testcase.keyCRN, zap.NewNop())
+			if testcase.expectedCode != 0 {
+				assert.Equal(t, testcase.expectedCode, models.GetResponseMetadata(err).StatusCode)
+				return
+			}
+			assert.Nil(t, err)
+			assert.Equal(t, testcase.expectedKey, key)
+		})
+	}
+
+	// Requests without an access token are rejected as by the key management service
+	resp, err := http.Get(s.URL + "/api/v2/keys/key-1")
+	assert.Nil(t, err)
+	defer resp.Body.Close()
+	assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+}
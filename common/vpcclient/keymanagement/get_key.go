@@ -0,0 +1,67 @@
+/**
+ * Copyright 2020 IBM Corp.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package keymanagement ...
+package keymanagement
+
+import (
+	"time"
+
+	util "github.com/IBM/ibmcloud-volume-interface/lib/utils"
+	"github.com/IBM/ibmcloud-volume-vpc/common/vpcclient/client"
+	"github.com/IBM/ibmcloud-volume-vpc/common/vpcclient/models"
+	"go.uber.org/zap"
+)
+
+const (
+	keyIDParam = "key-id"
+	keysPath   = "/api/v2/keys"
+	keyIDPath  = keysPath + "/{" + keyIDParam + "}"
+
+	// instanceHeader is the header naming the key management instance of a request
+	instanceHeader = "bluemix-instance"
+)
+
+// GetKey GETs /api/v2/keys/{key-id} of the instance of the key
+func (ks *KeyService) GetKey(keyCRN KeyCRN, ctxLogger *zap.Logger) (*models.Key, error) {
+	ctxLogger.Debug("Entry Backend GetKey")
+	defer ctxLogger.Debug("Exit Backend GetKey")
+
+	defer util.TimeTracker("GetKey", time.Now())
+
+	operation := &client.Operation{
+		Name:        "GetKey",
+		Method:      "GET",
+		PathPattern: keyIDPath,
+	}
+
+	var keys models.KeyList
+	var apiErr models.KeyManagementError
+
+	request := ks.clientOf(keyCRN).NewRequest(operation)
+	req := request.PathParameter(keyIDParam, keyCRN.KeyID).SetHeader(instanceHeader, keyCRN.InstanceID)
+	ctxLogger.Info("Equivalent curl command", zap.Reflect("URL", req.URL()), zap.Reflect("Operation", operation))
+
+	_, err := req.JSONSuccess(&keys).JSONError(&apiErr).Invoke()
+	if err != nil {
+		return nil, err
+	}
+	if len(keys.Resources) == 0 {
+		return nil, &models.KeyManagementError{Resources: []models.KeyManagementErrorItem{{ErrorMsg: "No key in the response"}}}
+	}
+
+	return &keys.Resources[0], nil
+}
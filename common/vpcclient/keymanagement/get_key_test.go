@@ -0,0 +1,92 @@
+/**
+ * Copyright 2020 IBM Corp.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package keymanagement_test ...
+package keymanagement_test
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/IBM/ibmcloud-volume-vpc/common/vpcclient/keymanagement"
+	"github.com/IBM/ibmcloud-volume-vpc/common/vpcclient/models"
+	"github.com/IBM/ibmcloud-volume-vpc/common/vpcclient/riaas/test"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+func TestGetKey(t *testing.T) {
+	keyCRN := keymanagement.KeyCRN{Region: "us-south", InstanceID: "instance-1", KeyID: "key-1"}
+
+	testCases := []struct {
+		name string
+
+		// Response
+		status  int
+		content string
+
+		// Expected return
+		expectErr    string
+		expectedKey  *models.Key
+		expectedCode int
+	}{
+		{
+			name:        "Verify that the key is returned",
+			status:      http.StatusOK,
+			content:     `{"metadata":{"collectionTotal":1},"resources":[{"id":"key-1","name":"root-key","state":1,"extractable":false}]}`,
+			expectedKey: &models.Key{ID: "key-1", Name: "root-key", State: models.KeyStateActive},
+		}, {
+			name:      "Verify that a response without key is an error",
+			status:    http.StatusOK,
+			content:   `{"resources":[]}`,
+			expectErr: "No key in the response",
+		}, {
+			name:         "Verify that the error of the key management service is returned to the caller",
+			status:       http.StatusForbidden,
+			content:      `{"resources":[{"errorMsg":"Forbidden: The user does not have access to the specified resource"}]}`,
+			expectErr:    "Forbidden: The user does not have access to the specified resource",
+			expectedCode: http.StatusForbidden,
+		},
+	}
+
+	for _, testcase := range testCases {
+		t.Run(testcase.name, func(t *testing.T) {
+			mux, client, teardown := test.SetupServer(t)
+			test.SetupMuxResponse(t, mux, "/api/v2/keys/key-1", http.MethodGet, nil, testcase.status, testcase.content, func(t *testing.T, r *http.Request) {
+				assert.Equal(t, "instance-1", r.Header.Get("bluemix-instance"))
+			})
+			defer teardown()
+
+			core, logs := observer.New(zap.InfoLevel)
+			key, err := keymanagement.NewKeyService(client).GetKey(keyCRN, zap.New(core))
+			// Curl command logs the URL of the key
+			curl := logs.FilterMessage("Equivalent curl command").All()
+			if assert.Equal(t, 1, len(curl)) {
+				assert.Contains(t, curl[0].ContextMap()["URL"], "/api/v2/keys/key-1")
+			}
+			if testcase.expectErr != "" {
+				assert.EqualError(t, err, testcase.expectErr)
+				if testcase.expectedCode != 0 {
+					assert.Equal(t, testcase.expectedCode, models.GetResponseMetadata(err).StatusCode)
+				}
+				return
+			}
+			assert.Nil(t, err)
+			assert.Equal(t, testcase.expectedKey, key)
+		})
+	}
+}
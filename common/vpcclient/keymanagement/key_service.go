@@ -0,0 +1,52 @@
+/**
+ * Copyright 2020 IBM Corp.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package keymanagement ...
+package keymanagement
+
+import (
+	"github.com/IBM/ibmcloud-volume-vpc/common/vpcclient/client"
+	"github.com/IBM/ibmcloud-volume-vpc/common/vpcclient/models"
+	"go.uber.org/zap"
+)
+
+// KeyManager operations
+//
+//go:generate counterfeiter -o fakes/key.go --fake-name KeyManager . KeyManager
+type KeyManager interface {
+	// Get the key of the CRN from its instance
+	GetKey(keyCRN KeyCRN, ctxLogger *zap.Logger) (*models.Key, error)
+
+	// Check that Block Storage is authorized to use the keys of the instance of the key CRN, checked is false if
+	// there is no policy endpoint to check it with
+	CheckServiceAuthorization(keyCRN KeyCRN, ctxLogger *zap.Logger) (authorized bool, checked bool, err error)
+}
+
+// KeyService ...
+type KeyService struct {
+	clientOf     func(keyCRN KeyCRN) client.SessionClient
+	policyClient func() client.SessionClient // Client of the IAM policy management, nil if not configured
+}
+
+var _ KeyManager = &KeyService{}
+
+// NewKeyService returns the service reading all the keys from the endpoint of the client, the authorizations
+// of their instances are not checked
+func NewKeyService(kmClient client.SessionClient) KeyManager {
+	return &KeyService{
+		clientOf: func(KeyCRN) client.SessionClient { return kmClient },
+	}
+}
@@ -0,0 +1,145 @@
+/**
+ * Copyright 2020 IBM Corp.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package keymanagement ...
+package keymanagement
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/IBM/ibmcloud-volume-vpc/common/vpcclient/client"
+)
+
+// Endpoints of Key Protect, {region} is replaced with the region of the keys. HPCS instances have their own
+// endpoint, see Config.InstanceURLs.
+const (
+	KeyProtectURL        = "https://{region}.kms.cloud.ibm.com"
+	KeyProtectPrivateURL = "https://private.{region}.kms.cloud.ibm.com"
+)
+
+// IAMPolicyURL is the endpoint of the IAM policy management, holding the service authorizations to the instances
+const IAMPolicyURL = "https://iam.cloud.ibm.com"
+
+// Config for the Session
+type Config struct {
+	// BaseURL is the endpoint of the key management service. {region} and {instance} are replaced with the region
+	// and instance of the key CRN, so that the keys of all the regions are read from their own endpoint e.g
+	// KeyProtectURL.
+	BaseURL string
+	// InstanceURLs are the endpoints of the instances by instance ID e.g the ones of the HPCS instances, they take
+	// precedence over BaseURL
+	InstanceURLs map[string]string
+	// PolicyURL is the endpoint of the IAM policy management checking that Block Storage is authorized to use the
+	// keys of the instances e.g IAMPolicyURL. The authorization is not checked if it is empty.
+	PolicyURL string
+	ContextID string
+
+	HTTPClient *http.Client
+	Context    context.Context
+
+	// Middlewares wrap all the requests of the session e.g for tracing
+	Middlewares []client.Middleware
+}
+
+// Endpoint returns the endpoint of the instance of the key
+func (c Config) Endpoint(keyCRN KeyCRN) string {
+	if instanceURL := c.InstanceURLs[keyCRN.InstanceID]; instanceURL != "" {
+		return instanceURL
+	}
+	return strings.NewReplacer("{region}", keyCRN.Region, "{instance}", keyCRN.InstanceID).Replace(c.BaseURL)
+}
+
+// Session is the client of the key management API of Key Protect and Hyper Protect Crypto Services. It has a
+// client per endpoint, created on the first request for a key of the endpoint.
+type Session struct {
+	config     Config
+	ctx        context.Context
+	httpClient *http.Client
+	login      func(client.SessionClient) client.SessionClient
+
+	mu      sync.Mutex
+	clients map[string]client.SessionClient
+}
+
+// New creates a new Session, using the supplied config
+func New(config Config) *Session {
+	ctx := config.Context
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	httpClient := config.HTTPClient
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	return &Session{config: config, ctx: ctx, httpClient: httpClient, clients: map[string]client.SessionClient{}}
+}
+
+// Login configures the session with the supplied Authentication token
+func (s *Session) Login(token string) {
+	s.setLogin(func(c client.SessionClient) client.SessionClient {
+		return c.WithAuthToken(token)
+	})
+}
+
+// LoginWithTokenSource configures the session with the token source providing the Authentication token
+func (s *Session) LoginWithTokenSource(tokenSource client.TokenSource) {
+	s.setLogin(func(c client.SessionClient) client.SessionClient {
+		return c.WithTokenSource(tokenSource)
+	})
+}
+
+// setLogin replaces the authentication of the clients
+func (s *Session) setLogin(login func(client.SessionClient) client.SessionClient) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.login = login
+	s.clients = map[string]client.SessionClient{}
+}
+
+// KeyService returns the service for reading the keys and the authorizations of their instances
+func (s *Session) KeyService() KeyManager {
+	keyService := &KeyService{clientOf: s.clientOf}
+	if s.config.PolicyURL != "" {
+		keyService.policyClient = func() client.SessionClient { return s.clientOfURL(s.config.PolicyURL) }
+	}
+	return keyService
+}
+
+// clientOf returns the client of the endpoint of the key
+func (s *Session) clientOf(keyCRN KeyCRN) client.SessionClient {
+	return s.clientOfURL(s.config.Endpoint(keyCRN))
+}
+
+// clientOfURL returns the client of the endpoint
+func (s *Session) clientOfURL(baseURL string) client.SessionClient {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if kmClient, ok := s.clients[baseURL]; ok {
+		return kmClient
+	}
+	kmClient := client.New(s.ctx, baseURL, nil, s.httpClient, s.config.ContextID, "")
+	if len(s.config.Middlewares) > 0 {
+		kmClient = kmClient.WithMiddleware(s.config.Middlewares...)
+	}
+	if s.login != nil {
+		kmClient = s.login(kmClient)
+	}
+	s.clients[baseURL] = kmClient
+	return kmClient
+}
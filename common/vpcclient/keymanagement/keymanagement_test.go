@@ -0,0 +1,71 @@
+/**
+ * Copyright 2020 IBM Corp.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package keymanagement_test ...
+package keymanagement_test
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"github.com/IBM/ibmcloud-volume-vpc/common/vpcclient/keymanagement"
+	"github.com/IBM/ibmcloud-volume-vpc/common/vpcclient/models"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+)
+
+func TestConfigEndpoint(t *testing.T) {
+	config := keymanagement.Config{
+		BaseURL:      keymanagement.KeyProtectURL,
+		InstanceURLs: map[string]string{"hpcs-instance": "https://api.us-south.hs-crypto.cloud.ibm.com:9730"},
+	}
+
+	// Keys are read from the endpoint of their region, or of their instance
+	assert.Equal(t, "https://us-south.kms.cloud.ibm.com", config.Endpoint(keymanagement.KeyCRN{Region: "us-south", InstanceID: "instance-1"}))
+	assert.Equal(t, "https://eu-de.kms.cloud.ibm.com", config.Endpoint(keymanagement.KeyCRN{Region: "eu-de", InstanceID: "instance-2"}))
+	assert.Equal(t, "https://api.us-south.hs-crypto.cloud.ibm.com:9730", config.Endpoint(keymanagement.KeyCRN{Region: "us-south", InstanceID: "hpcs-instance"}))
+
+	config.BaseURL = "https://private.{region}.kms.cloud.ibm.com/{instance}"
+	assert.Equal(t, "https://private.eu-de.kms.cloud.ibm.com/instance-2", config.Endpoint(keymanagement.KeyCRN{Region: "eu-de", InstanceID: "instance-2"}))
+}
+
+func TestSessionEndpoints(t *testing.T) {
+	regional := keymanagement.NewEmulator()
+	regional.AddKey("instance-1", models.Key{ID: "key-1", State: models.KeyStateActive})
+	regionalServer := httptest.NewServer(regional)
+	defer regionalServer.Close()
+	hpcs := keymanagement.NewEmulator()
+	hpcs.AddKey("hpcs-instance", models.Key{ID: "key-2", State: models.KeyStateActive})
+	hpcsServer := httptest.NewServer(hpcs)
+	defer hpcsServer.Close()
+
+	session := keymanagement.New(keymanagement.Config{
+		BaseURL:      regionalServer.URL,
+		InstanceURLs: map[string]string{"hpcs-instance": hpcsServer.URL},
+	})
+	session.Login("auth-token")
+	keyService := session.KeyService()
+
+	// Keys of each instance are read from its own endpoint
+	key, err := keyService.GetKey(keymanagement.KeyCRN{Region: "us-south", InstanceID: "instance-1", KeyID: "key-1"}, zap.NewNop())
+	assert.Nil(t, err)
+	assert.Equal(t, "key-1", key.ID)
+	key, err = keyService.GetKey(keymanagement.KeyCRN{Region: "us-south", InstanceID: "hpcs-instance", KeyID: "key-2"}, zap.NewNop())
+	assert.Nil(t, err)
+	assert.Equal(t, "key-2", key.ID)
+	_, err = keyService.GetKey(keymanagement.KeyCRN{Region: "us-south", InstanceID: "instance-1", KeyID: "key-2"}, zap.NewNop())
+	assert.NotNil(t, err)
+}
@@ -17,7 +17,70 @@
 // Package models ...
 package models
 
+import (
+	"fmt"
+)
+
 // VolumeEncryptionKey ...
 type VolumeEncryptionKey struct {
 	CRN string `json:"crn,omitempty"`
 }
+
+// Key states of the key management services, see https://cloud.ibm.com/docs/key-protect?topic=key-protect-key-states
+const (
+	KeyStatePreActivation = 0
+	KeyStateActive        = 1
+	KeyStateSuspended     = 2
+	KeyStateDeactivated   = 3
+	KeyStateDestroyed     = 5
+)
+
+// keyStateNames ...
+var keyStateNames = map[int]string{
+	KeyStatePreActivation: "pre-activation",
+	KeyStateActive:        "active",
+	KeyStateSuspended:     "suspended",
+	KeyStateDeactivated:   "deactivated",
+	KeyStateDestroyed:     "destroyed",
+}
+
+// KeyStateName returns the name of the key state
+func KeyStateName(state int) string {
+	if name, ok := keyStateNames[state]; ok {
+		return name
+	}
+	return fmt.Sprintf("unknown (%d)", state)
+}
+
+// Key is a key of a Key Protect or Hyper Protect Crypto Services instance
+type Key struct {
+	ID          string `json:"id"`
+	Name        string `json:"name,omitempty"`
+	CRN         string `json:"crn,omitempty"`
+	State       int    `json:"state"`
+	Extractable bool   `json:"extractable"`
+}
+
+// KeyList is the keys returned by the key management services
+type KeyList struct {
+	Resources []Key `json:"resources"`
+}
+
+// KeyManagementError is the error body of the key management services
+type KeyManagementError struct {
+	Resources []KeyManagementErrorItem `json:"resources"`
+	ResponseMetadata
+}
+
+// KeyManagementErrorItem ...
+type KeyManagementErrorItem struct {
+	ErrorMsg string `json:"errorMsg"`
+}
+
+// Error ...
+func (kmerr KeyManagementError) Error() string {
+	if len(kmerr.Resources) > 0 {
+		return kmerr.Resources[0].ErrorMsg
+	}
+	return "Unknown error"
+}
@@ -49,6 +49,7 @@ const (
 type Error struct {
 	Errors []ErrorItem `json:"errors"`
 	Trace  string      `json:"trace,omitempty"`
+	ResponseMetadata
 }
 
 // ErrorItem ...
@@ -88,6 +89,7 @@ type IksError struct {
 	RecoveryCLI string    `json:"recoveryCLI,omitempty"`
 	RecoveryUI  string    `json:"recoveryUI,omitempty"`
 	RC          int       `json:"rc,omitempty"`
+	ResponseMetadata
 }
 
 // Error ...
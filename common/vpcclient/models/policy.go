@@ -0,0 +1,70 @@
+/**
+ * Copyright 2020 IBM Corp.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package models ...
+package models
+
+// Attribute names of the subjects and resources of the IAM policies
+const (
+	PolicyServiceNameAttribute     = "serviceName"
+	PolicyServiceInstanceAttribute = "serviceInstance"
+	PolicyAccountIDAttribute       = "accountId"
+)
+
+// Policy is an IAM policy, e.g an authorization of a source service to a target service
+type Policy struct {
+	ID        string           `json:"id,omitempty"`
+	Type      string           `json:"type"`
+	Subjects  []PolicySubject  `json:"subjects"`
+	Roles     []PolicyRole     `json:"roles"`
+	Resources []PolicyResource `json:"resources"`
+}
+
+// PolicySubject ...
+type PolicySubject struct {
+	Attributes []PolicyAttribute `json:"attributes"`
+}
+
+// PolicyResource ...
+type PolicyResource struct {
+	Attributes []PolicyAttribute `json:"attributes"`
+}
+
+// PolicyAttribute ...
+type PolicyAttribute struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+// PolicyRole ...
+type PolicyRole struct {
+	RoleID string `json:"role_id"`
+}
+
+// PolicyList is the policies returned by the IAM policy management
+type PolicyList struct {
+	Policies []Policy `json:"policies"`
+}
+
+// PolicyAttributeValue returns the value of the attribute, empty if there is none i.e any value
+func PolicyAttributeValue(attributes []PolicyAttribute, name string) string {
+	for _, attribute := range attributes {
+		if attribute.Name == name {
+			return attribute.Value
+		}
+	}
+	return ""
+}
@@ -22,4 +22,41 @@ type ResourceGroup struct {
 	Href string `json:"href,omitempty"`
 	ID   string `json:"id,omitempty"`
 	Name string `json:"name,omitempty"`
+
+	// AccountID and State are returned by the Resource Manager only
+	AccountID string `json:"account_id,omitempty"`
+	State     string `json:"state,omitempty"`
+}
+
+// GetID returns the ID of the resource group, empty if nil
+func (rg *ResourceGroup) GetID() string {
+	if rg == nil {
+		return ""
+	}
+	return rg.ID
+}
+
+// ResourceGroupList is the resource groups returned by the Resource Manager
+type ResourceGroupList struct {
+	Resources []ResourceGroup `json:"resources"`
+}
+
+// ListResourceGroupFilters ...
+type ListResourceGroupFilters struct {
+	Name      string
+	AccountID string
+}
+
+// ResourceManagerError is the error body of the Resource Manager
+type ResourceManagerError struct {
+	Message    string `json:"message"`
+	Code       string `json:"code,omitempty"`
+	StatusCode int    `json:"status_code,omitempty"`
+	Trace      string `json:"trace,omitempty"`
+	ResponseMetadata
+}
+
+// Error ...
+func (rmerr ResourceManagerError) Error() string {
+	return "Trace Code:" + rmerr.Trace + ", " + rmerr.Code + ": " + rmerr.Message
 }
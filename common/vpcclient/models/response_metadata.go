@@ -0,0 +1,91 @@
+/**
+ * Copyright 2022 IBM Corp.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package models ...
+package models
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// ResponseMetadata is the metadata of the HTTP response an error was returned for
+type ResponseMetadata struct {
+	StatusCode int           `json:"-"`
+	Header     http.Header   `json:"-"`
+	RetryAfter time.Duration `json:"-"`
+	RequestID  string        `json:"-"`
+}
+
+// SetResponseMetadata ...
+func (m *ResponseMetadata) SetResponseMetadata(metadata ResponseMetadata) {
+	*m = metadata
+}
+
+// Response ...
+func (m *ResponseMetadata) Response() *ResponseMetadata {
+	return m
+}
+
+// ResponseError is an error which carries the metadata of the HTTP response
+type ResponseError interface {
+	error
+	Response() *ResponseMetadata
+}
+
+// GetResponseMetadata returns the response metadata of the error, nil if the error is not for an HTTP response
+func GetResponseMetadata(err error) *ResponseMetadata {
+	var responseErr ResponseError
+	if errors.As(err, &responseErr) && responseErr.Response().StatusCode != 0 {
+		return responseErr.Response()
+	}
+	return nil
+}
+
+// HTTPError is returned for an unsuccessful response whose body is not a known error
+type HTTPError struct {
+	ResponseMetadata
+	Err error
+}
+
+// Error returns the error of the body, the status and the request ID are in the response metadata
+func (e *HTTPError) Error() string {
+	return e.Err.Error()
+}
+
+// Unwrap ...
+func (e *HTTPError) Unwrap() error {
+	return e.Err
+}
+
+// EndpointUnavailableError is returned without sending the request while the circuit of the endpoint is open
+type EndpointUnavailableError struct {
+	BaseURL string
+	RetryAt time.Time
+}
+
+// Error ...
+func (e *EndpointUnavailableError) Error() string {
+	return fmt.Sprintf("endpoint %s is unavailable, retry after %s", e.BaseURL, e.RetryAt.Format(time.RFC3339))
+}
+
+// IsEndpointUnavailable returns true if the error is because the circuit of the endpoint is open
+func IsEndpointUnavailable(err error) bool {
+	var unavailable *EndpointUnavailableError
+	return errors.As(err, &unavailable)
+}
@@ -0,0 +1,91 @@
+/**
+ * Copyright 2020 IBM Corp.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package resourcemanager ...
+package resourcemanager
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/IBM/ibmcloud-volume-vpc/common/vpcclient/models"
+)
+
+// Emulator is a local stand-in of the Resource Manager, it serves the resource group lookups of the provider from
+// the resource groups it is given. Set the Resource Manager URL of the config to the address of the emulator to
+// resolve resource group names without an IBM Cloud account e.g in development clusters and tests.
+type Emulator struct {
+	mu     sync.RWMutex
+	groups []models.ResourceGroup
+}
+
+var _ http.Handler = &Emulator{}
+
+// NewEmulator returns an emulator serving the resource groups
+func NewEmulator(groups ...models.ResourceGroup) *Emulator {
+	e := &Emulator{}
+	for _, group := range groups {
+		e.Add(group)
+	}
+	return e
+}
+
+// Add adds the resource group to the ones served by the emulator, active if it has no state
+func (e *Emulator) Add(group models.ResourceGroup) {
+	if group.State == "" {
+		group.State = "ACTIVE"
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.groups = append(e.groups, group)
+}
+
+// ServeHTTP serves GET /v2/resource_groups with the name and account_id filters of the Resource Manager
+func (e *Emulator) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	authorization := r.Header.Get("Authorization")
+	switch {
+	case !strings.HasPrefix(authorization, "Bearer ") || strings.TrimSpace(strings.TrimPrefix(authorization, "Bearer ")) == "":
+		writeEmulatorResponse(w, http.StatusUnauthorized, models.ResourceManagerError{Message: "No access token in the request", Code: "BXNIM0308E", StatusCode: http.StatusUnauthorized})
+		return
+	case r.URL.Path != resourceGroupsPath:
+		writeEmulatorResponse(w, http.StatusNotFound, models.ResourceManagerError{Message: "Resource not found", Code: "not_found", StatusCode: http.StatusNotFound})
+		return
+	case r.Method != http.MethodGet:
+		writeEmulatorResponse(w, http.StatusMethodNotAllowed, models.ResourceManagerError{Message: "Method not allowed", Code: "method_not_allowed", StatusCode: http.StatusMethodNotAllowed})
+		return
+	}
+
+	name := r.URL.Query().Get("name")
+	accountID := r.URL.Query().Get("account_id")
+	list := models.ResourceGroupList{Resources: []models.ResourceGroup{}}
+	e.mu.RLock()
+	for _, group := range e.groups {
+		if (name == "" || group.Name == name) && (accountID == "" || group.AccountID == accountID) {
+			list.Resources = append(list.Resources, group)
+		}
+	}
+	e.mu.RUnlock()
+	writeEmulatorResponse(w, http.StatusOK, list)
+}
+
+// writeEmulatorResponse ...
+func writeEmulatorResponse(w http.ResponseWriter, statusCode int, body interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	_ = json.NewEncoder(w).Encode(body)
+}
@@ -0,0 +1,83 @@
+/**
+ * Copyright 2020 IBM Corp.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package resourcemanager_test ...
+package resourcemanager_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/IBM/ibmcloud-volume-vpc/common/vpcclient/models"
+	"github.com/IBM/ibmcloud-volume-vpc/common/vpcclient/resourcemanager"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+)
+
+func TestEmulator(t *testing.T) {
+	emulator := resourcemanager.NewEmulator(
+		models.ResourceGroup{ID: "rg-1", Name: "default", AccountID: "account-1"},
+		models.ResourceGroup{ID: "rg-2", Name: "default", AccountID: "account-2"},
+	)
+	emulator.Add(models.ResourceGroup{ID: "rg-3", Name: "storage", AccountID: "account-1"})
+	s := httptest.NewServer(emulator)
+	defer s.Close()
+
+	testCases := []struct {
+		name        string
+		filters     *models.ListResourceGroupFilters
+		expectedIDs []string
+	}{
+		{
+			name:        "all resource groups",
+			expectedIDs: []string{"rg-1", "rg-2", "rg-3"},
+		}, {
+			name:        "resource groups of the name",
+			filters:     &models.ListResourceGroupFilters{Name: "default"},
+			expectedIDs: []string{"rg-1", "rg-2"},
+		}, {
+			name:        "resource groups of the name and account",
+			filters:     &models.ListResourceGroupFilters{Name: "default", AccountID: "account-2"},
+			expectedIDs: []string{"rg-2"},
+		}, {
+			name:        "unknown name",
+			filters:     &models.ListResourceGroupFilters{Name: "unknown"},
+			expectedIDs: []string{},
+		},
+	}
+
+	for _, testcase := range testCases {
+		t.Run(testcase.name, func(t *testing.T) {
+			session := resourcemanager.New(resourcemanager.Config{BaseURL: s.URL})
+			session.Login("auth-token")
+			resourceGroups, err := session.ResourceGroupService().ListResourceGroups(testcase.filters, zap.NewNop())
+			assert.Nil(t, err)
+			ids := []string{}
+			for _, resourceGroup := range resourceGroups.Resources {
+				ids = append(ids, resourceGroup.ID)
+				assert.Equal(t, "ACTIVE", resourceGroup.State)
+			}
+			assert.Equal(t, testcase.expectedIDs, ids)
+		})
+	}
+
+	// Requests without an access token are rejected as by the Resource Manager
+	resp, err := http.Get(s.URL + "/v2/resource_groups")
+	assert.Nil(t, err)
+	defer resp.Body.Close()
+	assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+}
@@ -0,0 +1,66 @@
+/**
+ * Copyright 2020 IBM Corp.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package resourcemanager ...
+package resourcemanager
+
+import (
+	"time"
+
+	util "github.com/IBM/ibmcloud-volume-interface/lib/utils"
+	"github.com/IBM/ibmcloud-volume-vpc/common/vpcclient/client"
+	"github.com/IBM/ibmcloud-volume-vpc/common/vpcclient/models"
+	"go.uber.org/zap"
+)
+
+const resourceGroupsPath = "/v2/resource_groups"
+
+// ListResourceGroups GETs /v2/resource_groups
+func (rs *ResourceGroupService) ListResourceGroups(filters *models.ListResourceGroupFilters, ctxLogger *zap.Logger) (*models.ResourceGroupList, error) {
+	ctxLogger.Debug("Entry Backend ListResourceGroups")
+	defer ctxLogger.Debug("Exit Backend ListResourceGroups")
+
+	defer util.TimeTracker("ListResourceGroups", time.Now())
+
+	operation := &client.Operation{
+		Name:        "ListResourceGroups",
+		Method:      "GET",
+		PathPattern: resourceGroupsPath,
+	}
+
+	var resourceGroups models.ResourceGroupList
+	var apiErr models.ResourceManagerError
+
+	request := rs.client.NewRequest(operation)
+	req := request.JSONSuccess(&resourceGroups).JSONError(&apiErr)
+
+	if filters != nil {
+		if filters.Name != "" {
+			req.AddQueryValue("name", filters.Name)
+		}
+		if filters.AccountID != "" {
+			req.AddQueryValue("account_id", filters.AccountID)
+		}
+	}
+	ctxLogger.Info("Equivalent curl command", zap.Reflect("URL", req.URL()), zap.Reflect("Operation", operation))
+
+	_, err := req.Invoke()
+	if err != nil {
+		return nil, err
+	}
+
+	return &resourceGroups, nil
+}
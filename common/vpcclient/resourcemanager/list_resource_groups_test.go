@@ -0,0 +1,96 @@
+/**
+ * Copyright 2020 IBM Corp.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package resourcemanager_test ...
+package resourcemanager_test
+
+import (
+	"net/http"
+	"net/url"
+	"testing"
+
+	"github.com/IBM/ibmcloud-volume-vpc/common/vpcclient/models"
+	"github.com/IBM/ibmcloud-volume-vpc/common/vpcclient/resourcemanager"
+	"github.com/IBM/ibmcloud-volume-vpc/common/vpcclient/riaas/test"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+)
+
+func TestListResourceGroups(t *testing.T) {
+	logger := zap.NewNop()
+
+	testCases := []struct {
+		name string
+
+		// Response
+		status  int
+		content string
+
+		filters *models.ListResourceGroupFilters
+
+		// Expected return
+		expectErr    string
+		expectedIDs  []string
+		expectedCode int
+		muxVerify    func(*testing.T, *http.Request)
+	}{
+		{
+			name:        "Verify that the resource groups are returned",
+			status:      http.StatusOK,
+			content:     `{"resources":[{"id":"rg-1","name":"default","account_id":"account-1","state":"ACTIVE"}]}`,
+			expectedIDs: []string{"rg-1"},
+		}, {
+			name:    "Verify that the name and account_id filters are added to the query",
+			status:  http.StatusOK,
+			content: `{"resources":[]}`,
+			filters: &models.ListResourceGroupFilters{Name: "default", AccountID: "account-1"},
+			muxVerify: func(t *testing.T, r *http.Request) {
+				expectedValues := url.Values{"name": []string{"default"}, "account_id": []string{"account-1"}, "version": []string{models.APIVersion}}
+				assert.Equal(t, expectedValues, r.URL.Query())
+			},
+		}, {
+			name:         "Verify that the error of the Resource Manager is returned to the caller",
+			status:       http.StatusForbidden,
+			content:      `{"message":"You are not authorized","code":"not_authorized","status_code":403,"trace":"trace-1"}`,
+			expectErr:    "Trace Code:trace-1, not_authorized: You are not authorized",
+			expectedCode: http.StatusForbidden,
+		},
+	}
+
+	for _, testcase := range testCases {
+		t.Run(testcase.name, func(t *testing.T) {
+			mux, client, teardown := test.SetupServer(t)
+			test.SetupMuxResponse(t, mux, "/v2/resource_groups", http.MethodGet, nil, testcase.status, testcase.content, testcase.muxVerify)
+			defer teardown()
+
+			resourceGroups, err := resourcemanager.NewResourceGroupService(client).ListResourceGroups(testcase.filters, logger)
+			if testcase.expectErr != "" {
+				assert.EqualError(t, err, testcase.expectErr)
+				assert.Equal(t, testcase.expectedCode, models.GetResponseMetadata(err).StatusCode)
+				return
+			}
+			assert.Nil(t, err)
+			ids := []string{}
+			for _, resourceGroup := range resourceGroups.Resources {
+				ids = append(ids, resourceGroup.ID)
+			}
+			if testcase.expectedIDs == nil {
+				testcase.expectedIDs = []string{}
+			}
+			assert.Equal(t, testcase.expectedIDs, ids)
+		})
+	}
+}
@@ -0,0 +1,46 @@
+/**
+ * Copyright 2020 IBM Corp.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package resourcemanager ...
+package resourcemanager
+
+import (
+	"github.com/IBM/ibmcloud-volume-vpc/common/vpcclient/client"
+	"github.com/IBM/ibmcloud-volume-vpc/common/vpcclient/models"
+	"go.uber.org/zap"
+)
+
+// ResourceGroupManager operations
+//
+//go:generate counterfeiter -o fakes/resource_group.go --fake-name ResourceGroupManager . ResourceGroupManager
+type ResourceGroupManager interface {
+	// List the resource groups of the account, matching the filters
+	ListResourceGroups(filters *models.ListResourceGroupFilters, ctxLogger *zap.Logger) (*models.ResourceGroupList, error)
+}
+
+// ResourceGroupService ...
+type ResourceGroupService struct {
+	client client.SessionClient
+}
+
+var _ ResourceGroupManager = &ResourceGroupService{}
+
+// NewResourceGroupService ...
+func NewResourceGroupService(client client.SessionClient) ResourceGroupManager {
+	return &ResourceGroupService{
+		client: client,
+	}
+}
@@ -0,0 +1,83 @@
+/**
+ * Copyright 2020 IBM Corp.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package resourcemanager ...
+package resourcemanager
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/IBM/ibmcloud-volume-vpc/common/vpcclient/client"
+)
+
+// DefaultURL is the public endpoint of the Resource Manager
+const DefaultURL = "https://resource-controller.cloud.ibm.com"
+
+// Config for the Session
+type Config struct {
+	// BaseURL is the endpoint of the Resource Manager, DefaultURL if empty
+	BaseURL   string
+	ContextID string
+
+	HTTPClient *http.Client
+	Context    context.Context
+
+	// Middlewares wrap all the requests of the session e.g for tracing
+	Middlewares []client.Middleware
+}
+
+// Session is the client of the Resource Manager API
+type Session struct {
+	client client.SessionClient
+}
+
+// New creates a new Session, using the supplied config
+func New(config Config) *Session {
+	ctx := config.Context
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	baseURL := config.BaseURL
+	if baseURL == "" {
+		baseURL = DefaultURL
+	}
+	httpClient := config.HTTPClient
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+
+	rmClient := client.New(ctx, baseURL, nil, httpClient, config.ContextID, "")
+	if len(config.Middlewares) > 0 {
+		rmClient = rmClient.WithMiddleware(config.Middlewares...)
+	}
+	return &Session{client: rmClient}
+}
+
+// Login configures the session with the supplied Authentication token
+func (s *Session) Login(token string) {
+	s.client = s.client.WithAuthToken(token)
+}
+
+// LoginWithTokenSource configures the session with the token source providing the Authentication token
+func (s *Session) LoginWithTokenSource(tokenSource client.TokenSource) {
+	s.client = s.client.WithTokenSource(tokenSource)
+}
+
+// ResourceGroupService returns the service for looking up resource groups
+func (s *Session) ResourceGroupService() ResourceGroupManager {
+	return NewResourceGroupService(s.client)
+}
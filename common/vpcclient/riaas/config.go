@@ -21,6 +21,8 @@ import (
 	"context"
 	"io"
 	"net/http"
+
+	"github.com/IBM/ibmcloud-volume-vpc/common/vpcclient/client"
 )
 
 // Config for the Session
@@ -38,6 +40,19 @@ type Config struct {
 	Context       context.Context
 	APIVersion    string
 	APIGeneration int
+
+	// RateLimit enables the client side rate limiter, shared by the sessions of the same AccountID and BaseURL.
+	// The latest config given for an account and endpoint reconfigures the shared limiter
+	RateLimit *client.RateLimitConfig
+
+	// CircuitBreaker enables the circuit breaker, shared by the sessions of the same BaseURL
+	CircuitBreaker *client.CircuitBreakerConfig
+
+	// Coalescer lets identical concurrent reads of the sessions configured with it share one request, nil disables it
+	Coalescer *client.Coalescer
+
+	// Middlewares wrap all the requests of the session e.g for tracing
+	Middlewares []client.Middleware
 }
 
 func (c Config) httpClient() *http.Client {
@@ -2,8 +2,10 @@
 package fakes
 
 import (
+	"context"
 	"sync"
 
+	"github.com/IBM/ibmcloud-volume-vpc/common/vpcclient/client"
 	"github.com/IBM/ibmcloud-volume-vpc/common/vpcclient/instances"
 	"github.com/IBM/ibmcloud-volume-vpc/common/vpcclient/riaas"
 	"github.com/IBM/ibmcloud-volume-vpc/common/vpcclient/vpcvolume"
@@ -31,6 +33,17 @@ type RegionalAPI struct {
 	loginReturnsOnCall map[int]struct {
 		result1 error
 	}
+	LoginWithTokenSourceStub        func(client.TokenSource) error
+	loginWithTokenSourceMutex       sync.RWMutex
+	loginWithTokenSourceArgsForCall []struct {
+		arg1 client.TokenSource
+	}
+	loginWithTokenSourceReturns struct {
+		result1 error
+	}
+	loginWithTokenSourceReturnsOnCall map[int]struct {
+		result1 error
+	}
 	SnapshotServiceStub        func() vpcvolume.SnapshotManager
 	snapshotServiceMutex       sync.RWMutex
 	snapshotServiceArgsForCall []struct {
@@ -61,6 +74,17 @@ type RegionalAPI struct {
 	volumeServiceReturnsOnCall map[int]struct {
 		result1 vpcvolume.VolumeManager
 	}
+	WithContextStub        func(context.Context) riaas.RegionalAPI
+	withContextMutex       sync.RWMutex
+	withContextArgsForCall []struct {
+		arg1 context.Context
+	}
+	withContextReturns struct {
+		result1 riaas.RegionalAPI
+	}
+	withContextReturnsOnCall map[int]struct {
+		result1 riaas.RegionalAPI
+	}
 	invocations      map[string][][]interface{}
 	invocationsMutex sync.RWMutex
 }
@@ -179,6 +203,67 @@ func (fake *RegionalAPI) LoginReturnsOnCall(i int, result1 error) {
 	}{result1}
 }
 
+func (fake *RegionalAPI) LoginWithTokenSource(arg1 client.TokenSource) error {
+	fake.loginWithTokenSourceMutex.Lock()
+	ret, specificReturn := fake.loginWithTokenSourceReturnsOnCall[len(fake.loginWithTokenSourceArgsForCall)]
+	fake.loginWithTokenSourceArgsForCall = append(fake.loginWithTokenSourceArgsForCall, struct {
+		arg1 client.TokenSource
+	}{arg1})
+	stub := fake.LoginWithTokenSourceStub
+	fakeReturns := fake.loginWithTokenSourceReturns
+	fake.recordInvocation("LoginWithTokenSource", []interface{}{arg1})
+	fake.loginWithTokenSourceMutex.Unlock()
+	if stub != nil {
+		return stub(arg1)
+	}
+	if specificReturn {
+		return ret.result1
+	}
+	return fakeReturns.result1
+}
+
+func (fake *RegionalAPI) LoginWithTokenSourceCallCount() int {
+	fake.loginWithTokenSourceMutex.RLock()
+	defer fake.loginWithTokenSourceMutex.RUnlock()
+	return len(fake.loginWithTokenSourceArgsForCall)
+}
+
+func (fake *RegionalAPI) LoginWithTokenSourceCalls(stub func(client.TokenSource) error) {
+	fake.loginWithTokenSourceMutex.Lock()
+	defer fake.loginWithTokenSourceMutex.Unlock()
+	fake.LoginWithTokenSourceStub = stub
+}
+
+func (fake *RegionalAPI) LoginWithTokenSourceArgsForCall(i int) client.TokenSource {
+	fake.loginWithTokenSourceMutex.RLock()
+	defer fake.loginWithTokenSourceMutex.RUnlock()
+	argsForCall := fake.loginWithTokenSourceArgsForCall[i]
+	return argsForCall.arg1
+}
+
+func (fake *RegionalAPI) LoginWithTokenSourceReturns(result1 error) {
+	fake.loginWithTokenSourceMutex.Lock()
+	defer fake.loginWithTokenSourceMutex.Unlock()
+	fake.LoginWithTokenSourceStub = nil
+	fake.loginWithTokenSourceReturns = struct {
+		result1 error
+	}{result1}
+}
+
+func (fake *RegionalAPI) LoginWithTokenSourceReturnsOnCall(i int, result1 error) {
+	fake.loginWithTokenSourceMutex.Lock()
+	defer fake.loginWithTokenSourceMutex.Unlock()
+	fake.LoginWithTokenSourceStub = nil
+	if fake.loginWithTokenSourceReturnsOnCall == nil {
+		fake.loginWithTokenSourceReturnsOnCall = make(map[int]struct {
+			result1 error
+		})
+	}
+	fake.loginWithTokenSourceReturnsOnCall[i] = struct {
+		result1 error
+	}{result1}
+}
+
 func (fake *RegionalAPI) SnapshotService() vpcvolume.SnapshotManager {
 	fake.snapshotServiceMutex.Lock()
 	ret, specificReturn := fake.snapshotServiceReturnsOnCall[len(fake.snapshotServiceArgsForCall)]
@@ -338,6 +423,67 @@ func (fake *RegionalAPI) VolumeServiceReturnsOnCall(i int, result1 vpcvolume.Vol
 	}{result1}
 }
 
+func (fake *RegionalAPI) WithContext(arg1 context.Context) riaas.RegionalAPI {
+	fake.withContextMutex.Lock()
+	ret, specificReturn := fake.withContextReturnsOnCall[len(fake.withContextArgsForCall)]
+	fake.withContextArgsForCall = append(fake.withContextArgsForCall, struct {
+		arg1 context.Context
+	}{arg1})
+	stub := fake.WithContextStub
+	fakeReturns := fake.withContextReturns
+	fake.recordInvocation("WithContext", []interface{}{arg1})
+	fake.withContextMutex.Unlock()
+	if stub != nil {
+		return stub(arg1)
+	}
+	if specificReturn {
+		return ret.result1
+	}
+	return fakeReturns.result1
+}
+
+func (fake *RegionalAPI) WithContextCallCount() int {
+	fake.withContextMutex.RLock()
+	defer fake.withContextMutex.RUnlock()
+	return len(fake.withContextArgsForCall)
+}
+
+func (fake *RegionalAPI) WithContextCalls(stub func(context.Context) riaas.RegionalAPI) {
+	fake.withContextMutex.Lock()
+	defer fake.withContextMutex.Unlock()
+	fake.WithContextStub = stub
+}
+
+func (fake *RegionalAPI) WithContextArgsForCall(i int) context.Context {
+	fake.withContextMutex.RLock()
+	defer fake.withContextMutex.RUnlock()
+	argsForCall := fake.withContextArgsForCall[i]
+	return argsForCall.arg1
+}
+
+func (fake *RegionalAPI) WithContextReturns(result1 riaas.RegionalAPI) {
+	fake.withContextMutex.Lock()
+	defer fake.withContextMutex.Unlock()
+	fake.WithContextStub = nil
+	fake.withContextReturns = struct {
+		result1 riaas.RegionalAPI
+	}{result1}
+}
+
+func (fake *RegionalAPI) WithContextReturnsOnCall(i int, result1 riaas.RegionalAPI) {
+	fake.withContextMutex.Lock()
+	defer fake.withContextMutex.Unlock()
+	fake.WithContextStub = nil
+	if fake.withContextReturnsOnCall == nil {
+		fake.withContextReturnsOnCall = make(map[int]struct {
+			result1 riaas.RegionalAPI
+		})
+	}
+	fake.withContextReturnsOnCall[i] = struct {
+		result1 riaas.RegionalAPI
+	}{result1}
+}
+
 func (fake *RegionalAPI) Invocations() map[string][][]interface{} {
 	fake.invocationsMutex.RLock()
 	defer fake.invocationsMutex.RUnlock()
@@ -345,12 +491,16 @@ func (fake *RegionalAPI) Invocations() map[string][][]interface{} {
 	defer fake.iKSVolumeAttachServiceMutex.RUnlock()
 	fake.loginMutex.RLock()
 	defer fake.loginMutex.RUnlock()
+	fake.loginWithTokenSourceMutex.RLock()
+	defer fake.loginWithTokenSourceMutex.RUnlock()
 	fake.snapshotServiceMutex.RLock()
 	defer fake.snapshotServiceMutex.RUnlock()
 	fake.volumeAttachServiceMutex.RLock()
 	defer fake.volumeAttachServiceMutex.RUnlock()
 	fake.volumeServiceMutex.RLock()
 	defer fake.volumeServiceMutex.RUnlock()
+	fake.withContextMutex.RLock()
+	defer fake.withContextMutex.RUnlock()
 	copiedInvocations := map[string][][]interface{}{}
 	for key, value := range fake.invocations {
 		copiedInvocations[key] = value
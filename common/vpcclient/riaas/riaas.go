@@ -34,6 +34,8 @@ import (
 //go:generate counterfeiter -o fakes/regional_api.go --fake-name RegionalAPI . RegionalAPI
 type RegionalAPI interface {
 	Login(token string) error
+	LoginWithTokenSource(tokenSource client.TokenSource) error
+	WithContext(ctx context.Context) RegionalAPI
 
 	VolumeService() vpcvolume.VolumeManager
 	VolumeAttachService() instances.VolumeAttachManager
@@ -75,10 +77,23 @@ func New(config Config) (*Session, error) {
 		"generation": []string{strconv.Itoa(apiGen)},
 	}
 
-	riaasClient := client.New(ctx, config.baseURL(), queryValues, config.httpClient(), config.ContextID, config.ResourceGroup)
+	var opts []client.Option
+	if config.RateLimit != nil {
+		opts = append(opts, client.WithRateLimiter(client.SharedRateLimiter(config.AccountID, config.baseURL(), *config.RateLimit)))
+	}
+	if config.CircuitBreaker != nil {
+		opts = append(opts, client.WithCircuitBreaker(client.SharedCircuitBreaker(config.baseURL(), *config.CircuitBreaker)))
+	}
+	if config.Coalescer != nil {
+		opts = append(opts, client.WithCoalescer(config.Coalescer))
+	}
+	riaasClient := client.New(ctx, config.baseURL(), queryValues, config.httpClient(), config.ContextID, config.ResourceGroup, opts...)
 
 	if config.DebugWriter != nil {
-		riaasClient.WithDebug(config.DebugWriter)
+		riaasClient = riaasClient.WithDebug(config.DebugWriter)
+	}
+	if len(config.Middlewares) > 0 {
+		riaasClient = riaasClient.WithMiddleware(config.Middlewares...)
 	}
 	return &Session{
 		client: riaasClient,
@@ -89,10 +104,28 @@ func New(config Config) (*Session, error) {
 // Login configures the session with the supplied Authentication token
 // which is used for all requests to the API
 func (s *Session) Login(token string) error {
-	s.client.WithAuthToken(token)
+	s.client = s.client.WithAuthToken(token)
+	return nil
+}
+
+// LoginWithTokenSource configures the session with the token source providing the Authentication token
+// of all requests to the API, which is refreshed once the API rejects it
+func (s *Session) LoginWithTokenSource(tokenSource client.TokenSource) error {
+	s.client = s.client.WithTokenSource(tokenSource)
 	return nil
 }
 
+// WithContext returns a copy of the session whose services send their requests with the context
+func (s *Session) WithContext(ctx context.Context) RegionalAPI {
+	return s.withContext(ctx)
+}
+
+func (s *Session) withContext(ctx context.Context) *Session {
+	derived := *s
+	derived.client = s.client.WithContext(ctx)
+	return &derived
+}
+
 // VolumeService returns the Volume service for managing volumes
 func (s *Session) VolumeService() vpcvolume.VolumeManager {
 	return vpcvolume.New(s.client)
@@ -140,6 +173,11 @@ type IKSSession struct {
 
 var _ RegionalAPI = &IKSSession{}
 
+// WithContext returns a copy of the session whose services send their requests with the context
+func (s *IKSSession) WithContext(ctx context.Context) RegionalAPI {
+	return &IKSSession{Session: *s.Session.withContext(ctx)}
+}
+
 // VolumeService returns the Volume service for managing volumes
 func (s *IKSSession) VolumeService() vpcvolume.VolumeManager {
 	return vpcvolume.NewIKSVolumeService(s.client)
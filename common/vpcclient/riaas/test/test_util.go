@@ -25,6 +25,8 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"net/url"
+	"os"
+	"strconv"
 	"testing"
 
 	"github.com/IBM/ibmcloud-volume-vpc/common/vpcclient/client"
@@ -86,3 +88,27 @@ func CheckTestFail(t *testing.T, buf *bytes.Buffer) {
 		t.Log(buf)
 	}
 }
+
+// RecordCassettesEnv set to true makes CassetteHTTPClient record the exchanges with the real endpoints
+const RecordCassettesEnv = "RECORD_CASSETTES"
+
+// CassetteHTTPClient returns an HTTP client replaying the cassette at path, and failing the test if some of its
+// interactions were not replayed. With RECORD_CASSETTES=true, it records the real exchanges to path instead.
+func CassetteHTTPClient(t *testing.T, path string) *http.Client {
+	if record, _ := strconv.ParseBool(os.Getenv(RecordCassettesEnv)); record {
+		cassette := client.NewCassetteRecorder(path, nil)
+		t.Cleanup(func() {
+			assert.Nil(t, cassette.Save())
+		})
+		return &http.Client{Transport: cassette}
+	}
+
+	cassette, err := client.NewCassetteReplayer(path)
+	if !assert.Nil(t, err) {
+		t.FailNow()
+	}
+	t.Cleanup(func() {
+		assert.Equal(t, 0, cassette.Remaining(), "interactions of %s not replayed", path)
+	})
+	return &http.Client{Transport: cassette}
+}
@@ -45,7 +45,7 @@ func (ss *SnapshotService) CreateSnapshot(snapshotTemplate *models.Snapshot, ctx
 	request := ss.client.NewRequest(operation)
 	ctxLogger.Info("Equivalent curl command and payload details", zap.Reflect("URL", request.URL()), zap.Reflect("Payload", snapshotTemplate), zap.Reflect("Operation", operation))
 
-	_, err := request.JSONBody(snapshotTemplate).JSONSuccess(&snapshot).JSONError(&apiErr).Invoke()
+	_, err := request.ResourceGroup(snapshotTemplate.ResourceGroup.GetID()).JSONBody(snapshotTemplate).JSONSuccess(&snapshot).JSONError(&apiErr).Invoke()
 	if err != nil {
 		return nil, err
 	}
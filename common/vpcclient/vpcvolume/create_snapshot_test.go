@@ -107,3 +107,22 @@ func TestCreateSnapshot(t *testing.T) {
 		})
 	}
 }
+
+func TestCreateSnapshotResourceGroup(t *testing.T) {
+	logger, _ := GetTestContextLogger()
+	defer logger.Sync()
+
+	mux, client, teardown := test.SetupServer(t)
+	defer teardown()
+
+	// Resource group of the template is the one of the request, not the "default" one of the client
+	requestBody := `{"name":"snapshot-name","resource_group":{"id":"rg-snapshot"}}` + "\n"
+	test.SetupMuxResponse(t, mux, vpcvolume.Version+"/snapshots", http.MethodPost, &requestBody, http.StatusOK, `{"id":"snapshot-id"}`, func(t *testing.T, r *http.Request) {
+		assert.Equal(t, "rg-snapshot", r.Header.Get("X-Auth-Resource-Group-ID"))
+	})
+
+	template := &models.Snapshot{Name: "snapshot-name", ResourceGroup: &models.ResourceGroup{ID: "rg-snapshot"}}
+	snapshot, err := vpcvolume.NewSnapshotManager(client).CreateSnapshot(template, logger)
+	assert.Nil(t, err)
+	assert.Equal(t, "snapshot-id", snapshot.ID)
+}
@@ -118,3 +118,22 @@ func TestCreateVolume(t *testing.T) {
 		})
 	}
 }
+
+func TestCreateVolumeResourceGroup(t *testing.T) {
+	logger, _ := GetTestContextLogger()
+	defer logger.Sync()
+
+	mux, client, teardown := test.SetupServer(t)
+	defer teardown()
+
+	// Resource group of the template is the one of the request, not the "default" one of the client
+	requestBody := `{"name":"volume-name","resource_group":{"id":"rg-volume"}}` + "\n"
+	test.SetupMuxResponse(t, mux, vpcvolume.Version+"/volumes", http.MethodPost, &requestBody, http.StatusOK, `{"id":"volume-id"}`, func(t *testing.T, r *http.Request) {
+		assert.Equal(t, "rg-volume", r.Header.Get("X-Auth-Resource-Group-ID"))
+	})
+
+	template := &models.Volume{Name: "volume-name", ResourceGroup: &models.ResourceGroup{ID: "rg-volume"}}
+	volume, err := vpcvolume.New(client).CreateVolume(template, logger)
+	assert.Nil(t, err)
+	assert.Equal(t, "volume-id", volume.ID)
+}
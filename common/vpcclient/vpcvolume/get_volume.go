@@ -46,10 +46,14 @@ func (vs *VolumeService) GetVolume(volumeID string, ctxLogger *zap.Logger) (*mod
 	ctxLogger.Info("Equivalent curl command", zap.Reflect("URL", request.URL()), zap.Reflect("Operation", operation))
 
 	req := request.PathParameter(volumeIDParam, volumeID)
-	_, err := req.JSONSuccess(&volume).JSONError(&apiErr).Invoke()
+	// Concurrent identical calls share one request
+	shared, err := req.JSONSuccess(&volume).JSONError(&apiErr).InvokeCoalesced()
 	if err != nil {
 		return nil, err
 	}
+	if shared {
+		ctxLogger.Info("Shared the result of an identical in-flight request", zap.String("volumeID", volumeID))
+	}
 
 	return &volume, nil
 }
@@ -17,10 +17,18 @@
 package vpcvolume_test
 
 import (
+	"context"
+	"fmt"
 	"net/http"
+	"net/http/httptest"
+	"net/url"
 	"os"
+	"sync"
+	"sync/atomic"
 	"testing"
+	"time"
 
+	"github.com/IBM/ibmcloud-volume-vpc/common/vpcclient/client"
 	"github.com/IBM/ibmcloud-volume-vpc/common/vpcclient/models"
 	"github.com/IBM/ibmcloud-volume-vpc/common/vpcclient/riaas/test"
 	"github.com/IBM/ibmcloud-volume-vpc/common/vpcclient/vpcvolume"
@@ -185,3 +193,69 @@ func TestGetVolumeByName(t *testing.T) {
 		})
 	}
 }
+
+func TestGetVolumeCoalesced(t *testing.T) {
+	// Setup new style zap logger
+	logger, _ := GetTestContextLogger()
+	defer logger.Sync()
+
+	mux := http.NewServeMux()
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	var hits int32
+	mux.HandleFunc(vpcvolume.Version+"/volumes/volume-id", func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&hits, 1)
+		// Keep the request in flight till all the callers have joined
+		time.Sleep(200 * time.Millisecond)
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, "{\"id\":\"vol1\",\"name\":\"vol1\",\"capacity\":10,\"status\":\"available\",\"zone\":{\"name\":\"zone-1\"}}")
+	})
+	queryValues := url.Values{"version": []string{models.APIVersion}}
+	coalescer := client.NewCoalescer()
+	sessionClient := client.New(context.Background(), server.URL, queryValues, http.DefaultClient, "test-context", "default", client.WithCoalescer(coalescer)).WithAuthToken("auth-token")
+
+	getVolumes := func(callers int) []*models.Volume {
+		volumes := make([]*models.Volume, callers)
+		start := make(chan struct{})
+		var wg sync.WaitGroup
+		for i := 0; i < callers; i++ {
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				<-start
+				volume, err := vpcvolume.New(sessionClient).GetVolume("volume-id", logger)
+				assert.NoError(t, err)
+				volumes[i] = volume
+			}(i)
+		}
+		close(start)
+		wg.Wait()
+		return volumes
+	}
+
+	volumes := getVolumes(10)
+	assert.Equal(t, int32(1), atomic.LoadInt32(&hits))
+	assert.Equal(t, uint64(9), coalescer.Stats().Saved)
+	for _, volume := range volumes {
+		if assert.NotNil(t, volume) {
+			assert.Equal(t, "vol1", volume.ID)
+			assert.Equal(t, "zone-1", volume.Zone.Name)
+		}
+	}
+	// Every caller gets its own deep copy
+	assert.NotSame(t, volumes[0], volumes[1])
+	assert.NotSame(t, volumes[0].Zone, volumes[1].Zone)
+
+	// Each caller makes its own request when coalescing is disabled
+	coalescer.SetEnabled(false)
+	atomic.StoreInt32(&hits, 0)
+	getVolumes(3)
+	assert.Equal(t, int32(3), atomic.LoadInt32(&hits))
+
+	// Clients without coalescer do not share their requests
+	sessionClient = client.New(context.Background(), server.URL, queryValues, http.DefaultClient, "test-context", "default").WithAuthToken("auth-token")
+	atomic.StoreInt32(&hits, 0)
+	getVolumes(3)
+	assert.Equal(t, int32(3), atomic.LoadInt32(&hits))
+}
@@ -58,7 +58,7 @@ func (ss *SnapshotService) ListSnapshots(limit int, start string, filters *model
 
 	if filters != nil {
 		if filters.ResourceGroupID != "" {
-			req.AddQueryValue("resource_group.id", filters.ResourceGroupID)
+			req.AddQueryValue("resource_group.id", filters.ResourceGroupID).ResourceGroup(filters.ResourceGroupID)
 		}
 		if filters.Name != "" {
 			req.AddQueryValue("name", filters.Name)
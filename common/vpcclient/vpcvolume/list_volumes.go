@@ -58,7 +58,7 @@ func (vs *VolumeService) ListVolumes(limit int, start string, filters *models.Li
 
 	if filters != nil {
 		if filters.ResourceGroupID != "" {
-			req.AddQueryValue("resource_group.id", filters.ResourceGroupID)
+			req.AddQueryValue("resource_group.id", filters.ResourceGroupID).ResourceGroup(filters.ResourceGroupID)
 		}
 		if filters.Tag != "" {
 			req.AddQueryValue("tag", filters.Tag)
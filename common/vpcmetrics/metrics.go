@@ -0,0 +1,186 @@
+/**
+ * Copyright 2022 IBM Corp.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package vpcmetrics collects Prometheus metrics of the API calls, retries, waits and token exchanges.
+// Nothing is exposed until the application registers the collector e.g registry.MustRegister(vpcmetrics.Default())
+package vpcmetrics
+
+import (
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Namespace of the metrics
+const Namespace = "ibmcloud_volume_vpc"
+
+// Waits of the volume operations
+const (
+	WaitAttach      = "attach"
+	WaitDetach      = "detach"
+	WaitVolumeReady = "volume_ready"
+)
+
+// Methods of the token exchanges
+const (
+	TokenExchangeRefreshToken = "refresh_token"
+	TokenExchangeAPIKey       = "api_key"
+	TokenExchangeIMSToken     = "ims_token"
+)
+
+// StatusClassError is the status class of the requests that got no response
+const StatusClassError = "error"
+
+// UnknownOperation labels the retries made outside of a session operation
+const UnknownOperation = "unknown"
+
+// Collector holds the metrics of the module, it is a prometheus.Collector
+type Collector struct {
+	requests               *prometheus.CounterVec
+	requestDuration        *prometheus.HistogramVec
+	retryAttempts          *prometheus.CounterVec
+	waitDuration           *prometheus.HistogramVec
+	tokenExchanges         *prometheus.CounterVec
+	tokenExchangesFailures *prometheus.CounterVec
+}
+
+var _ prometheus.Collector = &Collector{}
+
+// NewCollector creates a collector with empty metrics
+func NewCollector() *Collector {
+	return &Collector{
+		requests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: Namespace,
+			Name:      "api_requests_total",
+			Help:      "API requests sent, by operation and HTTP status class.",
+		}, []string{"operation", "status_class"}),
+		requestDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: Namespace,
+			Name:      "api_request_duration_seconds",
+			Help:      "Latency of the API requests, by operation and HTTP status class.",
+			Buckets:   prometheus.ExponentialBuckets(0.05, 2, 10),
+		}, []string{"operation", "status_class"}),
+		retryAttempts: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: Namespace,
+			Name:      "retry_attempts_total",
+			Help:      "Attempts made after a failed one, by session operation.",
+		}, []string{"operation"}),
+		waitDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: Namespace,
+			Name:      "wait_duration_seconds",
+			Help:      "Time spent waiting for attach, detach and volume ready, by result.",
+			Buckets:   prometheus.ExponentialBuckets(1, 2, 12),
+		}, []string{"wait", "result"}),
+		tokenExchanges: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: Namespace,
+			Name:      "token_exchanges_total",
+			Help:      "Token exchanges, by method.",
+		}, []string{"method"}),
+		tokenExchangesFailures: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: Namespace,
+			Name:      "token_exchange_failures_total",
+			Help:      "Failed token exchanges, by method.",
+		}, []string{"method"}),
+	}
+}
+
+// Describe implements prometheus.Collector
+func (c *Collector) Describe(ch chan<- *prometheus.Desc) {
+	c.requests.Describe(ch)
+	c.requestDuration.Describe(ch)
+	c.retryAttempts.Describe(ch)
+	c.waitDuration.Describe(ch)
+	c.tokenExchanges.Describe(ch)
+	c.tokenExchangesFailures.Describe(ch)
+}
+
+// Collect implements prometheus.Collector
+func (c *Collector) Collect(ch chan<- prometheus.Metric) {
+	c.requests.Collect(ch)
+	c.requestDuration.Collect(ch)
+	c.retryAttempts.Collect(ch)
+	c.waitDuration.Collect(ch)
+	c.tokenExchanges.Collect(ch)
+	c.tokenExchangesFailures.Collect(ch)
+}
+
+// ObserveRequest records an API request, statusCode is 0 when no response was received
+func (c *Collector) ObserveRequest(operation string, statusCode int, duration time.Duration) {
+	statusClass := StatusClass(statusCode)
+	c.requests.WithLabelValues(operation, statusClass).Inc()
+	c.requestDuration.WithLabelValues(operation, statusClass).Observe(duration.Seconds())
+}
+
+// RetryAttempt records an attempt made after a failed one
+func (c *Collector) RetryAttempt(operation string) {
+	if operation == "" {
+		operation = UnknownOperation
+	}
+	c.retryAttempts.WithLabelValues(operation).Inc()
+}
+
+// ObserveWait records the time spent in a wait started at start
+func (c *Collector) ObserveWait(wait string, start time.Time, err error) {
+	c.waitDuration.WithLabelValues(wait, result(err)).Observe(time.Since(start).Seconds())
+}
+
+// TokenExchange records a token exchange
+func (c *Collector) TokenExchange(method string, err error) {
+	c.tokenExchanges.WithLabelValues(method).Inc()
+	if err != nil {
+		c.tokenExchangesFailures.WithLabelValues(method).Inc()
+	}
+}
+
+// StatusClass returns the class of an HTTP status e.g 2xx
+func StatusClass(statusCode int) string {
+	if statusCode < 100 || statusCode > 599 {
+		return StatusClassError
+	}
+	return strconv.Itoa(statusCode/100) + "xx"
+}
+
+func result(err error) string {
+	if err != nil {
+		return "failure"
+	}
+	return "success"
+}
+
+var (
+	defaultMu        sync.RWMutex
+	builtIn          = NewCollector()
+	defaultCollector = builtIn
+)
+
+// Default returns the collector the module records to
+func Default() *Collector {
+	defaultMu.RLock()
+	defer defaultMu.RUnlock()
+	return defaultCollector
+}
+
+// SetDefault replaces the collector the module records to, nil goes back to the built-in one
+func SetDefault(c *Collector) {
+	defaultMu.Lock()
+	defer defaultMu.Unlock()
+	if c == nil {
+		c = builtIn
+	}
+	defaultCollector = c
+}
@@ -0,0 +1,103 @@
+/**
+ * Copyright 2022 IBM Corp.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package vpcmetrics ...
+package vpcmetrics
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStatusClass(t *testing.T) {
+	testCases := []struct {
+		statusCode int
+		expected   string
+	}{
+		{statusCode: 200, expected: "2xx"},
+		{statusCode: 204, expected: "2xx"},
+		{statusCode: 404, expected: "4xx"},
+		{statusCode: 503, expected: "5xx"},
+		{statusCode: 0, expected: StatusClassError},
+		{statusCode: 600, expected: StatusClassError},
+	}
+	for _, testcase := range testCases {
+		assert.Equal(t, testcase.expected, StatusClass(testcase.statusCode), testcase.statusCode)
+	}
+}
+
+func TestCollector(t *testing.T) {
+	c := NewCollector()
+	registry := prometheus.NewPedanticRegistry()
+	assert.Nil(t, registry.Register(c))
+
+	c.ObserveRequest("GetVolume", 200, 100*time.Millisecond)
+	c.ObserveRequest("GetVolume", 200, 200*time.Millisecond)
+	c.ObserveRequest("GetVolume", 404, 100*time.Millisecond)
+	c.ObserveRequest("CreateVolume", 0, time.Second)
+	c.RetryAttempt("CreateVolume")
+	c.RetryAttempt("")
+	c.ObserveWait(WaitAttach, time.Now().Add(-3*time.Second), nil)
+	c.ObserveWait(WaitDetach, time.Now(), errors.New("timeout"))
+	c.TokenExchange(TokenExchangeAPIKey, nil)
+	c.TokenExchange(TokenExchangeAPIKey, errors.New("failed"))
+
+	assert.Equal(t, float64(2), testutil.ToFloat64(c.requests.WithLabelValues("GetVolume", "2xx")))
+	assert.Equal(t, float64(1), testutil.ToFloat64(c.requests.WithLabelValues("GetVolume", "4xx")))
+	assert.Equal(t, float64(1), testutil.ToFloat64(c.requests.WithLabelValues("CreateVolume", StatusClassError)))
+	assert.Equal(t, float64(1), testutil.ToFloat64(c.retryAttempts.WithLabelValues("CreateVolume")))
+	assert.Equal(t, float64(1), testutil.ToFloat64(c.retryAttempts.WithLabelValues(UnknownOperation)))
+	assert.Equal(t, float64(2), testutil.ToFloat64(c.tokenExchanges.WithLabelValues(TokenExchangeAPIKey)))
+	assert.Equal(t, float64(1), testutil.ToFloat64(c.tokenExchangesFailures.WithLabelValues(TokenExchangeAPIKey)))
+
+	// Histograms are exposed through the registry
+	families, err := registry.Gather()
+	assert.Nil(t, err)
+	histograms := map[string]*dto.Histogram{}
+	for _, family := range families {
+		for _, metric := range family.GetMetric() {
+			if metric.GetHistogram() == nil {
+				continue
+			}
+			name := family.GetName()
+			for _, label := range metric.GetLabel() {
+				name += " " + label.GetValue()
+			}
+			histograms[name] = metric.GetHistogram()
+		}
+	}
+	assert.Equal(t, 5, len(histograms))
+	assert.Equal(t, uint64(2), histograms["ibmcloud_volume_vpc_api_request_duration_seconds GetVolume 2xx"].GetSampleCount())
+	attach := histograms["ibmcloud_volume_vpc_wait_duration_seconds success attach"]
+	assert.Equal(t, uint64(1), attach.GetSampleCount())
+	assert.True(t, attach.GetSampleSum() >= 3)
+	assert.Equal(t, uint64(1), histograms["ibmcloud_volume_vpc_wait_duration_seconds failure detach"].GetSampleCount())
+}
+
+func TestDefault(t *testing.T) {
+	builtIn := Default()
+	c := NewCollector()
+	SetDefault(c)
+	assert.Equal(t, c, Default())
+	SetDefault(nil)
+	assert.Equal(t, builtIn, Default())
+}
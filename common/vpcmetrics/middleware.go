@@ -0,0 +1,41 @@
+/**
+ * Copyright 2022 IBM Corp.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package vpcmetrics ...
+package vpcmetrics
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/IBM/ibmcloud-volume-vpc/common/vpcclient/client"
+)
+
+// Middleware records the count and latency of the requests in the default collector, by operation name
+func Middleware() client.Middleware {
+	return client.MiddlewareFunc(func(next client.Handler) client.Handler {
+		return func(request *client.Request, httpRequest *http.Request) (*http.Response, error) {
+			start := time.Now()
+			resp, err := next(request, httpRequest)
+			statusCode := 0
+			if err == nil && resp != nil {
+				statusCode = resp.StatusCode
+			}
+			Default().ObserveRequest(request.Operation().Name, statusCode, time.Since(start))
+			return resp, err
+		}
+	})
+}
@@ -0,0 +1,57 @@
+/**
+ * Copyright 2022 IBM Corp.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package vpcmetrics ...
+package vpcmetrics
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/IBM/ibmcloud-volume-vpc/common/vpcclient/client"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMiddleware(t *testing.T) {
+	c := NewCollector()
+	SetDefault(c)
+	defer SetDefault(nil)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/volumes/missing" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	vpcClient := client.New(context.Background(), server.URL, nil, http.DefaultClient, "test-context", "default").WithAuthToken("auth-token").WithMiddleware(Middleware())
+	operation := &client.Operation{Name: "GetVolume", Method: http.MethodGet, PathPattern: "/volumes/{volume-id}"}
+	for _, volumeID := range []string{"volume-1", "volume-2", "missing"} {
+		_, _ = vpcClient.NewRequest(operation).PathParameter("volume-id", volumeID).Invoke()
+	}
+
+	// No response without auth token
+	_, _ = client.New(context.Background(), server.URL, nil, http.DefaultClient, "test-context", "default").WithMiddleware(Middleware()).NewRequest(operation).Invoke()
+
+	assert.Equal(t, float64(2), testutil.ToFloat64(c.requests.WithLabelValues("GetVolume", "2xx")))
+	assert.Equal(t, float64(1), testutil.ToFloat64(c.requests.WithLabelValues("GetVolume", "4xx")))
+	assert.Equal(t, float64(1), testutil.ToFloat64(c.requests.WithLabelValues("GetVolume", StatusClassError)))
+}
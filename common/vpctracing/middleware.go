@@ -0,0 +1,94 @@
+/**
+ * Copyright 2022 IBM Corp.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package vpctracing ...
+package vpctracing
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"net/http"
+
+	"github.com/IBM/ibmcloud-volume-vpc/common/vpcclient/client"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	semconv "go.opentelemetry.io/otel/semconv/v1.12.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Middleware creates a client span for each request and propagates the W3C trace context in its headers.
+// The span is a child of the span in the request context, see client.SessionClient.WithContext.
+func Middleware() client.Middleware {
+	return client.MiddlewareFunc(func(next client.Handler) client.Handler {
+		return func(request *client.Request, httpRequest *http.Request) (*http.Response, error) {
+			ctx, span := Tracer().Start(httpRequest.Context(), request.Operation().Name,
+				trace.WithSpanKind(trace.SpanKindClient),
+				trace.WithAttributes(
+					OperationKey.String(request.Operation().Name),
+					semconv.HTTPMethodKey.String(httpRequest.Method),
+					semconv.HTTPURLKey.String(httpRequest.URL.String()),
+				))
+			propagation.TraceContext{}.Inject(ctx, propagation.HeaderCarrier(httpRequest.Header))
+
+			resp, err := next(request, httpRequest.WithContext(ctx))
+			if err != nil {
+				End(span, err)
+				return resp, err
+			}
+			span.SetAttributes(semconv.HTTPStatusCodeKey.Int(resp.StatusCode))
+			if resp.StatusCode >= http.StatusBadRequest {
+				span.SetStatus(codes.Error, resp.Status)
+				if traceCode := peekTraceCode(resp); traceCode != "" {
+					span.SetAttributes(TraceCodeKey.String(traceCode))
+				}
+			}
+			span.End()
+			return resp, nil
+		}
+	})
+}
+
+// maxErrorBodySize is the size of the error body read for the trace code
+const maxErrorBodySize = 64 * 1024
+
+// peekTraceCode reads the trace code from the error body and puts the body back for the error consumer
+func peekTraceCode(resp *http.Response) string {
+	if resp.Body == nil {
+		return ""
+	}
+	body, err := ioutil.ReadAll(io.LimitReader(resp.Body, maxErrorBodySize))
+	resp.Body = struct {
+		io.Reader
+		io.Closer
+	}{io.MultiReader(bytes.NewReader(body), resp.Body), resp.Body}
+	if err != nil {
+		return ""
+	}
+
+	var errorBody struct {
+		Trace      string `json:"trace"`
+		IncidentID string `json:"incidentID"`
+	}
+	if json.Unmarshal(body, &errorBody) != nil {
+		return ""
+	}
+	if errorBody.Trace != "" {
+		return errorBody.Trace
+	}
+	return errorBody.IncidentID
+}
@@ -0,0 +1,127 @@
+/**
+ * Copyright 2022 IBM Corp.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package vpctracing traces the volume operations with OpenTelemetry. Spans go to the global tracer
+// provider, which is a no-op unless the application sets one, or to the provider set by SetTracerProvider.
+package vpctracing
+
+import (
+	"context"
+	"errors"
+	"sync"
+
+	"github.com/IBM/ibmcloud-volume-vpc/common/vpcclient/models"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// InstrumentationName is the name of the tracer
+const InstrumentationName = "github.com/IBM/ibmcloud-volume-vpc"
+
+// Attribute keys of the spans
+const (
+	OperationKey  = attribute.Key("vpc.operation")
+	VolumeIDKey   = attribute.Key("vpc.volume.id")
+	InstanceIDKey = attribute.Key("vpc.instance.id")
+	SnapshotIDKey = attribute.Key("vpc.snapshot.id")
+	AttemptKey    = attribute.Key("vpc.retry.attempt")
+	TraceCodeKey  = attribute.Key("vpc.trace_code")
+)
+
+var (
+	providerMu     sync.RWMutex
+	tracerProvider trace.TracerProvider
+)
+
+// SetTracerProvider sets the tracer provider of the module, nil goes back to the global one
+func SetTracerProvider(tp trace.TracerProvider) {
+	providerMu.Lock()
+	defer providerMu.Unlock()
+	tracerProvider = tp
+}
+
+// Tracer returns the tracer of the module
+func Tracer() trace.Tracer {
+	providerMu.RLock()
+	defer providerMu.RUnlock()
+	if tracerProvider != nil {
+		return tracerProvider.Tracer(InstrumentationName)
+	}
+	return otel.GetTracerProvider().Tracer(InstrumentationName)
+}
+
+// End records the error, if any, and ends the span
+func End(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		if traceCode := TraceCode(err); traceCode != "" {
+			span.SetAttributes(TraceCodeKey.String(traceCode))
+		}
+	}
+	span.End()
+}
+
+// TraceCode returns the trace code of a VPC error or the incident ID of an IKS error
+func TraceCode(err error) string {
+	var vpcErr *models.Error
+	if errors.As(err, &vpcErr) {
+		return vpcErr.Trace
+	}
+	var iksErr *models.IksError
+	if errors.As(err, &iksErr) {
+		return iksErr.ReqID
+	}
+	return ""
+}
+
+// operationKey is the context key of the operation of the span in the context
+type operationKey struct{}
+
+// Start starts a child span of the span in the context and returns the context carrying it, the returned
+// function ends it. Each operation passes its own context down, so that concurrent operations of a session
+// keep their spans apart. A nil context does not trace.
+func Start(ctx context.Context, name string, attrs ...attribute.KeyValue) (context.Context, func(err error)) {
+	if ctx == nil {
+		return nil, func(error) {}
+	}
+	ctx, span := Tracer().Start(ctx, name, trace.WithAttributes(attrs...))
+	for _, attr := range attrs {
+		if attr.Key == OperationKey {
+			ctx = context.WithValue(ctx, operationKey{}, attr.Value.AsString())
+		}
+	}
+	return ctx, func(err error) { End(span, err) }
+}
+
+// Operation returns the operation of the span in the context, set by the OperationKey attribute of it or of an ancestor
+func Operation(ctx context.Context) string {
+	if ctx == nil {
+		return ""
+	}
+	operation, _ := ctx.Value(operationKey{}).(string)
+	return operation
+}
+
+// SetAttributes sets attributes on the span in the context
+func SetAttributes(ctx context.Context, attrs ...attribute.KeyValue) {
+	if ctx == nil {
+		return
+	}
+	trace.SpanFromContext(ctx).SetAttributes(attrs...)
+}
@@ -0,0 +1,133 @@
+/**
+ * Copyright 2022 IBM Corp.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package vpctracing ...
+package vpctracing
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/IBM/ibmcloud-volume-vpc/common/vpcclient/client"
+	"github.com/IBM/ibmcloud-volume-vpc/common/vpcclient/models"
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+// useInMemoryExporter is test.UseInMemoryExporter, which this package cannot import
+func useInMemoryExporter() *tracetest.InMemoryExporter {
+	exporter := tracetest.NewInMemoryExporter()
+	SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter)))
+	return exporter
+}
+
+func attributeValue(span tracetest.SpanStub, key attribute.Key) attribute.Value {
+	for _, kv := range span.Attributes {
+		if kv.Key == key {
+			return kv.Value
+		}
+	}
+	return attribute.Value{}
+}
+
+func TestStart(t *testing.T) {
+	exporter := useInMemoryExporter()
+	defer SetTracerProvider(nil)
+
+	ctx, endOperation := Start(context.Background(), "VPCSession.GetVolume", OperationKey.String("GetVolume"), VolumeIDKey.String("volume-1"))
+	attemptCtx, endAttempt := Start(ctx, "attempt", AttemptKey.Int(1))
+	assert.Equal(t, "GetVolume", Operation(attemptCtx))
+	SetAttributes(attemptCtx, InstanceIDKey.String("instance-1"))
+
+	// Concurrent operations do not share their spans
+	otherCtx, endOther := Start(context.Background(), "VPCSession.GetSnapshot", OperationKey.String("GetSnapshot"))
+	assert.Equal(t, "GetSnapshot", Operation(otherCtx))
+	assert.Equal(t, "GetVolume", Operation(attemptCtx))
+	endOther(nil)
+
+	endAttempt(&models.Error{Trace: "trace-1", Errors: []models.ErrorItem{{Message: "failed"}}})
+	endOperation(nil)
+	assert.Equal(t, "", Operation(context.Background()))
+
+	spans := exporter.GetSpans()
+	if assert.Equal(t, 3, len(spans)) {
+		other, attempt, operation := spans[0], spans[1], spans[2]
+		assert.Equal(t, "attempt", attempt.Name)
+		assert.Equal(t, operation.SpanContext.SpanID(), attempt.Parent.SpanID())
+		assert.False(t, other.Parent.IsValid())
+		assert.Equal(t, codes.Error, attempt.Status.Code)
+		assert.Equal(t, "trace-1", attributeValue(attempt, TraceCodeKey).AsString())
+		assert.Equal(t, "instance-1", attributeValue(attempt, InstanceIDKey).AsString())
+		assert.Equal(t, "volume-1", attributeValue(operation, VolumeIDKey).AsString())
+		assert.Equal(t, codes.Unset, operation.Status.Code)
+	}
+
+	// Nil context does not trace
+	noCtx, end := Start(nil, "not-traced") //nolint:staticcheck
+	end(errors.New("failed"))
+	SetAttributes(noCtx, VolumeIDKey.String("volume-1"))
+	assert.Equal(t, "", Operation(noCtx))
+	assert.Equal(t, 3, len(exporter.GetSpans()))
+}
+
+func TestTraceCode(t *testing.T) {
+	assert.Equal(t, "trace-1", TraceCode(&models.Error{Trace: "trace-1"}))
+	assert.Equal(t, "incident-1", TraceCode(fmt.Errorf("wrapped: %w", &models.IksError{ReqID: "incident-1"})))
+	assert.Equal(t, "", TraceCode(errors.New("failed")))
+}
+
+func TestMiddleware(t *testing.T) {
+	exporter := useInMemoryExporter()
+	defer SetTracerProvider(nil)
+
+	var traceparent string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		traceparent = r.Header.Get("traceparent")
+		w.WriteHeader(http.StatusNotFound)
+		fmt.Fprint(w, `{"errors":[{"message":"Volume not found","code":"not_found"}],"trace":"trace-2"}`)
+	}))
+	defer server.Close()
+
+	ctx, end := Start(context.Background(), "VPCSession.GetVolume")
+	c := client.New(context.Background(), server.URL, nil, http.DefaultClient, "test-context", "default").WithAuthToken("auth-token").WithMiddleware(Middleware()).WithContext(ctx)
+
+	var errResult models.Error
+	_, err := c.NewRequest(&client.Operation{Name: "GetVolume", Method: http.MethodGet, PathPattern: "/volumes/volume-1"}).JSONError(&errResult).Invoke()
+	end(err)
+
+	// Error body is still consumed after the trace code was read
+	assert.EqualError(t, err, "Trace Code:trace-2, Volume not found Please check ")
+
+	spans := exporter.GetSpans()
+	if assert.Equal(t, 2, len(spans)) {
+		request, operation := spans[0], spans[1]
+		assert.Equal(t, "GetVolume", request.Name)
+		assert.Equal(t, operation.SpanContext.SpanID(), request.Parent.SpanID())
+		assert.Equal(t, int64(http.StatusNotFound), attributeValue(request, "http.status_code").AsInt64())
+		assert.Equal(t, "trace-2", attributeValue(request, TraceCodeKey).AsString())
+		assert.Equal(t, codes.Error, request.Status.Code)
+
+		// W3C trace context of the request span is propagated
+		assert.Equal(t, fmt.Sprintf("00-%s-%s-01", request.SpanContext.TraceID(), request.SpanContext.SpanID()), traceparent)
+	}
+}
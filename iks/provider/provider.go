@@ -71,7 +71,7 @@ func NewProvider(conf *vpcconfig.VPCBlockConfig, k8sClient *k8s_utils.Kubernetes
 		iksBlockProvider: iksBlockProvider,
 	}
 
-	iksVpcBlockProvider.iksBlockProvider.ContextCF, err = vpcauth.NewVPCContextCredentialsFactory(iksVpcBlockProvider.vpcBlockProvider.Config, k8sClient)
+	iksVpcBlockProvider.iksBlockProvider.ContextCF, err = vpcauth.NewVPCContextCredentialsFactory(iksVpcBlockProvider.vpcBlockProvider.Config, k8sClient, iksVpcBlockProvider.vpcBlockProvider.APIConfig.HTTPClient)
 	if err != nil {
 		logger.Error("Error initializing context credentials factory", zap.Error(err))
 		return nil, err
@@ -87,10 +87,15 @@ func (iksp *IksVpcBlockProvider) OpenSession(ctx context.Context, contextCredent
 	defer func() {
 		ctxLogger.Debug("Exiting IksVpcBlockProvider.OpenSession")
 	}()
+	// Read cache, HTTP log, session pool, rate limit and circuit breaker of the dual provider apply to both the sessions.
+	// They are set on copies of the inner providers, which are shared by the concurrent sessions.
+	vpcBlockProvider := iksp.sessionProvider(iksp.vpcBlockProvider, iksp.vpcBlockProvider.ClientProvider)
+	iksBlockProvider := iksp.sessionProvider(iksp.iksBlockProvider, riaas.IKSRegionalAPIClientProvider{})
+
 	ctxLogger.Info("Opening VPC block session")
-	ccf, _ := iksp.vpcBlockProvider.ContextCredentialsFactory(nil)
+	ccf, _ := vpcBlockProvider.ContextCredentialsFactory(nil)
 	ctxLogger.Info("Its IKS dual session. Getttng IAM token for  VPC block session")
-	vpcContextCredentials, err := ccf.ForIAMAccessToken(iksp.iksBlockProvider.Config.VPCConfig.G2APIKey, ctxLogger)
+	vpcContextCredentials, err := ccf.ForIAMAccessToken(iksp.iksBlockProvider.APIKey(), ctxLogger)
 	if err != nil {
 		ctxLogger.Error("Error occurred while generating IAM token for VPC", zap.Error(err))
 		if util.ErrorReasonCode(err) == utilReasonCode.EndpointNotReachable {
@@ -103,7 +108,7 @@ func (iksp *IksVpcBlockProvider) OpenSession(ctx context.Context, contextCredent
 		}
 		return nil, err
 	}
-	session, err := iksp.vpcBlockProvider.OpenSession(ctx, vpcContextCredentials, ctxLogger)
+	session, err := vpcBlockProvider.OpenSession(ctx, vpcContextCredentials, ctxLogger)
 	if err != nil {
 		ctxLogger.Error("Error occurred while opening VPCSession", zap.Error(err))
 		return nil, err
@@ -111,11 +116,10 @@ func (iksp *IksVpcBlockProvider) OpenSession(ctx context.Context, contextCredent
 	vpcSession, _ := session.(*vpcprovider.VPCSession)
 	ctxLogger.Info("Opening IKS block session")
 
-	ccf = iksp.iksBlockProvider.ContextCF
-	iksp.iksBlockProvider.ClientProvider = riaas.IKSRegionalAPIClientProvider{}
+	ccf, _ = iksBlockProvider.ContextCredentialsFactory(nil)
 
 	ctxLogger.Info("Its ISK dual session. Getttng IAM token for  IKS block session")
-	iksContextCredentials, err := ccf.ForIAMAccessToken(iksp.iksBlockProvider.Config.VPCConfig.G2APIKey, ctxLogger)
+	iksContextCredentials, err := ccf.ForIAMAccessToken(iksp.iksBlockProvider.APIKey(), ctxLogger)
 	if err != nil {
 		ctxLogger.Warn("Error occurred while generating IAM token for IKS. But continue with VPC session alone. \n Volume Mount operation will fail but volume provisioning will work", zap.Error(err))
 		session = &vpcprovider.VPCSession{
@@ -123,7 +127,7 @@ func (iksp *IksVpcBlockProvider) OpenSession(ctx context.Context, contextCredent
 			SessionError: err,
 		} // Empty session to avoid Nil references.
 	} else {
-		session, err = iksp.iksBlockProvider.OpenSession(ctx, iksContextCredentials, ctxLogger)
+		session, err = iksBlockProvider.OpenSession(ctx, iksContextCredentials, ctxLogger)
 		if err != nil {
 			ctxLogger.Error("Error occurred while opening IKSSession", zap.Error(err))
 		}
@@ -131,18 +135,60 @@ func (iksp *IksVpcBlockProvider) OpenSession(ctx context.Context, contextCredent
 
 	iksSession, ok := session.(*vpcprovider.VPCSession)
 	if ok && iksSession.Apiclient != nil {
-		iksSession.APIClientVolAttachMgr = iksSession.Apiclient.IKSVolumeAttachService()
+		iksSession.UseIKSVolumeAttachService()
 	}
 	// Setup Dual Session that handles for VPC and IKS connections
 	vpcIksSession := IksVpcSession{
 		VPCSession: *vpcSession,
 		IksSession: iksSession,
 	}
+	// Volume lease tags are managed through the VPC session, see IksVpcSession.AttachVolume
+	vpcIksSession.VolumeLease = iksp.VolumeLease
+	if iksSession != nil {
+		iksSession.VolumeLease = nil
+		iksSession.BatchWorkers = iksp.BatchWorkers
+	}
 	ctxLogger.Debug("IksVpcSession", zap.Reflect("IksVpcSession", vpcIksSession))
 	return &vpcIksSession, nil
 }
 
+// sessionProvider returns a copy of the inner provider using the settings of the dual provider and clientProvider
+func (iksp *IksVpcBlockProvider) sessionProvider(inner *vpcprovider.VPCBlockProvider, clientProvider riaas.RegionalAPIClientProvider) *vpcprovider.VPCBlockProvider {
+	derived := *inner
+	derived.ClientProvider = clientProvider
+	derived.ReadCache = iksp.ReadCache
+	derived.VolumePoller = iksp.VolumePoller
+	derived.HTTPLog = iksp.HTTPLog
+	derived.SessionPool = iksp.SessionPool
+	derived.APIConfig.RateLimit = iksp.APIConfig.RateLimit
+	derived.APIConfig.CircuitBreaker = iksp.APIConfig.CircuitBreaker
+	return &derived
+}
+
 // ContextCredentialsFactory ...
 func (iksp *IksVpcBlockProvider) ContextCredentialsFactory(zone *string) (local.ContextCredentialsFactory, error) {
-	return iksp.iksBlockProvider.ContextCF, nil
+	return iksp.iksBlockProvider.ContextCredentialsFactory(zone)
+}
+
+// ReloadCredentials swaps the credentials of both the VPC and IKS providers with the ones of the config data,
+// once they are validated for both. The current credentials are kept if any validation fails.
+func (iksp *IksVpcBlockProvider) ReloadCredentials(data string, logger *zap.Logger) error {
+	vpcCredentials, err := iksp.vpcBlockProvider.NewCredentials(data, logger)
+	if err != nil {
+		logger.Error("Failed to validate the rotated credentials of the VPC provider", zap.Error(err))
+		return err
+	}
+	iksCredentials, err := iksp.iksBlockProvider.NewCredentials(data, logger)
+	if err != nil {
+		logger.Error("Failed to validate the rotated credentials of the IKS provider", zap.Error(err))
+		return err
+	}
+	if err = iksp.vpcBlockProvider.SetCredentials(vpcCredentials); err != nil {
+		return err
+	}
+	if err = iksp.iksBlockProvider.SetCredentials(iksCredentials); err != nil {
+		return err
+	}
+	logger.Info("Reloaded the VPC and IKS provider credentials")
+	return nil
 }
@@ -27,6 +27,7 @@ import (
 	"github.com/IBM/ibmcloud-volume-interface/provider/auth"
 	"github.com/IBM/ibmcloud-volume-interface/provider/local"
 	vpcconfig "github.com/IBM/ibmcloud-volume-vpc/block/vpcconfig"
+	userError "github.com/IBM/ibmcloud-volume-vpc/common/messages"
 	"github.com/IBM/secret-utils-lib/pkg/k8s_utils"
 	"github.com/stretchr/testify/assert"
 	"go.uber.org/zap"
@@ -106,3 +107,40 @@ func TestNewProvider(t *testing.T) {
 	assert.Nil(t, err)
 	assert.NotNil(t, prov)
 }
+
+func TestReloadCredentials(t *testing.T) {
+	conf := &vpcconfig.VPCBlockConfig{
+		ServerConfig: &config.ServerConfig{
+			DebugTrace: true,
+		},
+		VPCConfig: &config.VPCProviderConfig{
+			Enabled:         false,
+			EndpointURL:     TestEndpointURL,
+			VPCTimeout:      "30s",
+			G2APIKey:        "api-key",
+			IamClientID:     IamClientID,
+			IamClientSecret: IamClientSecret,
+		},
+	}
+	logger, teardown := GetTestLogger(t)
+	defer teardown()
+	userError.MessagesEn = userError.InitMessages()
+
+	kc, _ := k8s_utils.FakeGetk8sClientSet()
+	pwd, _ := os.Getwd()
+	file := filepath.Join(pwd, "..", "..", "etc", "libconfig.toml")
+	_ = k8s_utils.FakeCreateSecret(kc, "DEFAULT", file)
+	prov, err := NewProvider(conf, &kc, logger)
+	assert.Nil(t, err)
+	iksp := prov.(*IksVpcBlockProvider)
+	ccf, _ := iksp.ContextCredentialsFactory(nil)
+	assert.NotNil(t, ccf)
+
+	// Invalid data keeps the credentials of both providers
+	err = iksp.ReloadCredentials("[vpc]\n  g2_api_key = \"\"\n", logger)
+	assert.Equal(t, userError.CredentialsReloadFailed, userError.GetUserErrorCode(err))
+	assert.Equal(t, "api-key", iksp.vpcBlockProvider.APIKey())
+	assert.Equal(t, "api-key", iksp.iksBlockProvider.APIKey())
+	reloadedCCF, _ := iksp.ContextCredentialsFactory(nil)
+	assert.Equal(t, ccf, reloadedCCF)
+}
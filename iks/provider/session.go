@@ -63,14 +63,26 @@ func (vpcIks *IksVpcSession) Type() provider.VolumeType {
 func (vpcIks *IksVpcSession) AttachVolume(volumeAttachmentRequest provider.VolumeAttachmentRequest) (*provider.VolumeAttachmentResponse, error) {
 	vpcIks.Logger.Debug("Entry of IksVpcSession.AttachVolume method...")
 	defer vpcIks.Logger.Debug("Exit from IksVpcSession.AttachVolume method...")
-	return vpcIks.IksSession.AttachVolume(volumeAttachmentRequest)
+	lease, err := vpcIks.acquireVolumeLease(volumeAttachmentRequest.VolumeID)
+	if err != nil {
+		return nil, err
+	}
+	response, err := vpcIks.IksSession.AttachVolume(volumeAttachmentRequest)
+	vpcIks.holdVolumeLease(lease, err)
+	return response, err
 }
 
 // DetachVolume attach volume based on given volume attachment request
 func (vpcIks *IksVpcSession) DetachVolume(volumeAttachmentRequest provider.VolumeAttachmentRequest) (*http.Response, error) {
 	vpcIks.IksSession.Logger.Debug("Entry of IksVpcSession.DetachVolume method...")
 	defer vpcIks.Logger.Debug("Exit from IksVpcSession.DetachVolume method...")
-	return vpcIks.IksSession.DetachVolume(volumeAttachmentRequest)
+	lease, err := vpcIks.acquireVolumeLease(volumeAttachmentRequest.VolumeID)
+	if err != nil {
+		return nil, err
+	}
+	response, err := vpcIks.IksSession.DetachVolume(volumeAttachmentRequest) //nolint:bodyclose
+	vpcIks.holdVolumeLease(lease, err)
+	return response, err
 }
 
 // GetVolumeAttachment attach volume based on given volume attachment request
@@ -84,6 +96,7 @@ func (vpcIks *IksVpcSession) GetVolumeAttachment(volumeAttachmentRequest provide
 func (vpcIks *IksVpcSession) WaitForAttachVolume(volumeAttachmentRequest provider.VolumeAttachmentRequest) (*provider.VolumeAttachmentResponse, error) {
 	vpcIks.Logger.Debug("Entry of IksVpcSession.WaitForAttachVolume method...")
 	defer vpcIks.Logger.Debug("Exit from IksVpcSession.WaitForAttachVolume method...")
+	defer vpcIks.VPCSession.ReleaseVolumeLease(volumeAttachmentRequest.VolumeID)
 	return vpcIks.IksSession.WaitForAttachVolume(volumeAttachmentRequest)
 }
 
@@ -91,5 +104,67 @@ func (vpcIks *IksVpcSession) WaitForAttachVolume(volumeAttachmentRequest provide
 func (vpcIks *IksVpcSession) WaitForDetachVolume(volumeAttachmentRequest provider.VolumeAttachmentRequest) error {
 	vpcIks.Logger.Debug("Entry of IksVpcSession.WaitForDetachVolume method...")
 	defer vpcIks.Logger.Debug("Exit from IksVpcSession.WaitForDetachVolume method...")
+	defer vpcIks.VPCSession.ReleaseVolumeLease(volumeAttachmentRequest.VolumeID)
 	return vpcIks.IksSession.WaitForDetachVolume(volumeAttachmentRequest)
 }
+
+// AttachVolumes attaches many volumes through the IKS session and waits till all of them are attached
+func (vpcIks *IksVpcSession) AttachVolumes(volumeAttachmentRequests []provider.VolumeAttachmentRequest) []vpcprovider.VolumeAttachmentResult {
+	vpcIks.Logger.Debug("Entry of IksVpcSession.AttachVolumes method...")
+	defer vpcIks.Logger.Debug("Exit from IksVpcSession.AttachVolumes method...")
+	return vpcIks.runLeasedBatch(volumeAttachmentRequests, vpcIks.IksSession.AttachVolumes)
+}
+
+// DetachVolumes detaches many volumes through the IKS session and waits till all of them are detached
+func (vpcIks *IksVpcSession) DetachVolumes(volumeAttachmentRequests []provider.VolumeAttachmentRequest) []vpcprovider.VolumeAttachmentResult {
+	vpcIks.Logger.Debug("Entry of IksVpcSession.DetachVolumes method...")
+	defer vpcIks.Logger.Debug("Exit from IksVpcSession.DetachVolumes method...")
+	return vpcIks.runLeasedBatch(volumeAttachmentRequests, vpcIks.IksSession.DetachVolumes)
+}
+
+// runLeasedBatch acquires the volume leases (if enabled) of the batch, items whose lease could not be
+// acquired are not passed to the IKS session. As for the VPC batch, the leases acquired by the batch are
+// held from the attach or detach of the items till the end of the batch wait, then only they are released.
+func (vpcIks *IksVpcSession) runLeasedBatch(volumeAttachmentRequests []provider.VolumeAttachmentRequest, runBatch func([]provider.VolumeAttachmentRequest) []vpcprovider.VolumeAttachmentResult) []vpcprovider.VolumeAttachmentResult {
+	results := make([]vpcprovider.VolumeAttachmentResult, len(volumeAttachmentRequests))
+	var leased []provider.VolumeAttachmentRequest
+	var leasedIndex []int
+	var leases []*vpcprovider.VolumeLease
+	for i, request := range volumeAttachmentRequests {
+		results[i].Request = request
+		lease, err := vpcIks.acquireVolumeLease(request.VolumeID)
+		if err != nil {
+			results[i].Error = err
+			continue
+		}
+		leases = append(leases, lease)
+		leased = append(leased, request)
+		leasedIndex = append(leasedIndex, i)
+	}
+	for j, result := range runBatch(leased) {
+		results[leasedIndex[j]] = result
+	}
+	for _, lease := range leases {
+		lease.Release()
+	}
+	return results
+}
+
+// holdVolumeLease keeps the lease of a successful attach or detach till the end of its wait,
+// the lease is released right away if the request failed
+func (vpcIks *IksVpcSession) holdVolumeLease(lease *vpcprovider.VolumeLease, err error) {
+	if err != nil {
+		lease.Release()
+		return
+	}
+	vpcIks.VPCSession.HoldVolumeLease(lease)
+}
+
+// acquireVolumeLease acquires the volume lease through the VPC session, as lease tags are
+// not supported by the IKS storage API. The returned lease may be nil if leases are disabled.
+func (vpcIks *IksVpcSession) acquireVolumeLease(volumeID string) (*vpcprovider.VolumeLease, error) {
+	if vpcIks.VolumeLease == nil {
+		return nil, nil
+	}
+	return vpcIks.VPCSession.AcquireVolumeLease(volumeID, *vpcIks.VolumeLease)
+}
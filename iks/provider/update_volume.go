@@ -52,7 +52,7 @@ func (vpcIks *IksVpcSession) UpdateVolume(volumeRequest provider.Volume) (err er
 
 	if err != nil {
 		vpcIks.Logger.Debug("Failed to update volume", zap.Reflect("BackendError", err))
-		return userError.GetUserError("UpdateFailed", err)
+		return vpc_provider.GetBackendUserError("UpdateFailed", err)
 	}
 
 	return err